@@ -0,0 +1,118 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchmath
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{10, 11, 12, 11, 10, 50}, nil, nil)
+	if want := []float64{10, 11, 12, 11, 10}; !reflect.DeepEqual(s.RValues, want) {
+		t.Errorf("RValues = %v, want %v", s.RValues, want)
+	}
+	if want := []float64{50}; !reflect.DeepEqual(s.Outliers, want) {
+		t.Errorf("Outliers = %v, want %v", s.Outliers, want)
+	}
+	if s.Min != 10 || s.Max != 12 || s.Center != 10.8 {
+		t.Errorf("Min, Max, Center = %v, %v, %v, want 10, 12, 10.8", s.Min, s.Max, s.Center)
+	}
+	if v := s.Variation(); math.Abs(v-(12/10.8-1)) > 1e-12 {
+		t.Errorf("Variation = %v, want %v", v, 12/10.8-1)
+	}
+
+	s = Summarize([]float64{10, 11, 12, 11, 10, 50}, NoOutliers, MedianCenter)
+	if len(s.RValues) != 6 || s.Center != 11 {
+		t.Errorf("without outliers: %d values, center %v, want 6, 11", len(s.RValues), s.Center)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		center Center
+		want   float64
+	}{
+		{"min", MinCenter, 10},
+		{"max", MaxCenter, 12},
+		{"p0", PercentileCenter(0), 10},
+		{"p100", PercentileCenter(1), 12},
+	} {
+		if s := Summarize([]float64{10, 11, 12, 11, 10, 50}, nil, tt.center); s.Center != tt.want {
+			t.Errorf("%s: center %v, want %v", tt.name, s.Center, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	summarize := func(values ...float64) *Summary { return Summarize(values, nil, nil) }
+	for _, tt := range []struct {
+		name                                  string
+		old, new                              *Summary
+		test                                  Test
+		significant, exact, rounding, underpw bool
+	}{
+		{"slower", summarize(100, 101, 102, 103, 104), summarize(110, 111, 112, 113, 114), UTest, true, false, false, false},
+		{"same", summarize(100, 104, 102, 103, 101), summarize(101, 103, 100, 104, 102), UTest, false, false, false, false},
+		{"few", summarize(100, 101), summarize(110, 111), UTest, false, false, false, true},
+		{"exact", summarize(3, 3, 3), summarize(4, 4, 4), UTest, true, true, false, false},
+		{"rounding", summarize(3, 4, 3, 4), summarize(4, 4, 3, 4), UTest, false, false, true, false},
+		{"notest", summarize(100, 101), summarize(110, 111), NoTest, true, false, false, false},
+	} {
+		c := Compare(tt.old, tt.new, tt.test, 0.05)
+		if c.Significant != tt.significant || c.Exact != tt.exact || c.Rounding != tt.rounding || c.Underpowered != tt.underpw {
+			t.Errorf("%s: Compare = %+v, want significant %v, exact %v, rounding %v, underpowered %v", tt.name, c, tt.significant, tt.exact, tt.rounding, tt.underpw)
+		}
+		if c.Ratio != tt.new.Center/tt.old.Center {
+			t.Errorf("%s: Ratio = %v, want %v", tt.name, c.Ratio, tt.new.Center/tt.old.Center)
+		}
+	}
+
+	c := Compare(Summarize([]float64{100, 101, 102, 103, 104}, nil, nil), Summarize([]float64{110, 111, 112, 113, 114}, nil, nil), UTest, 0.05)
+	if !(c.Lo < c.Ratio && c.Ratio < c.Hi) || c.P < 0 || c.P >= 0.05 {
+		t.Errorf("Compare = %+v, want ratio within interval and p < 0.05", c)
+	}
+	// Every difference of logs is near log(1.1), so the median is too.
+	if !(c.Lo <= c.Estimate && c.Estimate <= c.Hi) || math.Abs(c.Estimate-1.1) > 0.01 {
+		t.Errorf("Compare = %+v, want estimate about 1.1 within interval", c)
+	}
+}
+
+func TestTTestMoments(t *testing.T) {
+	old, new := []float64{100, 101, 102, 103, 104}, []float64{103, 105, 107, 109}
+	want, err := TTest(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := TTestMoments(SampleMoments(old), SampleMoments(new))
+	if err != nil || math.Abs(got-want) > 1e-12 {
+		t.Errorf("TTestMoments = %v, %v, want %v", got, err, want)
+	}
+	if _, err := TTestMoments(Moments{N: 1, Mean: 1}, SampleMoments(new)); err != ErrSampleSize {
+		t.Errorf("TTestMoments of one value: err = %v, want %v", err, ErrSampleSize)
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	values := []float64{4, 1, 3, 2, 5}
+	if q := Quantile(values, 0.5); q != 3 {
+		t.Errorf("Quantile(0.5) = %v, want 3", q)
+	}
+	if q := Quantile(values, 1); q != 5 {
+		t.Errorf("Quantile(1) = %v, want 5", q)
+	}
+	if q := Quantile(nil, 0.5); !math.IsNaN(q) {
+		t.Errorf("Quantile of no values = %v, want NaN", q)
+	}
+	if p := CDF(values, 2.5); p != 0.4 {
+		t.Errorf("CDF(2.5) = %v, want 0.4", p)
+	}
+	if have, want := Histogram(values, 1, 5, 4), []int{1, 1, 1, 2}; !reflect.DeepEqual(have, want) {
+		t.Errorf("Histogram = %v, want %v", have, want)
+	}
+	if have, want := Histogram(values, 2, 2, 3), []int{0, 1, 0}; !reflect.DeepEqual(have, want) {
+		t.Errorf("Histogram of one value = %v, want %v", have, want)
+	}
+}
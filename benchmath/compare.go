@@ -0,0 +1,261 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchmath
+
+import (
+	"errors"
+	"math"
+
+	"golang.org/x/perf/internal/stats"
+)
+
+// A Test compares two samples and returns the probability that they
+// are drawn from the same distribution, or an error explaining why it
+// cannot. Common errors are ErrSamplesEqual, ErrSampleSize, and
+// ErrZeroVariance. As a special case, NoTest returns -1, nil.
+type Test func(old, new []float64) (p float64, err error)
+
+// Errors returned by a Test.
+var (
+	ErrSamplesEqual = errors.New("all equal")
+	ErrSampleSize   = errors.New("too few samples")
+	ErrZeroVariance = errors.New("zero variance")
+)
+
+// NoTest applies no test; it returns -1, nil.
+func NoTest(old, new []float64) (p float64, err error) {
+	return -1, nil
+}
+
+// TTest is a Test using the two-sample Welch t-test.
+func TTest(old, new []float64) (p float64, err error) {
+	t, err := stats.TwoSampleWelchTTest(stats.Sample{Xs: old}, stats.Sample{Xs: new}, stats.LocationDiffers)
+	if err != nil {
+		return -1, convertErr(err)
+	}
+	return t.P, nil
+}
+
+// Moments describe a sample by its size, mean, and variance alone,
+// as a summary of the sample written by benchstat does.
+type Moments struct {
+	N        int
+	Mean     float64
+	Variance float64 // sample variance, with N-1 degrees of freedom
+}
+
+// SampleMoments returns the Moments of values. The variance of fewer
+// than two values is 0.
+func SampleMoments(values []float64) Moments {
+	m := Moments{N: len(values), Mean: stats.Mean(values)}
+	if len(values) > 1 {
+		m.Variance = stats.Variance(values)
+	}
+	return m
+}
+
+// TTestMoments is TTest for samples known only by their Moments.
+// It gives the same p-value as TTest of the samples themselves.
+func TTestMoments(old, new Moments) (p float64, err error) {
+	t, err := stats.TwoSampleWelchTTest(momentSample{old}, momentSample{new}, stats.LocationDiffers)
+	if err != nil {
+		return -1, convertErr(err)
+	}
+	return t.P, nil
+}
+
+// momentSample adapts Moments to a stats.TTestSample.
+type momentSample struct{ m Moments }
+
+func (s momentSample) Weight() float64   { return float64(s.m.N) }
+func (s momentSample) Mean() float64     { return s.m.Mean }
+func (s momentSample) Variance() float64 { return s.m.Variance }
+
+// UTest is a Test using the Mann-Whitney U test.
+func UTest(old, new []float64) (p float64, err error) {
+	u, err := stats.MannWhitneyUTest(old, new, stats.LocationDiffers)
+	if err != nil {
+		return -1, convertErr(err)
+	}
+	return u.P, nil
+}
+
+// convertErr converts from the stats package's internal errors to the
+// errors exported by this package, which clients can use without
+// access to the internal package.
+func convertErr(err error) error {
+	switch err {
+	case stats.ErrZeroVariance:
+		return ErrZeroVariance
+	case stats.ErrSampleSize:
+		return ErrSampleSize
+	case stats.ErrSamplesEqual:
+		return ErrSamplesEqual
+	}
+	return err
+}
+
+// Underpowered reports whether samples of sizes n1 and n2 are too
+// small for test to return a p-value below alpha, however different
+// they are. It applies test to two samples that do not overlap at all,
+// which must give the smallest possible p-value.
+func Underpowered(test Test, alpha float64, n1, n2 int) bool {
+	old, new := make([]float64, n1), make([]float64, n2)
+	for i := range old {
+		old[i] = float64(i)
+	}
+	for i := range new {
+		new[i] = float64(n1+i) * 1e6
+	}
+	p, err := test(old, new)
+	return err != nil || p >= alpha
+}
+
+// Discrete reports whether the samples old and new, including any
+// outliers, are of a discrete metric, like allocs/op, that tests
+// compare poorly. If both samples are constant, a test reports a
+// p-value that depends only on the sample sizes, so the samples are
+// better compared exactly. If the samples are of integers that all lie
+// within one of each other, any difference is within the error of
+// rounding each measurement to an integer, which go test does for B/op
+// and allocs/op, even if a test would call it significant.
+func Discrete(old, new []float64) (exact, rounding bool) {
+	if constant(old) && constant(new) {
+		return true, false
+	}
+	all := append(append([]float64(nil), old...), new...)
+	if integers(all) {
+		lo, hi := stats.Bounds(all)
+		return false, hi-lo <= 1
+	}
+	return false, false
+}
+
+// constant reports whether xs holds at least two values, all equal.
+func constant(xs []float64) bool {
+	if len(xs) < 2 {
+		return false
+	}
+	for _, x := range xs {
+		if x != xs[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// integers reports whether xs is non-empty and holds only integers.
+func integers(xs []float64) bool {
+	for _, x := range xs {
+		if x != math.Trunc(x) {
+			return false
+		}
+	}
+	return len(xs) > 0
+}
+
+// RatioInterval returns the ratio of the centers of new and old, and
+// a confidence interval for it at the given level, or zeros for any it
+// cannot compute. The interval is that of the shift between the
+// logarithms of the samples, so it requires positive values.
+func RatioInterval(old, new *Summary, confidence float64) (ratio, lo, hi float64) {
+	if old.Center == 0 {
+		return 0, 0, 0
+	}
+	_, lo, hi = RatioShift(old, new, confidence)
+	return new.Center / old.Center, lo, hi
+}
+
+// RatioShift returns the Hodges-Lehmann estimate of the ratio of new
+// to old, the exponential of the median difference between the
+// logarithms of their values, and the confidence interval around it
+// at the given level that RatioInterval returns, or zeros for any it
+// cannot compute. Unlike the ratio of the centers, the estimate always
+// lies within the interval.
+func RatioShift(old, new *Summary, confidence float64) (est, lo, hi float64) {
+	logs := func(xs []float64) []float64 {
+		var ls []float64
+		for _, x := range xs {
+			if x <= 0 {
+				return nil
+			}
+			ls = append(ls, math.Log(x))
+		}
+		return ls
+	}
+	lold, lnew := logs(old.RValues), logs(new.RValues)
+	if lold == nil || lnew == nil {
+		return 0, 0, 0
+	}
+	est = math.Exp(stats.ShiftEstimate(lold, lnew))
+	l, h, err := stats.ShiftInterval(lold, lnew, confidence)
+	if err != nil {
+		return est, 0, 0
+	}
+	return est, math.Exp(l), math.Exp(h)
+}
+
+// A Comparison is the outcome of comparing two samples.
+type Comparison struct {
+	// Ratio is the ratio of the new center to the old, and Lo and Hi
+	// bound its confidence interval at level 1-alpha. Each is 0 if it
+	// cannot be computed.
+	Ratio, Lo, Hi float64
+
+	// Estimate is the Hodges-Lehmann estimate of the ratio, which
+	// Lo and Hi bound, as RatioShift returns, or 0 if it cannot be
+	// computed.
+	Estimate float64
+
+	// P is the p-value of the test, or -1 if there is none.
+	P float64
+
+	// Err explains why the test could not compute a p-value.
+	Err error
+
+	// Significant reports whether the samples differ significantly:
+	// the p-value is below alpha, or, if there is no test, the centers
+	// differ at all.
+	Significant bool
+
+	// Exact reports that both samples are constant, and so were
+	// compared exactly, without the test: they are significantly
+	// different if they differ at all. Rounding reports that they
+	// differ only by the error of rounding integer values, and so are
+	// not significantly different.
+	Exact, Rounding bool
+
+	// Underpowered reports that the samples are too small for the
+	// test ever to find a significant difference.
+	Underpowered bool
+}
+
+// Compare compares the samples old and new with test at level alpha,
+// as benchstat does when comparing two configurations.
+func Compare(old, new *Summary, test Test, alpha float64) *Comparison {
+	c := &Comparison{P: -1}
+	if old.Center != 0 {
+		c.Ratio = new.Center / old.Center
+	}
+	c.Estimate, c.Lo, c.Hi = RatioShift(old, new, 1-alpha)
+	p, err := test(old.RValues, new.RValues)
+	if p != -1 || err != nil {
+		// Without a test, every change is reported.
+		c.Exact, c.Rounding = Discrete(old.Values, new.Values)
+	}
+	switch {
+	case c.Exact:
+		c.Significant = new.Center != old.Center
+	case c.Rounding:
+	default:
+		c.Err = err
+		if err == nil {
+			c.P = p
+			c.Significant = p < alpha && new.Center != old.Center
+		}
+		c.Underpowered = Underpowered(test, alpha, len(old.RValues), len(new.RValues))
+	}
+	return c
+}
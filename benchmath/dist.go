@@ -0,0 +1,56 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchmath
+
+import "golang.org/x/perf/internal/stats"
+
+// Quantile returns the q-quantile of values, for q in [0, 1],
+// interpolating between the nearest values as benchstat does for the
+// median and quartiles. Quantile(values, 0.5) is the median. It
+// returns NaN if there are no values.
+func Quantile(values []float64, q float64) float64 {
+	return stats.Sample{Xs: values}.Percentile(q)
+}
+
+// CDF returns the empirical probability that a value is at most x:
+// the fraction of values less than or equal to x, or 0 if there are
+// no values.
+func CDF(values []float64, x float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	n := 0
+	for _, v := range values {
+		if v <= x {
+			n++
+		}
+	}
+	return float64(n) / float64(len(values))
+}
+
+// Histogram returns the number of values in each of n buckets of equal
+// width spanning [min, max]. The last bucket includes max, and values
+// outside the range are not counted. If min equals max, all values
+// equal to them are counted in the middle bucket.
+func Histogram(values []float64, min, max float64, n int) []int {
+	counts := make([]int, n)
+	if n == 0 {
+		return counts
+	}
+	for _, v := range values {
+		if v < min || v > max {
+			continue
+		}
+		i := n / 2
+		if max > min {
+			i = int((v - min) / (max - min) * float64(n))
+			if i == n {
+				i--
+			}
+		}
+		counts[i]++
+	}
+	return counts
+}
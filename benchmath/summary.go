@@ -0,0 +1,143 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package benchmath computes the statistics that benchstat reports:
+// summaries of samples of benchmark measurements, with outliers
+// removed, and comparisons of two samples, with a test of whether
+// they differ significantly and a confidence interval for the change.
+//
+// It works on plain slices of values, so that other tools can decide
+// whether a change is significant exactly as benchstat does, without
+// building the tables of package golang.org/x/perf/benchstat.
+package benchmath
+
+import (
+	"math"
+
+	"golang.org/x/perf/internal/stats"
+)
+
+// A Summary summarizes a sample of measurements of one benchmark.
+type Summary struct {
+	Values   []float64 // all values measured
+	RValues  []float64 // Values with outliers removed
+	Outliers []float64 // Values rejected as outliers
+	Lo, Hi   float64   // values outside [Lo, Hi] are outliers
+	Min      float64   // min of RValues
+	Max      float64   // max of RValues
+	Mean     float64   // mean of RValues
+	Center   float64   // center of RValues; see Summarize
+}
+
+// Summarize summarizes values, removing those outliers rejects and
+// taking the center of the rest with center. If outliers is nil, it
+// defaults to IQROutliers, and if center is nil, to MeanCenter.
+func Summarize(values []float64, outliers Outliers, center Center) *Summary {
+	if outliers == nil {
+		outliers = IQROutliers
+	}
+	if center == nil {
+		center = MeanCenter
+	}
+	s := &Summary{Values: values}
+	s.Lo, s.Hi = outliers(values)
+	for _, v := range values {
+		if s.Lo <= v && v <= s.Hi {
+			s.RValues = append(s.RValues, v)
+		} else {
+			s.Outliers = append(s.Outliers, v)
+		}
+	}
+	s.Min, s.Max = stats.Bounds(s.RValues)
+	s.Mean = stats.Mean(s.RValues)
+	s.Center = s.Mean
+	if len(s.RValues) > 0 {
+		s.Center = center(s.RValues)
+	}
+	return s
+}
+
+// Variation returns the larger of the distances of the min and the
+// max from the center, relative to the center, which benchstat shows
+// as "± 3%". It returns 0 if the center or the max is 0.
+func (s *Summary) Variation() float64 {
+	if s.Center == 0 || s.Max == 0 {
+		return 0
+	}
+	return math.Max(1-s.Min/s.Center, s.Max/s.Center-1)
+}
+
+// A Center returns the central value of a sample of values.
+type Center func(values []float64) float64
+
+// MeanCenter is a Center returning the arithmetic mean of the values.
+func MeanCenter(values []float64) float64 {
+	return stats.Mean(values)
+}
+
+// MedianCenter is a Center returning the median of the values.
+// Unlike the mean, the median is not pulled by a long tail of slow
+// values, as is common in latency benchmarks.
+func MedianCenter(values []float64) float64 {
+	return stats.Sample{Xs: values}.Percentile(0.5)
+}
+
+// MinCenter is a Center returning the smallest of the values. On noisy
+// shared machines, where interference only ever slows a benchmark down,
+// the fastest run best approximates the true cost of the code.
+func MinCenter(values []float64) float64 {
+	min, _ := stats.Bounds(values)
+	return min
+}
+
+// MaxCenter is a Center returning the largest of the values, which is
+// the best of rates like MB/s, as MinCenter is of times.
+func MaxCenter(values []float64) float64 {
+	_, max := stats.Bounds(values)
+	return max
+}
+
+// PercentileCenter returns a Center returning the p'th quantile of the
+// values, for p in [0, 1], such as 0.1 for a center nearly as robust to
+// interference as MinCenter but less sensitive to a single lucky run.
+func PercentileCenter(p float64) Center {
+	return func(values []float64) float64 {
+		return stats.Sample{Xs: values}.Percentile(p)
+	}
+}
+
+// An Outliers test returns the range [lo, hi] of values that are not
+// outliers.
+type Outliers func(values []float64) (lo, hi float64)
+
+// IQROutliers rejects values more than 1.5 times the interquartile
+// range below the first or above the third quartile.
+func IQROutliers(values []float64) (lo, hi float64) {
+	s := stats.Sample{Xs: values}
+	q1, q3 := s.Percentile(0.25), s.Percentile(0.75)
+	return q1 - 1.5*(q3-q1), q3 + 1.5*(q3-q1)
+}
+
+// NoOutliers keeps all values.
+func NoOutliers(values []float64) (lo, hi float64) {
+	return math.Inf(-1), math.Inf(+1)
+}
+
+// ZScoreOutliers returns an Outliers test rejecting values more than
+// k standard deviations from the mean.
+func ZScoreOutliers(k float64) Outliers {
+	return func(values []float64) (lo, hi float64) {
+		mean, sd := stats.Mean(values), stats.StdDev(values)
+		return mean - k*sd, mean + k*sd
+	}
+}
+
+// PercentOutliers returns an Outliers test rejecting the lowest and
+// highest pct percent of values.
+func PercentOutliers(pct float64) Outliers {
+	return func(values []float64) (lo, hi float64) {
+		s := stats.Sample{Xs: values}
+		return s.Percentile(pct / 100), s.Percentile(1 - pct/100)
+	}
+}
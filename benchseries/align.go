@@ -0,0 +1,148 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import "sort"
+
+// An Alignment is the series of one benchmark and unit split by the
+// values of the split keys, such as one for each builder, with their
+// points aligned by commit for comparison.
+type Alignment struct {
+	Benchmark string
+	Unit      string
+	Series    []*Series
+	Commits   []*Commit  // commits with a point in any of Series, in the order of history
+	Points    [][]*Point // Points[i][j] is the point of Series[j] at Commits[i], or nil
+}
+
+// A GapPolicy says how to fill the gaps in a series: the commits
+// where it has no point but others do, because the benchmark was not
+// run there, or not on every machine, or not as often.
+type GapPolicy int
+
+const (
+	// LeaveGaps leaves the gaps empty.
+	LeaveGaps GapPolicy = iota
+
+	// CarryForward fills each gap with the point before it, if any.
+	CarryForward
+
+	// Interpolate fills each gap between two points by linear
+	// interpolation of their medians and intervals, by the position
+	// of the commits in history, so that series measured at different
+	// intervals line up. Gaps before the first point or after the last
+	// are left empty.
+	Interpolate
+)
+
+// A commitIndex maps commits to their positions in history.
+type commitIndex map[*Commit]int
+
+func newCommitIndex(history []*Commit) commitIndex {
+	index := make(commitIndex)
+	for i, c := range history {
+		index[c] = i
+	}
+	return index
+}
+
+// sort sorts commits in the order of history.
+func (index commitIndex) sort(commits []*Commit) {
+	sort.Slice(commits, func(i, j int) bool { return index[commits[i]] < index[commits[j]] })
+}
+
+// Commits returns the commits with a point in any of series, in the
+// order of history.
+func Commits(history []*Commit, series []*Series) []*Commit {
+	return newCommitIndex(history).commits(series)
+}
+
+func (index commitIndex) commits(series []*Series) []*Commit {
+	seen := make(map[*Commit]bool)
+	var commits []*Commit
+	for _, s := range series {
+		for _, p := range s.Points {
+			if !seen[p.Commit] {
+				seen[p.Commit] = true
+				commits = append(commits, p.Commit)
+			}
+		}
+	}
+	index.sort(commits)
+	return commits
+}
+
+// Fill returns the points of s at each of commits, which are in the
+// order of history, filling the gaps as policy says. Filled points
+// have Filled set and no Values.
+func Fill(history []*Commit, s *Series, commits []*Commit, policy GapPolicy) []*Point {
+	return newCommitIndex(history).fill(s, commits, policy)
+}
+
+func (index commitIndex) fill(s *Series, commits []*Commit, policy GapPolicy) []*Point {
+	out := make([]*Point, len(commits))
+	next := 0 // index in s.Points of the first point at or after the commit
+	for i, c := range commits {
+		x := index[c]
+		for next < len(s.Points) && index[s.Points[next].Commit] < x {
+			next++
+		}
+		if next < len(s.Points) && s.Points[next].Commit == c {
+			out[i] = s.Points[next]
+			continue
+		}
+		if next == 0 {
+			continue
+		}
+		prev := s.Points[next-1]
+		switch policy {
+		case CarryForward:
+			out[i] = &Point{Commit: c, Center: prev.Center, Lo: prev.Lo, Hi: prev.Hi, Filled: true}
+		case Interpolate:
+			if next == len(s.Points) {
+				continue
+			}
+			p := s.Points[next]
+			x0, x1 := index[prev.Commit], index[p.Commit]
+			t := float64(x-x0) / float64(x1-x0)
+			lerp := func(a, b float64) float64 { return a + t*(b-a) }
+			out[i] = &Point{Commit: c, Center: lerp(prev.Center, p.Center), Lo: lerp(prev.Lo, p.Lo), Hi: lerp(prev.Hi, p.Hi), Filled: true}
+		}
+	}
+	return out
+}
+
+// Align groups series by benchmark and unit, in the order first seen,
+// and aligns the points of each group at the commits of history,
+// filling the gaps as policy says.
+func Align(history []*Commit, series []*Series, policy GapPolicy) []*Alignment {
+	index := newCommitIndex(history)
+	type key struct{ benchmark, unit string }
+	groups := make(map[key]*Alignment)
+	var out []*Alignment
+	for _, s := range series {
+		k := key{s.Benchmark, s.Unit}
+		a := groups[k]
+		if a == nil {
+			a = &Alignment{Benchmark: s.Benchmark, Unit: s.Unit}
+			groups[k] = a
+			out = append(out, a)
+		}
+		a.Series = append(a.Series, s)
+	}
+	for _, a := range out {
+		a.Commits = index.commits(a.Series)
+		a.Points = make([][]*Point, len(a.Commits))
+		for i := range a.Points {
+			a.Points[i] = make([]*Point, len(a.Series))
+		}
+		for j, s := range a.Series {
+			for i, p := range index.fill(s, a.Commits, policy) {
+				a.Points[i][j] = p
+			}
+		}
+	}
+	return out
+}
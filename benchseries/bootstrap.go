@@ -0,0 +1,43 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"math/rand"
+
+	"golang.org/x/perf/internal/stats"
+)
+
+// median returns the median of xs, which it sorts.
+func median(xs []float64) float64 {
+	s := stats.Sample{Xs: xs}
+	s.Sort()
+	return s.Percentile(0.5)
+}
+
+// bootstrap returns a confidence interval, at the given level, for the
+// median of values, from the medians of resamples of values drawn with
+// replacement. The resamples are drawn from a source seeded the same
+// way every time, so the interval of the same values is always the
+// same.
+func bootstrap(values []float64, confidence float64, resamples int) (lo, hi float64) {
+	if len(values) < 2 {
+		m := median(append([]float64(nil), values...))
+		return m, m
+	}
+	r := rand.New(rand.NewSource(1))
+	medians := make([]float64, resamples)
+	xs := make([]float64, len(values))
+	for i := range medians {
+		for j := range xs {
+			xs[j] = values[r.Intn(len(values))]
+		}
+		medians[i] = median(xs)
+	}
+	s := stats.Sample{Xs: medians}
+	s.Sort()
+	alpha := (1 - confidence) / 2
+	return s.Percentile(alpha), s.Percentile(1 - alpha)
+}
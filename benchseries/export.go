@@ -0,0 +1,96 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// labelKeys returns the keys of the labels of series, sorted.
+func labelKeys(series []*Series) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, s := range series {
+		for k := range s.Labels {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteCSV writes the points of series to w as CSV, with a header
+// line and then a line for each point: the benchmark, the unit, the
+// value of each label of the series, and the commit, its date, and
+// the median, its confidence interval, and the number of values at
+// the commit.
+func WriteCSV(w io.Writer, series []*Series) error {
+	keys := labelKeys(series)
+	cw := csv.NewWriter(w)
+	header := append([]string{"benchmark", "unit"}, keys...)
+	cw.Write(append(header, "commit", "date", "center", "lo", "hi", "n"))
+	for _, s := range series {
+		for _, p := range s.Points {
+			rec := []string{s.Benchmark, s.Unit}
+			for _, k := range keys {
+				rec = append(rec, s.Labels[k])
+			}
+			rec = append(rec,
+				p.Commit.Hash,
+				p.Commit.Date.Format(time.RFC3339),
+				strconv.FormatFloat(p.Center, 'g', -1, 64),
+				strconv.FormatFloat(p.Lo, 'g', -1, 64),
+				strconv.FormatFloat(p.Hi, 'g', -1, 64),
+				strconv.Itoa(len(p.Values)))
+			cw.Write(rec)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// A jsonSeries is the JSON form of a Series written by WriteJSON.
+type jsonSeries struct {
+	Benchmark string            `json:"benchmark"`
+	Unit      string            `json:"unit"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Points    []jsonPoint       `json:"points"`
+}
+
+// A jsonPoint is the JSON form of a Point written by WriteJSON.
+type jsonPoint struct {
+	Commit string    `json:"commit"`
+	Date   time.Time `json:"date"`
+	Center float64   `json:"center"`
+	Lo     float64   `json:"lo"`
+	Hi     float64   `json:"hi"`
+	N      int       `json:"n"`
+	Filled bool      `json:"filled,omitempty"`
+}
+
+// WriteJSON writes series to w as a JSON array, with an object for
+// each series giving its benchmark, unit, labels, and points, each
+// with the fields of the lines written by WriteCSV.
+func WriteJSON(w io.Writer, series []*Series) error {
+	out := []jsonSeries{}
+	for _, s := range series {
+		js := jsonSeries{Benchmark: s.Benchmark, Unit: s.Unit, Labels: s.Labels, Points: []jsonPoint{}}
+		for _, p := range s.Points {
+			js.Points = append(js.Points, jsonPoint{p.Commit.Hash, p.Commit.Date, p.Center, p.Lo, p.Hi, len(p.Values), p.Filled})
+		}
+		out = append(out, js)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
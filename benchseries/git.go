@@ -0,0 +1,125 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Commit is a commit in the history of a repository.
+type Commit struct {
+	Hash    string // full hash, in lower case
+	Date    time.Time
+	Parents []string // hashes of the parents
+	Tags    []string // names of the tags of the commit, if known
+}
+
+// History returns the commits reachable from revs in the git
+// repository in dir, in topological order from the oldest: every
+// commit follows its parents. The revs may be any revisions git
+// accepts, such as abbreviated hashes, branches, and tags, or ranges,
+// such as "go1.11..master", to limit the history to the commits
+// reachable from one revision but not another.
+func History(dir string, revs []string) ([]*Commit, error) {
+	if len(revs) == 0 {
+		return nil, nil
+	}
+	args := append([]string{"log", "--topo-order", "--reverse", "--format=%H %ct %P", "--end-of-options"}, revs...)
+	out, err := git(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	var history []*Commit
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		t, err := strconv.ParseInt(f[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("git log: bad line %q", line)
+		}
+		history = append(history, &Commit{Hash: f[0], Date: time.Unix(t, 0).UTC(), Parents: f[2:]})
+	}
+	return history, nil
+}
+
+// Resolve returns the hash of the commit named by rev in the git
+// repository in dir.
+func Resolve(dir, rev string) (string, error) {
+	out, err := git(dir, "rev-parse", "--verify", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Tags returns the names of the tags in the git repository in dir
+// matching any of patterns, as in "git tag --list", by the hash of the
+// commit tagged. If there are no patterns, it returns all tags.
+func Tags(dir string, patterns []string) (map[string][]string, error) {
+	var refs []string
+	for _, p := range patterns {
+		refs = append(refs, "refs/tags/"+p)
+	}
+	if refs == nil {
+		refs = []string{"refs/tags/"}
+	}
+	// Annotated tags name the tag object, and the commit it tags
+	// with a "*" prefix; lightweight tags name the commit.
+	args := append([]string{"for-each-ref", "--format=%(objectname) %(*objectname) %(refname:strip=2)"}, refs...)
+	out, err := git(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		f := strings.SplitN(line, " ", 3)
+		if len(f) < 3 {
+			continue
+		}
+		hash := f[0]
+		if f[1] != "" {
+			hash = f[1]
+		}
+		tags[hash] = append(tags[hash], f[2])
+	}
+	return tags, nil
+}
+
+// TagCommits sets the Tags of the commits of history to their tags,
+// as returned by Tags, and returns the commits that have any, in the
+// order of history.
+func TagCommits(history []*Commit, tags map[string][]string) []*Commit {
+	var tagged []*Commit
+	for _, c := range history {
+		c.Tags = tags[c.Hash]
+		if c.Tags != nil {
+			tagged = append(tagged, c)
+		}
+	}
+	return tagged
+}
+
+// git runs git with args in dir and returns its standard output.
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return out, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import "golang.org/x/perf/storage/benchfmt"
+
+// An Overlay compares the series of a benchmark on two branches of a
+// repository, such as master and a release branch, built from the
+// same results with the history of each branch.
+type Overlay struct {
+	Benchmark string
+	Unit      string
+	Labels    benchfmt.Labels
+	A, B      *Series
+
+	// Fork is the last point of the series in the history of both
+	// branches, or nil if there is none.
+	Fork *Point
+
+	// Delta is the change of the last point of B from the last of A.
+	Delta float64
+
+	// Since is the first point of B after Fork from which every
+	// point of B differs from the point of A current at the same
+	// time, the last one committed no later, with the confidence
+	// intervals of the two apart; or nil if B has not diverged.
+	Since *Point
+}
+
+// Diverged reports whether the branches have diverged: whether every
+// point of B from some point on differs from the point of A current
+// at the same time, including the last point of each.
+func (o *Overlay) Diverged() bool {
+	return o.Since != nil
+}
+
+// OverlayBranches pairs the series of a benchmark on two branches, a
+// and b, by benchmark, unit, and labels, in the order of b, and
+// compares each pair. Series on only one branch are left out.
+func OverlayBranches(a, b []*Series) []*Overlay {
+	type key struct{ benchmark, unit, labels string }
+	keyOf := func(s *Series) key {
+		return key{s.Benchmark, s.Unit, s.labelKey()}
+	}
+	byKey := make(map[key]*Series)
+	for _, s := range a {
+		byKey[keyOf(s)] = s
+	}
+	var out []*Overlay
+	for _, sb := range b {
+		sa := byKey[keyOf(sb)]
+		if sa == nil || len(sa.Points) == 0 || len(sb.Points) == 0 {
+			continue
+		}
+		out = append(out, overlay(sa, sb))
+	}
+	return out
+}
+
+// overlay compares series a and b of the same benchmark.
+func overlay(a, b *Series) *Overlay {
+	o := &Overlay{Benchmark: b.Benchmark, Unit: b.Unit, Labels: b.Labels, A: a, B: b}
+	inA := make(map[string]bool)
+	for _, p := range a.Points {
+		inA[p.Commit.Hash] = true
+	}
+	start := 0 // index in b.Points of the first point after the fork
+	for i, p := range b.Points {
+		if inA[p.Commit.Hash] {
+			o.Fork, start = p, i+1
+		}
+	}
+	if last := a.Points[len(a.Points)-1]; last.Center != 0 {
+		o.Delta = b.Points[len(b.Points)-1].Center/last.Center - 1
+	}
+	for _, p := range b.Points[start:] {
+		// The point of a current at the time of p.
+		var cur *Point
+		for _, q := range a.Points {
+			if !q.Commit.Date.After(p.Commit.Date) && (cur == nil || !q.Commit.Date.Before(cur.Commit.Date)) {
+				cur = q
+			}
+		}
+		switch {
+		case cur == nil || (p.Lo <= cur.Hi && cur.Lo <= p.Hi):
+			o.Since = nil
+		case o.Since == nil:
+			o.Since = p
+		}
+	}
+	if o.Since != nil {
+		// The last points must differ as well, even if a has moved
+		// on since the last point of b.
+		pa, pb := a.Points[len(a.Points)-1], b.Points[len(b.Points)-1]
+		if pb.Lo <= pa.Hi && pa.Lo <= pb.Hi {
+			o.Since = nil
+		}
+	}
+	return o
+}
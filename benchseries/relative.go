@@ -0,0 +1,84 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"math"
+	"strings"
+
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+// At returns the point of s at the commit with the given hash, or nil
+// if there is none.
+func (s *Series) At(hash string) *Point {
+	for _, p := range s.Points {
+		if p.Commit.Hash == hash {
+			return p
+		}
+	}
+	return nil
+}
+
+// Family returns the family of a benchmark: its name without any
+// sub-benchmarks or GOMAXPROCS suffix, so that Encode/small-8 and
+// Encode/large-8 are both in the family Encode.
+func Family(benchmark string) string {
+	if i := strings.Index(benchmark, "/"); i >= 0 {
+		return benchmark[:i]
+	}
+	if i := strings.LastIndex(benchmark, "-"); i >= 0 {
+		if strings.Trim(benchmark[i+1:], "0123456789") == "" && i+1 < len(benchmark) {
+			return benchmark[:i]
+		}
+	}
+	return benchmark
+}
+
+// A Drift is the change of a family of benchmarks, in one unit, from
+// a baseline commit, such as the latest release, to the last commit
+// measured.
+type Drift struct {
+	Family     string
+	Unit       string
+	Labels     benchfmt.Labels
+	Benchmarks int     // number of series with a point at the baseline
+	Ratio      float64 // geometric mean of the ratios of last to baseline medians
+	Min, Max   float64 // smallest and largest of the ratios
+}
+
+// Drifts returns the drift of each family of benchmarks in series, by
+// unit and labels, in the order first seen, from the points at the
+// commit with the given hash. Series without a point there are left
+// out.
+func Drifts(series []*Series, base string) []*Drift {
+	type key struct{ family, unit, labels string }
+	drifts := make(map[key]*Drift)
+	logs := make(map[key]float64)
+	var out []*Drift
+	for _, s := range series {
+		b := s.At(base)
+		if b == nil || b.Center == 0 {
+			continue
+		}
+		ratio := s.Points[len(s.Points)-1].Center / b.Center
+		if ratio <= 0 {
+			continue
+		}
+		k := key{Family(s.Benchmark), s.Unit, s.labelKey()}
+		d := drifts[k]
+		if d == nil {
+			d = &Drift{Family: k.family, Unit: s.Unit, Labels: s.Labels, Min: ratio, Max: ratio}
+			drifts[k] = d
+			out = append(out, d)
+		}
+		d.Benchmarks++
+		d.Min = math.Min(d.Min, ratio)
+		d.Max = math.Max(d.Max, ratio)
+		logs[k] += math.Log(ratio)
+		d.Ratio = math.Exp(logs[k] / float64(d.Benchmarks))
+	}
+	return out
+}
@@ -0,0 +1,238 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package benchseries assembles benchmark results measured at many
+// commits of a repository into series, one for each benchmark and
+// unit, ordered by the history of the repository, for trend analysis.
+//
+// Each result is tagged with the commit it measures by a file
+// configuration line, such as
+//
+//	commit: 1b0a2e0a5b55
+//
+// preceding it. The commits are ordered topologically, parents before
+// children, rather than by date, so that results from branches merged
+// late, or from commits with skewed clocks, fall where they belong.
+package benchseries
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+// A Builder collects benchmark results by commit and assembles them
+// into series.
+type Builder struct {
+	// CommitKey is the configuration key whose value names the
+	// commit of each result. If empty, it is "commit".
+	// Results without the key are ignored.
+	CommitKey string
+
+	// SplitKeys are configuration keys, such as the builder or the
+	// Go version, whose values split the results of each benchmark
+	// into separate series.
+	SplitKeys []string
+
+	// Confidence is the confidence level of the interval around the
+	// median of each point, computed by bootstrapping. If zero, it
+	// is 0.95.
+	Confidence float64
+
+	// Resamples is the number of resamples drawn to compute each
+	// interval. If zero, it is 1000.
+	Resamples int
+
+	// commits lists the commits of the results added, in the order
+	// first seen.
+	commits []string
+
+	// values holds the values of each series by commit.
+	values map[seriesKey]map[string][]float64
+
+	// keys lists the keys of values in the order first seen.
+	keys []seriesKey
+}
+
+// A seriesKey identifies a series.
+type seriesKey struct {
+	Benchmark, Unit string
+	Labels          string // values of the split keys, each followed by a NUL
+}
+
+// A Series is the measurements of one benchmark in one unit at each
+// commit measured, in the order of the repository's history.
+type Series struct {
+	Benchmark string // name, without the "Benchmark" prefix
+	Unit      string
+	Labels    benchfmt.Labels // values of the Builder's SplitKeys
+	Points    []*Point
+}
+
+// labelKey returns a string identifying the labels of s.
+func (s *Series) labelKey() string {
+	var key string
+	for _, k := range s.Labels.Keys() {
+		key += k + "=" + s.Labels[k] + "\x00"
+	}
+	return key
+}
+
+// A Point is the measurements of a benchmark at one commit.
+type Point struct {
+	Commit *Commit
+	Values []float64 // in the order measured
+	Center float64   // median of Values
+	Lo, Hi float64   // confidence interval of Center; see Builder.Confidence
+	Filled bool      // filled in for a gap, not measured; see GapPolicy
+}
+
+// AddFile adds the benchmark results read from r.
+func (b *Builder) AddFile(r io.Reader) error {
+	key := b.CommitKey
+	if key == "" {
+		key = "commit"
+	}
+	br := benchfmt.NewReader(r)
+	for br.Next() {
+		res := br.Result()
+		if commit := res.Labels[key]; commit != "" {
+			b.addResult(commit, b.splitLabels(res.Labels), res.Content)
+		}
+	}
+	return br.Err()
+}
+
+// splitLabels returns the values of b.SplitKeys in labels, each
+// followed by a NUL.
+func (b *Builder) splitLabels(labels benchfmt.Labels) string {
+	var s string
+	for _, k := range b.SplitKeys {
+		s += labels[k] + "\x00"
+	}
+	return s
+}
+
+// addResult adds the measurements in a benchmark line, content, to
+// the series with the split labels at commit.
+func (b *Builder) addResult(commit, labels, content string) {
+	f := strings.Fields(content)
+	if len(f) < 4 || !strings.HasPrefix(f[0], "Benchmark") {
+		return
+	}
+	if n, _ := strconv.Atoi(f[1]); n == 0 {
+		return
+	}
+	name := strings.TrimPrefix(f[0], "Benchmark")
+	for i := 2; i+1 < len(f); i += 2 {
+		v, err := strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			continue
+		}
+		k := seriesKey{name, f[i+1], labels}
+		if b.values == nil {
+			b.values = make(map[seriesKey]map[string][]float64)
+		}
+		byCommit := b.values[k]
+		if byCommit == nil {
+			byCommit = make(map[string][]float64)
+			b.values[k] = byCommit
+			b.keys = append(b.keys, k)
+		}
+		if !b.seen(commit) {
+			b.commits = append(b.commits, commit)
+		}
+		byCommit[commit] = append(byCommit[commit], v)
+	}
+}
+
+// seen reports whether b has results for commit.
+func (b *Builder) seen(commit string) bool {
+	for _, c := range b.commits {
+		if c == commit {
+			return true
+		}
+	}
+	return false
+}
+
+// Commits returns the commits of the results added, as they were
+// named, in the order first seen.
+func (b *Builder) Commits() []string {
+	return b.commits
+}
+
+// Series returns the series of each benchmark, unit, and values of the
+// split keys, in the order first seen, with points at the commits of
+// history that have results, in the order of history. The commits of
+// results may be abbreviated. Results at commits not in history are
+// left out.
+func (b *Builder) Series(history []*Commit) []*Series {
+	find := commitFinder(history)
+	index := make(map[*Commit]int)
+	for i, c := range history {
+		index[c] = i
+	}
+	var out []*Series
+	for _, k := range b.keys {
+		s := &Series{Benchmark: k.Benchmark, Unit: k.Unit}
+		if len(b.SplitKeys) > 0 {
+			s.Labels = make(benchfmt.Labels)
+			for i, v := range strings.Split(k.Labels, "\x00")[:len(b.SplitKeys)] {
+				s.Labels[b.SplitKeys[i]] = v
+			}
+		}
+		for commit, values := range b.values[k] {
+			c := find(commit)
+			if c == nil {
+				continue
+			}
+			s.Points = append(s.Points, b.newPoint(c, values))
+		}
+		if len(s.Points) == 0 {
+			continue
+		}
+		sort.Slice(s.Points, func(i, j int) bool {
+			return index[s.Points[i].Commit] < index[s.Points[j].Commit]
+		})
+		out = append(out, s)
+	}
+	return out
+}
+
+// newPoint returns the point of values at c.
+func (b *Builder) newPoint(c *Commit, values []float64) *Point {
+	confidence, resamples := b.Confidence, b.Resamples
+	if confidence == 0 {
+		confidence = 0.95
+	}
+	if resamples == 0 {
+		resamples = 1000
+	}
+	p := &Point{Commit: c, Values: values, Center: median(append([]float64(nil), values...))}
+	p.Lo, p.Hi = bootstrap(values, confidence, resamples)
+	return p
+}
+
+// commitFinder returns a function finding the commit of history with
+// a hash beginning with the given prefix, or nil if there is none or
+// the prefix is ambiguous.
+func commitFinder(history []*Commit) func(prefix string) *Commit {
+	sorted := append([]*Commit(nil), history...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Hash < sorted[j].Hash })
+	return func(prefix string) *Commit {
+		prefix = strings.ToLower(prefix)
+		i := sort.Search(len(sorted), func(i int) bool { return sorted[i].Hash >= prefix })
+		if i == len(sorted) || !strings.HasPrefix(sorted[i].Hash, prefix) {
+			return nil
+		}
+		if i+1 < len(sorted) && strings.HasPrefix(sorted[i+1].Hash, prefix) {
+			return nil
+		}
+		return sorted[i]
+	}
+}
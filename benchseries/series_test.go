@@ -0,0 +1,382 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gitRepo creates a git repository in a temporary directory with a
+// commit for each date, each a child of the one before, and returns
+// the directory and the hashes of the commits. Dates may go backward,
+// as clocks sometimes do.
+func gitRepo(t *testing.T, dates ...string) (string, []string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir, err := ioutil.TempDir("", "benchseries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(env []string, args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=gopher", "-c", "user.email=gopher@golang.org"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	run(nil, "init", "-q")
+	var hashes []string
+	for _, date := range dates {
+		env := []string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}
+		run(env, "commit", "-q", "--allow-empty", "-m", date)
+		hashes = append(hashes, run(nil, "rev-parse", "HEAD"))
+	}
+	return dir, hashes
+}
+
+func TestSeries(t *testing.T) {
+	dir, hashes := gitRepo(t, "2019-03-01T00:00:00Z", "2019-02-01T00:00:00Z", "2019-03-02T00:00:00Z")
+	defer os.RemoveAll(dir)
+
+	// The results are out of order, and abbreviate the commits.
+	var input string
+	for _, i := range []int{2, 0, 1} {
+		input += fmt.Sprintf("commit: %s\n", hashes[i][:10])
+		for k := 0; k < 3; k++ {
+			input += fmt.Sprintf("BenchmarkEncode 100 %d ns/op %d B/op\n", 100*(i+1)+k, 64)
+		}
+	}
+	input += "commit:\nBenchmarkEncode 100 1 ns/op\n"
+
+	var b Builder
+	if err := b.AddFile(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	history, err := History(dir, b.Commits())
+	if err != nil {
+		t.Fatal(err)
+	}
+	series := b.Series(history)
+	if len(series) != 2 {
+		t.Fatalf("have %d series, want 2", len(series))
+	}
+	s := series[0]
+	if s.Benchmark != "Encode" || s.Unit != "ns/op" {
+		t.Errorf("have series %s %s, want Encode ns/op", s.Benchmark, s.Unit)
+	}
+	var have []string
+	var centers []float64
+	for _, p := range s.Points {
+		have = append(have, p.Commit.Hash)
+		centers = append(centers, p.Center)
+	}
+	if !reflect.DeepEqual(have, hashes) {
+		t.Errorf("have commits %v, want %v", have, hashes)
+	}
+	if want := []float64{101, 201, 301}; !reflect.DeepEqual(centers, want) {
+		t.Errorf("have medians %v, want %v", centers, want)
+	}
+	if p := s.Points[1]; !reflect.DeepEqual(p.Commit.Parents, []string{hashes[0]}) || p.Commit.Date.Month() != 2 {
+		t.Errorf("have commit %+v, want parent %s and date in February", p.Commit, hashes[0])
+	}
+}
+
+func TestCommitFinder(t *testing.T) {
+	find := commitFinder([]*Commit{{Hash: "abc123"}, {Hash: "abd456"}, {Hash: "ffe789"}})
+	for _, tt := range []struct {
+		prefix, want string
+	}{
+		{"abc", "abc123"},
+		{"ABD4", "abd456"},
+		{"ffe789", "ffe789"},
+		{"ab", ""},
+		{"abe", ""},
+		{"ffe7890", ""},
+	} {
+		have := ""
+		if c := find(tt.prefix); c != nil {
+			have = c.Hash
+		}
+		if have != tt.want {
+			t.Errorf("find(%q) = %q, want %q", tt.prefix, have, tt.want)
+		}
+	}
+}
+
+func TestAlign(t *testing.T) {
+	history := []*Commit{{Hash: "aaa"}, {Hash: "bbb"}, {Hash: "ccc"}}
+	input := `
+builder: linux
+commit: aaa
+BenchmarkEncode 100 10 ns/op
+commit: ccc
+BenchmarkEncode 100 12 ns/op
+builder: arm
+commit: bbb
+BenchmarkEncode 100 21 ns/op
+commit: ccc
+BenchmarkEncode 100 24 ns/op
+BenchmarkDecode 100 5 ns/op
+`
+	b := Builder{SplitKeys: []string{"builder"}}
+	if err := b.AddFile(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	alignments := Align(history, b.Series(history), LeaveGaps)
+	if len(alignments) != 2 {
+		t.Fatalf("have %d alignments, want 2", len(alignments))
+	}
+	a := alignments[0]
+	var builders []string
+	for _, s := range a.Series {
+		builders = append(builders, s.Labels["builder"])
+	}
+	if want := []string{"linux", "arm"}; !reflect.DeepEqual(builders, want) {
+		t.Errorf("have builders %v, want %v", builders, want)
+	}
+	var have []string
+	for i, c := range a.Commits {
+		row := c.Hash
+		for _, p := range a.Points[i] {
+			if p == nil {
+				row += " -"
+			} else {
+				row += fmt.Sprintf(" %g", p.Center)
+			}
+		}
+		have = append(have, row)
+	}
+	if want := []string{"aaa 10 -", "bbb - 21", "ccc 12 24"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("have rows %q, want %q", have, want)
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	values := []float64{9, 1, 8, 2, 7, 3, 6, 4, 5}
+	lo, hi := bootstrap(values, 0.95, 1000)
+	if !(2 <= lo && lo < 5 && 5 < hi && hi <= 8) {
+		t.Errorf("bootstrap(1..9) = [%g, %g], want an interval around 5", lo, hi)
+	}
+	if lo2, hi2 := bootstrap(values, 0.95, 1000); lo2 != lo || hi2 != hi {
+		t.Errorf("bootstrap(1..9) = [%g, %g], then [%g, %g]", lo, hi, lo2, hi2)
+	}
+	if lo, hi := bootstrap([]float64{3}, 0.95, 1000); lo != 3 || hi != 3 {
+		t.Errorf("bootstrap(3) = [%g, %g], want [3, 3]", lo, hi)
+	}
+	if want := []float64{9, 1, 8, 2, 7, 3, 6, 4, 5}; !reflect.DeepEqual(values, want) {
+		t.Errorf("bootstrap reordered values to %v", values)
+	}
+}
+
+func TestExport(t *testing.T) {
+	c := &Commit{Hash: "aaa", Date: time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)}
+	series := []*Series{{
+		Benchmark: "Encode",
+		Unit:      "ns/op",
+		Labels:    map[string]string{"builder": "linux"},
+		Points:    []*Point{{Commit: c, Values: []float64{1, 2, 3}, Center: 2, Lo: 1, Hi: 2.5}},
+	}}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, series); err != nil {
+		t.Fatal(err)
+	}
+	want := "benchmark,unit,builder,commit,date,center,lo,hi,n\nEncode,ns/op,linux,aaa,2019-03-01T12:00:00Z,2,1,2.5,3\n"
+	if buf.String() != want {
+		t.Errorf("WriteCSV:\nhave %q\nwant %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, series); err != nil {
+		t.Fatal(err)
+	}
+	var have []jsonSeries
+	if err := json.Unmarshal(buf.Bytes(), &have); err != nil {
+		t.Fatal(err)
+	}
+	p := jsonPoint{"aaa", c.Date, 2, 1, 2.5, 3, false}
+	if len(have) != 1 || have[0].Labels["builder"] != "linux" || !reflect.DeepEqual(have[0].Points, []jsonPoint{p}) {
+		t.Errorf("WriteJSON wrote %s", buf.Bytes())
+	}
+}
+
+func TestFill(t *testing.T) {
+	history := []*Commit{{Hash: "a"}, {Hash: "b"}, {Hash: "c"}, {Hash: "d"}, {Hash: "e"}, {Hash: "f"}}
+	s := &Series{Points: []*Point{
+		{Commit: history[1], Center: 10, Lo: 9, Hi: 11},
+		{Commit: history[4], Center: 40, Lo: 36, Hi: 44},
+	}}
+	for _, tt := range []struct {
+		policy GapPolicy
+		want   string
+	}{
+		{LeaveGaps, "a:- b:10 c:- e:40 f:-"},
+		{CarryForward, "a:- b:10 c:10* e:40 f:40*"},
+		{Interpolate, "a:- b:10 c:20[18,22]* e:40 f:-"},
+	} {
+		commits := []*Commit{history[0], history[1], history[2], history[4], history[5]}
+		var have []string
+		for i, p := range Fill(history, s, commits, tt.policy) {
+			v := "-"
+			if p != nil {
+				v = fmt.Sprint(p.Center)
+				if tt.policy == Interpolate && p.Filled {
+					v += fmt.Sprintf("[%g,%g]", p.Lo, p.Hi)
+				}
+				if p.Filled {
+					v += "*"
+				}
+			}
+			have = append(have, commits[i].Hash+":"+v)
+		}
+		if got := strings.Join(have, " "); got != tt.want {
+			t.Errorf("Fill with policy %d = %s, want %s", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	dir, hashes := gitRepo(t, "2019-03-01T00:00:00Z", "2019-03-02T00:00:00Z", "2019-03-03T00:00:00Z")
+	defer os.RemoveAll(dir)
+	for _, args := range [][]string{
+		{"tag", "go1.1", hashes[0]},
+		{"-c", "user.name=gopher", "-c", "user.email=gopher@golang.org", "tag", "-a", "-m", "release", "go1.2", hashes[2]},
+		{"tag", "weekly", hashes[1]},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	history, err := History(dir, []string{hashes[0] + ".." + hashes[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Hash != hashes[1] || history[1].Hash != hashes[2] {
+		t.Fatalf("History(first..last) has %d commits, want the last 2", len(history))
+	}
+
+	tags, err := Tags(dir, []string{"go1.*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string][]string{hashes[0]: {"go1.1"}, hashes[2]: {"go1.2"}}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("Tags(go1.*) = %v, want %v", tags, want)
+	}
+	tagged := TagCommits(history, tags)
+	if len(tagged) != 1 || tagged[0] != history[1] || !reflect.DeepEqual(history[1].Tags, []string{"go1.2"}) {
+		t.Errorf("TagCommits returned %d commits, want the last, tagged go1.2", len(tagged))
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2019, 3, d, 0, 0, 0, 0, time.UTC) }
+	point := func(hash string, d int, center float64) *Point {
+		return &Point{Commit: &Commit{Hash: hash, Date: day(d)}, Center: center, Lo: center - 1, Hi: center + 1}
+	}
+	master := &Series{Benchmark: "Encode", Unit: "ns/op", Points: []*Point{
+		point("a", 1, 100), point("b", 2, 100), point("d", 4, 100),
+	}}
+	release := &Series{Benchmark: "Encode", Unit: "ns/op", Points: []*Point{
+		point("a", 1, 100), point("c", 2, 101), point("e", 3, 110), point("f", 5, 112),
+	}}
+	other := &Series{Benchmark: "Decode", Unit: "ns/op", Points: []*Point{point("a", 1, 50)}}
+	overlays := OverlayBranches([]*Series{master}, []*Series{other, release})
+	if len(overlays) != 1 {
+		t.Fatalf("have %d overlays, want 1", len(overlays))
+	}
+	o := overlays[0]
+	if o.Fork == nil || o.Fork.Commit.Hash != "a" {
+		t.Errorf("have fork %v, want a", o.Fork)
+	}
+	if math.Abs(o.Delta-0.12) > 1e-9 {
+		t.Errorf("have delta %g, want 0.12", o.Delta)
+	}
+	if !o.Diverged() || o.Since.Commit.Hash != "e" {
+		t.Errorf("have divergence since %v, want e", o.Since)
+	}
+
+	// Catching up on the release branch ends the divergence.
+	release.Points = append(release.Points, point("g", 6, 100.5))
+	if o := OverlayBranches([]*Series{master}, []*Series{release})[0]; o.Diverged() {
+		t.Errorf("have divergence since %s after catching up", o.Since.Commit.Hash)
+	}
+}
+
+func TestDrifts(t *testing.T) {
+	for name, want := range map[string]string{
+		"Encode":          "Encode",
+		"Encode-8":        "Encode",
+		"Encode/small-8":  "Encode",
+		"Encode/n=1-4":    "Encode",
+		"Get-Put":         "Get-Put",
+		"Sort-":           "Sort-",
+		"Decode/gzip/big": "Decode",
+	} {
+		if have := Family(name); have != want {
+			t.Errorf("Family(%q) = %q, want %q", name, have, want)
+		}
+	}
+
+	base, next := &Commit{Hash: "a"}, &Commit{Hash: "b"}
+	series := func(name string, v0, v1 float64) *Series {
+		return &Series{Benchmark: name, Unit: "ns/op", Points: []*Point{{Commit: base, Center: v0}, {Commit: next, Center: v1}}}
+	}
+	drifts := Drifts([]*Series{
+		series("Encode/small-8", 100, 125),
+		series("Decode-8", 100, 100),
+		series("Encode/large-8", 100, 80),
+		{Benchmark: "Encode/new-8", Unit: "ns/op", Points: []*Point{{Commit: next, Center: 50}}},
+	}, "a")
+	if len(drifts) != 2 {
+		t.Fatalf("have %d drifts, want 2", len(drifts))
+	}
+	d := drifts[0]
+	if d.Family != "Encode" || d.Benchmarks != 2 || math.Abs(d.Ratio-1) > 1e-9 || d.Min != 0.8 || d.Max != 1.25 {
+		t.Errorf("have drift %+v, want Encode drift of 2 benchmarks with ratio 1 in [0.8, 1.25]", d)
+	}
+}
+
+func TestCompareWindows(t *testing.T) {
+	s := new(Series)
+	for i := 0; i < 25; i++ {
+		// Noisy from commit to commit, and 5% slower for the last 5.
+		center := 100 + float64(i%3)
+		if i >= 20 {
+			center *= 1.05
+		}
+		s.Points = append(s.Points, &Point{Commit: &Commit{Hash: strconv.Itoa(i)}, Center: center})
+	}
+	c := CompareWindows(s, len(s.Points), 5, 10)
+	if c == nil || len(c.Before) != 10 || len(c.Recent) != 5 {
+		t.Fatalf("CompareWindows(5, 10) = %+v, want windows of 10 and 5 points", c)
+	}
+	if c.P >= 0.05 || math.Abs(c.Ratio-1.05) > 0.01 {
+		t.Errorf("have ratio %g (p=%g), want a significant 5%% change", c.Ratio, c.P)
+	}
+	// The window before the change is steady.
+	if c := CompareWindows(s, 20, 5, 0); c == nil || len(c.Before) != 15 || c.P < 0.05 {
+		t.Errorf("CompareWindows(end 20) = %+v, want no significant change in 15+5 points", c)
+	}
+	if c := CompareWindows(s, 3, 5, 0); c != nil {
+		t.Errorf("CompareWindows(end 3) = %+v, want nil", c)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import "golang.org/x/perf/internal/stats"
+
+// A WindowChange compares a window of recent points of a series with
+// the window of points preceding it, to catch gradual changes that no
+// comparison of one commit with the next finds significant.
+type WindowChange struct {
+	Series         *Series
+	Before, Recent []*Point // measured points of each window, in order
+
+	// Ratio is the median of the medians of Recent divided by that of
+	// Before.
+	Ratio float64
+
+	// P is the p-value of a Mann-Whitney U-test of whether the
+	// medians of the points of the two windows come from the same
+	// distribution.
+	P float64
+}
+
+// CompareWindows compares the k points of s ending before the point
+// at index end with the w points preceding them, or with all the
+// points preceding them if w is 0. Sliding end along the series makes
+// a rolling comparison. Each point counts once, by its median, so that
+// the variation from commit to commit, not that of the runs of one
+// commit, decides significance. Filled points are left out.
+// CompareWindows returns nil if either window has no points measured.
+func CompareWindows(s *Series, end, k, w int) *WindowChange {
+	if end > len(s.Points) {
+		end = len(s.Points)
+	}
+	mid := end - k
+	if mid < 0 {
+		mid = 0
+	}
+	start := 0
+	if w > 0 && mid-w > 0 {
+		start = mid - w
+	}
+	c := &WindowChange{Series: s, Before: measured(s.Points[start:mid]), Recent: measured(s.Points[mid:end])}
+	if len(c.Before) == 0 || len(c.Recent) == 0 {
+		return nil
+	}
+	before, recent := centers(c.Before), centers(c.Recent)
+	if mb := median(append([]float64(nil), before...)); mb != 0 {
+		c.Ratio = median(append([]float64(nil), recent...)) / mb
+	}
+	c.P = 1
+	if u, err := stats.MannWhitneyUTest(before, recent, stats.LocationDiffers); err == nil {
+		c.P = u.P
+	}
+	return c
+}
+
+// measured returns the points that are not filled.
+func measured(points []*Point) []*Point {
+	var out []*Point
+	for _, p := range points {
+		if !p.Filled {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// centers returns the medians of points.
+func centers(points []*Point) []float64 {
+	xs := make([]float64, len(points))
+	for i, p := range points {
+		xs[i] = p.Center
+	}
+	return xs
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Normalizing results by each machine's baseline.
+
+package benchstat
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+// BaselineSuffix is appended to the unit of each value normalized by
+// a Baseline, so that ns/op becomes ns/op/base.
+const BaselineSuffix = "/base"
+
+// A Baseline holds the historical results of the benchmarks on each of
+// several machines. A Collection with a Baseline divides each value by
+// the baseline of the same benchmark and unit on the machine that
+// measured it, so that results from a fleet of differing machines can
+// be aggregated and compared even when old and new did not run on the
+// same hardware.
+type Baseline struct {
+	// Keys lists the labels identifying the machine of a result,
+	// such as "machine", as recorded by benchsave -machine.
+	Keys []string
+
+	values  map[baselineKey][]float64
+	centers map[baselineKey]float64
+}
+
+// A baselineKey identifies the values of one unit of one benchmark on
+// one machine.
+type baselineKey struct {
+	machine, benchmark, unit string
+}
+
+// NewBaseline returns an empty Baseline identifying machines by the
+// labels keys.
+func NewBaseline(keys []string) *Baseline {
+	return &Baseline{Keys: keys, values: make(map[baselineKey][]float64)}
+}
+
+// AddFile adds the benchmark results read from r to b.
+func (b *Baseline) AddFile(r io.Reader) error {
+	br := benchfmt.NewReader(r)
+	for br.Next() {
+		b.add(br.Result())
+	}
+	return br.Err()
+}
+
+// AddResults adds the benchmark results to b.
+func (b *Baseline) AddResults(results []*benchfmt.Result) {
+	for _, r := range results {
+		b.add(r)
+	}
+}
+
+func (b *Baseline) add(r *benchfmt.Result) {
+	f := strings.Fields(r.Content)
+	if len(f) < 4 || !strings.HasPrefix(f[0], "Benchmark") {
+		return
+	}
+	key := baselineKey{machine: b.machine(r), benchmark: strings.TrimPrefix(f[0], "Benchmark")}
+	for i := 2; i+1 < len(f); i += 2 {
+		val, err := strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			continue
+		}
+		var factor float64
+		key.unit, factor = NormalizeUnit(f[i+1])
+		b.values[key] = append(b.values[key], val*factor)
+	}
+	b.centers = nil
+}
+
+// machine returns the values of the labels b.Keys of r, which identify
+// the machine that measured it.
+func (b *Baseline) machine(r *benchfmt.Result) string {
+	var vals []string
+	for _, k := range b.Keys {
+		vals = append(vals, label(r, k))
+	}
+	return strings.Join(vals, "\x00")
+}
+
+// value returns the baseline of the unit of the benchmark on the
+// machine, which is the median of the values added, and whether there
+// is one. The unit must be in the canonical form of NormalizeUnit.
+func (b *Baseline) value(machine, benchmark, unit string) (float64, bool) {
+	if b.centers == nil {
+		b.centers = make(map[baselineKey]float64)
+		for key, vals := range b.values {
+			if center := MedianCenter(vals); center != 0 {
+				b.centers[key] = center
+			}
+		}
+	}
+	center, ok := b.centers[baselineKey{machine, benchmark, unit}]
+	return center, ok
+}
+
+// baselineUnit returns the unit whose values were normalized into
+// those of unit, and whether unit holds normalized values at all.
+func (c *Collection) baselineUnit(unit string) (string, bool) {
+	if c.Baseline == nil || !strings.HasSuffix(unit, BaselineSuffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(unit, BaselineSuffix), true
+}
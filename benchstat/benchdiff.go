@@ -0,0 +1,158 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The benchdiff format, recording the outcome of a comparison.
+
+package benchstat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// BenchdiffVersion is the version of the benchdiff format written by
+// WriteBenchdiff. Readers should reject versions they do not know.
+const BenchdiffVersion = 1
+
+// A Benchdiff is the outcome of comparing two configurations: for each
+// benchmark and unit, the direction and size of the change and, if the
+// comparison was gated, whether the change passed the gate. It is meant
+// as a stable contract between benchstat and the tools acting on its
+// results, such as CI gates, which need none of its formatting.
+//
+// The benchdiff format is JSON Lines: a line holding the header, the
+// Benchdiff without its Results, as in
+//
+//	{"benchdiff":1,"configs":["old.txt","new.txt"],"decision":"fail"}
+//
+// followed by a line for each of the Results, as in
+//
+//	{"id":"5f0c6e1d2a9b3c47","group":"pkg:example.com/codec","name":"Encode","unit":"ns/op","direction":"regressed","delta":7.21,"low":5.9,"high":8.4,"estimate":7.05,"p":0.008,"gate":"fail"}
+//
+// Fields that are unknown or do not apply are omitted. Later versions
+// of the format may add fields, which readers should ignore, but will
+// change the version if they change the meaning of a field.
+type Benchdiff struct {
+	// Version is the version of the format, BenchdiffVersion.
+	Version int `json:"benchdiff"`
+
+	// Configs names the old and new configurations.
+	Configs []string `json:"configs"`
+
+	// Decision is "pass" or "fail", the decision of the CI gate
+	// on the comparison as a whole, or "" if it was not gated.
+	Decision string `json:"decision,omitempty"`
+
+	Results []*BenchdiffResult `json:"-"`
+}
+
+// A BenchdiffResult is the outcome of comparing one unit of one
+// benchmark.
+type BenchdiffResult struct {
+	ID    string `json:"id"`              // Table.RowID of the row compared
+	Group string `json:"group,omitempty"` // group of the benchmark, such as "pkg:example.com/codec"
+	Name  string `json:"name"`            // name of the benchmark
+	Unit  string `json:"unit"`            // unit compared, such as "ns/op"
+
+	// Direction is "improved" or "regressed" for a significant
+	// change, or "unchanged".
+	Direction string `json:"direction"`
+
+	// Delta is the percent change from old to new, and Low and High
+	// bound its confidence interval around Estimate, the
+	// Hodges-Lehmann estimate of the change.
+	Delta    *float64 `json:"delta,omitempty"`
+	Low      *float64 `json:"low,omitempty"`
+	High     *float64 `json:"high,omitempty"`
+	Estimate *float64 `json:"estimate,omitempty"`
+
+	// P is the p-value of the delta test.
+	P *float64 `json:"p,omitempty"`
+
+	// Gate is "pass" or "fail", the decision of the CI gate on this
+	// change, or "" if its unit was not gated.
+	Gate string `json:"gate,omitempty"`
+}
+
+// NewBenchdiff returns the Benchdiff of the tables comparing two
+// configurations, omitting the geometric means. If gate is not nil, it
+// sets the Gate of the result for each row.
+func NewBenchdiff(tables []*Table, gate func(*Table, *Row) string) *Benchdiff {
+	d := &Benchdiff{Version: BenchdiffVersion}
+	for _, table := range tables {
+		if !table.OldNewDelta {
+			continue
+		}
+		if d.Configs == nil {
+			d.Configs = table.Configs
+		}
+		for _, row := range table.Rows {
+			if row.Benchmark == "[Geo mean]" {
+				continue
+			}
+			r := &BenchdiffResult{
+				ID:        table.RowID(row),
+				Group:     row.Group,
+				Name:      row.Benchmark,
+				Unit:      table.Unit,
+				Direction: "unchanged",
+				Delta:     percent(row.Ratio),
+				Low:       percent(row.RatioLo),
+				High:      percent(row.RatioHi),
+				Estimate:  percent(row.RatioEstimate),
+			}
+			switch {
+			case row.Change > 0:
+				r.Direction = "improved"
+			case row.Change < 0:
+				r.Direction = "regressed"
+			}
+			if row.PValue >= 0 {
+				p := row.PValue
+				r.P = &p
+			}
+			if gate != nil {
+				r.Gate = gate(table, row)
+			}
+			d.Results = append(d.Results, r)
+		}
+	}
+	return d
+}
+
+// WriteBenchdiff writes d to w in the benchdiff format.
+func WriteBenchdiff(w io.Writer, d *Benchdiff) error {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(d); err != nil {
+		return err
+	}
+	for _, r := range d.Results {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadBenchdiff reads a Benchdiff in the benchdiff format from r.
+func ReadBenchdiff(r io.Reader) (*Benchdiff, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	d := new(Benchdiff)
+	if err := dec.Decode(d); err != nil {
+		return nil, fmt.Errorf("reading benchdiff header: %v", err)
+	}
+	if d.Version != BenchdiffVersion {
+		return nil, fmt.Errorf("unsupported benchdiff version %d", d.Version)
+	}
+	for dec.More() {
+		res := new(BenchdiffResult)
+		if err := dec.Decode(res); err != nil {
+			return nil, fmt.Errorf("reading benchdiff result %d: %v", len(d.Results)+1, err)
+		}
+		d.Results = append(d.Results, res)
+	}
+	return d, nil
+}
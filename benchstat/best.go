@@ -0,0 +1,119 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Picking the best of several candidate configurations.
+
+package benchstat
+
+// A Pick is the outcome of choosing the best configuration for one
+// benchmark, or for the geometric mean of all benchmarks, in one unit.
+type Pick struct {
+	Group     string // group of the benchmark, as in Row
+	Benchmark string // benchmark name, or "[Geo mean]"
+	Unit      string // unit compared
+
+	// Best is the configuration with the best value, and RunnerUp the
+	// one with the next best. Margin is the percent change from the
+	// value of RunnerUp to that of Best, such as -12.5 for a time.
+	Best, RunnerUp string
+	Margin         float64
+
+	// Wins reports whether Best is significantly better than every
+	// other configuration, by the delta test of the unit. Geometric
+	// means are not tested, so their picks never win.
+	Wins bool
+}
+
+// PickBest returns, for each of the tables returned by c.Tables that
+// shows two or more configurations, a Pick for each benchmark measured
+// in at least two of them, in the order read, followed, if there is
+// more than one such benchmark, by a Pick for the geometric mean of all
+// of them. Choosing among several variants of an optimization,
+// measured in one run, is then a matter of finding the configuration
+// that wins the most benchmarks, or the geometric mean.
+func (c *Collection) PickBest(tables []*Table) []*Pick {
+	var picks []*Pick
+	for _, t := range tables {
+		if len(t.Configs) < 2 {
+			continue
+		}
+		deltaTest, alpha := c.unitTest(t.Unit)
+		n := 0
+		key := Key{Unit: t.Unit}
+		for _, key.Group = range c.Groups {
+			for _, key.Benchmark = range c.Benchmarks[key.Group] {
+				var metrics []*Metrics
+				for _, key.Config = range c.Configs {
+					metrics = append(metrics, c.Metrics[key])
+				}
+				best, next := pickBest(t, metrics)
+				if next < 0 {
+					continue
+				}
+				p := c.newPick(t, key.Benchmark, metrics, best, next)
+				if len(c.Groups) > 1 {
+					p.Group = key.Group
+				}
+				p.Wins = true
+				for i, m := range metrics {
+					if i == best || m == nil {
+						continue
+					}
+					d := &Row{PValue: -1}
+					c.compare(d, t.Unit, m, metrics[best], deltaTest, alpha, true)
+					if d.Change <= 0 {
+						p.Wins = false
+						break
+					}
+				}
+				picks = append(picks, p)
+				n++
+			}
+		}
+		if n > 1 {
+			g, _ := c.geomeanRow(t.Unit, c.Groups, false)
+			if best, next := pickBest(t, g.Metrics); next >= 0 {
+				picks = append(picks, c.newPick(t, g.Benchmark, g.Metrics, best, next))
+			}
+		}
+	}
+	return picks
+}
+
+// pickBest returns the indexes of the best and the next best of the
+// metrics of t, ignoring missing ones, or -1 for each it lacks.
+func pickBest(t *Table, metrics []*Metrics) (best, next int) {
+	better := func(i, j int) bool {
+		if t.Better == HigherIsBetter {
+			return metrics[i].Center > metrics[j].Center
+		}
+		return metrics[i].Center < metrics[j].Center
+	}
+	best, next = -1, -1
+	for i, m := range metrics {
+		switch {
+		case m == nil || m.Unit == "":
+		case best < 0 || better(i, best):
+			best, next = i, best
+		case next < 0 || better(i, next):
+			next = i
+		}
+	}
+	return best, next
+}
+
+// newPick returns the Pick of the best and next best of the metrics of
+// benchmark in t.
+func (c *Collection) newPick(t *Table, benchmark string, metrics []*Metrics, best, next int) *Pick {
+	p := &Pick{
+		Benchmark: benchmark,
+		Unit:      t.Unit,
+		Best:      c.Configs[best],
+		RunnerUp:  c.Configs[next],
+	}
+	if r := metrics[next].Center; r != 0 {
+		p.Margin = (metrics[best].Center/r - 1) * 100
+	}
+	return p
+}
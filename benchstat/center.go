@@ -0,0 +1,57 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Central values.
+
+package benchstat
+
+import "golang.org/x/perf/benchmath"
+
+// A Center returns the central value of a sample of values.
+// The center of each metric is the value shown in tables,
+// and the delta between two metrics compares their centers.
+type Center func(values []float64) float64
+
+// MeanCenter is a Center returning the arithmetic mean of the values.
+// It is the default.
+func MeanCenter(values []float64) float64 {
+	return benchmath.MeanCenter(values)
+}
+
+// MedianCenter is a Center returning the median of the values.
+// Unlike the mean, the median is not pulled by a long tail of slow
+// values, as is common in latency benchmarks.
+func MedianCenter(values []float64) float64 {
+	return benchmath.MedianCenter(values)
+}
+
+// MinCenter is a Center returning the smallest of the values, the
+// "best of N" runs. On noisy shared machines, where interference only
+// ever slows a benchmark down, the fastest run best approximates the
+// true cost of the code.
+func MinCenter(values []float64) float64 {
+	return benchmath.MinCenter(values)
+}
+
+// MaxCenter is a Center returning the largest of the values, which is
+// the best of rates like MB/s, as MinCenter is of times.
+func MaxCenter(values []float64) float64 {
+	return benchmath.MaxCenter(values)
+}
+
+// PercentileCenter returns a Center returning the p'th quantile of the
+// values, for p in [0, 1], such as 0.1 for a center nearly as robust to
+// interference as MinCenter but less sensitive to a single lucky run.
+func PercentileCenter(p float64) Center {
+	return Center(benchmath.PercentileCenter(p))
+}
+
+// BestCenter returns the Center returning the best of the values by
+// better: MinCenter if lower values are better, or else MaxCenter.
+func BestCenter(better Direction) Center {
+	if better == HigherIsBetter {
+		return MaxCenter
+	}
+	return MinCenter
+}
@@ -0,0 +1,58 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+// ValueColumns holds the raw values of a Collection in columnar form:
+// parallel string columns Config, Group, Benchmark, and Unit, and the
+// values of every row concatenated in Values, so that row i holds
+// Values[Offsets[i]:Offsets[i+1]].
+//
+// ValueColumns is plain Go slices, not an Apache Arrow record batch,
+// and benchstat does not depend on an Arrow library. Offsets and
+// Values do follow the layout of the offsets and child buffers of an
+// Arrow list<float64> array, so a caller that links one can build
+// Arrow arrays from them without converting each value.
+type ValueColumns struct {
+	Config, Group, Benchmark, Unit []string
+	Offsets                        []int32
+	Values                         []float64
+}
+
+// Len returns the number of rows in c.
+func (c *ValueColumns) Len() int {
+	return len(c.Config)
+}
+
+// Row returns the values of row i.
+func (c *ValueColumns) Row(i int) []float64 {
+	return c.Values[c.Offsets[i]:c.Offsets[i+1]]
+}
+
+// ValueColumns returns the raw values in c in columnar form, with one row
+// per Key, ordered by config, group, benchmark, and unit, in the
+// order they were added to c.
+func (c *Collection) ValueColumns() *ValueColumns {
+	cols := &ValueColumns{Offsets: []int32{0}}
+	key := Key{}
+	for _, key.Config = range c.Configs {
+		for _, key.Group = range c.Groups {
+			for _, key.Benchmark = range c.Benchmarks[key.Group] {
+				for _, key.Unit = range c.Units {
+					m := c.Metrics[key]
+					if m == nil {
+						continue
+					}
+					cols.Config = append(cols.Config, key.Config)
+					cols.Group = append(cols.Group, key.Group)
+					cols.Benchmark = append(cols.Benchmark, key.Benchmark)
+					cols.Unit = append(cols.Unit, key.Unit)
+					cols.Values = append(cols.Values, m.Values...)
+					cols.Offsets = append(cols.Offsets, int32(len(cols.Values)))
+				}
+			}
+		}
+	}
+	return cols
+}
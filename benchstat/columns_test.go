@@ -0,0 +1,34 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValueColumns(t *testing.T) {
+	c := new(Collection)
+	c.AddConfig("old", []byte("BenchmarkA 1 10 ns/op 5 B/op\nBenchmarkA 1 11 ns/op 5 B/op\nBenchmarkB 1 20 ns/op\n"))
+	c.AddConfig("new", []byte("BenchmarkB 1 21 ns/op\nBenchmarkB 1 22 ns/op\nBenchmarkB 1 23 ns/op\n"))
+	cols := c.ValueColumns()
+	want := &ValueColumns{
+		Config:    []string{"old", "old", "old", "new"},
+		Group:     []string{"", "", "", ""},
+		Benchmark: []string{"A", "A", "B", "B"},
+		Unit:      []string{"ns/op", "B/op", "ns/op", "ns/op"},
+		Offsets:   []int32{0, 2, 4, 5, 8},
+		Values:    []float64{10, 11, 5, 5, 20, 21, 22, 23},
+	}
+	if !reflect.DeepEqual(cols, want) {
+		t.Fatalf("ValueColumns() = %+v, want %+v", cols, want)
+	}
+	if cols.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", cols.Len())
+	}
+	if have, want := cols.Row(3), []float64{21, 22, 23}; !reflect.DeepEqual(have, want) {
+		t.Errorf("Row(3) = %v, want %v", have, want)
+	}
+}
@@ -7,9 +7,13 @@ package benchstat
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"math/rand"
 	"strconv"
 	"strings"
+	"unicode"
 
+	"golang.org/x/perf/benchmath"
 	"golang.org/x/perf/internal/stats"
 	"golang.org/x/perf/storage/benchfmt"
 )
@@ -37,13 +41,230 @@ type Collection struct {
 	// If zero, it defaults to 0.05.
 	Alpha float64
 
+	// Outliers is the test used to discard outliers.
+	// If nil, it defaults to IQROutliers.
+	Outliers OutlierTest
+
+	// Center computes the central value of each metric, which tables
+	// show and deltas compare. If nil, it defaults to MeanCenter.
+	Center Center
+
 	// AddGeoMean specifies whether to add a line to the table
 	// showing the geometric mean of all the benchmark results.
 	AddGeoMean bool
 
+	// GroupGeoMean specifies whether to add a line to each group
+	// of a table showing the geometric mean of the group's
+	// benchmarks, which rolls up each package when results are
+	// split by pkg. See also Rollups.
+	GroupGeoMean bool
+
+	// SortByName specifies that tables should order their groups, and
+	// the benchmarks of each group, by name, in the natural order of
+	// NameLess, rather than as they were read in.
+	SortByName bool
+
+	// StatColumns specifies that tables should show sample sizes
+	// and p-values in columns of their own. See Table.StatColumns.
+	StatColumns bool
+
+	// Intervals specifies that tables comparing configurations should
+	// compute the confidence interval and Hodges-Lehmann estimate of
+	// each ratio, Row.RatioLo, RatioHi, and RatioEstimate, which
+	// json, CSV, and benchdiff output report. It takes time that grows
+	// with the sample sizes, so text and HTML tables, which do not
+	// show the interval, leave it unset.
+	Intervals bool
+
+	// AbsDelta specifies that tables comparing two configurations
+	// should show the absolute difference of each significant change
+	// next to the percentage. See Row.AbsDelta.
+	AbsDelta bool
+
+	// Plot, if not nil, draws the distribution of each benchmark's
+	// values in HTML tables. See Table.Plot.
+	Plot Plot
+
+	// Heatmap specifies that HTML tables comparing configurations
+	// color each value by its performance relative to the first
+	// configuration. See Table.Heatmap.
+	Heatmap bool
+
+	// DeltaMode specifies how tables of more than two configurations
+	// compare them. By default, they show no deltas.
+	DeltaMode DeltaMode
+
+	// Missing specifies how tables show benchmarks that were not
+	// measured in every configuration.
+	Missing MissingPolicy
+
 	// SplitBy specifies the labels to split results by.
 	// By default, results will only be split by full name.
 	SplitBy []string
+
+	// Filter, if not nil, selects the results to add; the others
+	// are ignored.
+	Filter *Filter
+
+	// Pivot, if set, lists labels whose values, rather than the
+	// configuration named when adding results, give the
+	// configuration of each result, so that a single file mixing
+	// results from, say, several machines compares the machines.
+	// Results with none of the labels keep the configuration named.
+	Pivot []string
+
+	// Labels, if set, are labels to attach to the results added,
+	// overriding any values of the same keys that the results
+	// record, for splitting, filtering, and pivoting. Callers may
+	// change it between calls to AddFile to tag each file.
+	Labels benchfmt.Labels
+
+	// GoVersion, if set, is the Go version of the results added
+	// that do not record one, for splitting, filtering, and
+	// pivoting by the label GoVersionKey. Callers may change it
+	// between calls to AddFile to set the version of each file.
+	GoVersion string
+
+	// RequireSame lists labels, such as goos or cpu, that must have
+	// the same values in every configuration. See Mismatches.
+	RequireSame []string
+
+	// DiffLabels specifies whether to record the configuration keys
+	// of the results added, for LabelDiffs.
+	DiffLabels bool
+
+	// Baseline, if not nil, normalizes each value by the baseline
+	// of the same benchmark and unit on the machine that measured
+	// it. The normalized values are recorded in the unit with the
+	// suffix BaselineSuffix, such as ns/op/base, and values with no
+	// baseline are dropped.
+	Baseline *Baseline
+
+	// Reservoir, if non-zero, is the maximum number of raw values
+	// kept for each metric. Once a metric has seen more values
+	// than this, a uniformly random subset of Reservoir values is
+	// retained, bounding memory use on very large inputs at the
+	// cost of computing statistics from a sample of the data.
+	// The quartiles used to reject outliers are still estimated
+	// from all values.
+	Reservoir int
+
+	// Warmup is the number of results of each benchmark in each
+	// configuration to discard, in the order read, before the
+	// results that enter the statistics. The first runs of a
+	// benchmark are often slower, as caches fill and pages fault in.
+	Warmup int
+
+	// TimeUnit and SizeUnit, if set, are units to display all
+	// times and sizes in, instead of choosing a scale for each
+	// row. See ConvertUnit for the supported units.
+	TimeUnit, SizeUnit string
+
+	// BinaryPrefixes specifies whether to scale sizes using binary
+	// prefixes (KiB, MiB, ...) rather than decimal ones (kB, MB, ...).
+	BinaryPrefixes bool
+
+	// Digits, if positive, is the number of significant digits of
+	// formatted values, in place of three. Values of 1000 or more in
+	// the unit chosen for them show all their integer digits.
+	Digits int
+
+	// DeltaPrecision, if positive, is the number of decimal places
+	// of percent changes, in place of two.
+	DeltaPrecision int
+
+	// Numbers sets the separators of the numbers in formatted values,
+	// deltas, and p-values.
+	Numbers NumberFormat
+
+	// NormalizeUnits specifies whether to convert equivalent units,
+	// like µs/op and ns/op or B/s and MB/s, to a single canonical
+	// unit as results are added, so that results from harnesses
+	// reporting in different units are compared with each other.
+	// See NormalizeUnit.
+	NormalizeUnits bool
+
+	// PerItem, if set, is a unit counting the items processed per
+	// operation, such as "items/op". For each result reporting it,
+	// every other per-op measurement is also divided by the count
+	// and recorded in a derived per-item unit, so that ns/op and
+	// B/op yield ns/item and B/item.
+	PerItem string
+
+	// ItemName is the name of an item in the derived per-item units,
+	// such as "item" or "query". If empty, it is "item" if PerItem is
+	// items/op, and otherwise PerItem without its /op suffix, as
+	// given: queries/op yields ns/queries.
+	ItemName string
+
+	// Derive lists units to compute from the other measurements of
+	// each result as it is added. See Derivation.
+	Derive []*Derivation
+
+	// Rename, if not nil, rewrites the name of each benchmark, without
+	// its "Benchmark" prefix, as results are added, so that results
+	// recorded before and after a benchmark was renamed, or under names
+	// holding details of the run, such as a commit hash, are compared
+	// with each other. Results it renames to "" are dropped. Filter
+	// sees the names as recorded. See RenameRules.
+	Rename func(name string) string
+
+	// UnitInfo overrides the interpretation of individual units,
+	// which otherwise comes from any unit metadata lines in the
+	// input or from defaults based on the unit's name.
+	UnitInfo map[string]*UnitInfo
+
+	// unitMeta holds the unit metadata read from input files.
+	unitMeta map[string]benchfmt.Labels
+
+	// unitNorms caches the normalized form of each unit seen
+	// when NormalizeUnits is set.
+	unitNorms map[string]unitNorm
+
+	// perItemUnits caches the per-item unit derived from each
+	// per-op unit when PerItem is set.
+	perItemUnits map[string]string
+
+	// renames caches the result of Rename for each name seen.
+	renames map[string]string
+
+	// sameValues records the values of the labels RequireSame
+	// seen in each configuration.
+	sameValues map[sameKey]bool
+
+	// labelValues records the values of the configuration keys
+	// seen in each configuration when DiffLabels is set, along with
+	// an entry with no label for each configuration with results.
+	// lastLabels and lastConfig are the labels and configuration of
+	// the last result recorded.
+	labelValues map[sameKey]bool
+	lastLabels  benchfmt.Labels
+	lastConfig  string
+
+	// warmups counts the results of each benchmark, by key with no
+	// unit, discarded as warmup.
+	warmups map[Key]int
+
+	// lineValues holds the measurements of the result being added,
+	// by unit, for evaluating c.Derive.
+	lineValues map[string]float64
+
+	// Parallelism is the maximum number of goroutines used to
+	// compute statistics and table rows. Results are the same,
+	// and in the same order, regardless of parallelism.
+	// If zero, it defaults to runtime.GOMAXPROCS(0).
+	Parallelism int
+
+	// rand is the source of randomness for reservoir sampling.
+	// It is seeded deterministically so output is reproducible.
+	rand *rand.Rand
+
+	// strings interns benchmark names, units, and groups.
+	strings map[string]string
+	// groupBuf is scratch space for makeGroup.
+	groupBuf []byte
+	// parsed is scratch space for addResult.
+	parsed ParsedResult
 }
 
 // A Key identifies one metric (e.g., "ns/op", "B/op") from one
@@ -56,34 +277,54 @@ type Key struct {
 // A Metrics holds the measurements of a single metric
 // (for example, ns/op or MB/s)
 // for all runs of a particular benchmark.
+//
+// The samples are kept in the order measured: RValues is the sample
+// that all statistics and delta tests are computed from, and Outliers
+// holds the values rejected from it, so that callers can apply their
+// own statistics or draw the distribution of a benchmark without
+// reading its results again. Both are computed by Collection.Tables.
 type Metrics struct {
-	Unit    string    // unit being measured
-	Values  []float64 // measured values
-	RValues []float64 // Values with outliers removed
-	Min     float64   // min of RValues
-	Mean    float64   // mean of RValues
-	Max     float64   // max of RValues
+	Unit     string    // unit being measured
+	Count    int       // number of values measured
+	Iters    float64   // mean number of iterations per value measured
+	Values   []float64 // measured values, or a sample of them if Count > len(Values)
+	RValues  []float64 // Values with outliers removed
+	Outliers []float64 // Values rejected as outliers
+	Min      float64   // min of RValues
+	Mean     float64   // mean of RValues
+	Center   float64   // center of RValues, shown and compared; see Collection.Center
+	Max      float64   // max of RValues
+	Lo, Hi   float64   // values outside [Lo, Hi] are outliers, excluded from RValues
+
+	// q1 and q3 estimate the quartiles of all values when only
+	// a sample of them is retained in Values.
+	q1, q3 *stats.P2Quantile
+
+	// summarized holds the statistics of a sample added by AddSummaries
+	// in place of its values.
+	summarized *benchmath.Moments
 }
 
-// FormatMean formats m.Mean using scaler.
+// FormatMean formats m.Center, which is the mean by default,
+// using scaler.
 func (m *Metrics) FormatMean(scaler Scaler) string {
 	var s string
 	if scaler != nil {
-		s = scaler(m.Mean)
+		s = scaler(m.Center)
 	} else {
-		s = fmt.Sprint(m.Mean)
+		s = fmt.Sprint(m.Center)
 	}
 	return s
 }
 
-// FormatDiff computes and formats the percent variation of max and min compared to mean.
-// If b.Mean or b.Max is zero, FormatDiff returns an empty string.
+// FormatDiff computes and formats the percent variation of max and min compared to center.
+// If b.Center or b.Max is zero, FormatDiff returns an empty string.
 func (m *Metrics) FormatDiff() string {
-	if m.Mean == 0 || m.Max == 0 {
+	if m.Center == 0 || m.Max == 0 {
 		return ""
 	}
-	diff := 1 - m.Min/m.Mean
-	if d := m.Max/m.Mean - 1; d > diff {
+	diff := 1 - m.Min/m.Center
+	if d := m.Max/m.Center - 1; d > diff {
 		diff = d
 	}
 	return fmt.Sprintf("%.0f%%", diff*100.0)
@@ -103,21 +344,32 @@ func (m *Metrics) Format(scaler Scaler) string {
 }
 
 // computeStats updates the derived statistics in m from the raw
-// samples in m.Values.
-func (m *Metrics) computeStats() {
+// samples in m.Values, discarding the values rejected by outliers.
+func (m *Metrics) computeStats(outliers OutlierTest, center Center) {
+	if m.summarized != nil {
+		m.Mean, m.Center = m.summarized.Mean, m.summarized.Mean
+		return
+	}
+	m.RValues = m.RValues[:0]
+	m.Outliers = m.Outliers[:0]
+
 	// Discard outliers.
-	values := stats.Sample{Xs: m.Values}
-	q1, q3 := values.Percentile(0.25), values.Percentile(0.75)
-	lo, hi := q1-1.5*(q3-q1), q3+1.5*(q3-q1)
+	m.Lo, m.Hi = outliers(m)
 	for _, value := range m.Values {
-		if lo <= value && value <= hi {
+		if m.Lo <= value && value <= m.Hi {
 			m.RValues = append(m.RValues, value)
+		} else {
+			m.Outliers = append(m.Outliers, value)
 		}
 	}
 
 	// Compute statistics of remaining data.
 	m.Min, m.Max = stats.Bounds(m.RValues)
 	m.Mean = stats.Mean(m.RValues)
+	m.Center = m.Mean
+	if len(m.RValues) > 0 {
+		m.Center = center(m.RValues)
+	}
 }
 
 // addMetrics returns the metrics with the given key from c,
@@ -152,70 +404,355 @@ func (c *Collection) addMetrics(key Key) *Metrics {
 	return m
 }
 
-// AddFile adds the benchmark results in the formatted data
+// AddConfig adds the benchmark results in the formatted data
 // to the named configuration.
 func (c *Collection) AddConfig(config string, data []byte) {
-	c.Configs = append(c.Configs, config)
+	if err := c.AddFile(config, bytes.NewReader(data)); err != nil {
+		// bytes.Reader never returns errors
+		panic(err)
+	}
+}
+
+// AddFile adds the benchmark results read from r to the named
+// configuration. Results are consumed as they are read, so only the
+// parsed measurements are held in memory, not the input itself.
+func (c *Collection) AddFile(config string, r io.Reader) error {
+	if len(c.Pivot) == 0 {
+		c.Configs = append(c.Configs, config)
+	}
 	key := Key{Config: config}
-	br := benchfmt.NewReader(bytes.NewReader(data))
+	br := benchfmt.NewReader(r)
 	for br.Next() {
 		c.addResult(key, br.Result())
 	}
-	if err := br.Err(); err != nil {
-		// bytes.Reader never returns errors
-		panic(err)
+	for unit, meta := range br.UnitMetadata() {
+		c.AddUnitMetadata(unit, meta)
+	}
+	return br.Err()
+}
+
+// AddUnitMetadata adds metadata describing unit, as read from a unit
+// metadata line by benchfmt.Reader, overriding any earlier values of
+// the same keys. AddFile adds the metadata in its input automatically.
+func (c *Collection) AddUnitMetadata(unit string, meta benchfmt.Labels) {
+	unit, _ = c.normalizeUnit(unit)
+	if c.unitMeta == nil {
+		c.unitMeta = make(map[string]benchfmt.Labels)
+	}
+	if c.unitMeta[unit] == nil {
+		c.unitMeta[unit] = make(benchfmt.Labels)
+	}
+	for k, v := range meta {
+		c.unitMeta[unit][k] = v
 	}
 }
 
 // AddResults adds the benchmark results to the named configuration.
 func (c *Collection) AddResults(config string, results []*benchfmt.Result) {
-	c.Configs = append(c.Configs, config)
+	if len(c.Pivot) == 0 {
+		c.Configs = append(c.Configs, config)
+	}
 	key := Key{Config: config}
 	for _, r := range results {
 		c.addResult(key, r)
 	}
 }
 
+// A ParsedResult is a benchmark result line split into its fields by
+// ParseResult.
+type ParsedResult struct {
+	// Labels and NameLabels are those of the benchfmt.Result.
+	Labels, NameLabels benchfmt.Labels
+
+	// Name is the benchmark name, including its "Benchmark" prefix.
+	Name  string
+	Iters int
+
+	// Values and Units are the measurements of the result,
+	// in the order the line reports them.
+	Values []float64
+	Units  []string
+}
+
+// ParseResult splits the benchmark result r into its fields. It
+// reports false if r is not a benchmark result line, which
+// AddParsedResults would ignore anyway.
+func ParseResult(r *benchfmt.Result) (ParsedResult, bool) {
+	p := ParsedResult{Labels: r.Labels, NameLabels: r.NameLabels}
+	if !p.parse(r.Content) {
+		return ParsedResult{}, false
+	}
+	return p, true
+}
+
+// AddParsedResults is like AddResults, but adds results already split
+// by ParseResult, so that a caller that caches parsed results need not
+// parse their lines again.
+func (c *Collection) AddParsedResults(config string, results []ParsedResult) {
+	if len(c.Pivot) == 0 {
+		c.Configs = append(c.Configs, config)
+	}
+	key := Key{Config: config}
+	var r benchfmt.Result
+	for i := range results {
+		p := &results[i]
+		r.Labels, r.NameLabels = p.Labels, p.NameLabels
+		c.addParsed(key, &r, p)
+	}
+}
+
+// parse sets the name, iterations, and measurements of p from the
+// benchmark line content, reusing the space of p.Values and p.Units.
+// It reports false if content is not a benchmark result line.
+func (p *ParsedResult) parse(content string) bool {
+	// Split the line into fields by hand rather than using
+	// strings.Fields, which allocates a slice for every result.
+	name, rest := nextField(content)
+	iters, rest := nextField(rest)
+	if rest == "" || !strings.HasPrefix(name, "Benchmark") {
+		return false
+	}
+	n, _ := strconv.Atoi(iters)
+	if n == 0 {
+		return false
+	}
+	p.Name, p.Iters = name, n
+	p.Values, p.Units = p.Values[:0], p.Units[:0]
+	for {
+		var value, unit string
+		value, rest = nextField(rest)
+		unit, rest = nextField(rest)
+		if unit == "" {
+			return true
+		}
+		val, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		p.Values = append(p.Values, val)
+		p.Units = append(p.Units, unit)
+	}
+}
+
+// value returns the value of unit in the measurements of p,
+// or 0 if p does not report unit.
+func (p *ParsedResult) value(unit string) float64 {
+	for i, u := range p.Units {
+		if u == unit {
+			return p.Values[i]
+		}
+	}
+	return 0
+}
+
 func (c *Collection) addResult(key Key, r *benchfmt.Result) {
-	f := strings.Fields(r.Content)
-	if len(f) < 4 {
+	// Parse into c.parsed, which is reused for every result.
+	if !c.parsed.parse(r.Content) {
 		return
 	}
-	name := f[0]
-	if !strings.HasPrefix(name, "Benchmark") {
+	c.addParsed(key, r, &c.parsed)
+}
+
+// addParsed adds the measurements in p, parsed from the benchmark
+// line of r, under key, whose Config is set.
+func (c *Collection) addParsed(key Key, r *benchfmt.Result, p *ParsedResult) {
+	if c.Filter != nil && !c.Filter.match(func(key string) string { return c.label(r, key) }) {
 		return
 	}
-	name = strings.TrimPrefix(name, "Benchmark")
-	n, _ := strconv.Atoi(f[1])
-	if n == 0 {
+	benchmark := c.rename(strings.TrimPrefix(p.Name, "Benchmark"))
+	if benchmark == "" {
 		return
 	}
+	if len(c.Pivot) > 0 {
+		if config := c.labelString(r, c.Pivot); config != "" {
+			key.Config = config
+		}
+	}
+	if len(c.RequireSame) > 0 {
+		c.noteSame(key.Config, r)
+	}
+	if c.DiffLabels {
+		c.noteLabels(key.Config, r)
+	}
 	key.Group = c.makeGroup(r)
-	key.Benchmark = name
-	for i := 2; i+2 <= len(f); i += 2 {
-		val, err := strconv.ParseFloat(f[i], 64)
-		if err != nil {
-			continue
+	key.Benchmark = benchmark
+	if c.Warmup > 0 {
+		if c.warmups == nil {
+			c.warmups = make(map[Key]int)
+		}
+		if c.warmups[key] < c.Warmup {
+			c.warmups[key]++
+			return
+		}
+	}
+	var machine string
+	if c.Baseline != nil {
+		machine = c.Baseline.machine(r)
+	}
+	var items float64
+	if c.PerItem != "" {
+		items = p.value(c.PerItem)
+	}
+	if c.Derive != nil && c.lineValues == nil {
+		c.lineValues = make(map[string]float64)
+	}
+	n := p.Iters
+	for i, unit := range p.Units {
+		val := p.Values[i]
+		var factor float64
+		key.Unit, factor = c.normalizeUnit(unit)
+		if c.Baseline != nil {
+			canon, f := NormalizeUnit(key.Unit)
+			base, ok := c.Baseline.value(machine, key.Benchmark, canon)
+			if !ok {
+				continue
+			}
+			key.Unit, val, factor = key.Unit+BaselineSuffix, val*factor*f/base, 1
 		}
-		key.Unit = f[i+1]
-		m := c.addMetrics(key)
+		key.Unit = c.intern(key.Unit)
+		c.addValue(c.addMetrics(key), val*factor, n)
+		if c.Derive != nil {
+			c.lineValues[key.Unit] = val * factor
+		}
+		if items > 0 && unit != c.PerItem && strings.HasSuffix(key.Unit, "/op") {
+			key.Unit = c.perItemUnit(key.Unit)
+			c.addValue(c.addMetrics(key), val*factor/items, n)
+			if c.Derive != nil {
+				c.lineValues[key.Unit] = val * factor / items
+			}
+		}
+	}
+	if c.Derive != nil {
+		c.addDerived(key, n)
+	}
+}
+
+// addDerived adds the values of each unit in c.Derive computed from
+// the values in c.lineValues, which hold the measurements of a single
+// result, and then clears c.lineValues. Later derivations may use
+// the values of earlier ones.
+func (c *Collection) addDerived(key Key, iters int) {
+	for _, d := range c.Derive {
+		if v, ok := d.Eval(c.lineValues); ok {
+			key.Unit = c.intern(d.Unit)
+			c.addValue(c.addMetrics(key), v, iters)
+			c.lineValues[key.Unit] = v
+		}
+	}
+	for unit := range c.lineValues {
+		delete(c.lineValues, unit)
+	}
+}
+
+// perItemUnit returns the unit derived from the per-op unit by
+// dividing by c.PerItem, named for c.ItemName, so that ns/op per
+// items/op is ns/item.
+func (c *Collection) perItemUnit(unit string) string {
+	if u, ok := c.perItemUnits[unit]; ok {
+		return u
+	}
+	if c.perItemUnits == nil {
+		c.perItemUnits = make(map[string]string)
+	}
+	item := c.ItemName
+	switch {
+	case item != "":
+	case c.PerItem == "items/op":
+		item = "item"
+	default:
+		item = strings.TrimSuffix(c.PerItem, "/op")
+	}
+	u := c.intern(strings.TrimSuffix(unit, "/op") + "/" + item)
+	c.perItemUnits[unit] = u
+	return u
+}
+
+// nextField returns the first space-separated field of s
+// and the remainder of s following that field.
+func nextField(s string) (field, rest string) {
+	i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsSpace(r) })
+	if i < 0 {
+		return "", ""
+	}
+	s = s[i:]
+	i = strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// intern returns a string equal to s, reusing a previous copy if
+// there is one. Interning keeps keys from pinning entire input lines
+// in memory and makes repeated lookups of the same key cheap.
+func (c *Collection) intern(s string) string {
+	if t, ok := c.strings[s]; ok {
+		return t
+	}
+	if c.strings == nil {
+		c.strings = make(map[string]string)
+	}
+	s = string([]byte(s))
+	c.strings[s] = s
+	return s
+}
+
+// addValue records val, measured over the given number of
+// iterations, in m. If c.Reservoir is set and m already
+// holds that many values, val replaces a random existing value
+// with the probability needed to keep m.Values a uniform sample
+// of all values seen (Vitter's Algorithm R).
+func (c *Collection) addValue(m *Metrics, val float64, iters int) {
+	m.Count++
+	m.Iters += (float64(iters) - m.Iters) / float64(m.Count)
+	if c.Reservoir <= 0 {
 		m.Values = append(m.Values, val)
+		return
+	}
+	// Track the quartiles of the full stream so that outliers
+	// are judged against all values, not just the reservoir.
+	if m.q1 == nil {
+		m.q1, m.q3 = stats.NewP2Quantile(0.25), stats.NewP2Quantile(0.75)
+	}
+	m.q1.Add(val)
+	m.q3.Add(val)
+	if len(m.Values) < c.Reservoir {
+		m.Values = append(m.Values, val)
+		return
+	}
+	if c.rand == nil {
+		c.rand = rand.New(rand.NewSource(1))
+	}
+	if i := c.rand.Intn(m.Count); i < len(m.Values) {
+		m.Values[i] = val
 	}
 }
 
 func (c *Collection) makeGroup(r *benchfmt.Result) string {
-	var out string
-	for _, s := range c.SplitBy {
-		v := r.NameLabels[s]
-		if v == "" {
-			v = r.Labels[s]
-		}
+	if len(c.SplitBy) == 0 {
+		return ""
+	}
+	return c.labelString(r, c.SplitBy)
+}
+
+// labelString returns the values of the labels keys of r, as
+// "key:value" pairs separated by spaces, omitting those r lacks.
+func (c *Collection) labelString(r *benchfmt.Result, keys []string) string {
+	buf := c.groupBuf[:0]
+	for _, s := range keys {
+		v := c.label(r, s)
 		if v != "" {
-			if out != "" {
-				out = out + " "
+			if len(buf) > 0 {
+				buf = append(buf, ' ')
 			}
-			out += fmt.Sprintf("%s:%s", s, v)
+			buf = append(buf, s...)
+			buf = append(buf, ':')
+			buf = append(buf, v...)
 		}
 	}
-	return out
+	c.groupBuf = buf
+	if g, ok := c.strings[string(buf)]; ok {
+		return g
+	}
+	return c.intern(string(buf))
 }
@@ -0,0 +1,124 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+// readCollection returns a Collection of the results in files, a
+// configuration for each.
+func readCollection(t *testing.T, files ...string) *Collection {
+	c := new(Collection)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.AddFile(file, f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestPerItemUnits(t *testing.T) {
+	for _, tt := range []struct {
+		perItem, name, want string
+	}{
+		{"items/op", "", "ns/item"},
+		{"items/op", "item", "ns/item"},
+		{"items/op", "element", "ns/element"},
+		{"queries/op", "", "ns/queries"},
+		{"queries/op", "query", "ns/query"},
+		{"pass/op", "", "ns/pass"},
+	} {
+		c := &Collection{PerItem: tt.perItem, ItemName: tt.name}
+		c.AddConfig("a", []byte("BenchmarkX 1 100 ns/op 4 "+tt.perItem+"\n"))
+		if !reflect.DeepEqual(c.Units, []string{"ns/op", tt.want, tt.perItem}) {
+			t.Errorf("PerItem %q, ItemName %q: units %q, want %s", tt.perItem, tt.name, c.Units, tt.want)
+		}
+	}
+}
+
+func TestAddParsedResults(t *testing.T) {
+	// Results added parsed compare as those added as lines would.
+	f, err := os.Open("testdata/exampleold.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var parsed []ParsedResult
+	br := benchfmt.NewReader(f)
+	for br.Next() {
+		if p, ok := ParseResult(br.Result()); ok {
+			parsed = append(parsed, p)
+		}
+	}
+	if err := br.Err(); err != nil {
+		t.Fatal(err)
+	}
+	c := &Collection{SplitBy: []string{"goos"}}
+	c.AddParsedResults("testdata/exampleold.txt", parsed)
+	want := &Collection{SplitBy: []string{"goos"}}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if err := want.AddFile("testdata/exampleold.txt", f); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.Metrics, want.Metrics) || !reflect.DeepEqual(c.Groups, want.Groups) {
+		t.Errorf("parsed results added metrics %v, groups %q, want %v, %q", c.Metrics, c.Groups, want.Metrics, want.Groups)
+	}
+
+	if _, ok := ParseResult(&benchfmt.Result{Content: "BenchmarkX 0 10 ns/op"}); ok {
+		t.Errorf("ParseResult of a result with no iterations succeeded")
+	}
+}
+
+func TestReservoir(t *testing.T) {
+	const n, size = 1000, 10
+	var buf strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "BenchmarkX 1 %d ns/op\n", i)
+	}
+	sample := func() *Metrics {
+		c := &Collection{Reservoir: size}
+		c.AddConfig("a", []byte(buf.String()))
+		return c.Metrics[Key{Config: "a", Benchmark: "X", Unit: "ns/op"}]
+	}
+	m := sample()
+	if len(m.Values) != size || m.Count != n {
+		t.Fatalf("kept %d values of %d, want %d of %d", len(m.Values), m.Count, size, n)
+	}
+	seen := make(map[float64]bool)
+	later := 0
+	for _, v := range m.Values {
+		if v != math.Trunc(v) || v < 0 || v >= n || seen[v] {
+			t.Fatalf("sample %v holds values not read, or a value twice", m.Values)
+		}
+		seen[v] = true
+		if v >= size {
+			later++
+		}
+	}
+	if later == 0 {
+		t.Errorf("sample %v holds only the first values read", m.Values)
+	}
+	// The sample is the same on every run.
+	if again := sample(); !reflect.DeepEqual(again.Values, m.Values) {
+		t.Errorf("samples differ between runs: %v and %v", m.Values, again.Values)
+	}
+}
@@ -7,9 +7,9 @@
 package benchstat
 
 import (
-	"errors"
+	"math"
 
-	"golang.org/x/perf/internal/stats"
+	"golang.org/x/perf/benchmath"
 )
 
 // A DeltaTest compares the old and new metrics and returns the
@@ -25,9 +25,9 @@ type DeltaTest func(old, new *Metrics) (float64, error)
 
 // Errors returned by DeltaTest.
 var (
-	ErrSamplesEqual = errors.New("all equal")
-	ErrSampleSize   = errors.New("too few samples")
-	ErrZeroVariance = errors.New("zero variance")
+	ErrSamplesEqual = benchmath.ErrSamplesEqual
+	ErrSampleSize   = benchmath.ErrSampleSize
+	ErrZeroVariance = benchmath.ErrZeroVariance
 )
 
 // NoDeltaTest applies no delta test; it returns -1, nil.
@@ -37,36 +37,89 @@ func NoDeltaTest(old, new *Metrics) (pval float64, err error) {
 
 // TTest is a DeltaTest using the two-sample Welch t-test.
 func TTest(old, new *Metrics) (pval float64, err error) {
-	t, err := stats.TwoSampleWelchTTest(stats.Sample{Xs: old.RValues}, stats.Sample{Xs: new.RValues}, stats.LocationDiffers)
-	if err != nil {
-		return -1, convertErr(err)
+	if old.summarized != nil || new.summarized != nil {
+		return summaryTTest(old, new)
 	}
-	return t.P, nil
+	return benchmath.TTest(old.RValues, new.RValues)
 }
 
-// UTest is a DeltaTest using the Mann-Whitney U test.
+// UTest is a DeltaTest using the Mann-Whitney U test. Metrics added
+// by AddSummaries have no values to rank, so UTest applies the t-test
+// to them instead.
 func UTest(old, new *Metrics) (pval float64, err error) {
-	u, err := stats.MannWhitneyUTest(old.RValues, new.RValues, stats.LocationDiffers)
-	if err != nil {
-		return -1, convertErr(err)
+	if old.summarized != nil || new.summarized != nil {
+		return summaryTTest(old, new)
 	}
-	return u.P, nil
+	return benchmath.UTest(old.RValues, new.RValues)
 }
 
-// convertErr converts from the stats package's internal errors
-// to errors exported by this package and expected from
-// a DeltaTest.
-// Using different errors makes it possible for clients to use
-// package benchstat without access to the internal stats package,
-// and it also gives us a chance to use shorter error messages.
-func convertErr(err error) error {
-	switch err {
-	case stats.ErrZeroVariance:
-		return ErrZeroVariance
-	case stats.ErrSampleSize:
-		return ErrSampleSize
-	case stats.ErrSamplesEqual:
-		return ErrSamplesEqual
+// summaryTTest is TTest of old and new, either of which may have only
+// a summary of its sample.
+func summaryTTest(old, new *Metrics) (pval float64, err error) {
+	mold, _ := old.moments()
+	mnew, _ := new.moments()
+	return benchmath.TTestMoments(mold, mnew)
+}
+
+// underpowered reports whether samples of sizes n1 and n2 are too
+// small for test to return a p-value below alpha, however different
+// they are.
+func underpowered(test DeltaTest, alpha float64, n1, n2 int) bool {
+	return benchmath.Underpowered(func(old, new []float64) (float64, error) {
+		return test(&Metrics{RValues: old}, &Metrics{RValues: new})
+	}, alpha, n1, n2)
+}
+
+// discrete reports whether the samples old and new of unit are of a
+// discrete metric, like allocs/op, that the delta tests compare poorly,
+// as benchmath.Discrete does. Outliers are considered too, since the
+// rejection of a value that differs by rounding would otherwise make
+// a sample look constant.
+//
+// Only the units go test rounds, B/op and allocs/op, differ by
+// rounding, and only if their centers are less than half a unit apart,
+// since rounding cannot explain 1, 1, 1, 2 becoming 2, 2, 2, 2.
+func discrete(unit string, old, new *Metrics) (exact, rounding bool) {
+	exact, rounding = benchmath.Discrete(old.Values, new.Values)
+	if rounding && (!roundedUnits[unit] || math.Abs(new.Center-old.Center) >= 0.5) {
+		rounding = false
+	}
+	return exact, rounding
+}
+
+// roundedUnits are the units whose values go test rounds to an integer.
+var roundedUnits = map[string]bool{
+	"B/op":      true,
+	"allocs/op": true,
+}
+
+// centerRatio returns the ratio of the centers of new and old, or 0 if
+// old's is 0.
+func centerRatio(old, new *Metrics) float64 {
+	if old.Center == 0 {
+		return 0
+	}
+	return new.Center / old.Center
+}
+
+// ratioShift returns the Hodges-Lehmann estimate of the ratio of new
+// to old, and a confidence interval for it at the given level, or
+// zeros for any it cannot compute, as benchmath.RatioShift does.
+func ratioShift(old, new *Metrics, confidence float64) (est, lo, hi float64) {
+	return benchmath.RatioShift(old.summary(), new.summary(), confidence)
+}
+
+// summary returns the statistics of m as a benchmath.Summary.
+func (m *Metrics) summary() *benchmath.Summary {
+	return &benchmath.Summary{
+		Values:   m.Values,
+		RValues:  m.RValues,
+		Outliers: m.Outliers,
+		Lo:       m.Lo,
+		Hi:       m.Hi,
+		Min:      m.Min,
+		Max:      m.Max,
+		Mean:     m.Mean,
+		Center:   m.Center,
 	}
-	return err
 }
@@ -0,0 +1,53 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiscrete(t *testing.T) {
+	// results returns go test output for Encode with the given values
+	// of unit.
+	results := func(unit string, values ...int) []byte {
+		var buf strings.Builder
+		for _, v := range values {
+			fmt.Fprintf(&buf, "BenchmarkEncode 1000 %d %s\n", v, unit)
+		}
+		return []byte(buf.String())
+	}
+	for _, tt := range []struct {
+		name     string
+		unit     string
+		old, new []int
+		delta    string
+		note     string
+	}{
+		{"all equal", "allocs/op", []int{2, 2, 2, 2}, []int{2, 2, 2, 2}, "0.00%", "(all equal)"},
+		{"exact", "allocs/op", []int{2, 2, 2, 2}, []int{3, 3, 3, 3}, "+50.00%", "(exact)"},
+		{"rounding", "B/op", []int{48, 49, 48, 49, 48}, []int{49, 48, 49, 48, 49}, "~", "(within rounding)"},
+		// A regression larger than rounding can explain is tested,
+		// however few the samples.
+		{"few", "allocs/op", []int{1, 1, 1, 2}, []int{2, 2, 2, 2}, "~", "(p=0.143 n=4+4)"},
+		{"regression", "allocs/op", []int{1, 1, 1, 2, 1, 1, 1, 2}, []int{2, 2, 2, 2, 2, 2, 2, 2}, "+60.00%", "(p=0.007 n=8+8)"},
+		// Only the units go test rounds differ by rounding.
+		{"not rounded", "ns/op", []int{48, 49, 48, 49, 48}, []int{49, 48, 49, 48, 49}, "~", "(p=1.000 n=5+5)"},
+	} {
+		c := &Collection{Outliers: NoOutliers}
+		c.AddConfig("old", results(tt.unit, tt.old...))
+		c.AddConfig("new", results(tt.unit, tt.new...))
+		tables := c.Tables()
+		if len(tables) != 1 || len(tables[0].Rows) != 1 {
+			t.Errorf("%s: want one table of one row", tt.name)
+			continue
+		}
+		row := tables[0].Rows[0]
+		if row.Delta != tt.delta || row.Note != tt.note {
+			t.Errorf("%s: delta %q %q, want %q %q", tt.name, row.Delta, row.Note, tt.delta, tt.note)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A Derivation defines a unit computed from the other measurements
+// of each benchmark result, such as
+//
+//	bytes-per-alloc = B/op / allocs/op
+//
+// Derived values are computed for each result before any statistics,
+// and are then treated like any other unit.
+type Derivation struct {
+	// Unit is the name of the derived unit.
+	Unit string
+
+	expr derivExpr
+}
+
+// ParseDerivation parses a derivation of the form "unit = expr".
+// The expression may use numbers, unit names, parentheses, and the
+// operators +, -, *, and /. Since unit names may themselves contain
+// "/" and "-", binary operators must be separated from their
+// operands by spaces.
+func ParseDerivation(s string) (*Derivation, error) {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return nil, fmt.Errorf("derivation %q: missing =", s)
+	}
+	unit := strings.TrimSpace(s[:i])
+	if unit == "" || strings.ContainsAny(unit, " \t") {
+		return nil, fmt.Errorf("derivation %q: bad unit name %q", s, unit)
+	}
+	p := &derivParser{toks: derivTokens(s[i+1:])}
+	expr, err := p.expr()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %q", p.toks[p.pos])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("derivation %q: %v", s, err)
+	}
+	return &Derivation{Unit: unit, expr: expr}, nil
+}
+
+func (d *Derivation) String() string {
+	return d.Unit + " = " + d.expr.String()
+}
+
+// Eval evaluates the derivation given the measurements of a single
+// result, keyed by unit. It reports false if the result lacks a unit
+// the expression uses or the value is not finite.
+func (d *Derivation) Eval(values map[string]float64) (float64, bool) {
+	v, ok := d.expr.eval(values)
+	if !ok || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, false
+	}
+	return v, true
+}
+
+// derivTokens splits s into tokens: parentheses, and space-separated
+// words, which are operators, numbers, or unit names.
+func derivTokens(s string) []string {
+	var toks []string
+	for _, f := range strings.Fields(s) {
+		for strings.HasPrefix(f, "(") {
+			toks = append(toks, "(")
+			f = f[1:]
+		}
+		n := 0
+		for strings.HasSuffix(f, ")") {
+			f = f[:len(f)-1]
+			n++
+		}
+		if f != "" {
+			toks = append(toks, f)
+		}
+		for ; n > 0; n-- {
+			toks = append(toks, ")")
+		}
+	}
+	return toks
+}
+
+// A derivParser is a recursive descent parser for the grammar
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = factor { ("*" | "/") factor }
+//	factor = number | unit | "-" factor | "(" expr ")"
+type derivParser struct {
+	toks []string
+	pos  int
+}
+
+func (p *derivParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *derivParser) expr() (derivExpr, error) {
+	x, err := p.term()
+	for err == nil && (p.peek() == "+" || p.peek() == "-") {
+		op := p.toks[p.pos][0]
+		p.pos++
+		var y derivExpr
+		y, err = p.term()
+		x = &derivBinary{op, x, y}
+	}
+	return x, err
+}
+
+func (p *derivParser) term() (derivExpr, error) {
+	x, err := p.factor()
+	for err == nil && (p.peek() == "*" || p.peek() == "/") {
+		op := p.toks[p.pos][0]
+		p.pos++
+		var y derivExpr
+		y, err = p.factor()
+		x = &derivBinary{op, x, y}
+	}
+	return x, err
+}
+
+func (p *derivParser) factor() (derivExpr, error) {
+	tok := p.peek()
+	p.pos++
+	switch tok {
+	case "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case "+", "*", "/", ")":
+		return nil, fmt.Errorf("unexpected %q", tok)
+	case "-":
+		x, err := p.factor()
+		return &derivBinary{'-', derivNum(0), x}, err
+	case "(":
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return x, nil
+	}
+	if v, err := strconv.ParseFloat(tok, 64); err == nil {
+		return derivNum(v), nil
+	}
+	return derivUnit(tok), nil
+}
+
+// A derivExpr is a node of a parsed derivation expression.
+type derivExpr interface {
+	eval(values map[string]float64) (float64, bool)
+	String() string
+}
+
+type derivNum float64
+
+func (x derivNum) eval(map[string]float64) (float64, bool) { return float64(x), true }
+func (x derivNum) String() string                          { return strconv.FormatFloat(float64(x), 'g', -1, 64) }
+
+type derivUnit string
+
+func (x derivUnit) eval(values map[string]float64) (float64, bool) {
+	v, ok := values[string(x)]
+	return v, ok
+}
+func (x derivUnit) String() string { return string(x) }
+
+type derivBinary struct {
+	op   byte
+	x, y derivExpr
+}
+
+func (b *derivBinary) eval(values map[string]float64) (float64, bool) {
+	x, ok := b.x.eval(values)
+	if !ok {
+		return 0, false
+	}
+	y, ok := b.y.eval(values)
+	if !ok {
+		return 0, false
+	}
+	switch b.op {
+	case '+':
+		return x + y, true
+	case '-':
+		return x - y, true
+	case '*':
+		return x * y, true
+	}
+	return x / y, true
+}
+
+func (b *derivBinary) String() string {
+	return "(" + b.x.String() + " " + string(b.op) + " " + b.y.String() + ")"
+}
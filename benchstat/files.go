@@ -0,0 +1,58 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Comparing inputs held in memory.
+
+package benchstat
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// A File is a named input of benchmark results, such as the output of
+// "go test -bench" pasted into a web page.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// CompareFiles returns the tables comparing the benchmark results in
+// files, each read as a configuration named by its Name, split by pkg,
+// goos, and goarch, as the benchstat command splits them by default,
+// and ending with the geometric mean of each table. Given a single
+// file, it returns the tables summarizing it. It needs no file system
+// or other facilities of the operating system, so that it serves
+// callers such as a web page running benchstat compiled to
+// WebAssembly.
+func CompareFiles(files ...File) ([]*Table, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files to compare")
+	}
+	c := &Collection{
+		AddGeoMean: true,
+		SplitBy:    []string{"pkg", "goos", "goarch"},
+	}
+	for _, f := range files {
+		if err := c.AddFile(f.Name, bytes.NewReader(f.Data)); err != nil {
+			return nil, fmt.Errorf("%s: %v", f.Name, err)
+		}
+	}
+	for _, f := range files {
+		if !c.hasConfig(f.Name) {
+			return nil, fmt.Errorf("%s: no benchmark results", f.Name)
+		}
+	}
+	return c.Tables(), nil
+}
+
+// hasConfig reports whether c holds any results of config.
+func (c *Collection) hasConfig(config string) bool {
+	for key := range c.Metrics {
+		if key.Config == config {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareFiles(t *testing.T) {
+	var files []File
+	for _, name := range []string{"pkgs-old.txt", "pkgs-new.txt"} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, File{Name: name, Data: data})
+	}
+	tables, err := CompareFiles(files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || !tables[0].OldNewDelta {
+		t.Fatalf("got %d tables, want 1 comparing two configurations", len(tables))
+	}
+	rows := tables[0].Rows
+	if first, want := rows[0].Group, "pkg:example.com/codec goos:linux goarch:amd64"; first != want {
+		t.Errorf("first group = %q, want %q", first, want)
+	}
+	if last := rows[len(rows)-1].Benchmark; last != "[Geo mean]" {
+		t.Errorf("last row = %q, want [Geo mean]", last)
+	}
+
+	if _, err := CompareFiles(files[0], File{Name: "empty"}); err == nil || err.Error() != "empty: no benchmark results" {
+		t.Errorf("comparing with no results: err = %v", err)
+	}
+	if _, err := CompareFiles(); err == nil {
+		t.Errorf("comparing no files succeeded")
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Filtering results by their labels.
+
+package benchstat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+// A Filter selects results by their labels: the configuration keys of
+// the file they were read from, such as goos, cpu, or any custom key,
+// and the labels parsed from the benchmark name.
+type Filter struct {
+	terms []filterTerm
+}
+
+// A filterTerm requires a label to match, or not to match, a pattern.
+type filterTerm struct {
+	key     string
+	pattern *regexp.Regexp
+	negate  bool
+}
+
+// ParseFilter parses a filter, a comma-separated list of terms, all of
+// which a result must satisfy. A term key:pattern requires the label
+// key to match the pattern, in which * matches any run of characters
+// and ? any one character; a pattern may list alternatives separated
+// by |. A term -key:pattern requires the label not to match. A missing
+// label matches only the empty pattern. For example,
+//
+//	goos:linux,cpu:*Xeon*,-builder:slow|flaky
+//
+// selects the results measured on Linux with a Xeon CPU, other than
+// those from the slow and flaky builders.
+func ParseFilter(s string) (*Filter, error) {
+	f := new(Filter)
+	for _, t := range strings.Split(s, ",") {
+		i := strings.Index(t, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid filter term %q: want key:pattern", t)
+		}
+		term := filterTerm{key: strings.TrimSpace(t[:i])}
+		if strings.HasPrefix(term.key, "-") {
+			term.key, term.negate = term.key[1:], true
+		}
+		if term.key == "" {
+			return nil, fmt.Errorf("invalid filter term %q: missing key", t)
+		}
+		var alts []string
+		for _, p := range strings.Split(t[i+1:], "|") {
+			p = regexp.QuoteMeta(p)
+			p = strings.Replace(p, `\*`, ".*", -1)
+			p = strings.Replace(p, `\?`, ".", -1)
+			alts = append(alts, p)
+		}
+		term.pattern = regexp.MustCompile("^(?:" + strings.Join(alts, "|") + ")$")
+		f.terms = append(f.terms, term)
+	}
+	return f, nil
+}
+
+// Match reports whether the result r satisfies every term of f.
+func (f *Filter) Match(r *benchfmt.Result) bool {
+	return f.match(func(key string) string { return label(r, key) })
+}
+
+// match reports whether the labels returned by lookup satisfy every
+// term of f.
+func (f *Filter) match(lookup func(key string) string) bool {
+	for _, t := range f.terms {
+		if t.pattern.MatchString(lookup(t.key)) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// label returns the value of the label key of r: the label parsed from
+// the benchmark name, if any, or else the configuration key. The labels
+// GoVersionKey and go-version are both derived from any of the keys
+// GoVersion accepts.
+func label(r *benchfmt.Result, key string) string {
+	if v := r.NameLabels[key]; v != "" {
+		return v
+	}
+	if isGoVersionKey(key) {
+		return GoVersion(r.Labels)
+	}
+	return r.Labels[key]
+}
+
+// label returns the value of the label key of r, as the function label
+// does, unless c.Labels sets it, defaulting the Go version to
+// c.GoVersion.
+func (c *Collection) label(r *benchfmt.Result, key string) string {
+	if v, ok := c.Labels[key]; ok {
+		return v
+	}
+	v := label(r, key)
+	if v == "" && isGoVersionKey(key) {
+		v = c.GoVersion
+	}
+	return v
+}
+
+// ParseLabel parses a label to attach to results, written key=value.
+// The key must be valid as a configuration key: it must begin with a
+// lower-case letter and contain no upper-case letters, spaces, or
+// colons.
+func ParseLabel(s string) (key, value string, err error) {
+	i := strings.Index(s, "=")
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid label %q: want key=value", s)
+	}
+	key, value = s[:i], s[i+1:]
+	for j, c := range key {
+		if j == 0 && !unicode.IsLower(c) || unicode.IsUpper(c) || unicode.IsSpace(c) || c == ':' {
+			return "", "", fmt.Errorf("invalid label key %q", key)
+		}
+	}
+	if key == "" {
+		return "", "", fmt.Errorf("invalid label %q: missing key", s)
+	}
+	if strings.ContainsAny(value, "\n") {
+		return "", "", fmt.Errorf("invalid label %q: value contains a newline", s)
+	}
+	return key, value, nil
+}
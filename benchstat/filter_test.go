@@ -0,0 +1,63 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"testing"
+
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+func TestParseFilter(t *testing.T) {
+	r := &benchfmt.Result{
+		Labels:     benchfmt.Labels{"pkg": "golang.org/x/perf/benchstat", "cpu": "Intel(R) Xeon(R) CPU @ 2.20GHz", "builder": "linux-race"},
+		NameLabels: benchfmt.Labels{"name": "Encode"},
+	}
+	for filter, want := range map[string]bool{
+		"pkg:golang.org/*":            true,
+		"cpu:*Xeon*,builder:linux-*":  true,
+		"-builder:*-race":             false,
+		"builder:linux-amd64|*-race":  true,
+		"name:Enc?de":                 true,
+		"name:Decode":                 false,
+		"goos:linux":                  false,
+		"goos:":                       true,
+		"-goos:linux,pkg:*/benchstat": true,
+	} {
+		f, err := ParseFilter(filter)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", filter, err)
+			continue
+		}
+		if got := f.Match(r); got != want {
+			t.Errorf("ParseFilter(%q).Match = %v, want %v", filter, got, want)
+		}
+	}
+	for _, filter := range []string{"goos", "goos:linux,", ":linux", "-:x"} {
+		if _, err := ParseFilter(filter); err == nil {
+			t.Errorf("ParseFilter(%q) succeeded, want error", filter)
+		}
+	}
+}
+
+func TestParseLabel(t *testing.T) {
+	for _, tt := range []struct {
+		in, key, value string
+	}{
+		{"branch=feature-x", "branch", "feature-x"},
+		{"runner=gha ubuntu=22", "runner", "gha ubuntu=22"},
+		{"empty=", "empty", ""},
+	} {
+		key, value, err := ParseLabel(tt.in)
+		if err != nil || key != tt.key || value != tt.value {
+			t.Errorf("ParseLabel(%q) = %q, %q, %v, want %q, %q, nil", tt.in, key, value, err, tt.key, tt.value)
+		}
+	}
+	for _, in := range []string{"branch", "=x", "Branch=x", "my branch=x", "a:b=x", "note=a\nb"} {
+		if _, _, err := ParseLabel(in); err == nil {
+			t.Errorf("ParseLabel(%q) succeeded, want error", in)
+		}
+	}
+}
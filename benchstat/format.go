@@ -0,0 +1,235 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Formatting tables in any of the output formats.
+
+package benchstat
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FormatOptions control the output of Format.
+type FormatOptions struct {
+	// Format is the output format: "text" (the default), "html",
+	// "csv", "markdown", or "json".
+	Format string
+
+	// Color colors the rows of significant improvements green and
+	// those of regressions red in text output, using ANSI escapes.
+	Color bool
+
+	// StatColumns adds the sample sizes and p-values of every table
+	// in columns of their own, as if each table's StatColumns were set.
+	StatColumns bool
+
+	// Precision, if positive, is the number of decimal places of each
+	// delta, in place of the default two.
+	Precision int
+
+	// Units lists units, such as "ms" and "MB", to convert values to
+	// in JSON output. Each value is converted to the first unit that
+	// ConvertUnit can convert it to.
+	Units []string
+}
+
+// Format writes the tables to w in the format set by opts. Tables and
+// their rows are left unchanged.
+func Format(w io.Writer, tables []*Table, opts FormatOptions) error {
+	if opts.StatColumns || opts.Precision > 0 {
+		tables = withOptions(tables, opts)
+	}
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+		formatText(w, tables, opts.Color)
+		return nil
+	case "html":
+		var buf bytes.Buffer
+		FormatHTML(&buf, tables)
+		_, err := w.Write(buf.Bytes())
+		return err
+	case "csv":
+		return formatCSV(w, tables)
+	case "markdown", "md":
+		return formatMarkdown(w, tables)
+	case "json":
+		return formatJSON(w, tables, opts.Units)
+	}
+	return fmt.Errorf("unknown output format %q", opts.Format)
+}
+
+// withOptions returns copies of tables with the StatColumns and
+// Precision of opts applied.
+func withOptions(tables []*Table, opts FormatOptions) []*Table {
+	var out []*Table
+	for _, table := range tables {
+		t := new(Table)
+		*t = *table
+		t.StatColumns = t.StatColumns || opts.StatColumns
+		if opts.Precision > 0 {
+			t.Rows = nil
+			for _, row := range table.Rows {
+				r := new(Row)
+				*r = *row
+				r.Delta = formatDelta(row, opts.Precision)
+				t.Rows = append(t.Rows, r)
+			}
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// formatDelta formats the percent change of row to prec decimal
+// places, from its ratio if known or else from its Delta. A Delta
+// that is not a percentage, such as ~, is returned unchanged.
+func formatDelta(row *Row, prec int) string {
+	if !strings.HasSuffix(row.Delta, "%") {
+		return row.Delta
+	}
+	pct := (row.Ratio - 1) * 100
+	if row.Ratio == 0 {
+		var err error
+		pct, err = strconv.ParseFloat(strings.TrimSuffix(row.Delta, "%"), 64)
+		if err != nil {
+			return row.Delta
+		}
+	}
+	if pct == 0 {
+		return fmt.Sprintf("%.*f%%", prec, 0.0)
+	}
+	return fmt.Sprintf("%+.*f%%", prec, pct)
+}
+
+// formatCSV writes the tables to w as CSV, with the cells of text
+// output, a record holding only the name of each group, and an empty
+// record between tables. The first column holds the Table.RowID of
+// each row of values. The last columns hold the unit of the table and
+// the size, mean, and variance of the sample of each value, to full
+// precision, for ReadSummaries, and, in tables comparing two
+// configurations, the percent change as a number, with the bounds of
+// its confidence interval and its Hodges-Lehmann estimate, if known.
+func formatCSV(w io.Writer, tables []*Table) error {
+	cw := csv.NewWriter(w)
+	for i, table := range tables {
+		if i > 0 {
+			cw.Write(nil)
+		}
+		rows := toText(table)
+		width := 0
+		for _, row := range rows {
+			if width < len(row.cols) {
+				width = len(row.cols)
+			}
+		}
+		for j, row := range rows {
+			rec := append([]string{row.id}, trimCells(row.cols)...)
+			switch {
+			case j == 0:
+				rec[0] = "id"
+				rec = append(padCells(rec, 1+width), "unit")
+				for _, config := range table.Configs {
+					rec = append(rec, config+" n", config+" mean", config+" variance")
+				}
+				if len(table.Configs) == 2 {
+					rec = append(rec, "delta", "delta_low", "delta_high", "delta_estimate")
+				}
+			case row.row != nil:
+				rec = append(padCells(rec, 1+width), table.Unit)
+				for _, m := range row.row.Metrics {
+					mo, ok := m.moments()
+					if !ok {
+						rec = append(rec, "", "", "")
+						continue
+					}
+					rec = append(rec, strconv.Itoa(mo.N), strconv.FormatFloat(mo.Mean, 'g', -1, 64), strconv.FormatFloat(mo.Variance, 'g', -1, 64))
+				}
+				if len(table.Configs) == 2 {
+					r := row.row
+					rec = append(rec, csvPercent(r.Ratio), csvPercent(r.RatioLo), csvPercent(r.RatioHi), csvPercent(r.RatioEstimate))
+				}
+			}
+			cw.Write(rec)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvPercent formats the percent change of ratio for CSV output, or
+// returns "" if ratio is 0, unknown.
+func csvPercent(ratio float64) string {
+	if p := percent(ratio); p != nil {
+		return strconv.FormatFloat(*p, 'g', -1, 64)
+	}
+	return ""
+}
+
+// padCells returns cols extended with empty cells to n cells.
+func padCells(cols []string, n int) []string {
+	for len(cols) < n {
+		cols = append(cols, "")
+	}
+	return cols
+}
+
+// formatMarkdown writes the tables to w as Markdown tables, with the
+// name of each group in bold in a row of its own.
+func formatMarkdown(w io.Writer, tables []*Table) error {
+	var buf bytes.Buffer
+	for i, table := range tables {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		rows := toText(table)
+		n := 0
+		for _, row := range rows {
+			if n < len(row.cols) {
+				n = len(row.cols)
+			}
+		}
+		line := func(cols []string) {
+			for j := 0; j < n; j++ {
+				s := ""
+				if j < len(cols) {
+					s = strings.Replace(cols[j], "|", `\|`, -1)
+				}
+				fmt.Fprintf(&buf, "| %s ", s)
+			}
+			buf.WriteString("|\n")
+		}
+		line(trimCells(rows[0].cols))
+		for j := 0; j < n; j++ {
+			if j == 0 {
+				buf.WriteString("|:---")
+			} else {
+				buf.WriteString("|---:")
+			}
+		}
+		buf.WriteString("|\n")
+		for _, row := range rows[1:] {
+			if len(row.cols) == 1 {
+				line([]string{"**" + row.cols[0] + "**"})
+				continue
+			}
+			line(trimCells(row.cols))
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// trimCells returns cols with the padding aligning text output removed.
+func trimCells(cols []string) []string {
+	out := make([]string, len(cols))
+	for i, s := range cols {
+		out[i] = strings.TrimSpace(s)
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	c := new(Collection)
+	for _, file := range []string{"testdata/exampleold.txt", "testdata/examplenew.txt"} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.AddConfig(file, data)
+	}
+	tables := c.Tables()
+
+	var buf bytes.Buffer
+	if err := Format(&buf, tables, FormatOptions{Color: true, Precision: 3}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"\x1b[32mGobEncode", "-13.308%", "\x1b[0m\n", "JSONEncode"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("colored text output lacks %q:\n%s", want, buf.String())
+		}
+	}
+	if tables[0].Rows[0].Delta != "-13.31%" {
+		t.Errorf("Format changed the delta of a row to %s", tables[0].Rows[0].Delta)
+	}
+
+	buf.Reset()
+	if err := Format(&buf, tables, FormatOptions{Format: "json", StatColumns: true, Units: []string{"s"}}); err != nil {
+		t.Fatal(err)
+	}
+	var js [][]struct{ Cols []string }
+	if err := json.Unmarshal(buf.Bytes(), &js); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := js[0][1].Cols[:5], []string{"GobEncode", "0.013599058", "s/op", "1%", "4"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("json output row: have %q, want %q", have, want)
+	}
+
+	if err := Format(&buf, tables, FormatOptions{Format: "yaml"}); err == nil {
+		t.Errorf("Format with unknown format succeeded, want error")
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gate decides whether the changes in a benchstat comparison
+// are acceptable, with the semantics of benchstat -fail and -min-count,
+// so that bots and servers gating changes on benchmarks reach the same
+// decisions as the command.
+package gate
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/perf/benchstat"
+)
+
+// A Policy sets the changes a comparison may make.
+type Policy struct {
+	// Thresholds maps each gated unit to the largest significant
+	// regression allowed in it, in percent. The unit "" applies to
+	// all units not otherwise listed; without it, other units are not
+	// gated.
+	Thresholds map[string]float64
+
+	// MinCount, if positive, is the fewest samples each benchmark
+	// must have in each configuration.
+	MinCount int
+
+	// Correction corrects the significance of regressions for the
+	// number of comparisons made. With no correction, a regression is
+	// significant if the comparison reported it as a change.
+	Correction Correction
+
+	// Alpha is the level of significance of a corrected regression.
+	// The default is 0.05.
+	Alpha float64
+}
+
+// A Correction is a correction for multiple comparisons, which makes a
+// gate on many benchmarks less likely to fail on changes due to
+// chance alone.
+type Correction string
+
+const (
+	// NoCorrection gates on the significance of each change alone.
+	NoCorrection Correction = ""
+
+	// Bonferroni multiplies the p-value of each change by the number
+	// of changes tested.
+	Bonferroni Correction = "bonferroni"
+
+	// Holm applies the Holm–Bonferroni method, which is less strict
+	// than Bonferroni while making the same guarantee.
+	Holm Correction = "holm"
+)
+
+// A Decision is the outcome of gating a comparison.
+type Decision int
+
+const (
+	Pass Decision = iota // no violations
+	Fail                 // at least one violation
+)
+
+func (d Decision) String() string {
+	if d == Fail {
+		return "fail"
+	}
+	return "pass"
+}
+
+// Kinds of violations.
+const (
+	Regression    = "regression"      // a significant regression exceeds its threshold
+	TooFewSamples = "too few samples" // a benchmark has fewer than MinCount samples
+)
+
+// A Violation is a change, or a benchmark, that a Policy does not allow.
+type Violation struct {
+	Kind  string           // Regression or TooFewSamples
+	Table *benchstat.Table // table of the row
+	Row   *benchstat.Row   // row violating the policy
+
+	// Message describes the violation, as in
+	// "Encode: time/op +7.21% exceeds 5% threshold".
+	Message string
+}
+
+func (v *Violation) String() string {
+	return v.Kind + ": " + v.Message
+}
+
+// Threshold returns the threshold for unit and whether unit is gated.
+func (p *Policy) Threshold(unit string) (float64, bool) {
+	if pct, ok := p.Thresholds[unit]; ok {
+		return pct, true
+	}
+	pct, ok := p.Thresholds[""]
+	return pct, ok
+}
+
+// Evaluate gates the comparisons in tables by policy, returning the
+// decision and the violations, all regressions first.
+func Evaluate(tables []*benchstat.Table, policy *Policy) (Decision, []*Violation) {
+	var violations []*Violation
+	significant := policy.significance(tables)
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			if msg := policy.regression(table, row, significant); msg != "" {
+				violations = append(violations, &Violation{Regression, table, row, msg})
+			}
+		}
+	}
+	if policy.MinCount > 0 {
+		for _, table := range tables {
+			for _, row := range table.Rows {
+				for i, m := range row.Metrics {
+					if m.Unit != "" && m.Count < policy.MinCount {
+						msg := fmt.Sprintf("%s: %s has %d samples in %s, fewer than %d", row.Benchmark, table.Metric, m.Count, table.Configs[i], policy.MinCount)
+						violations = append(violations, &Violation{TooFewSamples, table, row, msg})
+					}
+				}
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return Fail, violations
+	}
+	return Pass, nil
+}
+
+// regression returns a description of the change in row of table if
+// it is a significant regression that exceeds its unit's threshold, or
+// else the empty string. If significant is not nil, it holds the rows
+// whose change is significant after correction.
+func (p *Policy) regression(table *benchstat.Table, row *benchstat.Row, significant map[*benchstat.Row]bool) string {
+	if !table.OldNewDelta || row.Change >= 0 {
+		return ""
+	}
+	if significant != nil && row.PValue >= 0 && !significant[row] {
+		return ""
+	}
+	old, new := row.Metrics[0], row.Metrics[1]
+	limit, ok := p.Threshold(old.Unit)
+	if !ok || old.Center == 0 {
+		return ""
+	}
+	pct := (new.Center/old.Center - 1) * 100
+	if pct < 0 {
+		pct = -pct
+	}
+	if pct <= limit {
+		return ""
+	}
+	name := row.Benchmark
+	if row.Group != "" {
+		name = row.Group + " " + name
+	}
+	return fmt.Sprintf("%s: %s %s exceeds %g%% threshold", name, table.Metric, row.Delta, limit)
+}
+
+// significance returns the rows of gated units in tables whose
+// changes remain significant after p.Correction, or nil if there is no
+// correction. Rows without a p-value, such as exact comparisons, are
+// left as the comparison reported them.
+func (p *Policy) significance(tables []*benchstat.Table) map[*benchstat.Row]bool {
+	if p.Correction == NoCorrection {
+		return nil
+	}
+	alpha := p.Alpha
+	if alpha == 0 {
+		alpha = 0.05
+	}
+	var rows []*benchstat.Row
+	for _, table := range tables {
+		if _, ok := p.Threshold(table.Unit); !ok || !table.OldNewDelta {
+			continue
+		}
+		for _, row := range table.Rows {
+			if row.PValue >= 0 {
+				rows = append(rows, row)
+			}
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].PValue < rows[j].PValue })
+
+	significant := make(map[*benchstat.Row]bool)
+	m := float64(len(rows))
+	for i, row := range rows {
+		switch p.Correction {
+		case Bonferroni:
+			significant[row] = row.PValue*m < alpha
+		case Holm:
+			// Stop at the first change that is not significant.
+			if row.PValue*(m-float64(i)) >= alpha {
+				return significant
+			}
+			significant[row] = true
+		}
+	}
+	return significant
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gate
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"golang.org/x/perf/benchstat"
+)
+
+// compare returns the tables comparing n benchmarks, each 10% slower
+// in new than in old, with five samples of each.
+func compare(n int) []*benchstat.Table {
+	var old, new bytes.Buffer
+	for i := 0; i < n; i++ {
+		for j := 0; j < 5; j++ {
+			fmt.Fprintf(&old, "BenchmarkB%d 1 %d ns/op\n", i, 100+j)
+			fmt.Fprintf(&new, "BenchmarkB%d 1 %d ns/op\n", i, 110+j)
+		}
+	}
+	c := &benchstat.Collection{}
+	c.AddConfig("old", old.Bytes())
+	c.AddConfig("new", new.Bytes())
+	return c.Tables()
+}
+
+func TestEvaluate(t *testing.T) {
+	for _, tt := range []struct {
+		n          int
+		policy     Policy
+		violations int
+	}{
+		{3, Policy{Thresholds: map[string]float64{"": 5}}, 3},
+		{3, Policy{Thresholds: map[string]float64{"": 20}}, 0},
+		{3, Policy{Thresholds: map[string]float64{"B/op": 5}}, 0},
+		{10, Policy{Thresholds: map[string]float64{"": 5}}, 10},
+		{3, Policy{Thresholds: map[string]float64{"": 5}, Correction: Bonferroni}, 3},
+		{10, Policy{Thresholds: map[string]float64{"": 5}, Correction: Bonferroni}, 0},
+		{10, Policy{Thresholds: map[string]float64{"": 5}, Correction: Bonferroni, Alpha: 0.1}, 10},
+		{3, Policy{Thresholds: map[string]float64{"": 5}, Correction: Holm}, 3},
+		{10, Policy{Thresholds: map[string]float64{"": 5}, Correction: Holm}, 0},
+		{3, Policy{MinCount: 6}, 6},
+	} {
+		decision, violations := Evaluate(compare(tt.n), &tt.policy)
+		want := Pass
+		if tt.violations > 0 {
+			want = Fail
+		}
+		if decision != want || len(violations) != tt.violations {
+			t.Errorf("Evaluate(%d benchmarks, %+v) = %v, %d violations, want %v, %d", tt.n, tt.policy, decision, len(violations), want, tt.violations)
+		}
+	}
+
+	_, violations := Evaluate(compare(1), &Policy{Thresholds: map[string]float64{"ns/op": 5}})
+	if len(violations) != 1 {
+		t.Fatalf("Evaluate = %d violations, want 1", len(violations))
+	}
+	if have, want := violations[0].String(), "regression: B0: time/op +9.80% exceeds 5% threshold"; have != want {
+		t.Errorf("violation %q, want %q", have, want)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Finding the Go version that results were measured with.
+
+package benchstat
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+// GoVersionKey is the label holding the Go version of a result, which
+// label lookups derive from other configuration keys when a result
+// does not record it directly. Using it as a Pivot compares the Go
+// versions in a single file, as in go1.12 against go1.13.
+const GoVersionKey = "go"
+
+// isGoVersionKey reports whether the label key is the Go version.
+func isGoVersionKey(key string) bool {
+	return key == GoVersionKey || key == "go-version"
+}
+
+// goVersionKeys are the configuration keys that various tools record
+// the Go version under, in order of preference.
+var goVersionKeys = []string{"go", "go-version", "goversion", "toolchain", "version"}
+
+// goVersionRE matches a Go version, such as go1.12, go1.13.4, or
+// go1.14beta1, or the version of a development toolchain.
+var goVersionRE = regexp.MustCompile(`\bgo1(?:\.[0-9]+)*(?:(?:beta|rc)[0-9]+)?\b|\bdevel\b`)
+
+// GoVersion returns the Go version recorded in the configuration
+// labels, such as "go1.13.4", or "" if there is none. It accepts the
+// keys go, go-version, goversion, toolchain, and version, and values
+// such as "go1.13.4", "1.13.4", and the output of "go version".
+func GoVersion(labels benchfmt.Labels) string {
+	for _, key := range goVersionKeys {
+		if v := ParseGoVersion(labels[key]); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ParseGoVersion returns the Go version in s, in the form go1.13.4, or
+// "" if s holds none. A bare version, such as 1.13.4, gains the go
+// prefix, and a development toolchain's version is "devel".
+func ParseGoVersion(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "1.") {
+		s = "go" + s
+	}
+	return goVersionRE.FindString(s)
+}
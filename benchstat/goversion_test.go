@@ -0,0 +1,31 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"testing"
+
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+func TestGoVersion(t *testing.T) {
+	for _, tt := range []struct {
+		labels benchfmt.Labels
+		want   string
+	}{
+		{benchfmt.Labels{"go": "go1.13.4"}, "go1.13.4"},
+		{benchfmt.Labels{"go-version": "1.12"}, "go1.12"},
+		{benchfmt.Labels{"goversion": "go1.14beta1"}, "go1.14beta1"},
+		{benchfmt.Labels{"version": "go version go1.13.4 linux/amd64"}, "go1.13.4"},
+		{benchfmt.Labels{"toolchain": "devel +3f2a9c0 Tue Oct 1 2019"}, "devel"},
+		{benchfmt.Labels{"version": "v2.3.1"}, ""},
+		{benchfmt.Labels{"go": "gopher", "go-version": "go1.12"}, "go1.12"},
+		{nil, ""},
+	} {
+		if got := GoVersion(tt.labels); got != tt.want {
+			t.Errorf("GoVersion(%v) = %q, want %q", tt.labels, got, tt.want)
+		}
+	}
+}
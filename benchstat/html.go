@@ -6,37 +6,45 @@ package benchstat
 
 import (
 	"bytes"
+	"fmt"
 	"html/template"
+	"math"
 	"strings"
 )
 
+// The class names in HTML tables are stable, for use by style sheets:
+// tables have class benchstat, and oldnew if comparing two
+// configurations; rows are configs, heading, group, spacer, or a row
+// of values, classed better, worse, or unchanged if comparing; and
+// cells of values are name, value, n, plot, delta or nodelta,
+// absdelta, p, and note.
 var htmlTemplate = template.Must(template.New("").Funcs(htmlFuncs).Parse(`
 {{- if . -}}
 {{with index . 0}}
 <table class='benchstat {{if .OldNewDelta}}oldnew{{end}}'>
 {{if eq (len .Configs) 1}}
 {{- else -}}
-<tr class='configs'><th>{{range .Configs}}<th>{{.}}{{end}}
+<tr class='configs'><th>{{$t := .}}{{range $i, $c := .Configs}}<th{{with configspan $t $i}} colspan='{{.}}'{{end}}>{{$c}}{{end}}
 {{end}}
 {{end}}
 {{- range $i, $table := .}}
 <tbody>
 {{if eq (len .Configs) 1}}
-<tr><th><th>{{.Metric}}
+<tr class='heading'><th><th>{{.Metric}}{{if .StatColumns}}<th>n{{end}}{{if .Plot}}<th>{{end}}
 {{else -}}
-<tr><th><th colspan='{{len .Configs}}' class='metric'>{{.Metric}}{{if .OldNewDelta}}<th>delta{{end}}
+<tr class='heading'><th><th colspan='{{metricspan .}}' class='metric'>{{.Metric}}{{if .Plot}}<th>{{end}}{{if .OldNewDelta}}<th{{if .AbsDelta}} colspan='2'{{end}}>delta{{if .StatColumns}}<th>p{{end}}{{end}}
 {{end}}{{range $group := group $table.Rows -}}
-{{if and (gt (len $table.Groups) 1) (len (index . 0).Group)}}<tr class='group'><th colspan='{{colspan (len $table.Configs) $table.OldNewDelta}}'>{{(index . 0).Group}}{{end}}
+{{if and (gt (len $table.Groups) 1) (len (index . 0).Group)}}<tr class='group'><th colspan='{{colspan $table}}'>{{(index . 0).Group}}{{end}}
 {{- range $row := . -}}
 {{if $table.OldNewDelta -}}
 <tr class='{{if eq .Change 1}}better{{else if eq .Change -1}}worse{{else}}unchanged{{end}}'>
 {{- else -}}
 <tr>
 {{- end -}}
-<td>{{.Benchmark}}{{range .Metrics}}<td>{{.Format $row.Scaler}}{{end}}{{if $table.OldNewDelta}}<td class='{{if eq .Delta "~"}}nodelta{{else}}delta{{end}}'>{{replace .Delta "-" "−" -1}}<td class='note'>{{.Note}}{{end}}
+<td class='name'>{{.Benchmark}}{{range $i, $m := .Metrics}}<td class='value'{{if $table.Heatmap}}{{with heat $table $row .}} style='{{.}}'{{end}}{{end}}>{{.Format $row.Scaler}}{{if $table.StatColumns}}<td class='n'>{{formatN .}}{{end}}{{if and $i $table.DeltaMode}}{{with coldelta $row $i}}<td class='{{if eq .Delta "~"}}nodelta{{else}}delta{{end}}'>{{replace .Delta "-" "−" -1}}{{if $table.StatColumns}}<td class='p'>{{formatP $table .}}{{end}}{{else}}<td>{{if $table.StatColumns}}<td>{{end}}{{end}}{{end}}{{end}}{{if $table.Plot}}<td class='plot'>{{plot $table .}}{{end}}{{if $table.OldNewDelta}}<td class='{{if eq .Delta "~"}}nodelta{{else}}delta{{end}}'>{{replace .Delta "-" "−" -1}}{{if $table.AbsDelta}}<td class='absdelta'>{{replace .AbsDelta "-" "−" -1}}{{end}}{{if $table.StatColumns}}<td class='p'>{{formatP $table .}}{{end}}<td class='note'>{{.Note}}{{end}}
 {{end -}}
 {{- end -}}
-<tr><td>&nbsp;
+<tr class='spacer'><td>&nbsp;
 </tbody>
 {{end}}
 </table>
@@ -44,16 +52,107 @@ var htmlTemplate = template.Must(template.New("").Funcs(htmlFuncs).Parse(`
 `))
 
 var htmlFuncs = template.FuncMap{
-	"replace": strings.Replace,
-	"group":   htmlGroup,
-	"colspan": htmlColspan,
+	"replace":    strings.Replace,
+	"group":      htmlGroup,
+	"colspan":    htmlColspan,
+	"metricspan": htmlMetricspan,
+	"formatN":    formatN,
+	"formatP":    formatP,
+	"plot":       htmlPlot,
+	"heat":       htmlHeat,
+	"configspan": htmlConfigspan,
+	"coldelta":   htmlColdelta,
 }
 
-func htmlColspan(configs int, delta bool) int {
-	if delta {
-		configs++
+// htmlMetricspan returns the number of columns under the metric
+// heading of t.
+func htmlMetricspan(t *Table) int {
+	n := 0
+	for i := range t.Configs {
+		n += htmlColumns(t, i)
 	}
-	return configs + 1
+	return n
+}
+
+// htmlColumns returns the number of columns showing the i'th
+// configuration of t: its value, and any sample size and delta.
+func htmlColumns(t *Table, i int) int {
+	n := 1
+	if i > 0 && t.DeltaMode != DeltaDefault {
+		n++
+	}
+	if t.StatColumns {
+		n *= 2
+	}
+	return n
+}
+
+// htmlConfigspan returns the span of the heading of the i'th
+// configuration of t, or 0 if it spans just one column.
+func htmlConfigspan(t *Table, i int) int {
+	if n := htmlColumns(t, i); n > 1 {
+		return n
+	}
+	return 0
+}
+
+// htmlColdelta returns the delta shown after the i'th value of row r,
+// or nil if there is none.
+func htmlColdelta(r *Row, i int) *Row {
+	return r.delta(i - 1)
+}
+
+// htmlColspan returns the number of columns in t.
+func htmlColspan(t *Table) int {
+	n := htmlMetricspan(t) + 1
+	if t.Plot != nil {
+		n++
+	}
+	if t.OldNewDelta {
+		n++
+		if t.AbsDelta {
+			n++
+		}
+		if t.StatColumns {
+			n++
+		}
+	}
+	return n
+}
+
+// htmlPlot returns the plot of row r of t.
+func htmlPlot(t *Table, r *Row) template.HTML {
+	return t.Plot(r)
+}
+
+// htmlHeatLimit is the ratio to the first configuration at which
+// heatmap colors are fully saturated.
+const htmlHeatLimit = 1.25
+
+// htmlHeat returns the heatmap style of the cell of t showing m in
+// row r: red if m is worse than the first configuration of r, or
+// green if better, deepening with the size of the difference.
+func htmlHeat(t *Table, r *Row, m *Metrics) template.CSS {
+	base := r.Metrics[0]
+	if m == base || m.Unit == "" || base.Unit == "" || base.Center == 0 || m.Center == 0 {
+		return ""
+	}
+	ratio := m.Center / base.Center
+	if t.Better == HigherIsBetter {
+		ratio = 1 / ratio
+	}
+	heat := math.Log(ratio) / math.Log(htmlHeatLimit)
+	hue := 0
+	if heat < 0 {
+		heat, hue = -heat, 120
+	}
+	if heat > 1 {
+		heat = 1
+	}
+	if heat < 0.01 {
+		return ""
+	}
+	return template.CSS(fmt.Sprintf("background-color: hsl(%d, 60%%, %.0f%%)", hue, 100-25*heat))
 }
 
 func htmlGroup(rows []*Row) (out [][]*Row) {
@@ -0,0 +1,211 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// formatJSON writes a JSON formatting of the tables to w, as a list of
+// tables, each a list of rows, converting values to the first of units
+// that ConvertUnit can convert them to.
+func formatJSON(w io.Writer, tables []*Table, units []string) error {
+	var jsonTables [][]*jsonRow
+	for _, t := range tables {
+		jsonTables = append(jsonTables, toJSON(t, units))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jsonTables)
+}
+
+// A jsonRow is a row of JSON output.
+type jsonRow struct {
+	// ID is the Table.RowID of a row of values.
+	ID   string `json:",omitempty"`
+	Cols []string
+
+	// Delta is the percent change from old to new in a row comparing
+	// two configurations, and DeltaLow and DeltaHigh bound its
+	// confidence interval around DeltaEstimate, the Hodges-Lehmann
+	// estimate of the change. Each is omitted if unknown.
+	Delta         *float64 `json:",omitempty"`
+	DeltaLow      *float64 `json:",omitempty"`
+	DeltaHigh     *float64 `json:",omitempty"`
+	DeltaEstimate *float64 `json:",omitempty"`
+
+	// Stats summarizes the sample of each value of a row of values,
+	// for ReadSummaries, or holds null for a value with no sample.
+	// It is omitted from rows with no samples, like the geometric
+	// mean.
+	Stats []*jsonStats `json:",omitempty"`
+}
+
+// jsonStats summarizes the sample of one value in JSON output, in the
+// unit of its table, before any conversion to the units of Format.
+type jsonStats struct {
+	Config   string
+	Unit     string
+	N        int
+	Mean     float64
+	Variance float64
+}
+
+func newJSONRow(cols ...string) *jsonRow {
+	return &jsonRow{Cols: cols}
+}
+
+func (r *jsonRow) add(col string) {
+	r.Cols = append(r.Cols, col)
+}
+
+func (r *jsonRow) trim() {
+	for len(r.Cols) > 0 && r.Cols[len(r.Cols)-1] == "" {
+		r.Cols = r.Cols[:len(r.Cols)-1]
+	}
+}
+
+// toJSON converts the Table to rows of JSON output, which give the
+// center, unit, and variation of each value in columns of their own.
+func toJSON(t *Table, units []string) []*jsonRow {
+	var rows []*jsonRow
+	switch len(t.Configs) {
+	case 1:
+		rows = append(rows, newJSONRow("name", "value", t.Metric, "diff"))
+		if t.StatColumns {
+			rows[0].add("n")
+		}
+	case 2:
+		if t.StatColumns {
+			rows = append(rows, newJSONRow("name", "old value", "old "+t.Metric, "diff", "old n", "new value", "new "+t.Metric, "diff", "new n", "delta"))
+		} else {
+			rows = append(rows, newJSONRow("name", "old value", "old "+t.Metric, "diff", "new value", "new "+t.Metric, "diff", "delta"))
+		}
+		if t.AbsDelta {
+			rows[0].add("abs delta")
+		}
+		if t.StatColumns {
+			rows[0].add("p")
+		}
+		rows[0].add("significance")
+	default:
+		row := newJSONRow("name \\ " + t.Metric)
+		for i, config := range t.Configs {
+			row.add(config)
+			if t.StatColumns {
+				row.add("n")
+			}
+			if i > 0 && t.DeltaMode != DeltaDefault {
+				row.add("delta")
+				if t.StatColumns {
+					row.add("p")
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	var group string
+
+	for _, row := range t.Rows {
+		if row.Group != group {
+			group = row.Group
+			rows = append(rows, newJSONRow(group))
+		}
+
+		js := newJSONRow(row.Benchmark)
+		js.ID = t.RowID(row)
+		js.Stats = jsonSummaries(t, row)
+		for i, m := range row.Metrics {
+			mean, unit, diff := jsonValue(m, units)
+			js.Cols = append(js.Cols, mean, unit, diff)
+			if t.StatColumns {
+				js.add(formatN(m))
+			}
+			if i > 0 && t.DeltaMode != DeltaDefault {
+				delta, p := "", ""
+				if d := row.delta(i - 1); d != nil {
+					delta, p = d.Delta, formatP(t, d)
+				}
+				js.add(delta)
+				if t.StatColumns {
+					js.add(p)
+				}
+			}
+		}
+		if len(t.Configs) == 2 {
+			js.add(row.Delta)
+			if t.AbsDelta {
+				js.add(row.AbsDelta)
+			}
+			if t.StatColumns {
+				js.add(formatP(t, row))
+			}
+			js.add(row.Note)
+			js.Delta = percent(row.Ratio)
+			js.DeltaLow = percent(row.RatioLo)
+			js.DeltaHigh = percent(row.RatioHi)
+			js.DeltaEstimate = percent(row.RatioEstimate)
+		}
+		rows = append(rows, js)
+	}
+	for _, r := range rows {
+		r.trim()
+	}
+	return rows
+}
+
+// jsonSummaries returns the summaries of the samples of the values in
+// row, or nil if none has a sample.
+func jsonSummaries(t *Table, row *Row) []*jsonStats {
+	var stats []*jsonStats
+	any := false
+	for i, m := range row.Metrics {
+		mo, ok := m.moments()
+		if !ok {
+			stats = append(stats, nil)
+			continue
+		}
+		stats = append(stats, &jsonStats{Config: t.Configs[i], Unit: t.Unit, N: mo.N, Mean: mo.Mean, Variance: mo.Variance})
+		any = true
+	}
+	if !any {
+		return nil
+	}
+	return stats
+}
+
+// percent returns the percent change given by ratio,
+// or nil if ratio is 0, meaning unknown.
+func percent(ratio float64) *float64 {
+	if ratio == 0 {
+		return nil
+	}
+	pct := (ratio - 1) * 100
+	return &pct
+}
+
+// jsonValue returns the center of m, its unit, and its variation, as
+// by FormatDiff, converting the center to the first of units that
+// ConvertUnit can convert it to. The center is given to full
+// precision, unrounded and without separators of thousands.
+func jsonValue(m *Metrics, units []string) (mean, unit, diff string) {
+	if m.Unit == "" {
+		return "", "", ""
+	}
+	mean, unit = strconv.FormatFloat(m.Center, 'f', -1, 64), m.Unit
+	for _, target := range units {
+		if target == "" {
+			continue
+		}
+		if u, factor, ok := ConvertUnit(m.Unit, target); ok {
+			mean, unit = strconv.FormatFloat(m.Center*factor, 'f', -1, 64), u
+			break
+		}
+	}
+	return mean, unit, m.FormatDiff()
+}
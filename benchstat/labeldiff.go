@@ -0,0 +1,85 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"reflect"
+	"sort"
+
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+// A LabelDiff is a configuration key whose values differ between the
+// configurations of a Collection, such as the cpu or Go version that
+// the benchmarks ran with.
+type LabelDiff struct {
+	Key string
+
+	// Values holds the sorted values of Key in each configuration,
+	// in the order of Collection.Configs. A configuration with no
+	// value of Key has none.
+	Values [][]string
+}
+
+// noteLabels records the configuration keys of r, added to config.
+func (c *Collection) noteLabels(config string, r *benchfmt.Result) {
+	// Results read from the same configuration block share their
+	// labels, so only the first result of each block is recorded.
+	if c.lastLabels != nil && config == c.lastConfig &&
+		reflect.ValueOf(r.Labels).Pointer() == reflect.ValueOf(c.lastLabels).Pointer() {
+		return
+	}
+	c.lastLabels, c.lastConfig = r.Labels, config
+	if c.labelValues == nil {
+		c.labelValues = make(map[sameKey]bool)
+	}
+	c.labelValues[sameKey{"", config, ""}] = true
+	for k, v := range r.Labels {
+		c.labelValues[sameKey{k, config, v}] = true
+	}
+}
+
+// LabelDiffs returns the configuration keys whose values differ between
+// the configurations of c, sorted by key. It requires c.DiffLabels to
+// have been set as the results were added. Configurations with no
+// results are ignored.
+func (c *Collection) LabelDiffs() []*LabelDiff {
+	values := make(map[string]map[string][]string)
+	for k := range c.labelValues {
+		if k.label == "" {
+			continue
+		}
+		if values[k.label] == nil {
+			values[k.label] = make(map[string][]string)
+		}
+		values[k.label][k.config] = append(values[k.label][k.config], k.value)
+	}
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var diffs []*LabelDiff
+	for _, key := range keys {
+		d := &LabelDiff{Key: key}
+		differ := false
+		for _, config := range c.Configs {
+			if !c.labelValues[sameKey{"", config, ""}] {
+				continue
+			}
+			vals := values[key][config]
+			sort.Strings(vals)
+			if len(d.Values) > 0 && !reflect.DeepEqual(vals, d.Values[0]) {
+				differ = true
+			}
+			d.Values = append(d.Values, vals)
+		}
+		if differ {
+			diffs = append(diffs, d)
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,193 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Merging collections and removing results from them.
+
+package benchstat
+
+import "strings"
+
+// Merge adds the metrics of other to c, as if the results added to
+// other had been added to c, so that a long-lived Collection can
+// absorb newly uploaded results read into a Collection of their own.
+// Values of configurations in both are pooled, and configurations and
+// units new to c follow its own, in the order of other. Other's units
+// keep the form they were recorded in, so both should normalize units,
+// if at all, in the same way. Other is left unchanged.
+func (c *Collection) Merge(other *Collection) {
+	for _, config := range other.Configs {
+		if !contains(c.Configs, config) {
+			c.Configs = append(c.Configs, config)
+		}
+	}
+	for _, unit := range other.Units {
+		if !contains(c.Units, unit) {
+			c.Units = append(c.Units, c.intern(unit))
+		}
+	}
+	for _, group := range other.Groups {
+		for _, benchmark := range other.Benchmarks[group] {
+			for _, unit := range other.Units {
+				for _, config := range other.Configs {
+					key := Key{Config: config, Group: group, Benchmark: benchmark, Unit: unit}
+					om := other.Metrics[key]
+					if om == nil {
+						continue
+					}
+					key = Key{c.intern(config), c.intern(group), c.intern(benchmark), c.intern(unit)}
+					m := c.addMetrics(key)
+					for _, v := range om.Values {
+						c.addValue(m, v, int(om.Iters+0.5))
+					}
+					// Count values other sampled away too.
+					m.Count += om.Count - len(om.Values)
+					if om.summarized != nil && m.summarized == nil {
+						m.summarized = om.summarized
+					}
+				}
+			}
+		}
+	}
+	for unit, meta := range other.unitMeta {
+		c.AddUnitMetadata(unit, meta)
+	}
+	for k := range other.sameValues {
+		if c.sameValues == nil {
+			c.sameValues = make(map[sameKey]bool)
+		}
+		c.sameValues[k] = true
+	}
+	for k := range other.labelValues {
+		if c.labelValues == nil {
+			c.labelValues = make(map[sameKey]bool)
+		}
+		c.labelValues[k] = true
+	}
+}
+
+// RemoveConfig removes the named configuration and all its metrics
+// from c, as when expiring the oldest results of a long-lived
+// Collection.
+func (c *Collection) RemoveConfig(config string) {
+	c.remove(func(key Key) bool { return key.Config == config })
+	for i, name := range c.Configs {
+		if name == config {
+			c.Configs = append(c.Configs[:i:i], c.Configs[i+1:]...)
+			break
+		}
+	}
+	for k := range c.sameValues {
+		if k.config == config {
+			delete(c.sameValues, k)
+		}
+	}
+	for k := range c.labelValues {
+		if k.config == config {
+			delete(c.labelValues, k)
+		}
+	}
+}
+
+// Remove removes from c the metrics of every benchmark whose labels
+// match f. The labels of a benchmark are those of its group, which
+// hold the labels of SplitBy, along with the label "config" holding
+// its configuration and "name" holding its name. Other labels of the
+// results added are not kept, so they match only the empty pattern.
+// For example, with SplitBy set to goos, the filter goos:windows
+// removes the benchmarks measured on Windows.
+func (c *Collection) Remove(f *Filter) {
+	c.remove(func(key Key) bool {
+		return f.match(func(label string) string {
+			switch label {
+			case "config":
+				return key.Config
+			case "name":
+				return key.Benchmark
+			}
+			return c.groupLabel(key.Group, label)
+		})
+	})
+}
+
+// remove removes the metrics whose keys match from c, along with the
+// groups, benchmarks, and units left with no metrics.
+func (c *Collection) remove(match func(Key) bool) {
+	for key := range c.Metrics {
+		if match(key) {
+			delete(c.Metrics, key)
+		}
+	}
+	for key := range c.warmups {
+		if match(key) {
+			delete(c.warmups, key)
+		}
+	}
+
+	groups := make(map[string]bool)
+	units := make(map[string]bool)
+	benchmarks := make(map[[2]string]bool)
+	for key := range c.Metrics {
+		groups[key.Group] = true
+		units[key.Unit] = true
+		benchmarks[[2]string{key.Group, key.Benchmark}] = true
+	}
+	c.Groups = keep(c.Groups, func(g string) bool { return groups[g] })
+	c.Units = keep(c.Units, func(u string) bool { return units[u] })
+	for group, names := range c.Benchmarks {
+		names = keep(names, func(b string) bool { return benchmarks[[2]string{group, b}] })
+		if len(names) == 0 {
+			delete(c.Benchmarks, group)
+		} else {
+			c.Benchmarks[group] = names
+		}
+	}
+}
+
+// groupLabel returns the value of the label key in group, which
+// joins the key:value pairs of the labels SplitBy of a result with
+// spaces, or "" if group lacks it. Values may themselves hold spaces,
+// as the cpu label often does, so each value ends only where the next
+// key of SplitBy begins.
+func (c *Collection) groupLabel(group, key string) string {
+	rest := group
+	for i, k := range c.SplitBy {
+		if !strings.HasPrefix(rest, k+":") {
+			continue
+		}
+		value := rest[len(k)+1:]
+		rest = ""
+		for _, next := range c.SplitBy[i+1:] {
+			if j := strings.Index(value, " "+next+":"); j >= 0 {
+				value, rest = value[:j], value[j+1:]
+				break
+			}
+		}
+		if k == key {
+			return value
+		}
+	}
+	return ""
+}
+
+// contains reports whether list holds s.
+func contains(list []string, s string) bool {
+	for _, t := range list {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
+
+// keep returns the strings in list for which f returns true, reusing
+// the storage of list.
+func keep(list []string, f func(string) bool) []string {
+	out := list[:0]
+	for _, s := range list {
+		if f(s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	text := func(c *Collection) string {
+		var buf bytes.Buffer
+		FormatText(&buf, c.Tables())
+		return buf.String()
+	}
+	c := readCollection(t, "testdata/exampleold.txt")
+	c.Merge(readCollection(t, "testdata/examplenew.txt"))
+	if have, want := text(c), text(readCollection(t, "testdata/exampleold.txt", "testdata/examplenew.txt")); have != want {
+		t.Errorf("merged collection:\n%s\nwant:\n%s", have, want)
+	}
+
+	c.RemoveConfig("testdata/examplenew.txt")
+	if have, want := text(c), text(readCollection(t, "testdata/exampleold.txt")); have != want {
+		t.Errorf("after RemoveConfig:\n%s\nwant:\n%s", have, want)
+	}
+
+	f, err := ParseFilter("name:JSON*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Remove(f)
+	if want := []string{"GobEncode"}; !reflect.DeepEqual(c.Benchmarks[""], want) {
+		t.Errorf("after Remove(%s): benchmarks %q, want %q", "name:JSON*", c.Benchmarks[""], want)
+	}
+	f, _ = ParseFilter("config:*")
+	c.Remove(f)
+	if len(c.Metrics) != 0 || len(c.Units) != 0 || len(c.Groups) != 0 {
+		t.Errorf("after removing all: %d metrics, units %q, groups %q, want none", len(c.Metrics), c.Units, c.Groups)
+	}
+}
+
+func TestRemoveConfigLabels(t *testing.T) {
+	// A configuration removed and added again, as by a long-lived
+	// Collection keeping a window of recent results, is compared by
+	// the labels of its new results alone.
+	c := &Collection{RequireSame: []string{"goos"}, DiffLabels: true}
+	c.AddConfig("a", []byte("goos: linux\nBenchmarkX 1 10 ns/op\n"))
+	c.AddConfig("b", []byte("goos: windows\nBenchmarkX 1 12 ns/op\n"))
+	if len(c.Mismatches()) != 1 || len(c.LabelDiffs()) != 1 {
+		t.Fatalf("goos differs: mismatches %q, %d label diffs, want one each", c.Mismatches(), len(c.LabelDiffs()))
+	}
+	c.RemoveConfig("b")
+	c.AddConfig("b", []byte("goos: linux\nBenchmarkX 1 11 ns/op\n"))
+	if have := c.Mismatches(); have != nil {
+		t.Errorf("after RemoveConfig: mismatches %q, want none", have)
+	}
+	if have := c.LabelDiffs(); have != nil {
+		t.Errorf("after RemoveConfig: label diffs %+v, want none", have[0])
+	}
+}
+
+func TestRemoveGroupLabels(t *testing.T) {
+	const results = `cpu: Intel(R) Xeon(R) CPU @ 2.20GHz
+goos: linux
+BenchmarkX 1 10 ns/op
+goos: windows
+BenchmarkX 1 12 ns/op
+cpu: AMD EPYC 7B12 64-Core Processor
+goos: linux
+BenchmarkX 1 11 ns/op
+`
+	for _, tt := range []struct {
+		split  []string
+		filter string
+		want   []string
+	}{
+		{
+			[]string{"cpu", "goos"}, "cpu:Intel(R) Xeon(R) CPU @ 2.20GHz,goos:windows",
+			[]string{"cpu:Intel(R) Xeon(R) CPU @ 2.20GHz goos:linux", "cpu:AMD EPYC 7B12 64-Core Processor goos:linux"},
+		},
+		{
+			[]string{"cpu", "goos"}, "cpu:AMD *",
+			[]string{"cpu:Intel(R) Xeon(R) CPU @ 2.20GHz goos:linux", "cpu:Intel(R) Xeon(R) CPU @ 2.20GHz goos:windows"},
+		},
+		// A value holding spaces ends only where the next key begins,
+		// so the last value of a group may hold them too.
+		{
+			[]string{"goos", "cpu"}, "cpu:*64-Core Processor",
+			[]string{"goos:linux cpu:Intel(R) Xeon(R) CPU @ 2.20GHz", "goos:windows cpu:Intel(R) Xeon(R) CPU @ 2.20GHz"},
+		},
+		{
+			[]string{"goos", "cpu"}, "goos:linux,cpu:Intel(R) Xeon(R) CPU @ 2.20GHz",
+			[]string{"goos:windows cpu:Intel(R) Xeon(R) CPU @ 2.20GHz", "goos:linux cpu:AMD EPYC 7B12 64-Core Processor"},
+		},
+		// A key absent from SplitBy is absent from every group.
+		{
+			[]string{"cpu", "goos"}, "goarch:",
+			nil,
+		},
+	} {
+		c := &Collection{SplitBy: tt.split}
+		c.AddConfig("a", []byte(results))
+		f, err := ParseFilter(tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Remove(f)
+		if len(c.Groups) == 0 {
+			c.Groups = nil
+		}
+		if !reflect.DeepEqual(c.Groups, tt.want) {
+			t.Errorf("SplitBy %q, Remove(%s): groups %q, want %q", tt.split, tt.filter, c.Groups, tt.want)
+		}
+	}
+}
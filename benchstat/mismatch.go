@@ -0,0 +1,74 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+// A sameKey records that a result in a configuration had a value of a
+// label.
+type sameKey struct {
+	label, config, value string
+}
+
+// noteSame records the values of the labels c.RequireSame of r, added
+// to config.
+func (c *Collection) noteSame(config string, r *benchfmt.Result) {
+	if c.sameValues == nil {
+		c.sameValues = make(map[sameKey]bool)
+	}
+	for _, l := range c.RequireSame {
+		c.sameValues[sameKey{l, config, c.label(r, l)}] = true
+	}
+}
+
+// Mismatches describes each label of c.RequireSame whose values differ
+// between configurations, such as
+//
+//	goarch: old.txt has amd64, new.txt has arm64
+//
+// Comparing configurations measured on different machines, operating
+// systems, or Go versions is rarely meaningful, so callers may refuse
+// to report the tables of a Collection with mismatches.
+func (c *Collection) Mismatches() []string {
+	var mismatches []string
+	for _, l := range c.RequireSame {
+		var have []string
+		var first string
+		differ := false
+		for _, config := range c.Configs {
+			var vals []string
+			for k := range c.sameValues {
+				if k.label == l && k.config == config {
+					v := k.value
+					if v == "" {
+						v = "none"
+					}
+					vals = append(vals, v)
+				}
+			}
+			if len(vals) == 0 {
+				continue
+			}
+			sort.Strings(vals)
+			v := strings.Join(vals, " and ")
+			if len(have) == 0 {
+				first = v
+			} else if v != first {
+				differ = true
+			}
+			have = append(have, config+" has "+v)
+		}
+		if differ {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %s", l, strings.Join(have, ", ")))
+		}
+	}
+	return mismatches
+}
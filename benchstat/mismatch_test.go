@@ -0,0 +1,44 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMismatches(t *testing.T) {
+	c := &Collection{RequireSame: []string{"goos", "machine", "go-version"}}
+	for _, file := range []string{"testdata/machines.txt", "testdata/fleet-old.txt"} {
+		c.GoVersion = "go1.13"
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.AddFile(file, f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.GoVersion = "go1.12"
+	c.AddConfig("single", []byte("goos: linux\nmachine: fast\nBenchmarkEncode-8 1 1000 ns/op\n"))
+	have := c.Mismatches()
+	want := []string{
+		"machine: testdata/machines.txt has fast and slow, testdata/fleet-old.txt has fast and slow, single has fast",
+		"go-version: testdata/machines.txt has go1.13, testdata/fleet-old.txt has go1.13, single has go1.12",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %q, want %q", have, want)
+	}
+
+	c = &Collection{RequireSame: []string{"goos", "goarch"}}
+	c.AddConfig("old", []byte("goos: linux\nBenchmarkEncode-8 1 1000 ns/op\n"))
+	c.AddConfig("new", []byte("goos: linux\nBenchmarkEncode-8 1 900 ns/op\n"))
+	if have := c.Mismatches(); have != nil {
+		t.Errorf("same labels: have %q, want none", have)
+	}
+}
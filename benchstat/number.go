@@ -0,0 +1,67 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Separators of formatted numbers.
+
+package benchstat
+
+import "strings"
+
+// A NumberFormat sets the separators of the numbers in formatted
+// values, deltas, and p-values, such as "1.234,5" in place of
+// "1234.5". The zero NumberFormat is that of Go: a decimal point
+// and no separator of thousands.
+type NumberFormat struct {
+	Decimal   string // decimal separator, or "" for "."
+	Thousands string // separator of groups of three integer digits, or "" for none
+}
+
+// Format returns s with the separators of each number in it
+// replaced by those of f. The numbers in s are runs of digits, with
+// an optional decimal point followed by more digits, so that unit
+// suffixes and signs, as in "+1.50ms", are left unchanged.
+func (f NumberFormat) Format(s string) string {
+	if f.Decimal == "" && f.Thousands == "" {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if !isDigit(s[i]) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		j := digitRun(s, i)
+		for k := i; k < j; k++ {
+			if k > i && (j-k)%3 == 0 {
+				b.WriteString(f.Thousands)
+			}
+			b.WriteByte(s[k])
+		}
+		i = j
+		if i+1 < len(s) && s[i] == '.' && isDigit(s[i+1]) {
+			if f.Decimal != "" {
+				b.WriteString(f.Decimal)
+			} else {
+				b.WriteByte('.')
+			}
+			j = digitRun(s, i+1)
+			b.WriteString(s[i+1 : j])
+			i = j
+		}
+	}
+	return b.String()
+}
+
+// digitRun returns the end of the run of digits in s starting at i.
+func digitRun(s string, i int) int {
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,28 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"testing"
+)
+
+func TestNumberFormat(t *testing.T) {
+	f := NumberFormat{Decimal: ",", Thousands: "."}
+	for _, tt := range []struct{ in, want string }{
+		{"13.6ms", "13,6ms"},
+		{"+1234.50%", "+1.234,50%"},
+		{"1234567ns", "1.234.567ns"},
+		{"(p=0.016 n=4+5)", "(p=0,016 n=4+5)"},
+		{"go1.12.", "go1,12."},
+		{"~", "~"},
+	} {
+		if got := f.Format(tt.in); got != tt.want {
+			t.Errorf("Format(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got := (NumberFormat{}).Format("1234.5"); got != "1234.5" {
+		t.Errorf("zero NumberFormat formatted 1234.5 as %q", got)
+	}
+}
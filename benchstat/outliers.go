@@ -0,0 +1,50 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Outlier rejection.
+
+package benchstat
+
+import "golang.org/x/perf/benchmath"
+
+// An OutlierTest returns the range [lo, hi] of values of m that are
+// not outliers. Values outside the range are excluded from m.RValues
+// and so from all statistics and significance tests.
+type OutlierTest func(m *Metrics) (lo, hi float64)
+
+// IQROutliers is an OutlierTest rejecting values more than 1.5 times
+// the interquartile range below the first or above the third quartile.
+// It is the default.
+func IQROutliers(m *Metrics) (lo, hi float64) {
+	if m.q1 == nil {
+		return benchmath.IQROutliers(m.Values)
+	}
+	// Only a sample of the values is retained; use the estimated
+	// quartiles of all of them.
+	q1, q3 := m.q1.Value(), m.q3.Value()
+	return q1 - 1.5*(q3-q1), q3 + 1.5*(q3-q1)
+}
+
+// NoOutliers is an OutlierTest that keeps all values.
+func NoOutliers(m *Metrics) (lo, hi float64) {
+	return benchmath.NoOutliers(m.Values)
+}
+
+// ZScoreOutliers returns an OutlierTest rejecting values more than
+// k standard deviations from the mean.
+func ZScoreOutliers(k float64) OutlierTest {
+	outliers := benchmath.ZScoreOutliers(k)
+	return func(m *Metrics) (lo, hi float64) {
+		return outliers(m.Values)
+	}
+}
+
+// PercentOutliers returns an OutlierTest rejecting the lowest and
+// highest pct percent of values.
+func PercentOutliers(pct float64) OutlierTest {
+	outliers := benchmath.PercentOutliers(pct)
+	return func(m *Metrics) (lo, hi float64) {
+		return outliers(m.Values)
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetricsOutliers(t *testing.T) {
+	c := new(Collection)
+	c.AddConfig("a", []byte("BenchmarkX 1 10 ns/op\nBenchmarkX 1 11 ns/op\nBenchmarkX 1 50 ns/op\nBenchmarkX 1 12 ns/op\nBenchmarkX 1 11 ns/op\n"))
+	m := c.Tables()[0].Rows[0].Metrics[0]
+	if want := []float64{10, 11, 12, 11}; !reflect.DeepEqual(m.RValues, want) {
+		t.Errorf("RValues = %v, want %v", m.RValues, want)
+	}
+	if want := []float64{50}; !reflect.DeepEqual(m.Outliers, want) {
+		t.Errorf("Outliers = %v, want %v", m.Outliers, want)
+	}
+	if q := m.Quantile(0.5); q != 11 {
+		t.Errorf("Quantile(0.5) = %v, want 11", q)
+	}
+	if p := m.P(11); p != 0.75 {
+		t.Errorf("P(11) = %v, want 0.75", p)
+	}
+	if have, want := m.Histogram(10, 50, 4), []int{4, 0, 0, 1}; !reflect.DeepEqual(have, want) {
+		t.Errorf("Histogram = %v, want %v", have, want)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Plots of sample distributions.
+
+package benchstat
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+
+	"golang.org/x/perf/internal/stats"
+)
+
+// A Plot draws the distributions of the values of a row, one for each
+// configuration, as an inline SVG image, which HTML tables show in a
+// column after the values. It returns "" if there is nothing to draw.
+type Plot func(row *Row) template.HTML
+
+// Dimensions of plots, in pixels.
+const (
+	plotWidth  = 160 // width of the plot area
+	plotMargin = 3   // horizontal margin on each side
+	plotRow    = 12  // height of each configuration's plot
+)
+
+// plotColors are the colors of the configurations in plots that
+// overlay them, cycled through if there are more configurations.
+// The last configuration, usually the newest, is drawn in the color
+// of the text.
+var plotColors = []string{"#888", "#06c", "#c60", "#690"}
+
+// plotColor returns the color of configuration i of n.
+func plotColor(i, n int) string {
+	if i == n-1 {
+		return "currentColor"
+	}
+	return plotColors[i%len(plotColors)]
+}
+
+// plotRange returns the smallest and largest values of the metrics in
+// row, which determine the horizontal scale of its plot, and whether
+// there are any values.
+func plotRange(row *Row) (min, max float64, ok bool) {
+	for _, m := range row.Metrics {
+		for _, v := range m.Values {
+			if !ok || v < min {
+				min = v
+			}
+			if !ok || v > max {
+				max = v
+			}
+			ok = true
+		}
+	}
+	return min, max, ok
+}
+
+// plotScale returns a function mapping values in [min, max] to
+// horizontal positions in the plot area.
+func plotScale(min, max float64) func(float64) float64 {
+	if max == min {
+		return func(float64) float64 { return plotMargin + plotWidth/2 }
+	}
+	return func(v float64) float64 {
+		return plotMargin + (v-min)/(max-min)*plotWidth
+	}
+}
+
+// BoxPlot is a Plot drawing a box plot of each configuration's values:
+// a box spanning the middle half of the values, split at the median,
+// with whiskers reaching to the extremes of the values kept after
+// discarding outliers, and a dot for each outlier.
+func BoxPlot(row *Row) template.HTML {
+	min, max, ok := plotRange(row)
+	if !ok {
+		return ""
+	}
+	x := plotScale(min, max)
+	var buf bytes.Buffer
+	height := plotRow * len(row.Metrics)
+	fmt.Fprintf(&buf, "<svg class='plot box' width='%d' height='%d' viewBox='0 0 %[1]d %[2]d'>", plotWidth+2*plotMargin, height)
+	for i, m := range row.Metrics {
+		if len(m.Values) == 0 {
+			continue
+		}
+		y := float64(i*plotRow) + plotRow/2
+		s := stats.Sample{Xs: append([]float64(nil), m.Values...)}
+		s.Sort()
+		lo, hi := stats.Bounds(m.RValues)
+		if len(m.RValues) == 0 {
+			lo, hi = s.Bounds()
+		}
+		q1, med, q3 := x(s.Percentile(0.25)), x(s.Percentile(0.5)), x(s.Percentile(0.75))
+		fmt.Fprintf(&buf, "<g class='config%d'>", i)
+		for _, w := range [][2]float64{{x(lo), q1}, {q3, x(hi)}} {
+			fmt.Fprintf(&buf, "<line x1='%.1f' y1='%.1f' x2='%.1f' y2='%.1[2]f' stroke='currentColor'/>", w[0], y, w[1])
+		}
+		fmt.Fprintf(&buf, "<rect x='%.1f' y='%.1f' width='%.1f' height='%d' fill='none' stroke='currentColor'/>", q1, y-plotRow/2+2, q3-q1, plotRow-4)
+		fmt.Fprintf(&buf, "<line x1='%.1f' y1='%.1f' x2='%.1[1]f' y2='%.1[3]f' stroke='currentColor' stroke-width='2'/>", med, y-plotRow/2+2, y+plotRow/2-2)
+		for _, v := range m.Values {
+			if v < lo || v > hi {
+				fmt.Fprintf(&buf, "<circle cx='%.1f' cy='%.1f' r='1.5' fill='currentColor'/>", x(v), y)
+			}
+		}
+		buf.WriteString("</g>")
+	}
+	buf.WriteString("</svg>")
+	return template.HTML(buf.String())
+}
+
+// ecdfHeight is the height of an ECDF plot, in pixels.
+const ecdfHeight = 40
+
+// ECDFPlot is a Plot overlaying the empirical cumulative distribution
+// function of each configuration's values: a step rising by 1/n at
+// each of the n values. A shift of the whole distribution moves the
+// entire step, while a worse tail moves only its top.
+func ECDFPlot(row *Row) template.HTML {
+	min, max, ok := plotRange(row)
+	if !ok {
+		return ""
+	}
+	x := plotScale(min, max)
+	y := func(p float64) float64 { return ecdfHeight - 1 - p*(ecdfHeight-2) }
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<svg class='plot ecdf' width='%d' height='%d' viewBox='0 0 %[1]d %[2]d'>", plotWidth+2*plotMargin, ecdfHeight)
+	for i, m := range row.Metrics {
+		if len(m.Values) == 0 {
+			continue
+		}
+		xs := append([]float64(nil), m.Values...)
+		sort.Float64s(xs)
+		fmt.Fprintf(&buf, "<path class='config%d' d='M%.1f %.1f", i, x(min), y(0))
+		for k, v := range xs {
+			fmt.Fprintf(&buf, "H%.1fV%.1f", x(v), y(float64(k+1)/float64(len(xs))))
+		}
+		fmt.Fprintf(&buf, "H%.1f' fill='none' stroke='%s'/>", x(max), plotColor(i, len(row.Metrics)))
+	}
+	buf.WriteString("</svg>")
+	return template.HTML(buf.String())
+}
+
+// trendHeight is the height of a trend plot, in pixels.
+const trendHeight = 40
+
+// TrendPlot is a Plot for configurations measured over time, such as
+// nightly runs, in the order given. It scatters the values of each
+// configuration, in the order they were measured, across a slot of
+// its own, and draws the least-squares line through all the values,
+// so that gradual drifts show.
+func TrendPlot(row *Row) template.HTML {
+	min, max, ok := plotRange(row)
+	if !ok {
+		return ""
+	}
+	y := func(v float64) float64 {
+		if max == min {
+			return trendHeight / 2
+		}
+		return trendHeight - 2 - (v-min)/(max-min)*(trendHeight-4)
+	}
+	slot := float64(plotWidth) / float64(len(row.Metrics))
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<svg class='plot trend' width='%d' height='%d' viewBox='0 0 %[1]d %[2]d'>", plotWidth+2*plotMargin, trendHeight)
+	var n, sx, sy, sxx, sxy float64
+	for i, m := range row.Metrics {
+		for k, v := range m.Values {
+			x := plotMargin + (float64(i)+(float64(k)+0.5)/float64(len(m.Values)))*slot
+			fmt.Fprintf(&buf, "<circle cx='%.1f' cy='%.1f' r='1.5' fill='%s'/>", x, y(v), plotColor(i%2, 2))
+			n++
+			sx += x
+			sy += v
+			sxx += x * x
+			sxy += x * v
+		}
+	}
+	if d := n*sxx - sx*sx; n > 1 && d != 0 {
+		slope := (n*sxy - sx*sy) / d
+		icept := (sy - slope*sx) / n
+		x0, x1 := float64(plotMargin), float64(plotMargin+plotWidth)
+		fmt.Fprintf(&buf, "<line x1='%.1f' y1='%.1f' x2='%.1f' y2='%.1f' stroke='#c00'/>", x0, y(icept+slope*x0), x1, y(icept+slope*x1))
+	}
+	buf.WriteString("</svg>")
+	return template.HTML(buf.String())
+}
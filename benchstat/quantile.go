@@ -0,0 +1,30 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Distributions of values.
+
+package benchstat
+
+import "golang.org/x/perf/benchmath"
+
+// Quantile returns the q-quantile of m.RValues, for q in [0, 1], so
+// that Quantile(0.5) is the median of the values kept after rejecting
+// outliers. It returns NaN if there are no values.
+func (m *Metrics) Quantile(q float64) float64 {
+	return benchmath.Quantile(m.RValues, q)
+}
+
+// P returns the empirical probability that a value of m.RValues is at
+// most x, the inverse of Quantile.
+func (m *Metrics) P(x float64) float64 {
+	return benchmath.CDF(m.RValues, x)
+}
+
+// Histogram returns the number of values of m.Values, including the
+// outliers, in each of n buckets of equal width spanning [min, max],
+// as for drawing the distribution of a benchmark. Values outside the
+// range are not counted.
+func (m *Metrics) Histogram(min, max float64, n int) []int {
+	return benchmath.Histogram(m.Values, min, max, n)
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Renaming benchmarks as results are added.
+
+package benchstat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A RenameRule rewrites the names of benchmarks matching a regular
+// expression.
+type RenameRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string // as for Pattern.ReplaceAllString
+}
+
+// ParseRenameRule parses a rule of the form "pattern=>replacement".
+// The pattern is a regular expression matched against the names of
+// benchmarks, without their "Benchmark" prefix, and each match is
+// replaced by the replacement, in which $1 stands for the text of the
+// first parenthesized subexpression, and so on. For example,
+//
+//	/hash=[0-9a-f]+=>
+//
+// strips a sub-benchmark naming the commit measured, and
+//
+//	^Marshal(/|-|$)=>Encode$1
+//
+// follows the rename of BenchmarkMarshal, with its sub-benchmarks,
+// to BenchmarkEncode.
+func ParseRenameRule(s string) (*RenameRule, error) {
+	i := strings.Index(s, "=>")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid rename %q: want pattern=>replacement", s)
+	}
+	if i == 0 {
+		return nil, fmt.Errorf("invalid rename %q: missing pattern", s)
+	}
+	re, err := regexp.Compile(s[:i])
+	if err != nil {
+		return nil, fmt.Errorf("invalid rename %q: %v", s, err)
+	}
+	return &RenameRule{Pattern: re, Replacement: s[i+2:]}, nil
+}
+
+func (r *RenameRule) String() string {
+	return r.Pattern.String() + "=>" + r.Replacement
+}
+
+// Rename returns name with each match of r's pattern replaced.
+func (r *RenameRule) Rename(name string) string {
+	return r.Pattern.ReplaceAllString(name, r.Replacement)
+}
+
+// RenameRules returns a function, for Collection.Rename, that applies
+// each of rules in turn, to the name the previous rule returned.
+func RenameRules(rules []*RenameRule) func(name string) string {
+	return func(name string) string {
+		for _, r := range rules {
+			name = r.Rename(name)
+		}
+		return name
+	}
+}
+
+// rename returns the name of the benchmark name as rewritten by
+// c.Rename, interned. Rules run once for each distinct name, however
+// many results record it.
+func (c *Collection) rename(name string) string {
+	if c.Rename == nil {
+		return c.intern(name)
+	}
+	if n, ok := c.renames[name]; ok {
+		return n
+	}
+	if c.renames == nil {
+		c.renames = make(map[string]string)
+	}
+	n := c.intern(c.Rename(name))
+	c.renames[c.intern(name)] = n
+	return n
+}
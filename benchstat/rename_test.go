@@ -0,0 +1,32 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRename(t *testing.T) {
+	var rules []*RenameRule
+	for _, s := range []string{"/hash=[0-9a-f]+=>", "^Marshal(/|-|$)=>Encode$1", "^Slow.*=>"} {
+		r, err := ParseRenameRule(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, r)
+	}
+	c := &Collection{Rename: RenameRules(rules)}
+	c.AddConfig("a", []byte("BenchmarkMarshal/hash=1a2b/size=10-8 1 10 ns/op\nBenchmarkMarshalJSON-8 1 12 ns/op\nBenchmarkSlow-8 1 20 ns/op\n"))
+	if want := []string{"Encode/size=10-8", "MarshalJSON-8"}; !reflect.DeepEqual(c.Benchmarks[""], want) {
+		t.Errorf("renamed benchmarks %q, want %q", c.Benchmarks[""], want)
+	}
+
+	for _, s := range []string{"Marshal", "=>Encode", "(=>x"} {
+		if _, err := ParseRenameRule(s); err == nil {
+			t.Errorf("ParseRenameRule(%q) succeeded, want error", s)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Rolling up the benchmarks of each group, such as each package.
+
+package benchstat
+
+import "sort"
+
+// addGroupGeomeans returns rows with a "geomean" row added after the
+// rows of each group with more than one benchmark, showing the
+// geometric mean of the group's benchmarks.
+func (c *Collection) addGroupGeomeans(rows []*Row, unit string, delta bool) []*Row {
+	var out []*Row
+	for i, row := range rows {
+		out = append(out, row)
+		if i+1 < len(rows) && rows[i+1].Group == row.Group {
+			continue
+		}
+		if g, count := c.geomeanRow(unit, []string{row.Group}, delta); count > 1 {
+			g.Group = row.Group
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// Rollups returns, for each of the tables returned by c.Tables that
+// compares two configurations of more than one group, a summary table
+// with a row for each group showing the geometric mean of its
+// benchmarks, named by the group. The rows are ranked by the change
+// of their geometric mean, from the worst regression to the best
+// improvement, so that a sweep over many packages, split by pkg,
+// leads with the packages that got slower.
+func (c *Collection) Rollups(tables []*Table) []*Table {
+	var rollups []*Table
+	for _, t := range tables {
+		if !t.OldNewDelta || len(t.Groups) < 2 {
+			continue
+		}
+		rollup := &Table{
+			Metric:      t.Metric,
+			Unit:        t.Unit,
+			OldNewDelta: true,
+			Better:      t.Better,
+			Configs:     t.Configs,
+			Groups:      []string{""},
+		}
+		var gains []float64
+		for _, group := range t.Groups {
+			row, count := c.geomeanRow(t.Unit, []string{group}, true)
+			if count == 0 || row.Delta == "" {
+				continue
+			}
+			row.Benchmark = group
+			rollup.Rows = append(rollup.Rows, row)
+			gain := row.Metrics[1].Center / row.Metrics[0].Center
+			if t.Better != HigherIsBetter {
+				gain = 1 / gain
+			}
+			gains = append(gains, gain)
+		}
+		if len(rollup.Rows) == 0 {
+			continue
+		}
+		sort.Stable(byGain{rollup.Rows, gains})
+		rollups = append(rollups, rollup)
+	}
+	return rollups
+}
+
+// byGain sorts rows by increasing gain, worst first.
+type byGain struct {
+	rows  []*Row
+	gains []float64
+}
+
+func (x byGain) Len() int           { return len(x.rows) }
+func (x byGain) Less(i, j int) bool { return x.gains[i] < x.gains[j] }
+func (x byGain) Swap(i, j int) {
+	x.rows[i], x.rows[j] = x.rows[j], x.rows[i]
+	x.gains[i], x.gains[j] = x.gains[j], x.gains[i]
+}
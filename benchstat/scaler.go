@@ -6,6 +6,8 @@ package benchstat
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 )
 
@@ -18,51 +20,33 @@ type Scaler func(float64) string
 // NewScaler returns a Scaler appropriate for formatting
 // the measurement val, which has the given unit.
 func NewScaler(val float64, unit string) Scaler {
+	return newScaler(val, unit, 3)
+}
+
+// newScaler is NewScaler, showing values to digits significant
+// digits rather than three.
+func newScaler(val float64, unit string, digits int) Scaler {
 	if hasBaseUnit(unit, "ns/op") || hasBaseUnit(unit, "ns/GC") {
-		return timeScaler(val)
+		return timeScaler(val, digits)
 	}
 
-	var format string
-	var scale float64
-	var suffix string
-
 	prescale := 1.0
 	if hasBaseUnit(unit, "MB/s") {
 		prescale = 1e6
 	}
 
+	scale, suffix := 1.0, ""
 	switch x := val * prescale; {
-	case x >= 99500000000000:
-		format, scale, suffix = "%.0f", 1e12, "T"
-	case x >= 9950000000000:
-		format, scale, suffix = "%.1f", 1e12, "T"
-	case x >= 995000000000:
-		format, scale, suffix = "%.2f", 1e12, "T"
-	case x >= 99500000000:
-		format, scale, suffix = "%.0f", 1e9, "G"
-	case x >= 9950000000:
-		format, scale, suffix = "%.1f", 1e9, "G"
-	case x >= 995000000:
-		format, scale, suffix = "%.2f", 1e9, "G"
-	case x >= 99500000:
-		format, scale, suffix = "%.0f", 1e6, "M"
-	case x >= 9950000:
-		format, scale, suffix = "%.1f", 1e6, "M"
-	case x >= 995000:
-		format, scale, suffix = "%.2f", 1e6, "M"
-	case x >= 99500:
-		format, scale, suffix = "%.0f", 1e3, "k"
-	case x >= 9950:
-		format, scale, suffix = "%.1f", 1e3, "k"
-	case x >= 995:
-		format, scale, suffix = "%.2f", 1e3, "k"
-	case x >= 99.5:
-		format, scale, suffix = "%.0f", 1, ""
-	case x >= 9.95:
-		format, scale, suffix = "%.1f", 1, ""
-	default:
-		format, scale, suffix = "%.2f", 1, ""
+	case x >= nextScale(1e12, digits):
+		scale, suffix = 1e12, "T"
+	case x >= nextScale(1e9, digits):
+		scale, suffix = 1e9, "G"
+	case x >= nextScale(1e6, digits):
+		scale, suffix = 1e6, "M"
+	case x >= nextScale(1e3, digits):
+		scale, suffix = 1e3, "k"
 	}
+	format := digitsFormat(val*prescale/scale, digits)
 
 	if hasBaseUnit(unit, "B/op") || hasBaseUnit(unit, "bytes/op") || hasBaseUnit(unit, "bytes") {
 		suffix += "B"
@@ -77,42 +61,196 @@ func NewScaler(val float64, unit string) Scaler {
 	}
 }
 
-func timeScaler(ns float64) Scaler {
-	var format string
+func timeScaler(ns float64, digits int) Scaler {
+	var suffix string
 	var scale float64
-	switch x := ns / 1e9; {
-	case x >= 99.5:
-		format, scale = "%.0fs", 1
-	case x >= 9.95:
-		format, scale = "%.1fs", 1
-	case x >= 0.995:
-		format, scale = "%.2fs", 1
-	case x >= 0.0995:
-		format, scale = "%.0fms", 1000
-	case x >= 0.00995:
-		format, scale = "%.1fms", 1000
-	case x >= 0.000995:
-		format, scale = "%.2fms", 1000
-	case x >= 0.0000995:
-		format, scale = "%.0fµs", 1000*1000
-	case x >= 0.00000995:
-		format, scale = "%.1fµs", 1000*1000
-	case x >= 0.000000995:
-		format, scale = "%.2fµs", 1000*1000
-	case x >= 0.0000000995:
-		format, scale = "%.0fns", 1000*1000*1000
-	case x >= 0.00000000995:
-		format, scale = "%.1fns", 1000*1000*1000
+	switch {
+	case ns >= nextScale(1e9, digits):
+		suffix, scale = "s", 1
+	case ns >= nextScale(1e6, digits):
+		suffix, scale = "ms", 1000
+	case ns >= nextScale(1e3, digits):
+		suffix, scale = "µs", 1000*1000
 	default:
-		format, scale = "%.2fns", 1000*1000*1000
+		suffix, scale = "ns", 1000*1000*1000
 	}
+	format := digitsFormat(ns/1e9*scale, digits) + suffix
 	return func(ns float64) string {
 		return fmt.Sprintf(format, ns/1e9*scale)
 	}
 }
 
+// nextScale returns the least value that scalers show in units of
+// scale, a power of ten, rather than in the next smaller unit: the
+// value a little under scale that rounds up to it.
+func nextScale(scale float64, digits int) float64 {
+	p := math.Pow10(digits)
+	return scale * (p - 5) / p
+}
+
 // hasBaseUnit reports whether s has unit unit.
 // For now, it reports whether s == unit or s ends in -unit.
 func hasBaseUnit(s, unit string) bool {
 	return s == unit || strings.HasSuffix(s, "-"+unit)
 }
+
+// timeUnits and sizeUnits give the size of each supported target
+// unit for ConvertUnit, in nanoseconds and bytes respectively.
+var timeUnits = map[string]float64{
+	"ns": 1,
+	"us": 1e3,
+	"µs": 1e3,
+	"ms": 1e6,
+	"s":  1e9,
+}
+
+var sizeUnits = map[string]float64{
+	"B":   1,
+	"kB":  1e3,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+// ConvertUnit reports how to express values of unit in target,
+// a unit of time (ns, µs, ms, or s) or of size (B, kB, MB, GB, TB,
+// KiB, MiB, GiB, or TiB). Multiplying a value by factor converts it,
+// and newUnit is the name of the converted unit; for example,
+// converting ns/op to ms gives ms/op and a factor of 1e-6.
+// If unit is not measured in the same dimension as target,
+// ok is false.
+func ConvertUnit(unit, target string) (newUnit string, factor float64, ok bool) {
+	prefix, from, per := splitUnit(unit)
+	factor, ok = convertFactor(from, target)
+	if !ok {
+		return "", 0, false
+	}
+	return prefix + target + per, factor, true
+}
+
+// splitUnit splits unit into an optional prefix like "foo-", the
+// base unit, and an optional per-suffix like "/op", so that
+// "user-ns/op" splits into "user-", "ns", and "/op".
+// "bytes" is returned as "B".
+func splitUnit(unit string) (prefix, base, per string) {
+	base = unit
+	if i := strings.LastIndex(base, "-"); i >= 0 {
+		prefix, base = base[:i+1], base[i+1:]
+	}
+	if i := strings.Index(base, "/"); i >= 0 {
+		base, per = base[:i], base[i:]
+	}
+	if base == "bytes" {
+		base = "B"
+	}
+	return prefix, base, per
+}
+
+// convertFactor returns the factor converting values in the base
+// unit from to the base unit target, which must both be units of
+// time or both be units of size.
+func convertFactor(from, target string) (float64, bool) {
+	var fromSize, toSize float64
+	if toSize = timeUnits[target]; toSize != 0 {
+		fromSize = timeUnits[from]
+	} else if toSize = sizeUnits[target]; toSize != 0 {
+		fromSize = sizeUnits[from]
+	}
+	if fromSize == 0 {
+		return 0, false
+	}
+	return fromSize / toSize, true
+}
+
+// newFixedScaler returns a Scaler that converts values of unit,
+// measured in the base unit scale, to target and formats them with
+// the precision appropriate for val and digits significant digits.
+func newFixedScaler(val float64, unit, scale, target string, digits int) (Scaler, bool) {
+	factor, ok := convertFactor(scale, target)
+	if !ok {
+		return nil, false
+	}
+	// Display the converted unit the way automatic scaling
+	// would, without any per-op suffix: "ms", "KiB", "MB/s".
+	suffix := target
+	if isRate(unit) {
+		suffix += "/s"
+	}
+	format := digitsFormat(val*factor, digits)
+	return func(val float64) string {
+		return fmt.Sprintf(format+suffix, val*factor)
+	}, true
+}
+
+// newUnitScaler returns a Scaler for values of unit measured in
+// the base unit scale, which is a unit of time, a unit of size,
+// or "none" to format values without any scaling.
+// If binary is set, sizes are scaled with binary prefixes like KiB
+// rather than decimal prefixes like kB. Values are shown to digits
+// significant digits.
+func newUnitScaler(val float64, unit, scale string, binary bool, digits int) (Scaler, bool) {
+	var base Scaler
+	var factor float64
+	switch {
+	case scale == "none":
+		format := digitsFormat(val, digits)
+		return func(val float64) string {
+			return fmt.Sprintf(format, val)
+		}, true
+	case timeUnits[scale] != 0:
+		factor = timeUnits[scale]
+		base = timeScaler(val*factor, digits)
+	case sizeUnits[scale] != 0 && binary:
+		factor = sizeUnits[scale]
+		suffix := ""
+		if isRate(unit) {
+			suffix = "/s"
+		}
+		base = binaryScaler(val*factor, suffix, digits)
+	case sizeUnits[scale] != 0 && isRate(unit):
+		factor = sizeUnits[scale] / 1e6
+		base = newScaler(val*factor, "MB/s", digits)
+	case sizeUnits[scale] != 0:
+		factor = sizeUnits[scale]
+		base = newScaler(val*factor, "bytes", digits)
+	default:
+		return nil, false
+	}
+	return func(val float64) string {
+		return base(val * factor)
+	}, true
+}
+
+var binaryPrefixes = []string{"", "Ki", "Mi", "Gi", "Ti", "Pi"}
+
+// binaryScaler returns a Scaler for sizes like bytes, which is
+// in bytes, using binary prefixes. The scaled values are followed
+// by "B" and suffix, as in "1.50KiB" or "12.0MiB/s", and shown to
+// digits significant digits.
+func binaryScaler(bytes float64, suffix string, digits int) Scaler {
+	i, scale := 0, 1.0
+	for i < len(binaryPrefixes)-1 && math.Abs(bytes)/scale >= nextScale(1e3, digits) {
+		i, scale = i+1, scale*1024
+	}
+	format := digitsFormat(bytes/scale, digits) + binaryPrefixes[i] + "B" + suffix
+	return func(bytes float64) string {
+		return fmt.Sprintf(format, bytes/scale)
+	}
+}
+
+// digitsFormat returns the format for displaying values like x
+// with digits significant digits, or more if x needs more integer
+// digits than that.
+func digitsFormat(x float64, digits int) string {
+	prec := digits - 1
+	x = math.Abs(x)
+	for lim := 10.0; prec > 0 && x >= lim-0.5*math.Pow10(1-prec); lim *= 10 {
+		prec--
+	}
+	return "%." + strconv.Itoa(prec) + "f"
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Ordering benchmarks by name.
+
+package benchstat
+
+import "sort"
+
+// NameLess reports whether the name x sorts before y in natural
+// order, which compares runs of digits by their numeric value and
+// everything else byte by byte, so that "Encode/2" sorts before
+// "Encode/10" and "Encode-8" before "Encode-16".
+func NameLess(x, y string) bool {
+	for x != "" && y != "" {
+		if isDigit(x[0]) && isDigit(y[0]) {
+			i, j := digitRun(x, 0), digitRun(y, 0)
+			nx, ny := trimZeros(x[:i]), trimZeros(y[:j])
+			if len(nx) != len(ny) {
+				return len(nx) < len(ny)
+			}
+			if nx != ny {
+				return nx < ny
+			}
+			// Equal values: fewer leading zeros first.
+			if i != j {
+				return i < j
+			}
+			x, y = x[i:], y[j:]
+			continue
+		}
+		if x[0] != y[0] {
+			return x[0] < y[0]
+		}
+		x, y = x[1:], y[1:]
+	}
+	return x == "" && y != ""
+}
+
+// trimZeros returns the run of digits s without its leading zeros.
+func trimZeros(s string) string {
+	for len(s) > 1 && s[0] == '0' {
+		s = s[1:]
+	}
+	return s
+}
+
+// sortedNames returns copies of groups, and of the benchmarks of each
+// group, sorted by NameLess.
+func sortedNames(groups []string, benchmarks map[string][]string) ([]string, map[string][]string) {
+	sortNames := func(names []string) []string {
+		names = append([]string(nil), names...)
+		sort.SliceStable(names, func(i, j int) bool { return NameLess(names[i], names[j]) })
+		return names
+	}
+	sorted := make(map[string][]string, len(benchmarks))
+	for group, names := range benchmarks {
+		sorted[group] = sortNames(names)
+	}
+	return sortNames(groups), sorted
+}
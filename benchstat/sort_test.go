@@ -0,0 +1,20 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNameLess(t *testing.T) {
+	names := []string{"Encode/10", "Decode", "Encode/2", "Encode/1-8", "Encode/1-16", "Encode/02", "Encode", "Encode/size=1KB", "Encode/size=64B"}
+	sort.Slice(names, func(i, j int) bool { return NameLess(names[i], names[j]) })
+	want := []string{"Decode", "Encode", "Encode/1-8", "Encode/1-16", "Encode/2", "Encode/02", "Encode/10", "Encode/size=1KB", "Encode/size=64B"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("sorted names = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Saving the results of a collection and reading them back.
+
+package benchstat
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"io"
+
+	"golang.org/x/perf/benchmath"
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+// stateHeader begins every saved state. It must be changed whenever
+// savedState changes.
+const stateHeader = "benchstat state v2\n"
+
+// errBadState is returned by ReadState for input that is not a saved
+// state, such as a truncated or corrupt one, or is one from a different
+// version of benchstat.
+var errBadState = errors.New("not a saved benchstat state")
+
+// A savedState is the form of a Collection written by WriteState.
+// Names are stored once and metrics refer to them by index, since
+// large inputs measure many metrics of each benchmark and unit.
+type savedState struct {
+	Configs, Groups, Units []string
+	Benchmarks             [][]string // benchmarks of each of Groups
+	Metrics                []savedMetrics
+	UnitMeta               map[string]benchfmt.Labels
+	Same, Labels           []savedLabel
+}
+
+type savedMetrics struct {
+	Config, Group, Benchmark, Unit int // indexes into savedState
+	Count                          int
+	Iters                          float64
+	Values                         []float64
+	Summarized                     *benchmath.Moments // set for metrics added by AddSummaries
+}
+
+// A savedLabel is a sameKey, whose fields gob cannot see.
+type savedLabel struct {
+	Label, Config, Value string
+}
+
+// WriteState writes the results added to c to w in a compact binary
+// form that ReadState reads back, so that an analysis of large inputs
+// can parse them once, as in CI, and render tables from them later in
+// any format, with any options applied by Tables. The settings of c
+// are not written, and neither are the quartile estimates kept for
+// outlier rejection when Reservoir is set, so outliers of a sampled
+// metric are judged from the sample alone once read back.
+func (c *Collection) WriteState(w io.Writer) error {
+	s := &savedState{
+		Configs:  c.Configs,
+		Groups:   c.Groups,
+		Units:    c.Units,
+		UnitMeta: c.unitMeta,
+	}
+	for gi, group := range c.Groups {
+		s.Benchmarks = append(s.Benchmarks, c.Benchmarks[group])
+		for bi, benchmark := range c.Benchmarks[group] {
+			for ui, unit := range c.Units {
+				for ci, config := range c.Configs {
+					m := c.Metrics[Key{Config: config, Group: group, Benchmark: benchmark, Unit: unit}]
+					if m == nil {
+						continue
+					}
+					s.Metrics = append(s.Metrics, savedMetrics{ci, gi, bi, ui, m.Count, m.Iters, m.Values, m.summarized})
+				}
+			}
+		}
+	}
+	for k := range c.sameValues {
+		s.Same = append(s.Same, savedLabel{k.label, k.config, k.value})
+	}
+	for k := range c.labelValues {
+		s.Labels = append(s.Labels, savedLabel{k.label, k.config, k.value})
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(stateHeader)
+	if err := gob.NewEncoder(bw).Encode(s); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadState adds the results saved by WriteState in r to c, as Merge
+// would add those of the Collection that wrote them. The settings of c
+// apply to the results read, as to results added from files, except
+// that units are already normalized and derived units already added if
+// the Collection that wrote them did so.
+func (c *Collection) ReadState(r io.Reader) error {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(stateHeader))
+	if _, err := io.ReadFull(br, header); err != nil || string(header) != stateHeader {
+		return errBadState
+	}
+	s := new(savedState)
+	if err := gob.NewDecoder(br).Decode(s); err != nil {
+		return errBadState
+	}
+	if len(s.Benchmarks) != len(s.Groups) {
+		return errBadState
+	}
+
+	saved := &Collection{
+		Configs:    s.Configs,
+		Groups:     s.Groups,
+		Units:      s.Units,
+		Benchmarks: make(map[string][]string),
+		Metrics:    make(map[Key]*Metrics),
+		unitMeta:   s.UnitMeta,
+	}
+	for gi, group := range s.Groups {
+		saved.Benchmarks[group] = s.Benchmarks[gi]
+	}
+	for _, sm := range s.Metrics {
+		if uint(sm.Config) >= uint(len(s.Configs)) || uint(sm.Group) >= uint(len(s.Groups)) ||
+			uint(sm.Benchmark) >= uint(len(s.Benchmarks[sm.Group])) || uint(sm.Unit) >= uint(len(s.Units)) {
+			return errBadState
+		}
+		key := Key{s.Configs[sm.Config], s.Groups[sm.Group], s.Benchmarks[sm.Group][sm.Benchmark], s.Units[sm.Unit]}
+		saved.Metrics[key] = &Metrics{Unit: key.Unit, Count: sm.Count, Iters: sm.Iters, Values: sm.Values, summarized: sm.Summarized}
+	}
+	if len(s.Same) > 0 {
+		saved.sameValues = make(map[sameKey]bool)
+		for _, l := range s.Same {
+			saved.sameValues[sameKey{l.Label, l.Config, l.Value}] = true
+		}
+	}
+	if len(s.Labels) > 0 {
+		saved.labelValues = make(map[sameKey]bool)
+		for _, l := range s.Labels {
+			saved.labelValues[sameKey{l.Label, l.Config, l.Value}] = true
+		}
+	}
+	c.Merge(saved)
+	return nil
+}
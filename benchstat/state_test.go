@@ -0,0 +1,111 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestState(t *testing.T) {
+	text := func(c *Collection) string {
+		var buf bytes.Buffer
+		FormatText(&buf, c.Tables())
+		return buf.String()
+	}
+	c := readCollection(t, "testdata/exampleold.txt", "testdata/examplenew.txt")
+	var buf bytes.Buffer
+	if err := c.WriteState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	saved := new(Collection)
+	if err := saved.ReadState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := text(saved), text(c); have != want {
+		t.Errorf("read back state:\n%s\nwant:\n%s", have, want)
+	}
+
+	if err := saved.ReadState(strings.NewReader("BenchmarkX 1 10 ns/op\n")); err == nil {
+		t.Errorf("ReadState of benchmark results succeeded, want error")
+	}
+
+	// Summaries are saved too.
+	f, err := os.Open("testdata/examplesummary.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sums, err := ReadSummaries(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = readCollection(t, "testdata/examplenew.txt")
+	c.AddSummaries("summary", sums)
+	buf.Reset()
+	if err := c.WriteState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	saved = new(Collection)
+	if err := saved.ReadState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := text(saved), text(c); have != want || strings.Contains(have, "NaN") {
+		t.Errorf("read back state with summaries:\n%s\nwant:\n%s", have, want)
+	}
+}
+
+func TestReadBadState(t *testing.T) {
+	c := readCollection(t, "testdata/exampleold.txt", "testdata/examplenew.txt")
+	var buf bytes.Buffer
+	if err := c.WriteState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	state := buf.Bytes()
+
+	// Truncated states are rejected.
+	for _, n := range []int{0, len(stateHeader) - 1, len(stateHeader), len(state) / 2, len(state) - 1} {
+		if err := new(Collection).ReadState(bytes.NewReader(state[:n])); err != errBadState {
+			t.Errorf("ReadState of %d of %d bytes: %v, want %v", n, len(state), err, errBadState)
+		}
+	}
+
+	// States with indexes out of range are rejected.
+	for _, s := range []*savedState{
+		{Configs: []string{"a"}, Groups: []string{""}, Units: []string{"ns/op"}},
+		{Configs: []string{"a"}, Groups: []string{""}, Units: []string{"ns/op"}, Benchmarks: [][]string{{"X"}}, Metrics: []savedMetrics{{Config: 1}}},
+		{Configs: []string{"a"}, Groups: []string{""}, Units: []string{"ns/op"}, Benchmarks: [][]string{{"X"}}, Metrics: []savedMetrics{{Group: -1}}},
+		{Configs: []string{"a"}, Groups: []string{""}, Units: []string{"ns/op"}, Benchmarks: [][]string{{"X"}}, Metrics: []savedMetrics{{Benchmark: 1}}},
+		{Configs: []string{"a"}, Groups: []string{""}, Units: []string{"ns/op"}, Benchmarks: [][]string{{"X"}}, Metrics: []savedMetrics{{Unit: 2}}},
+	} {
+		buf.Reset()
+		buf.WriteString(stateHeader)
+		if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+			t.Fatal(err)
+		}
+		if err := new(Collection).ReadState(&buf); err != errBadState {
+			t.Errorf("ReadState of %+v: %v, want %v", s, err, errBadState)
+		}
+	}
+
+	// Corrupting any byte of a state may leave it readable, but must
+	// not panic.
+	corrupt := make([]byte, len(state))
+	for i := range state {
+		copy(corrupt, state)
+		corrupt[i] ^= 0xff
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					t.Errorf("ReadState with byte %d corrupted panicked: %v", i, err)
+				}
+			}()
+			new(Collection).ReadState(bytes.NewReader(corrupt))
+		}()
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Reading the summaries of samples in CSV and JSON output.
+
+package benchstat
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/perf/benchmath"
+)
+
+// A Summary gives the size, mean, and variance of the sample of one
+// benchmark in one unit and configuration, as Format records them in
+// CSV and JSON output. A Summary stands in for the values themselves,
+// so that results can be compared with those of an earlier run after
+// its input files are gone.
+type Summary struct {
+	Config, Group, Benchmark, Unit string
+	benchmath.Moments
+}
+
+// ReadSummaries reads the summaries of the samples in the CSV or JSON
+// output of Format from r, in the order written. The rows of geometric
+// means and of missing values summarize no sample and are skipped.
+func ReadSummaries(r io.Reader) ([]*Summary, error) {
+	br := bufio.NewReader(r)
+	for {
+		c, err := br.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return nil, nil
+			}
+			return nil, err
+		}
+		switch c[0] {
+		case ' ', '\t', '\r', '\n':
+			br.ReadByte()
+			continue
+		case '[':
+			return readJSONSummaries(br)
+		}
+		return readCSVSummaries(br)
+	}
+}
+
+func readJSONSummaries(r io.Reader) ([]*Summary, error) {
+	var tables [][]*jsonRow
+	if err := json.NewDecoder(r).Decode(&tables); err != nil {
+		return nil, fmt.Errorf("reading summaries: %v", err)
+	}
+	var sums []*Summary
+	for _, rows := range tables {
+		group := ""
+		for i, row := range rows {
+			switch {
+			case i == 0 || len(row.Cols) == 0:
+			case row.ID == "" && len(row.Cols) == 1:
+				group = row.Cols[0]
+			default:
+				for _, st := range row.Stats {
+					if st == nil {
+						continue
+					}
+					sums = append(sums, &Summary{
+						Config:    st.Config,
+						Group:     group,
+						Benchmark: row.Cols[0],
+						Unit:      st.Unit,
+						Moments:   benchmath.Moments{N: st.N, Mean: st.Mean, Variance: st.Variance},
+					})
+				}
+			}
+		}
+	}
+	return sums, nil
+}
+
+func readCSVSummaries(r io.Reader) ([]*Summary, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	var (
+		sums    []*Summary
+		group   string
+		unitCol int      // column of the unit, or 0 if the table has none
+		configs []string // configuration of each column of sizes after unitCol
+	)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return sums, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading summaries: %v", err)
+		}
+		switch {
+		case len(rec) < 2:
+		case rec[0] == "id":
+			group, unitCol, configs = "", 0, nil
+			for i := len(rec) - 1; i > 1 && unitCol == 0; i-- {
+				if rec[i] == "unit" {
+					unitCol = i
+				}
+			}
+			for i := unitCol + 1; unitCol > 0 && i+2 < len(rec) && strings.HasSuffix(rec[i], " n"); i += 3 {
+				configs = append(configs, strings.TrimSuffix(rec[i], " n"))
+			}
+		case rec[0] == "" && len(rec) == 2:
+			group = rec[1]
+		case unitCol > 0 && unitCol < len(rec):
+			for j, config := range configs {
+				i := unitCol + 1 + 3*j
+				if i+2 >= len(rec) || rec[i] == "" {
+					continue
+				}
+				s := &Summary{Config: config, Group: group, Benchmark: rec[1], Unit: rec[unitCol]}
+				var err1, err2, err3 error
+				s.N, err1 = strconv.Atoi(rec[i])
+				s.Mean, err2 = strconv.ParseFloat(rec[i+1], 64)
+				s.Variance, err3 = strconv.ParseFloat(rec[i+2], 64)
+				if err1 != nil || err2 != nil || err3 != nil {
+					return nil, fmt.Errorf("reading summaries: invalid summary of %s in %s", rec[1], config)
+				}
+				sums = append(sums, s)
+			}
+		}
+	}
+}
+
+// AddSummaries adds sums to the named configuration, whatever
+// configurations they summarize, as AddResults adds results. The
+// metrics of a summary have a Count and Center but no values, and
+// their center is always the mean: delta tests of them fall back to
+// the t-test, which needs only the size, mean, and variance of each
+// sample, and confidence intervals, outliers, and plots are omitted.
+// Collection.WriteState saves the summaries with the other results.
+func (c *Collection) AddSummaries(config string, sums []*Summary) {
+	if len(c.Pivot) == 0 {
+		c.Configs = append(c.Configs, config)
+	}
+	for _, s := range sums {
+		benchmark := c.rename(s.Benchmark)
+		if benchmark == "" || s.N == 0 {
+			continue
+		}
+		unit, factor := c.normalizeUnit(s.Unit)
+		key := Key{Config: config, Group: c.intern(s.Group), Benchmark: benchmark, Unit: c.intern(unit)}
+		m := c.addMetrics(key)
+		m.Count = s.N
+		m.summarized = &benchmath.Moments{N: s.N, Mean: s.Mean * factor, Variance: s.Variance * factor * factor}
+	}
+}
+
+// moments returns the Moments of the sample of m, and whether it has
+// one: the metrics of the geometric mean summarize no sample.
+func (m *Metrics) moments() (benchmath.Moments, bool) {
+	if m.summarized != nil {
+		return *m.summarized, true
+	}
+	if len(m.RValues) == 0 {
+		return benchmath.Moments{}, false
+	}
+	return benchmath.SampleMoments(m.RValues), true
+}
+
+// n returns the size of the sample of m.
+func (m *Metrics) n() int {
+	if m.summarized != nil {
+		return m.summarized.N
+	}
+	return len(m.RValues)
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSummaries(t *testing.T) {
+	c := readCollection(t, "testdata/exampleold.txt", "testdata/examplenew.txt")
+	want := "GobEncode time/op: testdata/exampleold.txt n=4 mean=1.3599058e+07 testdata/examplenew.txt n=5 mean=1.17892886e+07"
+	for _, format := range []string{"json", "csv"} {
+		var buf bytes.Buffer
+		if err := Format(&buf, c.Tables(), FormatOptions{Format: format}); err != nil {
+			t.Fatal(err)
+		}
+		sums, err := ReadSummaries(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(sums) != 8 {
+			t.Fatalf("%s: read %d summaries, want 8", format, len(sums))
+		}
+		have := fmt.Sprintf("%s time/op: %s n=%d mean=%g %s n=%d mean=%g", sums[0].Benchmark, sums[0].Config, sums[0].N, sums[0].Mean, sums[1].Config, sums[1].N, sums[1].Mean)
+		if have != want {
+			t.Errorf("%s: read %s, want %s", format, have, want)
+		}
+
+		// A summary compares by the t-test as its values would.
+		s := new(Collection)
+		s.AddSummaries("old", sums[:1])
+		s.AddSummaries("new", sums[1:2])
+		full := readCollection(t, "testdata/exampleold.txt", "testdata/examplenew.txt")
+		full.DeltaTest = TTest
+		if have, want := s.Tables()[0].Rows[0].Note, full.Tables()[0].Rows[0].Note; have != want {
+			t.Errorf("%s: summaries compared with note %s, want %s", format, have, want)
+		}
+	}
+
+	if _, err := ReadSummaries(strings.NewReader("[{")); err == nil {
+		t.Errorf("ReadSummaries of invalid JSON succeeded, want error")
+	}
+}
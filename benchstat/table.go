@@ -5,8 +5,13 @@
 package benchstat
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"golang.org/x/perf/internal/stats"
 )
@@ -14,12 +19,69 @@ import (
 // A Table is a table for display in the benchstat output.
 type Table struct {
 	Metric      string
-	OldNewDelta bool // is this an old-new-delta table?
+	Unit        string
+	OldNewDelta bool      // is this an old-new-delta table?
+	Better      Direction // whether higher or lower values of Unit are better
 	Configs     []string
 	Groups      []string
 	Rows        []*Row
+
+	// StatColumns specifies that the sample size of each value,
+	// and the p-value of each delta, are shown in columns of
+	// their own rather than in the note.
+	StatColumns bool
+
+	// AbsDelta specifies that the absolute difference of each
+	// change is shown in a column after the percent change.
+	AbsDelta bool
+
+	// Plot, if not nil, draws the distributions of each row's
+	// values, shown in a column of HTML tables after the values.
+	Plot Plot
+
+	// Heatmap specifies that HTML tables color each value by how
+	// much better or worse it is than the row's first value.
+	Heatmap bool
+
+	// DeltaMode specifies how a table of more than two configurations
+	// compares them. See Row.Deltas.
+	DeltaMode DeltaMode
+
+	// Numbers sets the separators of the numbers in p-values shown
+	// in columns of their own. The Collection that made the table
+	// formats every other number.
+	Numbers NumberFormat
 }
 
+// A MissingPolicy says how tables show benchmarks that were not
+// measured in every configuration.
+type MissingPolicy int
+
+const (
+	// MissingDefault omits such benchmarks from tables comparing
+	// two configurations and shows blanks for them otherwise.
+	MissingDefault MissingPolicy = iota
+	// MissingBlank shows blanks for the missing values,
+	// and no delta when comparing two configurations.
+	MissingBlank
+	// MissingHide omits such benchmarks from all tables.
+	MissingHide
+)
+
+// A DeltaMode says how tables of more than two configurations compare
+// them.
+type DeltaMode int
+
+const (
+	// DeltaDefault shows no deltas in such tables.
+	DeltaDefault DeltaMode = iota
+	// DeltaFirst compares each configuration with the first.
+	DeltaFirst
+	// DeltaPrevious compares each configuration with the one before
+	// it, as when each is the next step of a series of changes.
+	DeltaPrevious
+)
+
 // A Row is a table row for display in the benchstat output.
 type Row struct {
 	Benchmark string     // benchmark name
@@ -27,24 +89,92 @@ type Row struct {
 	Scaler    Scaler     // formatter for stats means
 	Metrics   []*Metrics // columns of statistics
 	Delta     string     // formatted percent change
+	AbsDelta  string     // formatted absolute change, if Delta is a nonzero percentage
 	Note      string     // additional information
 	Change    int        // +1 better, -1 worse, 0 unchanged
+	PValue    float64    // p-value of the delta test, or -1 if none
+
+	// Ratio is the ratio of the new center to the old when comparing
+	// two configurations, and RatioLo and RatioHi bound its confidence
+	// interval at level 1-alpha. RatioEstimate is the Hodges-Lehmann
+	// estimate of the ratio, which, unlike Ratio, always lies in the
+	// interval. The interval and estimate are computed only if the
+	// Collection's Intervals is set. Each is 0 if it cannot be
+	// computed.
+	Ratio, RatioLo, RatioHi float64
+	RatioEstimate           float64
+
+	// Underpowered reports that the compared samples are too small
+	// for the delta test to find a significant change, however
+	// large the change, so a ~ in Delta means nothing.
+	Underpowered bool
+
+	// Unbalanced describes how the compared samples appear to have
+	// been measured differently, such as "different -count", or is
+	// empty if they appear comparable.
+	Unbalanced string
+
+	// Deltas compares the values of a row of a table with a DeltaMode
+	// other than DeltaDefault: Deltas[i] compares Metrics[i+1] with
+	// Metrics[0] or Metrics[i], and has the fields above describing a
+	// comparison, from Delta on, set as in a table comparing those two
+	// configurations alone. Deltas[i] is nil if either was not
+	// measured, and Deltas is nil for rows, like geometric means, that
+	// compare nothing.
+	Deltas []*Row
+}
+
+// delta returns r.Deltas[i], or nil if there is none.
+func (r *Row) delta(i int) *Row {
+	if i < 0 || i >= len(r.Deltas) {
+		return nil
+	}
+	return r.Deltas[i]
+}
+
+// RowID returns an identifier for row of t that is stable across
+// reports: a hash of the row's group, which holds the labels results
+// are split by, its benchmark name, and the unit of t. External systems
+// can use it to track a benchmark from report to report, however the
+// tables are laid out and whatever else they hold.
+func (t *Table) RowID(row *Row) string {
+	h := sha256.Sum256([]byte(row.Group + "\x00" + row.Benchmark + "\x00" + t.Unit))
+	return hex.EncodeToString(h[:8])
 }
 
 // Tables returns tables comparing the benchmarks in the collection.
 func (c *Collection) Tables() []*Table {
-	deltaTest := c.DeltaTest
-	if deltaTest == nil {
-		deltaTest = UTest
+	// Update statistics.
+	metrics := make([]*Metrics, 0, len(c.Metrics))
+	for _, m := range c.Metrics {
+		metrics = append(metrics, m)
 	}
-	alpha := c.Alpha
-	if alpha == 0 {
-		alpha = 0.05
+	outliers := c.Outliers
+	if outliers == nil {
+		outliers = IQROutliers
+	}
+	center := c.Center
+	if center == nil {
+		center = MeanCenter
+	}
+	// Units may override the center, as UnitInfo.Center says.
+	centers := make(map[string]Center)
+	for unit, u := range c.UnitInfo {
+		if u != nil && u.Center != nil {
+			centers[unit] = u.Center
+		}
 	}
+	c.parallel(len(metrics), func(i int) {
+		if uc := centers[metrics[i].Unit]; uc != nil {
+			metrics[i].computeStats(outliers, uc)
+			return
+		}
+		metrics[i].computeStats(outliers, center)
+	})
 
-	// Update statistics.
-	for _, m := range c.Metrics {
-		m.computeStats()
+	groups, benchmarks := c.Groups, c.Benchmarks
+	if c.SortByName {
+		groups, benchmarks = sortedNames(groups, benchmarks)
 	}
 
 	var tables []*Table
@@ -52,74 +182,42 @@ func (c *Collection) Tables() []*Table {
 	for _, key.Unit = range c.Units {
 		table := new(Table)
 		table.Configs = c.Configs
-		table.Groups = c.Groups
+		table.Groups = groups
 		table.Metric = metricOf(key.Unit)
+		table.Unit = key.Unit
+		table.Better = c.unitInfo(key.Unit).Better
 		table.OldNewDelta = len(c.Configs) == 2
-		for _, key.Group = range c.Groups {
-			for _, key.Benchmark = range c.Benchmarks[key.Group] {
-				row := &Row{Benchmark: key.Benchmark}
-				if len(c.Groups) > 1 {
-					// Show group headers if there is more than one group.
-					row.Group = key.Group
-				}
-
-				for _, key.Config = range c.Configs {
-					m := c.Metrics[key]
-					if m == nil {
-						row.Metrics = append(row.Metrics, new(Metrics))
-						continue
-					}
-					row.Metrics = append(row.Metrics, m)
-					if row.Scaler == nil {
-						row.Scaler = NewScaler(m.Mean, m.Unit)
-					}
-				}
-
-				// If there are only two configs being compared, add stats.
-				if table.OldNewDelta {
-					k0 := key
-					k0.Config = c.Configs[0]
-					k1 := key
-					k1.Config = c.Configs[1]
-					old := c.Metrics[k0]
-					new := c.Metrics[k1]
-					// If one is missing, omit row entirely.
-					// TODO: Control this better.
-					if old == nil || new == nil {
-						continue
-					}
-					pval, testerr := deltaTest(old, new)
-					row.Delta = "~"
-					if testerr == stats.ErrZeroVariance {
-						row.Note = "(zero variance)"
-					} else if testerr == stats.ErrSampleSize {
-						row.Note = "(too few samples)"
-					} else if testerr == stats.ErrSamplesEqual {
-						row.Note = "(all equal)"
-					} else if testerr != nil {
-						row.Note = fmt.Sprintf("(%s)", testerr)
-					} else if pval < alpha {
-						if new.Mean == old.Mean {
-							row.Delta = "0.00%"
-						} else {
-							pct := ((new.Mean / old.Mean) - 1.0) * 100.0
-							row.Delta = fmt.Sprintf("%+.2f%%", pct)
-							if pct < 0 == (table.Metric != "speed") { // smaller is better, except speeds
-								row.Change = +1
-							} else {
-								row.Change = -1
-							}
-						}
-					}
-					if row.Note == "" && pval != -1 {
-						row.Note = fmt.Sprintf("(p=%0.3f n=%d+%d)", pval, len(old.RValues), len(new.RValues))
-					}
-				}
+		table.StatColumns = c.StatColumns
+		table.AbsDelta = c.AbsDelta && table.OldNewDelta
+		table.Plot = c.Plot
+		table.Heatmap = c.Heatmap && len(c.Configs) > 1
+		table.Numbers = c.Numbers
+		if len(c.Configs) > 2 {
+			table.DeltaMode = c.DeltaMode
+		}
 
+		// Rows are computed independently, possibly in parallel,
+		// and then collected in their original order.
+		var keys []Key
+		for _, key.Group = range groups {
+			for _, key.Benchmark = range benchmarks[key.Group] {
+				keys = append(keys, key)
+			}
+		}
+		unitTest, unitAlpha := c.unitTest(key.Unit)
+		rows := make([]*Row, len(keys))
+		c.parallel(len(keys), func(i int) {
+			rows[i] = c.newRow(table, keys[i], unitTest, unitAlpha)
+		})
+		for _, row := range rows {
+			if row != nil {
 				table.Rows = append(table.Rows, row)
 			}
 		}
 
+		if c.GroupGeoMean {
+			table.Rows = c.addGroupGeomeans(table.Rows, key.Unit, table.OldNewDelta)
+		}
 		if len(table.Rows) > 0 {
 			if c.AddGeoMean {
 				addGeomean(c, table, key.Unit, table.OldNewDelta)
@@ -131,6 +229,342 @@ func (c *Collection) Tables() []*Table {
 	return tables
 }
 
+// unitTest returns the delta test and the significance level that
+// apply to values of unit.
+func (c *Collection) unitTest(unit string) (DeltaTest, float64) {
+	deltaTest := c.DeltaTest
+	if deltaTest == nil {
+		deltaTest = UTest
+	}
+	alpha := c.Alpha
+	if alpha == 0 {
+		alpha = 0.05
+	}
+	info := c.unitInfo(unit)
+	if info.DeltaTest != nil {
+		deltaTest = info.DeltaTest
+	}
+	if info.Alpha != 0 {
+		alpha = info.Alpha
+	}
+	return deltaTest, alpha
+}
+
+// A MissingBenchmark is a benchmark that was not measured in every
+// configuration of a Collection.
+type MissingBenchmark struct {
+	Benchmark string   // benchmark name
+	Group     string   // group name, as in Row
+	Configs   []string // configurations it is missing from
+}
+
+// MissingBenchmarks returns the benchmarks in c that were not measured
+// in every configuration, in any of c.Units, in the order they were read.
+// When comparing two configurations, these are the benchmarks added
+// or removed between them.
+func (c *Collection) MissingBenchmarks() []*MissingBenchmark {
+	var missing []*MissingBenchmark
+	key := Key{}
+	for _, key.Group = range c.Groups {
+		for _, key.Benchmark = range c.Benchmarks[key.Group] {
+			var configs []string
+		Configs:
+			for _, key.Config = range c.Configs {
+				for _, key.Unit = range c.Units {
+					if c.Metrics[key] != nil {
+						continue Configs
+					}
+				}
+				configs = append(configs, key.Config)
+			}
+			if configs != nil {
+				m := &MissingBenchmark{Benchmark: key.Benchmark, Configs: configs}
+				if len(c.Groups) > 1 {
+					m.Group = key.Group
+				}
+				missing = append(missing, m)
+			}
+		}
+	}
+	return missing
+}
+
+// newRow returns the row of table for the benchmark identified
+// by key, whose Config is ignored. It returns nil if the benchmark
+// should be omitted from the table.
+func (c *Collection) newRow(table *Table, key Key, deltaTest DeltaTest, alpha float64) *Row {
+	row := &Row{Benchmark: key.Benchmark, PValue: -1}
+	if len(c.Groups) > 1 {
+		// Show group headers if there is more than one group.
+		row.Group = key.Group
+	}
+
+	for _, key.Config = range c.Configs {
+		m := c.Metrics[key]
+		if m == nil {
+			if c.Missing == MissingHide {
+				return nil
+			}
+			row.Metrics = append(row.Metrics, new(Metrics))
+			continue
+		}
+		row.Metrics = append(row.Metrics, m)
+		if row.Scaler == nil {
+			row.Scaler = c.newScaler(m.Center, m.Unit)
+		}
+	}
+
+	// If there are only two configs being compared, add stats.
+	if table.OldNewDelta {
+		k0 := key
+		k0.Config = c.Configs[0]
+		k1 := key
+		k1.Config = c.Configs[1]
+		old := c.Metrics[k0]
+		new := c.Metrics[k1]
+		if old == nil || new == nil {
+			if c.Missing == MissingBlank {
+				return row
+			}
+			return nil
+		}
+		c.compare(row, key.Unit, old, new, deltaTest, alpha, table.StatColumns)
+	}
+
+	// Otherwise compare each config after the first with the first
+	// or the previous one, as table.DeltaMode says.
+	if table.DeltaMode != DeltaDefault {
+		for i := 1; i < len(c.Configs); i++ {
+			k0, k1 := key, key
+			k0.Config, k1.Config = c.Configs[0], c.Configs[i]
+			if table.DeltaMode == DeltaPrevious {
+				k0.Config = c.Configs[i-1]
+			}
+			old, new := c.Metrics[k0], c.Metrics[k1]
+			if old == nil || new == nil {
+				row.Deltas = append(row.Deltas, nil)
+				continue
+			}
+			d := &Row{Benchmark: row.Benchmark, Group: row.Group, PValue: -1}
+			c.compare(d, key.Unit, old, new, deltaTest, alpha, table.StatColumns)
+			row.Deltas = append(row.Deltas, d)
+		}
+	}
+	return row
+}
+
+// compare sets the delta, note, and statistics of row, which compares
+// the old and new values of unit with deltaTest at level alpha. If
+// statColumns is set, the note omits the p-value and sample sizes,
+// which the table shows in columns of their own.
+func (c *Collection) compare(row *Row, unit string, old, new *Metrics, deltaTest DeltaTest, alpha float64, statColumns bool) {
+	row.Ratio = centerRatio(old, new)
+	if c.Intervals {
+		row.RatioEstimate, row.RatioLo, row.RatioHi = ratioShift(old, new, 1-alpha)
+	}
+	pval, testerr := deltaTest(old, new)
+	exact, rounding := false, false
+	if pval != -1 || testerr != nil {
+		// Without a delta test, every change is reported.
+		exact, rounding = discrete(unit, old, new)
+	}
+	if exact || rounding {
+		c.discreteRow(row, unit, old, new, exact)
+	} else {
+		if testerr == nil {
+			row.PValue = pval
+		}
+		row.Underpowered = underpowered(deltaTest, alpha, old.n(), new.n())
+		row.Delta = "~"
+		if testerr == stats.ErrZeroVariance {
+			row.Note = "(zero variance)"
+		} else if testerr == stats.ErrSampleSize {
+			row.Note = "(too few samples)"
+		} else if testerr == stats.ErrSamplesEqual {
+			row.Note = "(all equal)"
+		} else if testerr != nil {
+			row.Note = fmt.Sprintf("(%s)", testerr)
+		} else if pval < alpha {
+			if new.Center == old.Center {
+				row.Delta = c.percentDelta(0)
+			} else {
+				c.setDelta(row, unit, old, new)
+			}
+		}
+		if row.Note == "" && pval != -1 && !statColumns {
+			p := c.Numbers.Format(fmt.Sprintf("%0.3f", pval))
+			row.Note = fmt.Sprintf("(p=%s n=%d+%d)", p, old.n(), new.n())
+		}
+	}
+	if row.Delta != "~" && new.Center != old.Center {
+		row.AbsDelta = c.formatAbsDelta(new.Center-old.Center, unit)
+	}
+	if row.Unbalanced = unbalanced(old, new); row.Unbalanced != "" {
+		if row.Note != "" {
+			row.Note += " "
+		}
+		row.Note += "(" + row.Unbalanced + ")"
+	}
+}
+
+// setDelta sets the delta and change of row, which compares
+// different old and new values of unit.
+func (c *Collection) setDelta(row *Row, unit string, old, new *Metrics) {
+	pct := ((new.Center / old.Center) - 1.0) * 100.0
+	row.Delta = c.percentDelta(pct)
+	if pct < 0 == (c.unitInfo(unit).Better == LowerIsBetter) {
+		row.Change = +1
+	} else {
+		row.Change = -1
+	}
+}
+
+// discreteRow sets the delta and note of row, which compares the
+// discrete samples old and new of unit without a delta test. If exact
+// is set, each sample is constant, and any difference between them
+// is exact; otherwise the samples differ by less than rounding error.
+func (c *Collection) discreteRow(row *Row, unit string, old, new *Metrics, exact bool) {
+	switch {
+	case !exact:
+		row.Delta = "~"
+		row.Note = "(within rounding)"
+	case old.Center == new.Center:
+		row.Delta = c.percentDelta(0)
+		row.Note = "(all equal)"
+	default:
+		c.setDelta(row, unit, old, new)
+		row.Note = "(exact)"
+	}
+}
+
+// formatAbsDelta formats the absolute change diff in a value of unit,
+// with a scale chosen for diff itself, since a change is often far
+// smaller than the values changed.
+func (c *Collection) formatAbsDelta(diff float64, unit string) string {
+	sign := "+"
+	if diff < 0 {
+		sign, diff = "-", -diff
+	}
+	return sign + c.newScaler(diff, unit)(diff)
+}
+
+// unbalanced returns a description of how the samples old and new
+// appear to have been measured with different go test settings, or
+// "" if they appear comparable. Sample sizes differing by a factor of
+// two or more suggest different -count settings. For times per
+// operation, a factor of two or more in the total time measured per
+// result, which go test keeps near -benchtime, suggests different
+// -benchtime settings.
+func unbalanced(old, new *Metrics) string {
+	var diffs []string
+	if ratio(float64(old.Count), float64(new.Count)) >= 2 {
+		diffs = append(diffs, "-count")
+	}
+	if prefix, base, per := splitUnit(old.Unit); prefix == "" && timeUnits[base] != 0 && per == "/op" {
+		if ratio(old.Iters*old.Mean, new.Iters*new.Mean) >= 2 {
+			diffs = append(diffs, "-benchtime")
+		}
+	}
+	if diffs == nil {
+		return ""
+	}
+	return "different " + strings.Join(diffs, " and ")
+}
+
+// ratio returns the ratio of the larger of x and y to the smaller,
+// or 0 if either is not positive.
+func ratio(x, y float64) float64 {
+	if x <= 0 || y <= 0 {
+		return 0
+	}
+	if x < y {
+		x, y = y, x
+	}
+	return x / y
+}
+
+// percentDelta formats the percent change pct to c.DeltaPrecision
+// decimal places, with a sign unless it is 0.
+func (c *Collection) percentDelta(pct float64) string {
+	prec := c.DeltaPrecision
+	if prec <= 0 {
+		prec = 2
+	}
+	format := "%+.*f%%"
+	if pct == 0 {
+		format = "%.*f%%"
+	}
+	return c.Numbers.Format(fmt.Sprintf(format, prec, pct))
+}
+
+// newScaler returns a Scaler for values of unit in the row
+// containing val, honoring c.TimeUnit, c.SizeUnit, the unit's
+// Scale, c.Digits, and c.Numbers.
+func (c *Collection) newScaler(val float64, unit string) Scaler {
+	scaler := c.unitScaler(val, unit)
+	if c.Numbers == (NumberFormat{}) {
+		return scaler
+	}
+	return func(val float64) string {
+		return c.Numbers.Format(scaler(val))
+	}
+}
+
+// unitScaler is newScaler, leaving the separators of numbers unchanged.
+func (c *Collection) unitScaler(val float64, unit string) Scaler {
+	digits := c.Digits
+	if digits <= 0 {
+		digits = 3
+	}
+	scale := c.unitInfo(unit).Scale
+	for _, target := range []string{c.TimeUnit, c.SizeUnit} {
+		if target == "" {
+			continue
+		}
+		if scaler, ok := newFixedScaler(val, unit, scale, target, digits); ok {
+			return scaler
+		}
+	}
+	if scaler, ok := newUnitScaler(val, unit, scale, c.BinaryPrefixes, digits); ok {
+		return scaler
+	}
+	return newScaler(val, unit, digits)
+}
+
+// parallel calls f(i) for each i in [0, n), spreading the calls
+// over up to c.Parallelism goroutines. The calls must be independent.
+func (c *Collection) parallel(n int, f func(i int)) {
+	procs := c.Parallelism
+	if procs <= 0 {
+		procs = runtime.GOMAXPROCS(0)
+	}
+	if procs > n {
+		procs = n
+	}
+	if procs <= 1 {
+		for i := 0; i < n; i++ {
+			f(i)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	next := int64(-1)
+	for p := 0; p < procs; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				f(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 var metricSuffix = map[string]string{
 	"ns/op": "time/op",
 	"ns/GC": "time/GC",
@@ -155,13 +589,27 @@ func metricOf(unit string) string {
 // addGeomean adds a "geomean" row to the table,
 // showing the geometric mean of all the benchmarks.
 func addGeomean(c *Collection, t *Table, unit string, delta bool) {
-	row := &Row{Benchmark: "[Geo mean]"}
+	row, count := c.geomeanRow(unit, c.Groups, delta)
+	if count <= 1 {
+		// Only one benchmark contributed to this geomean.
+		// Since the geomean is the same as the benchmark
+		// result, don't bother outputting it.
+		return
+	}
+	t.Rows = append(t.Rows, row)
+}
+
+// geomeanRow returns a "geomean" row showing the geometric mean of the
+// benchmarks in groups, and the largest number of benchmarks that
+// contributed to the geomean of any configuration.
+func (c *Collection) geomeanRow(unit string, groups []string, delta bool) (*Row, int) {
+	row := &Row{Benchmark: "[Geo mean]", PValue: -1}
 	key := Key{Unit: unit}
 	geomeans := []float64{}
 	maxCount := 0
 	for _, key.Config = range c.Configs {
 		var means []float64
-		for _, key.Group = range c.Groups {
+		for _, key.Group = range groups {
 			for _, key.Benchmark = range c.Benchmarks[key.Group] {
 				m := c.Metrics[key]
 				// Omit 0 values from the geomean calculation,
@@ -170,8 +618,8 @@ func addGeomean(c *Collection, t *Table, unit string, delta bool) {
 				// typically comes up with things like
 				// allocation counts, where it's fine to just
 				// ignore the benchmark.
-				if m != nil && m.Mean != 0 {
-					means = append(means, m.Mean)
+				if m != nil && m.Center != 0 {
+					means = append(means, m.Center)
 				}
 			}
 		}
@@ -185,22 +633,17 @@ func addGeomean(c *Collection, t *Table, unit string, delta bool) {
 			geomean := stats.GeoMean(means)
 			geomeans = append(geomeans, geomean)
 			if row.Scaler == nil {
-				row.Scaler = NewScaler(geomean, unit)
+				row.Scaler = c.newScaler(geomean, unit)
 			}
 			row.Metrics = append(row.Metrics, &Metrics{
-				Unit: unit,
-				Mean: geomean,
+				Unit:   unit,
+				Mean:   geomean,
+				Center: geomean,
 			})
 		}
 	}
-	if maxCount <= 1 {
-		// Only one benchmark contributed to this geomean.
-		// Since the geomean is the same as the benchmark
-		// result, don't bother outputting it.
-		return
-	}
 	if delta {
-		row.Delta = fmt.Sprintf("%+.2f%%", ((geomeans[1]/geomeans[0])-1.0)*100.0)
+		row.Delta = c.percentDelta(((geomeans[1] / geomeans[0]) - 1.0) * 100.0)
 	}
-	t.Rows = append(t.Rows, row)
+	return row, maxCount
 }
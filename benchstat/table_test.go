@@ -0,0 +1,101 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRowID(t *testing.T) {
+	ids := func(tables []*Table) map[string]string {
+		m := make(map[string]string)
+		for _, table := range tables {
+			for _, row := range table.Rows {
+				m[row.Benchmark+" "+table.Unit] = table.RowID(row)
+			}
+		}
+		return m
+	}
+	compared := ids(readCollection(t, "testdata/exampleold.txt", "testdata/examplenew.txt").Tables())
+	single := ids(readCollection(t, "testdata/examplenew.txt").Tables())
+	if !reflect.DeepEqual(compared, single) {
+		t.Errorf("row ids comparing two files %v, of one file %v, want the same", compared, single)
+	}
+	if compared["GobEncode ns/op"] == compared["GobEncode MB/s"] {
+		t.Errorf("rows of different units have the same id %s", compared["GobEncode ns/op"])
+	}
+}
+
+func TestParallelism(t *testing.T) {
+	// A Collection computes the same tables, and is left the same,
+	// however many goroutines compute them.
+	read := func(parallelism int) (*Collection, []*Table) {
+		c := &Collection{Parallelism: parallelism, SplitBy: []string{"pkg"}, DeltaTest: TTest}
+		for _, file := range []string{"testdata/exampleold.txt", "testdata/examplenew.txt", "testdata/pkgs-old.txt", "testdata/pkgs-new.txt"} {
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.AddFile(file, f)
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		tables := c.Tables()
+		// Scalers are funcs, which reflect.DeepEqual never
+		// finds equal; the formatted values compare them.
+		for _, table := range tables {
+			for _, row := range table.Rows {
+				row.Scaler = nil
+			}
+		}
+		return c, tables
+	}
+	serial, serialTables := read(1)
+	for _, parallelism := range []int{2, 8} {
+		c, tables := read(parallelism)
+		if !reflect.DeepEqual(c.Configs, serial.Configs) || !reflect.DeepEqual(c.Groups, serial.Groups) ||
+			!reflect.DeepEqual(c.Benchmarks, serial.Benchmarks) || !reflect.DeepEqual(c.Units, serial.Units) {
+			t.Errorf("Parallelism %d: configs %q, groups %q, benchmarks %q, units %q, want %q, %q, %q, %q", parallelism,
+				c.Configs, c.Groups, c.Benchmarks, c.Units, serial.Configs, serial.Groups, serial.Benchmarks, serial.Units)
+		}
+		if !reflect.DeepEqual(c.Metrics, serial.Metrics) {
+			t.Errorf("Parallelism %d: metrics differ from those computed serially", parallelism)
+		}
+		if !reflect.DeepEqual(tables, serialTables) {
+			t.Errorf("Parallelism %d: tables differ from those computed serially", parallelism)
+		}
+	}
+}
+
+func TestIntervals(t *testing.T) {
+	row := func(c *Collection) *Row {
+		for _, table := range c.Tables() {
+			for _, row := range table.Rows {
+				if row.Benchmark == "GobEncode" && table.Unit == "ns/op" {
+					return row
+				}
+			}
+		}
+		t.Fatal("no GobEncode ns/op row")
+		return nil
+	}
+
+	// Without Intervals, only the ratio of the centers is computed.
+	r := row(readCollection(t, "testdata/exampleold.txt", "testdata/examplenew.txt"))
+	if r.Ratio == 0 || r.RatioLo != 0 || r.RatioHi != 0 || r.RatioEstimate != 0 {
+		t.Errorf("without Intervals: Ratio, RatioLo, RatioHi, RatioEstimate = %v, %v, %v, %v, want only Ratio", r.Ratio, r.RatioLo, r.RatioHi, r.RatioEstimate)
+	}
+
+	c := readCollection(t, "testdata/exampleold.txt", "testdata/examplenew.txt")
+	c.Intervals = true
+	r = row(c)
+	if !(0 < r.RatioLo && r.RatioLo <= r.RatioEstimate && r.RatioEstimate <= r.RatioHi && r.RatioHi < 1) {
+		t.Errorf("with Intervals: RatioLo, RatioEstimate, RatioHi = %v, %v, %v, want an improvement within the interval", r.RatioLo, r.RatioEstimate, r.RatioHi)
+	}
+}
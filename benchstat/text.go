@@ -12,6 +12,20 @@ import (
 
 // FormatText appends a fixed-width text formatting of the tables to w.
 func FormatText(w io.Writer, tables []*Table) {
+	formatText(w, tables, false)
+}
+
+// ANSI escapes coloring the rows of improvements and regressions in
+// text output.
+const (
+	colorBetter = "\x1b[32m"
+	colorWorse  = "\x1b[31m"
+	colorReset  = "\x1b[0m"
+)
+
+// formatText is FormatText, coloring the rows of significant changes
+// with ANSI escapes if color is set.
+func formatText(w io.Writer, tables []*Table, color bool) {
 	var textTables [][]*textRow
 	for _, t := range tables {
 		textTables = append(textTables, toText(t))
@@ -56,6 +70,13 @@ func FormatText(w io.Writer, tables []*Table) {
 
 		// data
 		for _, row := range table[1:] {
+			switch {
+			case !color:
+			case row.change > 0:
+				fmt.Fprint(w, colorBetter)
+			case row.change < 0:
+				fmt.Fprint(w, colorWorse)
+			}
 			for i, s := range row.cols {
 				switch {
 				case len(row.cols) == 1:
@@ -72,6 +93,9 @@ func FormatText(w io.Writer, tables []*Table) {
 					fmt.Fprintf(w, "  %*s", max[i], s)
 				}
 			}
+			if color && row.change != 0 {
+				fmt.Fprint(w, colorReset)
+			}
 			fmt.Fprintf(w, "\n")
 		}
 	}
@@ -79,7 +103,10 @@ func FormatText(w io.Writer, tables []*Table) {
 
 // A textRow is a row of printed text columns.
 type textRow struct {
-	cols []string
+	cols   []string
+	change int    // Change of the row of values, if any
+	id     string // Table.RowID of the row of values, if any
+	row    *Row   // row of values, if any
 }
 
 func newTextRow(cols ...string) *textRow {
@@ -103,11 +130,35 @@ func toText(t *Table) []*textRow {
 	switch len(t.Configs) {
 	case 1:
 		textRows = append(textRows, newTextRow("name", t.Metric))
+		if t.StatColumns {
+			textRows[0].add("n")
+		}
 	case 2:
-		textRows = append(textRows, newTextRow("name", "old "+t.Metric, "new "+t.Metric, "delta"))
+		if t.StatColumns {
+			textRows = append(textRows, newTextRow("name", "old "+t.Metric, "n", "new "+t.Metric, "n", "delta"))
+		} else {
+			textRows = append(textRows, newTextRow("name", "old "+t.Metric, "new "+t.Metric, "delta"))
+		}
+		if t.AbsDelta {
+			textRows[0].add("")
+		}
+		if t.StatColumns {
+			textRows[0].add("p")
+		}
 	default:
 		row := newTextRow("name \\ " + t.Metric)
-		row.cols = append(row.cols, t.Configs...)
+		for i, config := range t.Configs {
+			row.add(config)
+			if t.StatColumns {
+				row.add("n")
+			}
+			if i > 0 && t.DeltaMode != DeltaDefault {
+				row.add("delta")
+				if t.StatColumns {
+					row.add("p")
+				}
+			}
+		}
 		textRows = append(textRows, row)
 	}
 
@@ -119,8 +170,31 @@ func toText(t *Table) []*textRow {
 			textRows = append(textRows, newTextRow(group))
 		}
 		text := newTextRow(row.Benchmark)
-		for _, m := range row.Metrics {
+		text.id = t.RowID(row)
+		text.row = row
+		for i, m := range row.Metrics {
 			text.cols = append(text.cols, m.Format(row.Scaler))
+			if t.StatColumns {
+				text.add(formatN(m))
+			}
+			if i > 0 && t.DeltaMode != DeltaDefault {
+				d := row.delta(i - 1)
+				switch {
+				case d == nil:
+					text.add("")
+				case d.Delta == "~":
+					text.add("~   ")
+				default:
+					text.add(d.Delta)
+				}
+				if t.StatColumns {
+					p := ""
+					if d != nil {
+						p = formatP(t, d)
+					}
+					text.add(p)
+				}
+			}
 		}
 		if len(t.Configs) == 2 {
 			delta := row.Delta
@@ -128,7 +202,14 @@ func toText(t *Table) []*textRow {
 				delta = "~   "
 			}
 			text.cols = append(text.cols, delta)
+			if t.AbsDelta {
+				text.add(row.AbsDelta)
+			}
+			if t.StatColumns {
+				text.add(formatP(t, row))
+			}
 			text.cols = append(text.cols, row.Note)
+			text.change = row.Change
 		}
 		textRows = append(textRows, text)
 	}
@@ -137,3 +218,19 @@ func toText(t *Table) []*textRow {
 	}
 	return textRows
 }
+
+// formatN formats the sample size of m for a StatColumns table.
+func formatN(m *Metrics) string {
+	if m.n() == 0 {
+		return ""
+	}
+	return fmt.Sprint(m.n())
+}
+
+// formatP formats the p-value of row for the StatColumns table t.
+func formatP(t *Table, row *Row) string {
+	if row.PValue < 0 {
+		return ""
+	}
+	return t.Numbers.Format(fmt.Sprintf("%0.3f", row.PValue))
+}
@@ -0,0 +1,173 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchstat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Direction says whether larger or smaller values of a unit
+// are better.
+type Direction int
+
+const (
+	// UnknownDirection leaves the direction to be inferred from
+	// the unit: units of rate, like MB/s or ops/s, are taken to be
+	// HigherIsBetter and all others LowerIsBetter.
+	UnknownDirection Direction = 0
+	LowerIsBetter    Direction = -1
+	HigherIsBetter   Direction = +1
+)
+
+// ParseDirection parses "higher" or "lower" as a Direction.
+func ParseDirection(s string) (Direction, error) {
+	switch strings.ToLower(s) {
+	case "higher":
+		return HigherIsBetter, nil
+	case "lower":
+		return LowerIsBetter, nil
+	}
+	return UnknownDirection, fmt.Errorf("unknown direction %q (want higher or lower)", s)
+}
+
+func (d Direction) String() string {
+	switch d {
+	case HigherIsBetter:
+		return "higher"
+	case LowerIsBetter:
+		return "lower"
+	}
+	return "unknown"
+}
+
+// A UnitInfo describes how to interpret the values of a unit.
+// Zero fields mean the default for the unit.
+type UnitInfo struct {
+	// Better is whether higher or lower values are better.
+	Better Direction
+
+	// Scale is the unit that values are measured in, which
+	// determines how they are scaled for display: a unit of time
+	// like "ns" or "ms", a unit of size like "B" or "MB", or
+	// "none" to display values as they are. If empty, it is
+	// inferred from the name of the unit, so that "p99-ms" is
+	// taken to be measured in milliseconds.
+	Scale string
+
+	// DeltaTest and Alpha, if set, override Collection.DeltaTest
+	// and Collection.Alpha for this unit. Nearly deterministic
+	// units like allocs/op, for example, may warrant a stricter
+	// alpha than noisy ones like ns/op.
+	DeltaTest DeltaTest
+	Alpha     float64
+
+	// Center, if set, overrides Collection.Center for this unit, as
+	// BestCenter must to pick the best of times and rates alike.
+	Center Center
+}
+
+// unitInfo returns the information about unit, combining the
+// defaults for the unit, any metadata read from the input, and
+// c.UnitInfo, in increasing order of precedence.
+func (c *Collection) unitInfo(unit string) UnitInfo {
+	info := UnitInfo{Better: LowerIsBetter}
+	if isRate(unit) {
+		info.Better = HigherIsBetter
+	}
+	if _, base, _ := splitUnit(unit); timeUnits[base] != 0 || sizeUnits[base] != 0 {
+		info.Scale = base
+	}
+	if from, ok := c.baselineUnit(unit); ok {
+		// Normalized values are ratios, better in the same
+		// direction as the values they came from.
+		info = UnitInfo{Better: c.unitInfo(from).Better, Scale: "none"}
+	}
+
+	if meta := c.unitMeta[unit]; meta != nil {
+		if d, err := ParseDirection(meta["better"]); err == nil {
+			info.Better = d
+		}
+		if scale := meta["scale"]; isScale(scale) {
+			info.Scale = scale
+		}
+	}
+
+	if u := c.UnitInfo[unit]; u != nil {
+		if u.Better != UnknownDirection {
+			info.Better = u.Better
+		}
+		if u.Scale != "" {
+			info.Scale = u.Scale
+		}
+		info.DeltaTest = u.DeltaTest
+		info.Alpha = u.Alpha
+		info.Center = u.Center
+	}
+	return info
+}
+
+// Better returns whether higher or lower values of unit are better, as
+// given by c.UnitInfo, by metadata in the input, or by default.
+func (c *Collection) Better(unit string) Direction {
+	return c.unitInfo(unit).Better
+}
+
+// isScale reports whether s is a valid UnitInfo.Scale.
+func isScale(s string) bool {
+	return s == "none" || timeUnits[s] != 0 || sizeUnits[s] != 0
+}
+
+// isRate reports whether unit is a rate, measured per second,
+// like MB/s, ops/s, or items/s.
+func isRate(unit string) bool {
+	return strings.HasSuffix(unit, "/s")
+}
+
+// NormalizeUnit returns the canonical form of unit and the factor
+// that converts its values to the canonical unit. Times are
+// normalized to ns, sizes to B, and rates of size to MB/s, keeping
+// any prefix and per-suffix, so that µs/op becomes ns/op and B/s
+// becomes MB/s. Other units are returned unchanged, with factor 1.
+func NormalizeUnit(unit string) (canon string, factor float64) {
+	prefix, base, per := splitUnit(unit)
+	var target string
+	switch {
+	case timeUnits[base] != 0:
+		target = "ns"
+	case sizeUnits[base] != 0 && per == "/s":
+		target = "MB"
+	case sizeUnits[base] != 0:
+		target = "B"
+	default:
+		return unit, 1
+	}
+	factor, _ = convertFactor(base, target)
+	return prefix + target + per, factor
+}
+
+// A unitNorm records the normalized form of a unit.
+type unitNorm struct {
+	unit   string
+	factor float64
+}
+
+// normalizeUnit is like NormalizeUnit, but returns unit unchanged
+// unless c.NormalizeUnits is set, and remembers the result.
+func (c *Collection) normalizeUnit(unit string) (string, float64) {
+	if !c.NormalizeUnits {
+		return unit, 1
+	}
+	if n, ok := c.unitNorms[unit]; ok {
+		return n.unit, n.factor
+	}
+	if c.unitNorms == nil {
+		c.unitNorms = make(map[string]unitNorm)
+	}
+	canon, factor := NormalizeUnit(unit)
+	canon = c.intern(canon)
+	c.unitNorms[c.intern(unit)] = unitNorm{canon, factor}
+	return canon, factor
+}
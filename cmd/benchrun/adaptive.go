@@ -0,0 +1,139 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/perf/internal/stats"
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+// parsePrecision parses a relative precision, such as "1%" or 0.01.
+func parsePrecision(s string) (float64, error) {
+	var x float64
+	var err error
+	if strings.HasSuffix(s, "%") {
+		x, err = strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		x /= 100
+	} else {
+		x, err = strconv.ParseFloat(s, 64)
+	}
+	if err != nil || x <= 0 || x >= 1 {
+		return 0, fmt.Errorf("invalid precision %q", s)
+	}
+	return x, nil
+}
+
+// runAdaptive runs the benchmarks of versions as runInterleaved does,
+// warmup+count times, and then keeps running each top-level benchmark
+// in rounds, interleaved, until the 95% confidence interval of the mean
+// time of each of its results in each version is within ±precision of
+// the mean, or until the runs have taken budget, if not zero.
+func runAdaptive(versions []*version, warmup, count int, precision float64, budget time.Duration, iso *isolation) error {
+	start := time.Now()
+	if err := runInterleaved(versions, warmup, count, iso); err != nil {
+		return err
+	}
+	for round := count + 1; ; round++ {
+		pending := imprecise(versions, precision)
+		if len(pending) == 0 {
+			return nil
+		}
+		if budget != 0 && time.Since(start) >= budget {
+			log.Printf("time budget exhausted before reaching ±%g%% for %s", precision*100, strings.Join(pending, ", "))
+			return nil
+		}
+		if *flagVerbose {
+			log.Printf("run %d of %s", round, strings.Join(pending, ", "))
+		}
+		if err := runRound(versions, pending, iso); err != nil {
+			return err
+		}
+	}
+}
+
+// imprecise returns the top-level benchmarks with a result whose mean
+// time is not yet known to within ±precision in some version, in the
+// order first seen.
+func imprecise(versions []*version, precision float64) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, v := range versions {
+		times := v.times()
+		for _, name := range times.names {
+			top := topLevel(name)
+			if seen[top] || relativeWidth(times.values[name]) <= precision {
+				continue
+			}
+			seen[top] = true
+			names = append(names, top)
+		}
+	}
+	return names
+}
+
+// A timeSet is the times per op of the results of each benchmark.
+type timeSet struct {
+	names  []string // full benchmark names, in the order first seen
+	values map[string][]float64
+}
+
+// times returns the times per op of the results of v so far.
+func (v *version) times() *timeSet {
+	t := &timeSet{values: make(map[string][]float64)}
+	br := benchfmt.NewReader(bytes.NewReader(v.results.Bytes()))
+	for br.Next() {
+		f := strings.Fields(br.Result().Content)
+		if len(f) < 4 || !strings.HasPrefix(f[0], "Benchmark") {
+			continue
+		}
+		for i := 2; i+1 < len(f); i += 2 {
+			if f[i+1] != "ns/op" {
+				continue
+			}
+			x, err := strconv.ParseFloat(f[i], 64)
+			if err != nil {
+				continue
+			}
+			if t.values[f[0]] == nil {
+				t.names = append(t.names, f[0])
+			}
+			t.values[f[0]] = append(t.values[f[0]], x)
+		}
+	}
+	return t
+}
+
+// procsSuffix matches the GOMAXPROCS suffix of a benchmark name.
+var procsSuffix = regexp.MustCompile(`-[0-9]+$`)
+
+// topLevel returns the name of the top-level benchmark of the result
+// named name, without its sub-benchmarks and GOMAXPROCS suffix.
+func topLevel(name string) string {
+	if i := strings.Index(name, "/"); i >= 0 {
+		return name[:i]
+	}
+	return procsSuffix.ReplaceAllString(name, "")
+}
+
+// relativeWidth returns the half-width of the 95% confidence interval
+// of the mean of xs, by Student's t-distribution, relative to the mean.
+func relativeWidth(xs []float64) float64 {
+	n := float64(len(xs))
+	mean := stats.Mean(xs)
+	if n < 2 || mean == 0 {
+		return math.Inf(1)
+	}
+	t := stats.InvCDF(stats.TDist{V: n - 1})(0.975)
+	return t * stats.StdDev(xs) / math.Sqrt(n) / math.Abs(mean)
+}
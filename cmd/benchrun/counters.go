@@ -0,0 +1,113 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// counterEvents lists the hardware events benchrun can count.
+var counterEvents = []string{
+	"cycles",
+	"instructions",
+	"cache-references",
+	"cache-misses",
+	"branches",
+	"branch-misses",
+}
+
+// parseCounters parses a comma-separated list of hardware events.
+func parseCounters(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		ok := false
+		for _, ev := range counterEvents {
+			ok = ok || name == ev
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown counter %q; known counters are %s", name, strings.Join(counterEvents, ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// listBenchmarks returns the names of the benchmarks of v that match
+// -bench, or rather its first element, since the benchmarks listed are
+// only the top-level ones.
+func (v *version) listBenchmarks() ([]string, error) {
+	top := strings.SplitN(*flagBench, "/", 2)[0]
+	cmd := exec.Command(v.bin, "-test.list="+top)
+	cmd.Dir = v.dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: listing benchmarks: %v", v.name, err)
+	}
+	var names []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "Benchmark") {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
+
+// benchPattern returns the -test.bench pattern matching only the
+// benchmark name and, if -bench has several elements, the
+// sub-benchmarks matching the rest.
+func benchPattern(name string) string {
+	pattern := "^" + name + "$"
+	if f := strings.SplitN(*flagBench, "/", 2); len(f) == 2 {
+		pattern += "/" + f[1]
+	}
+	return pattern
+}
+
+// addCounts adds the counts of the hardware events in names, divided
+// by the number of iterations, to the result line of the benchmark
+// output out, as units such as "cycles/op". The counts cover the whole
+// process, so if out has several result lines, as when a benchmark has
+// sub-benchmarks, they cannot be divided among them and addCounts
+// returns out unchanged.
+func addCounts(out []byte, names []string, counts []float64) []byte {
+	lines := bytes.SplitAfter(out, []byte("\n"))
+	result := -1
+	var n float64
+	for i, line := range lines {
+		f := strings.Fields(string(line))
+		if len(f) < 4 || !strings.HasPrefix(f[0], "Benchmark") {
+			continue
+		}
+		iters, err := strconv.Atoi(f[1])
+		if err != nil || iters == 0 {
+			continue
+		}
+		if result >= 0 {
+			return out
+		}
+		result, n = i, float64(iters)
+	}
+	if result < 0 {
+		return out
+	}
+	var buf bytes.Buffer
+	line := lines[result]
+	buf.Write(bytes.TrimRight(line, "\n"))
+	for i, name := range names {
+		fmt.Fprintf(&buf, "\t%.2f %s/op", counts[i]/n, name)
+	}
+	if bytes.HasSuffix(line, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	lines[result] = buf.Bytes()
+	return bytes.Join(lines, nil)
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// perfEventAttr is the first version of struct perf_event_attr, which
+// has all the fields benchrun needs.
+type perfEventAttr struct {
+	Type         uint32
+	Size         uint32
+	Config       uint64
+	SamplePeriod uint64
+	SampleType   uint64
+	ReadFormat   uint64
+	Flags        uint64
+	WakeupEvents uint32
+	BPType       uint32
+	Config1      uint64
+}
+
+const (
+	perfTypeHardware = 0
+
+	perfFormatTotalTimeEnabled = 1 << 0
+	perfFormatTotalTimeRunning = 1 << 1
+
+	perfFlagDisabled      = 1 << 0
+	perfFlagInherit       = 1 << 1
+	perfFlagExcludeKernel = 1 << 5
+	perfFlagExcludeHV     = 1 << 6
+	perfFlagEnableOnExec  = 1 << 12
+
+	perfFlagFDCloexec = 1 << 3
+)
+
+// perfHardwareConfig maps the names in counterEvents to the
+// configurations of their generic hardware events.
+var perfHardwareConfig = map[string]uint64{
+	"cycles":           0,
+	"instructions":     1,
+	"cache-references": 2,
+	"cache-misses":     3,
+	"branches":         4,
+	"branch-misses":    5,
+}
+
+// A counter counts a hardware event in the processes started by the
+// thread that opened it.
+type counter struct {
+	name string
+	fd   int
+}
+
+// openCounter opens a counter of the named event for the calling
+// thread. The counter is disabled until a child process of the thread
+// executes a program, and counts only in user space, which needs no
+// privileges, and then in that child and its own children.
+func openCounter(name string) (*counter, error) {
+	attr := perfEventAttr{
+		Type:       perfTypeHardware,
+		Config:     perfHardwareConfig[name],
+		ReadFormat: perfFormatTotalTimeEnabled | perfFormatTotalTimeRunning,
+		Flags:      perfFlagDisabled | perfFlagInherit | perfFlagExcludeKernel | perfFlagExcludeHV | perfFlagEnableOnExec,
+	}
+	attr.Size = uint32(unsafe.Sizeof(attr))
+	fd, _, e := syscall.RawSyscall6(syscall.SYS_PERF_EVENT_OPEN, uintptr(unsafe.Pointer(&attr)), 0, ^uintptr(0), ^uintptr(0), perfFlagFDCloexec, 0)
+	if e != 0 {
+		return nil, fmt.Errorf("counting %s: perf_event_open: %v", name, e)
+	}
+	return &counter{name: name, fd: int(fd)}, nil
+}
+
+// openCounters opens counters of the named events for the calling
+// thread.
+func openCounters(names []string) ([]*counter, error) {
+	var cs []*counter
+	for _, name := range names {
+		c, err := openCounter(name)
+		if err != nil {
+			closeCounters(cs)
+			return nil, err
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
+
+// closeCounters closes the counters cs.
+func closeCounters(cs []*counter) {
+	for _, c := range cs {
+		c.close()
+	}
+}
+
+// read returns the count of c, once its processes have exited. If the
+// kernel could only count part of the time, as when more events are
+// counted than the hardware has counters for, read scales the count
+// to the whole time.
+func (c *counter) read() (float64, error) {
+	// The kernel writes the value, time enabled, and time running
+	// in native byte order.
+	var vals [3]uint64
+	buf := (*[unsafe.Sizeof(vals)]byte)(unsafe.Pointer(&vals))[:]
+	n, err := syscall.Read(c.fd, buf)
+	if err != nil {
+		return 0, fmt.Errorf("reading %s counter: %v", c.name, err)
+	}
+	if n != len(buf) {
+		return 0, fmt.Errorf("reading %s counter: short read", c.name)
+	}
+	value, enabled, running := vals[0], vals[1], vals[2]
+	if running == 0 {
+		return 0, nil
+	}
+	return float64(value) * float64(enabled) / float64(running), nil
+}
+
+// close closes c.
+func (c *counter) close() {
+	syscall.Close(c.fd)
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// An isolation is the measures benchrun takes to keep the rest of the
+// machine from disturbing the benchmarks.
+type isolation struct {
+	cpus     []int   // CPUs to run the benchmarks on; all if empty
+	governor string  // CPU frequency governor to set, if any
+	noTurbo  bool    // whether to disable turbo boost
+	memory   int64   // memory limit, in bytes, if not zero
+	cpuQuota float64 // CPU time limit, in CPUs, if not zero
+	image    string  // container image to run the benchmarks in, if any
+	runtime  string  // container runtime, such as docker or podman
+
+	cgroupRoot string // cgroup v2 hierarchy to create cgroups in
+	cgroup     string // cgroup created to run the benchmarks in, if any
+
+	undo []func() error // restore the machine's settings, in reverse order
+}
+
+// parseCPUs parses a list of CPUs, such as "0,2-3", as taskset -c does.
+func parseCPUs(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var cpus []int
+	seen := make(map[int]bool)
+	for _, r := range strings.Split(s, ",") {
+		lo, hi := r, r
+		if i := strings.Index(r, "-"); i >= 0 {
+			lo, hi = r[:i], r[i+1:]
+		}
+		from, err1 := strconv.Atoi(lo)
+		to, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || from < 0 || to < from {
+			return nil, fmt.Errorf("invalid CPU list %q", s)
+		}
+		for c := from; c <= to; c++ {
+			if !seen[c] {
+				seen[c] = true
+				cpus = append(cpus, c)
+			}
+		}
+	}
+	return cpus, nil
+}
+
+// parseBytes parses an amount of memory, such as 512M or 2G, in bytes
+// or with a binary suffix, K, M, or G.
+func parseBytes(s string) (int64, error) {
+	num, scale := s, int64(1)
+	if len(s) > 0 {
+		switch s[len(s)-1] {
+		case 'K', 'k':
+			scale = 1 << 10
+		case 'M', 'm':
+			scale = 1 << 20
+		case 'G', 'g':
+			scale = 1 << 30
+		}
+		if scale != 1 {
+			num = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid amount of memory %q", s)
+	}
+	return n * scale, nil
+}
+
+// command returns the command running the test binary bin with args in
+// dir, as isolated by iso: in a container of iso.image, if set, and
+// otherwise in iso.cgroup, if set.
+func (iso *isolation) command(bin, dir string, args []string) (*exec.Cmd, error) {
+	if iso.image != "" {
+		bin, err := filepath.Abs(bin)
+		if err != nil {
+			return nil, err
+		}
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		run := []string{"run", "--rm", "--network=none",
+			"-v", bin + ":/benchrun/bench.test:ro",
+			"-v", dir + ":/benchrun/src",
+			"-w", "/benchrun/src"}
+		if iso.memory != 0 {
+			run = append(run, fmt.Sprintf("--memory=%d", iso.memory))
+		}
+		if iso.cpuQuota != 0 {
+			run = append(run, fmt.Sprintf("--cpus=%g", iso.cpuQuota))
+		}
+		if len(iso.cpus) > 0 {
+			run = append(run, "--cpuset-cpus="+cpuList(iso.cpus))
+		}
+		run = append(run, iso.image, "/benchrun/bench.test")
+		return exec.Command(iso.runtime, append(run, args...)...), nil
+	}
+	var cmd *exec.Cmd
+	if iso.cgroup != "" {
+		// The shell moves itself into the cgroup before it
+		// becomes the test binary, so that none of the benchmark
+		// runs outside it.
+		script := `echo $$ >"$0/cgroup.procs" && exec "$@"`
+		cmd = exec.Command("/bin/sh", append([]string{"-c", script, iso.cgroup, bin}, args...)...)
+	} else {
+		cmd = exec.Command(bin, args...)
+	}
+	cmd.Dir = dir
+	return cmd, nil
+}
+
+// cpuList formats a list of CPUs for taskset -c or docker.
+func cpuList(cpus []int) string {
+	var list []string
+	for _, c := range cpus {
+		list = append(list, strconv.Itoa(c))
+	}
+	return strings.Join(list, ",")
+}
+
+// labels returns configuration lines recording the measures taken.
+func (iso *isolation) labels() []byte {
+	var buf bytes.Buffer
+	if len(iso.cpus) > 0 {
+		fmt.Fprintf(&buf, "cpus: %s\n", cpuList(iso.cpus))
+	}
+	if iso.governor != "" {
+		fmt.Fprintf(&buf, "governor: %s\n", iso.governor)
+	}
+	if iso.noTurbo {
+		fmt.Fprintf(&buf, "turbo: off\n")
+	}
+	if iso.memory != 0 {
+		fmt.Fprintf(&buf, "memory-limit: %d\n", iso.memory)
+	}
+	if iso.cpuQuota != 0 {
+		fmt.Fprintf(&buf, "cpu-quota: %g\n", iso.cpuQuota)
+	}
+	if iso.image != "" {
+		fmt.Fprintf(&buf, "image: %s\n", iso.image)
+	}
+	return buf.Bytes()
+}
+
+// restore restores the settings changed by setup, and returns the
+// first error restoring any of them.
+func (iso *isolation) restore() error {
+	var first error
+	for i := len(iso.undo) - 1; i >= 0; i-- {
+		if err := iso.undo[i](); err != nil && first == nil {
+			first = err
+		}
+	}
+	iso.undo = nil
+	return first
+}
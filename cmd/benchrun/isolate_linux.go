@@ -0,0 +1,171 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// setup sets the CPU frequency governor, disables turbo boost, and
+// creates a cgroup limiting memory and CPU time, as iso asks,
+// remembering how to restore them.
+func (iso *isolation) setup() error {
+	if (iso.memory != 0 || iso.cpuQuota != 0) && iso.image == "" {
+		if err := iso.setupCgroup(); err != nil {
+			return fmt.Errorf("creating cgroup: %v", err)
+		}
+	}
+	if iso.governor != "" {
+		var files []string
+		if len(iso.cpus) == 0 {
+			files, _ = filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/cpufreq/scaling_governor")
+		}
+		for _, c := range iso.cpus {
+			files = append(files, fmt.Sprintf("/sys/devices/system/cpu/cpu%d/cpufreq/scaling_governor", c))
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("cannot set governor: no CPU frequency scaling")
+		}
+		for _, file := range files {
+			if err := iso.write(file, iso.governor); err != nil {
+				return fmt.Errorf("setting governor: %v", err)
+			}
+		}
+	}
+	if iso.noTurbo {
+		// The intel_pstate driver has its own switch; other
+		// drivers share the generic one, which is inverted.
+		var err error
+		if _, serr := os.Stat("/sys/devices/system/cpu/intel_pstate/no_turbo"); serr == nil {
+			err = iso.write("/sys/devices/system/cpu/intel_pstate/no_turbo", "1")
+		} else if _, serr := os.Stat("/sys/devices/system/cpu/cpufreq/boost"); serr == nil {
+			err = iso.write("/sys/devices/system/cpu/cpufreq/boost", "0")
+		} else {
+			err = fmt.Errorf("no turbo boost control")
+		}
+		if err != nil {
+			return fmt.Errorf("disabling turbo: %v", err)
+		}
+	}
+	return nil
+}
+
+// setupCgroup creates a cgroup for the benchmarks under iso.cgroupRoot,
+// a cgroup v2 hierarchy, with the limits of iso.
+func (iso *isolation) setupCgroup() error {
+	// Enable the controllers for the children of the root, which
+	// may fail because they already are.
+	ioutil.WriteFile(filepath.Join(iso.cgroupRoot, "cgroup.subtree_control"), []byte("+memory +cpu"), 0644)
+	dir := filepath.Join(iso.cgroupRoot, fmt.Sprintf("benchrun.%d", os.Getpid()))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return err
+	}
+	iso.cgroup = dir
+	iso.undo = append(iso.undo, func() error { return os.Remove(dir) })
+	if iso.memory != 0 {
+		if err := ioutil.WriteFile(filepath.Join(dir, "memory.max"), []byte(fmt.Sprint(iso.memory)), 0644); err != nil {
+			return err
+		}
+		// Keep the benchmarks from swapping instead, if there is
+		// swap to control.
+		ioutil.WriteFile(filepath.Join(dir, "memory.swap.max"), []byte("0"), 0644)
+	}
+	if iso.cpuQuota != 0 {
+		const period = 100000 // µs
+		max := fmt.Sprintf("%d %d", int64(iso.cpuQuota*period), period)
+		if err := ioutil.WriteFile(filepath.Join(dir, "cpu.max"), []byte(max), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes value to the sysfs file, arranging for restore to
+// write back its old value.
+func (iso *isolation) write(file, value string) error {
+	old, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, []byte(value), 0); err != nil {
+		return err
+	}
+	iso.undo = append(iso.undo, func() error {
+		return ioutil.WriteFile(file, []byte(strings.TrimSpace(string(old))), 0)
+	})
+	return nil
+}
+
+// cpuMask is a CPU set for sched_setaffinity, wide enough for 1024 CPUs.
+type cpuMask [16]uint64
+
+// start starts cmd on the CPUs of iso, counting the hardware events
+// named by counters in it. It returns the counters, which the caller
+// must read, once cmd has exited, and close.
+func (iso *isolation) start(cmd *exec.Cmd, counters []string) (cs []*counter, err error) {
+	// A container runs on the CPUs of iso already.
+	cpus := iso.cpus
+	if iso.image != "" {
+		cpus = nil
+	}
+	if len(cpus) == 0 && len(counters) == 0 {
+		return nil, cmd.Start()
+	}
+	var mask cpuMask
+	for _, c := range cpus {
+		if c >= len(mask)*64 {
+			return nil, fmt.Errorf("CPU %d out of range", c)
+		}
+		mask[c/64] |= 1 << uint(c%64)
+	}
+
+	// A child inherits the affinity and the counters of the thread
+	// that forks it. Set them up on this thread for as long as it
+	// takes to start cmd.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	cs, err = openCounters(counters)
+	if err != nil {
+		return nil, err
+	}
+	if len(cpus) > 0 {
+		var old cpuMask
+		if err := schedAffinity(syscall.SYS_SCHED_GETAFFINITY, &old); err != nil {
+			closeCounters(cs)
+			return nil, err
+		}
+		if err := schedAffinity(syscall.SYS_SCHED_SETAFFINITY, &mask); err != nil {
+			closeCounters(cs)
+			return nil, fmt.Errorf("pinning to CPUs %v: %v", iso.cpus, err)
+		}
+		defer func() {
+			if rerr := schedAffinity(syscall.SYS_SCHED_SETAFFINITY, &old); rerr != nil && err == nil {
+				err = rerr
+			}
+		}()
+	}
+	if err := cmd.Start(); err != nil {
+		closeCounters(cs)
+		return nil, err
+	}
+	return cs, nil
+}
+
+// schedAffinity gets or sets the affinity of the calling thread.
+func schedAffinity(trap uintptr, mask *cpuMask) error {
+	_, _, e := syscall.RawSyscall(trap, 0, unsafe.Sizeof(*mask), uintptr(unsafe.Pointer(mask)))
+	if e != 0 {
+		return e
+	}
+	return nil
+}
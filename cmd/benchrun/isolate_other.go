@@ -0,0 +1,49 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// setup reports an error if iso asks for any measure, since they are
+// only implemented on Linux, except in a container.
+func (iso *isolation) setup() error {
+	if iso.governor != "" || iso.noTurbo {
+		return fmt.Errorf("-governor and -no-turbo are not supported on %s", runtime.GOOS)
+	}
+	if iso.image != "" {
+		return nil
+	}
+	if len(iso.cpus) > 0 || iso.memory != 0 || iso.cpuQuota != 0 {
+		return fmt.Errorf("-cpus, -memory, and -cpu-quota are only supported on %s with -image", runtime.GOOS)
+	}
+	return nil
+}
+
+// start starts cmd. Counting hardware events is only implemented
+// on Linux.
+func (iso *isolation) start(cmd *exec.Cmd, counters []string) ([]*counter, error) {
+	if len(counters) > 0 {
+		return nil, fmt.Errorf("-counters is not supported on %s", runtime.GOOS)
+	}
+	return nil, cmd.Start()
+}
+
+// A counter counts a hardware event.
+type counter struct {
+	name string
+}
+
+func (c *counter) read() (float64, error) { return 0, nil }
+
+func (c *counter) close() {}
+
+func closeCounters(cs []*counter) {}
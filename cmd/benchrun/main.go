@@ -0,0 +1,564 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Benchrun runs the benchmarks of two versions of a package, interleaved,
+// and compares them.
+//
+// Usage:
+//
+//	benchrun [-count n] [-bench regexp] [-repo dir] [-pkg path] [-o dir] old new
+//
+// Each of old and new is either a test binary, as built by "go test -c",
+// or a git revision of the repository in the directory given by -repo,
+// the current directory by default. For a revision, benchrun checks it
+// out into a temporary worktree, leaving the repository as it is, and
+// builds the test binary of the package given by -pkg, relative to the
+// root of the repository, the root package by default.
+//
+// Benchrun then runs the benchmarks matching -bench -count times for each
+// version, alternating between the versions: old, new, old, new, and so
+// on. Machines drift, as they warm up, as other work comes and goes, and
+// as their clocks change speed, and running all of old and then all of new
+// blames any such drift on the change. Interleaving the runs spreads it
+// evenly over both.
+//
+// The -warmup option adds rounds of runs before the others, still
+// interleaved, whose results benchrun discards. The first runs are often
+// slower than the rest, as caches fill, memory pages fault in, and the CPUs
+// leave their idle states, even for Go, which compiles ahead of time.
+// Warmup cannot discard the first iterations within a run, which the
+// benchmarks' own short calibration runs mostly absorb; to discard early
+// runs of recorded results instead, use benchstat -warmup.
+//
+// The -precision option runs benchmarks until their times are known
+// precisely enough, rather than a fixed number of times. After the first
+// -count rounds, benchrun keeps running, in further interleaved rounds,
+// each benchmark for which the 95% confidence interval of the mean time of
+// a result in either version is wider than the mean plus or minus the
+// given precision, such as "1%". The -budget option bounds the time spent,
+// counting from the first run; once it is exhausted, benchrun reports the
+// benchmarks that fell short and compares them as they are.
+//
+// The -rerun option settles borderline comparisons by running the
+// benchmarks concerned again, rather than reporting them as inconclusive.
+// A comparison is borderline if its p-value is within a factor of two of
+// the significance level given by -alpha, on either side, or if the samples
+// are too small for any change to be significant. Benchrun runs each
+// benchmark with a borderline comparison once more for each version,
+// interleaved, compares again, and repeats, up to the given number of
+// times, until no comparison is borderline. Testing again as results
+// accumulate makes a change slightly more likely to appear significant by
+// chance, so use a somewhat smaller -alpha than usual with -rerun.
+//
+// The -shuffle option runs the benchmarks one by one, in a random order in
+// each round, and the versions of each benchmark in a random order too, so
+// that noise that recurs on the machine, such as a periodic job, does not
+// always fall on the same benchmark or the same version. Benchrun logs the
+// seed of the random order and records it in the results as a
+// configuration line, "shuffle-seed: n"; the -seed option repeats the
+// order of an earlier invocation.
+//
+// Finally, benchrun prints the comparison of the versions, as benchstat does.
+// The -o option also writes the results of each version to the named
+// directory, as old.txt and new.txt, for later analysis with benchstat or
+// benchseries. Results of a version built from a revision begin with
+// configuration lines naming its commit and the Go version that built it,
+// such as "go: go1.13.4", by which benchstat -pivot go compares toolchains.
+// The results of both versions also record the machine they ran on, with
+// configuration lines describing its CPU model, number of CPUs, memory,
+// kernel, CPU frequency governor, and hypervisor, if any, such as
+//
+//	machine: 3f2a9c01d4e7
+//	machine-cpu: Intel(R) Xeon(R) CPU E5-2690 v4 @ 2.60GHz
+//	machine-cores: 56
+//	machine-memory: 256GiB
+//	machine-kernel: linux 4.19.0-5-amd64
+//	machine-governor: performance
+//	machine-virt: none
+//
+// The machine key identifies the hardware, leaving out the kernel and the
+// governor, so that results from different machines can be told apart.
+//
+// The -benchtime and -benchmem options are passed to the benchmarks, as
+// by "go test".
+//
+// On Linux, benchrun can also keep the rest of the machine from disturbing
+// the benchmarks. The -cpus option pins the benchmarks to a list of CPUs,
+// such as "2,3" or "2-3", ideally ones set aside from the scheduler with
+// the isolcpus kernel option. The -governor option sets the CPU frequency
+// governor of those CPUs, or of all CPUs, for instance to "performance",
+// and the -no-turbo option disables turbo boost, so that the speed of the
+// CPUs does not depend on their temperature or on the load of the others.
+// These two require root, and benchrun restores the previous settings when
+// it finishes. The results record the measures taken as configuration
+// lines, such as "cpus: 2,3".
+//
+// The -memory and -cpu-quota options limit the memory and the CPU time of
+// the benchmarks, so that other work on the machine cannot take them away
+// from the benchmarks, or the benchmarks from other work, unpredictably in
+// either case. On Linux, benchrun creates a cgroup with the limits, which
+// requires the cgroup v2 hierarchy mounted at /sys/fs/cgroup and the right
+// to create cgroups there, and removes it when it finishes. The -image
+// option instead runs each run of the benchmarks in a fresh container of
+// the named image, with the limits, its CPUs restricted to -cpus, and no
+// network, using the container runtime given by -runtime, docker by
+// default. The container sees the test binary and the directory of the
+// package it tests, so the binary must run in the image: build it with
+// CGO_ENABLED=0, or use an image of the same system. The results record
+// the limits and the image as configuration lines, such as "image: debian:9".
+//
+// Also on Linux, the -throttle option watches the frequency and temperature
+// of the CPUs the benchmarks run on during each run, and recognizes runs
+// during which the CPUs were throttled, as the kernel counts, or reached
+// the temperature given by -max-temp. With -throttle=annotate, the results
+// of each run are preceded by configuration lines recording the mean
+// frequency, the highest temperature, and whether the CPUs were throttled,
+// such as
+//
+//	cpu-mhz: 3392
+//	cpu-temp: 71
+//	throttled: true
+//
+// With -throttle=discard, the results of runs during which the CPUs were
+// throttled are dropped, and those of the others annotated.
+//
+// Also on Linux, the -counters option counts hardware events in each run
+// of each benchmark, and reports them per iteration as additional units,
+// such as "cycles/op", for comparison like any other. The events are
+// cycles, instructions, cache-references, cache-misses, branches, and
+// branch-misses, counted only in user space. To tell the counts of the
+// benchmarks apart, benchrun then runs each top-level benchmark in a
+// process of its own. The counts include the start of the process and the
+// short runs by which the benchmark picks its number of iterations, so
+// they slightly overstate the cost of an iteration; a fixed -benchtime,
+// such as "10000x", keeps those runs to one. A benchmark with several
+// sub-benchmarks gets no counts, since they cannot be divided among them.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/perf/benchstat"
+	"golang.org/x/perf/internal/machine"
+)
+
+var (
+	flagCount     = flag.Int("count", 10, "run each version's benchmarks `n` times")
+	flagBench     = flag.String("bench", ".", "run only benchmarks matching `regexp`")
+	flagWarmup    = flag.Int("warmup", 0, "run each version's benchmarks `n` more times first, discarding the results")
+	flagPrecision = flag.String("precision", "", "run each benchmark until the confidence interval of its mean time is within ±`p`, such as 1%")
+	flagBudget    = flag.Duration("budget", 0, "with -precision, stop running benchmarks after `d` (0 means no limit)")
+	flagAlpha     = flag.Float64("alpha", 0.05, "consider changes significant if p < `α`")
+	flagRerun     = flag.Int("rerun", 0, "run benchmarks whose comparison is borderline again, up to `n` more times")
+	flagBenchtime = flag.String("benchtime", "", "run each benchmark for duration `d`, as in go test")
+	flagBenchmem  = flag.Bool("benchmem", false, "report memory allocations of benchmarks")
+	flagRepo      = flag.String("repo", ".", "build revisions from the git repository in `dir`")
+	flagPkg       = flag.String("pkg", ".", "build revisions of the package at `path` in the repository")
+	flagOut       = flag.String("o", "", "write the results of each version to `dir`")
+	flagVerbose   = flag.Bool("v", false, "log each run")
+	flagCPUs      = flag.String("cpus", "", "pin the benchmarks to the CPUs in `list`, such as 2,3 or 2-3 (Linux only)")
+	flagGovernor  = flag.String("governor", "", "set the CPU frequency governor to `name` while benchmarking (Linux only)")
+	flagNoTurbo   = flag.Bool("no-turbo", false, "disable turbo boost while benchmarking (Linux only)")
+	flagMemory    = flag.String("memory", "", "limit the memory of the benchmarks to `n` bytes, or with a suffix K, M, or G (Linux or -image only)")
+	flagCPUQuota  = flag.Float64("cpu-quota", 0, "limit the CPU time of the benchmarks to that of `n` CPUs (Linux or -image only)")
+	flagImage     = flag.String("image", "", "run the benchmarks in a fresh container of `image`")
+	flagRuntime   = flag.String("runtime", "docker", "run containers with `command`, such as docker or podman")
+	flagShuffle   = flag.Bool("shuffle", false, "run the benchmarks, and the versions of each, in a random order in each round")
+	flagSeed      = flag.Int64("seed", 0, "with -shuffle, seed the random order with `n` (default random)")
+	flagCounters  = flag.String("counters", "", "count the hardware events in `list`, such as cycles,instructions (Linux only)")
+	flagThrottle  = flag.String("throttle", "off", "`policy` for runs during which the CPUs were throttled: off, annotate, or discard (Linux only)")
+	flagMaxTemp   = flag.Float64("max-temp", 0, "count the CPUs as throttled at `°C` and above")
+)
+
+// counterNames lists the hardware events to count, from -counters.
+var counterNames []string
+
+// mon watches the CPUs during each run, if -throttle asks to.
+var mon *monitor
+
+// shuffle randomizes the order of runs, if -shuffle asks to.
+var shuffle *rand.Rand
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: benchrun [options] old new\n")
+	fmt.Fprintf(os.Stderr, "options:\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	log.SetPrefix("benchrun: ")
+	log.SetFlags(0)
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+	}
+	if *flagCount < 1 {
+		log.Fatalf("invalid -count %d", *flagCount)
+	}
+	if *flagAlpha <= 0 || *flagAlpha > 1 {
+		log.Fatalf("invalid -alpha %g", *flagAlpha)
+	}
+	if *flagRerun < 0 {
+		log.Fatalf("invalid -rerun %d", *flagRerun)
+	}
+	if *flagWarmup < 0 {
+		log.Fatalf("invalid -warmup %d", *flagWarmup)
+	}
+	var precision float64
+	if *flagPrecision != "" {
+		var err error
+		if precision, err = parsePrecision(*flagPrecision); err != nil {
+			log.Fatal(err)
+		}
+	}
+	cpus, err := parseCPUs(*flagCPUs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	counterNames, err = parseCounters(*flagCounters)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch *flagThrottle {
+	case "off":
+	case "annotate", "discard":
+		mon = newMonitor("/sys", cpus, 100*time.Millisecond, *flagMaxTemp)
+	default:
+		log.Fatalf("invalid -throttle %q; want off, annotate, or discard", *flagThrottle)
+	}
+	iso := &isolation{
+		cpus:       cpus,
+		governor:   *flagGovernor,
+		noTurbo:    *flagNoTurbo,
+		cpuQuota:   *flagCPUQuota,
+		image:      *flagImage,
+		runtime:    *flagRuntime,
+		cgroupRoot: "/sys/fs/cgroup",
+	}
+	if *flagMemory != "" {
+		if iso.memory, err = parseBytes(*flagMemory); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if iso.cpuQuota < 0 {
+		log.Fatalf("invalid -cpu-quota %g", iso.cpuQuota)
+	}
+	if iso.image != "" && len(counterNames) > 0 {
+		log.Fatal("cannot count hardware events in a container")
+	}
+
+	tmp, err := ioutil.TempDir("", "benchrun")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var cleanups []func()
+	cleanup := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+		os.RemoveAll(tmp)
+	}
+	versions := make([]*version, 2)
+	for i, name := range []string{"old", "new"} {
+		v, done, err := prepare(name, flag.Arg(i), tmp)
+		if done != nil {
+			cleanups = append(cleanups, done)
+		}
+		if err != nil {
+			cleanup()
+			log.Fatal(err)
+		}
+		versions[i] = v
+	}
+
+	// Restore the machine's settings even if interrupted.
+	if err := iso.setup(); err != nil {
+		iso.restore()
+		cleanup()
+		log.Fatal(err)
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		iso.restore()
+		cleanup()
+		os.Exit(1)
+	}()
+	fingerprint := machine.Detect().Labels()
+	for _, v := range versions {
+		v.results.Write(fingerprint)
+		v.results.Write(iso.labels())
+	}
+	if *flagShuffle {
+		seed := *flagSeed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		log.Printf("shuffling with -seed %d", seed)
+		shuffle = rand.New(rand.NewSource(seed))
+		for _, v := range versions {
+			fmt.Fprintf(&v.results, "shuffle-seed: %d\n", seed)
+		}
+	}
+
+	if precision == 0 {
+		err = runInterleaved(versions, *flagWarmup, *flagCount, iso)
+	} else {
+		err = runAdaptive(versions, *flagWarmup, *flagCount, precision, *flagBudget, iso)
+	}
+	if err == nil && *flagRerun > 0 {
+		err = rerun(versions, *flagRerun, *flagAlpha, iso)
+	}
+	signal.Stop(interrupt)
+	if rerr := iso.restore(); rerr != nil {
+		log.Printf("restoring settings: %v", rerr)
+	}
+	cleanup()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *flagOut != "" {
+		if err := os.MkdirAll(*flagOut, 0777); err != nil {
+			log.Fatal(err)
+		}
+		for _, v := range versions {
+			if err := ioutil.WriteFile(filepath.Join(*flagOut, v.name+".txt"), v.results.Bytes(), 0666); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	benchstat.FormatText(&buf, compare(versions, *flagAlpha))
+	os.Stdout.Write(buf.Bytes())
+}
+
+// A version is one of the versions of the benchmarks compared.
+type version struct {
+	name    string       // old or new
+	bin     string       // test binary
+	dir     string       // directory to run the binary in
+	results bytes.Buffer // configuration lines and results of the runs
+
+	benchmarks []string // top-level benchmarks, once listed
+}
+
+// benchArgs returns the arguments to run the benchmarks of a test
+// binary matching pattern once.
+func benchArgs(pattern string) []string {
+	args := []string{"-test.run=^$", "-test.bench=" + pattern, "-test.count=1"}
+	if *flagBenchtime != "" {
+		args = append(args, "-test.benchtime="+*flagBenchtime)
+	}
+	if *flagBenchmem {
+		args = append(args, "-test.benchmem")
+	}
+	return args
+}
+
+// runInterleaved runs the benchmarks of versions warmup+count times
+// each, running every version once in each round, isolated by iso, and
+// discards the results of the first warmup rounds. If shuffling, each
+// round runs the benchmarks one by one, as runRound does.
+func runInterleaved(versions []*version, warmup, count int, iso *isolation) error {
+	if shuffle != nil {
+		names, err := allBenchmarks(versions)
+		if err != nil {
+			return err
+		}
+		lens := make([]int, len(versions))
+		for i := 0; i < warmup+count; i++ {
+			if *flagVerbose {
+				log.Printf("round %d", i+1)
+			}
+			for j, v := range versions {
+				lens[j] = v.results.Len()
+			}
+			if err := runRound(versions, names, iso); err != nil {
+				return err
+			}
+			if i < warmup {
+				for j, v := range versions {
+					v.results.Truncate(lens[j])
+				}
+			}
+		}
+		return nil
+	}
+	for i := 0; i < warmup+count; i++ {
+		for _, v := range versions {
+			if *flagVerbose {
+				if i < warmup {
+					log.Printf("warmup run %d of %s", i+1, v.name)
+				} else {
+					log.Printf("run %d of %s", i-warmup+1, v.name)
+				}
+			}
+			n := v.results.Len()
+			if err := v.run(iso); err != nil {
+				return err
+			}
+			if i < warmup {
+				v.results.Truncate(n)
+			}
+		}
+	}
+	return nil
+}
+
+// run runs the benchmarks of v once, isolated by iso, and adds their
+// results to v.results. If counting hardware events, it runs each
+// benchmark in a process of its own, to tell their counts apart.
+func (v *version) run(iso *isolation) error {
+	if len(counterNames) == 0 {
+		return v.runPattern(iso, *flagBench)
+	}
+	names, err := v.benchmarkNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := v.runPattern(iso, benchPattern(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// benchmarkNames returns the top-level benchmarks of v that match
+// -bench, listing them the first time.
+func (v *version) benchmarkNames() ([]string, error) {
+	if v.benchmarks == nil {
+		names, err := v.listBenchmarks()
+		if err != nil {
+			return nil, err
+		}
+		v.benchmarks = names
+	}
+	return v.benchmarks, nil
+}
+
+// runPattern runs the benchmarks of v matching pattern once, isolated
+// by iso, and adds their results to v.results.
+func (v *version) runPattern(iso *isolation, pattern string) error {
+	cmd, err := iso.command(v.bin, v.dir, benchArgs(pattern))
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = os.Stderr
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	var stop func() *watch
+	if mon != nil {
+		stop = mon.start()
+	}
+	cs, err := iso.start(cmd, counterNames)
+	if err == nil {
+		err = cmd.Wait()
+	}
+	var w *watch
+	if stop != nil {
+		w = stop()
+	}
+	if err != nil {
+		closeCounters(cs)
+		return fmt.Errorf("%s: %v\n%s", v.name, err, out.Bytes())
+	}
+
+	results := out.Bytes()
+	if len(cs) > 0 {
+		counts := make([]float64, len(cs))
+		for i, c := range cs {
+			counts[i], err = c.read()
+			if err != nil {
+				break
+			}
+		}
+		closeCounters(cs)
+		if err != nil {
+			return err
+		}
+		results = addCounts(results, counterNames, counts)
+	}
+	if w != nil {
+		if w.Throttled && *flagThrottle == "discard" {
+			log.Printf("%s: discarding run of %s: CPUs throttled", v.name, pattern)
+			return nil
+		}
+		v.results.Write(w.labels())
+	}
+	v.results.Write(results)
+	return nil
+}
+
+// prepare returns the version named name for the test binary or git
+// revision arg, building the binary in tmp if arg is a revision. If it
+// returns a function, it must be called when done with the version,
+// even if prepare also returns an error.
+func prepare(name, arg, tmp string) (*version, func(), error) {
+	if fi, err := os.Stat(arg); err == nil && fi.Mode().IsRegular() {
+		bin, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &version{name: name, bin: bin, dir: "."}, nil, nil
+	}
+
+	hash, err := git(*flagRepo, "rev-parse", "--verify", "--end-of-options", arg+"^{commit}")
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s is neither a file nor a revision: %v", arg, err)
+	}
+	tree := filepath.Join(tmp, name)
+	if _, err := git(*flagRepo, "worktree", "add", "--detach", tree, hash); err != nil {
+		return nil, nil, err
+	}
+	done := func() {
+		git(*flagRepo, "worktree", "remove", "--force", tree)
+	}
+	v := &version{name: name, bin: filepath.Join(tmp, name+".test"), dir: filepath.Join(tree, *flagPkg)}
+	if *flagVerbose {
+		log.Printf("building %s at %.12s", name, hash)
+	}
+	cmd := exec.Command("go", "test", "-c", "-o", v.bin, ".")
+	cmd.Dir = v.dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, done, fmt.Errorf("building %s: %v\n%s", arg, err, out)
+	}
+	fmt.Fprintf(&v.results, "commit: %s\n", hash)
+	cmd = exec.Command("go", "version")
+	cmd.Dir = v.dir
+	if out, err := cmd.Output(); err == nil {
+		if goVersion := benchstat.ParseGoVersion(string(out)); goVersion != "" {
+			fmt.Fprintf(&v.results, "go: %s\n", goVersion)
+		}
+	}
+	return v, done, nil
+}
+
+// git runs git with args in dir and returns its output, trimmed.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v\n%s", args[0], err, stderr.Bytes())
+	}
+	return string(bytes.TrimSpace(out)), nil
+}
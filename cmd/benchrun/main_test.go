@@ -0,0 +1,469 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterleaved(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test binaries are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "benchrun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each fake test binary logs its run and prints one result.
+	logFile := filepath.Join(dir, "log")
+	var versions []*version
+	for i, name := range []string{"old", "new"} {
+		bin := filepath.Join(dir, name+".test")
+		script := "#!/bin/sh\necho " + name + " >>" + logFile + "\necho 'BenchmarkX 1 " + strconv.Itoa(100*(i+1)) + " ns/op'\n"
+		if err := ioutil.WriteFile(bin, []byte(script), 0777); err != nil {
+			t.Fatal(err)
+		}
+		v, done, err := prepare(name, bin, dir)
+		if done != nil || err != nil {
+			t.Fatalf("prepare(%s) = _, %v, %v, want binary", bin, done != nil, err)
+		}
+		versions = append(versions, v)
+	}
+
+	if err := runInterleaved(versions, 1, 3, &isolation{}); err != nil {
+		t.Fatal(err)
+	}
+	log, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Fields(string(log)), "old new old new old new old new"; strings.Join(got, " ") != want {
+		t.Errorf("runs = %v, want %s", got, want)
+	}
+	if got, want := strings.Count(versions[1].results.String(), "BenchmarkX 1 200 ns/op\n"), 3; got != want {
+		t.Errorf("new has %d results, want %d:\n%s", got, want, versions[1].results.String())
+	}
+}
+
+func TestParseCPUs(t *testing.T) {
+	for _, tt := range []struct {
+		list string
+		want []int
+	}{
+		{"", nil},
+		{"3", []int{3}},
+		{"0,2-4", []int{0, 2, 3, 4}},
+		{"2-3,3,1", []int{2, 3, 1}},
+	} {
+		got, err := parseCPUs(tt.list)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCPUs(%q) = %v, %v, want %v", tt.list, got, err, tt.want)
+		}
+	}
+	for _, list := range []string{"a", "3-1", "-1", "1,", "1-"} {
+		if got, err := parseCPUs(list); err == nil {
+			t.Errorf("parseCPUs(%q) = %v, want error", list, got)
+		}
+	}
+
+	iso := &isolation{cpus: []int{2, 3}, governor: "performance", noTurbo: true}
+	if got, want := string(iso.labels()), "cpus: 2,3\ngovernor: performance\nturbo: off\n"; got != want {
+		t.Errorf("labels() = %q, want %q", got, want)
+	}
+}
+
+func TestPinned(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("pinning is only implemented on Linux")
+	}
+	iso := &isolation{cpus: []int{0}}
+	cmd := exec.Command("grep", "Cpus_allowed_list", "/proc/self/status")
+	out := new(strings.Builder)
+	cmd.Stdout = out
+	if _, err := iso.start(cmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(out.String()); len(got) != 2 || got[1] != "0" {
+		t.Errorf("pinned to CPU 0, got %q", out.String())
+	}
+}
+
+func TestAddCounts(t *testing.T) {
+	names := []string{"cycles", "instructions"}
+	counts := []float64{3000, 5000}
+	out := "goos: linux\nBenchmarkX-8   \t    1000\t      1.50 ns/op\nPASS\n"
+	want := "goos: linux\nBenchmarkX-8   \t    1000\t      1.50 ns/op\t3.00 cycles/op\t5.00 instructions/op\nPASS\n"
+	if got := string(addCounts([]byte(out), names, counts)); got != want {
+		t.Errorf("addCounts(%q) = %q, want %q", out, got, want)
+	}
+
+	// The counts of sub-benchmarks cannot be told apart.
+	out = "BenchmarkX/a-8 1000 1.50 ns/op\nBenchmarkX/b-8 1000 2.50 ns/op\n"
+	if got := string(addCounts([]byte(out), names, counts)); got != out {
+		t.Errorf("addCounts(%q) = %q, want it unchanged", out, got)
+	}
+}
+
+func TestCounters(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("counters are only implemented on Linux")
+	}
+	iso := &isolation{}
+	cmd := exec.Command("true")
+	cs, err := iso.start(cmd, []string{"instructions"})
+	if err != nil {
+		// Virtual machines and containers often allow no counters.
+		t.Skip(err)
+	}
+	defer closeCounters(cs)
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := cs[0].read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Errorf("counted no instructions")
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	root, err := ioutil.TempDir("", "benchrun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	write := func(file, value string) {
+		file = filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(value+"\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, cpu := range []string{"cpu0", "cpu1"} {
+		write("devices/system/cpu/"+cpu+"/cpufreq/scaling_cur_freq", "3000000")
+		write("devices/system/cpu/"+cpu+"/thermal_throttle/core_throttle_count", "7")
+	}
+	write("class/thermal/thermal_zone0/temp", "55000")
+
+	m := newMonitor(root, []int{1}, time.Hour, 0)
+	w := m.start()()
+	if want := (watch{MHz: 3000, Temp: 55}); *w != want {
+		t.Errorf("quiet run: got %+v, want %+v", *w, want)
+	}
+
+	stop := m.start()
+	write("devices/system/cpu/cpu0/thermal_throttle/core_throttle_count", "8")
+	if w := stop(); w.Throttled {
+		t.Errorf("throttling of an unwatched CPU counted")
+	}
+	stop = m.start()
+	write("devices/system/cpu/cpu1/thermal_throttle/core_throttle_count", "8")
+	if w := stop(); !w.Throttled {
+		t.Errorf("throttling not counted")
+	}
+
+	m = newMonitor(root, nil, time.Hour, 50)
+	if w := m.start()(); !w.Throttled {
+		t.Errorf("temperature above -max-temp not counted as throttling")
+	}
+	if got, want := string(w.labels()), "cpu-mhz: 3000\ncpu-temp: 55\nthrottled: false\n"; got != want {
+		t.Errorf("labels() = %q, want %q", got, want)
+	}
+}
+
+func TestAdaptive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test binaries are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "benchrun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Old is steady, and new alternates between 100 and 110 ns/op,
+	// so only new needs more runs to reach the precision.
+	logFile := filepath.Join(dir, "log")
+	scripts := map[string]string{
+		"old": "echo 'BenchmarkX-8 1 100 ns/op'",
+		"new": "n=$(wc -l <" + logFile + "); echo \"BenchmarkX-8 1 $((100 + n / 2 % 2 * 10)) ns/op\"",
+	}
+	var versions []*version
+	for _, name := range []string{"old", "new"} {
+		bin := filepath.Join(dir, name+".test")
+		script := "#!/bin/sh\necho " + name + " >>" + logFile + "\n" + scripts[name] + "\n"
+		if err := ioutil.WriteFile(bin, []byte(script), 0777); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, &version{name: name, bin: bin, dir: dir})
+	}
+
+	if err := runAdaptive(versions, 0, 3, 0.05, 0, &isolation{}); err != nil {
+		t.Fatal(err)
+	}
+	values := versions[1].times().values["BenchmarkX-8"]
+	if len(values) <= 3 {
+		t.Errorf("new ran %d times, want more than 3", len(values))
+	}
+	if w := relativeWidth(values); w > 0.05 {
+		t.Errorf("new has relative width %.3f, want at most 0.05", w)
+	}
+	if got := imprecise(versions, 0.05); len(got) != 0 {
+		t.Errorf("imprecise = %v, want none", got)
+	}
+	if got := imprecise(versions, 0.001); !reflect.DeepEqual(got, []string{"BenchmarkX"}) {
+		t.Errorf("imprecise at ±0.1%% = %v, want [BenchmarkX]", got)
+	}
+}
+
+func TestParsePrecision(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want float64
+	}{
+		{"1%", 0.01},
+		{"0.5%", 0.005},
+		{"0.02", 0.02},
+	} {
+		if got, err := parsePrecision(tt.s); err != nil || math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("parsePrecision(%q) = %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+	for _, s := range []string{"", "x", "0%", "100%", "-1%"} {
+		if got, err := parsePrecision(s); err == nil {
+			t.Errorf("parsePrecision(%q) = %v, want error", s, got)
+		}
+	}
+	for name, want := range map[string]string{
+		"BenchmarkX-8":     "BenchmarkX",
+		"BenchmarkX":       "BenchmarkX",
+		"BenchmarkX/a-b-8": "BenchmarkX",
+	} {
+		if got := topLevel(name); got != want {
+			t.Errorf("topLevel(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRerun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test binaries are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "benchrun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each version's times vary a little from run to run, and new is
+	// clearly slower.
+	var versions []*version
+	for _, name := range []string{"old", "new"} {
+		bin := filepath.Join(dir, name+".test")
+		logFile := filepath.Join(dir, name+".log")
+		base := map[string]string{"old": "100", "new": "120"}[name]
+		script := "#!/bin/sh\necho >>" + logFile + "\nn=$(wc -l <" + logFile + ")\necho \"BenchmarkX-8 1 $((" + base + " + n % 3)) ns/op\"\n"
+		if err := ioutil.WriteFile(bin, []byte(script), 0777); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, &version{name: name, bin: bin, dir: dir})
+	}
+	if err := runInterleaved(versions, 0, 3, &isolation{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// With 3 runs each, no change can be significant; with 4, the
+	// smallest p-value, 0.029, is within a factor of two of 0.05;
+	// with 5, it is 0.008, and the change is clear.
+	if got := borderline(compare(versions, 0.05), 0.05); !reflect.DeepEqual(got, []string{"BenchmarkX"}) {
+		t.Fatalf("borderline = %v, want [BenchmarkX]", got)
+	}
+	if err := rerun(versions, 10, 0.05, &isolation{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range versions {
+		if n := len(v.times().values["BenchmarkX-8"]); n != 5 {
+			t.Errorf("%s ran %d times, want 5", v.name, n)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	for s, want := range map[string]int64{"1000": 1000, "512K": 512 << 10, "2m": 2 << 20, "3G": 3 << 30} {
+		if got, err := parseBytes(s); err != nil || got != want {
+			t.Errorf("parseBytes(%q) = %d, %v, want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "G", "-1M", "1T", "1.5G"} {
+		if got, err := parseBytes(s); err == nil {
+			t.Errorf("parseBytes(%q) = %d, want error", s, got)
+		}
+	}
+}
+
+func TestContainerCommand(t *testing.T) {
+	iso := &isolation{cpus: []int{2, 3}, memory: 1 << 30, cpuQuota: 1.5, image: "debian:9", runtime: "podman"}
+	cmd, err := iso.command("/tmp/new.test", "/src/pkg", []string{"-test.bench=."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"podman", "run", "--rm", "--network=none",
+		"-v", "/tmp/new.test:/benchrun/bench.test:ro",
+		"-v", "/src/pkg:/benchrun/src",
+		"-w", "/benchrun/src",
+		"--memory=1073741824", "--cpus=1.5", "--cpuset-cpus=2,3",
+		"debian:9", "/benchrun/bench.test", "-test.bench=."}
+	if !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("command:\nhave %q\nwant %q", cmd.Args, want)
+	}
+	if got, want := string(iso.labels()), "cpus: 2,3\nmemory-limit: 1073741824\ncpu-quota: 1.5\nimage: debian:9\n"; got != want {
+		t.Errorf("labels() = %q, want %q", got, want)
+	}
+}
+
+func TestCgroup(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("cgroups are only implemented on Linux")
+	}
+	// Stand in for the cgroup hierarchy with a directory, in which the
+	// files of the cgroup are plain files.
+	root, err := ioutil.TempDir("", "benchrun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	iso := &isolation{memory: 64 << 20, cpuQuota: 0.5, cgroupRoot: root}
+	if err := iso.setup(); err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{"memory.max": "67108864", "cpu.max": "50000 100000"} {
+		data, err := ioutil.ReadFile(filepath.Join(iso.cgroup, file))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", file, data, err, want)
+		}
+	}
+
+	cmd, err := iso.command("/bin/echo", root, []string{"hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.Output()
+	if err != nil || string(out) != "hello\n" {
+		t.Errorf("command output = %q, %v, want %q", out, err, "hello\n")
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(iso.cgroup, "cgroup.procs")); len(strings.TrimSpace(string(data))) == 0 {
+		t.Errorf("command did not join the cgroup")
+	}
+
+	// A real cgroup can be removed once empty; this one must be
+	// emptied by hand.
+	for _, file := range []string{"memory.max", "memory.swap.max", "cpu.max", "cgroup.procs"} {
+		os.Remove(filepath.Join(iso.cgroup, file))
+	}
+	if err := iso.restore(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(iso.cgroup); !os.IsNotExist(err) {
+		t.Errorf("cgroup not removed: %v", err)
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test binaries are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "benchrun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() { shuffle = nil }()
+
+	// Each fake test binary lists three benchmarks and logs the
+	// version and -test.bench pattern of each run.
+	logFile := filepath.Join(dir, "log")
+	var versions []*version
+	for _, name := range []string{"old", "new"} {
+		bin := filepath.Join(dir, name+".test")
+		script := `#!/bin/sh
+case "$1" in
+-test.list=*) printf 'BenchmarkA\nBenchmarkB\nBenchmarkC\n'; exit;;
+esac
+echo ` + name + ` "$2" >>` + logFile + `
+echo 'BenchmarkA 1 100 ns/op'
+`
+		if err := ioutil.WriteFile(bin, []byte(script), 0777); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, &version{name: name, bin: bin, dir: dir})
+	}
+	runs := func(seed int64) []string {
+		os.Remove(logFile)
+		for _, v := range versions {
+			v.results.Reset()
+		}
+		shuffle = rand.New(rand.NewSource(seed))
+		if err := runInterleaved(versions, 0, 4, &isolation{}); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(logFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+
+	order := runs(1)
+	if len(order) != 4*3*2 {
+		t.Fatalf("ran %d times, want %d:\n%s", len(order), 4*3*2, strings.Join(order, "\n"))
+	}
+	shuffled := false
+	for round := 0; round < 4; round++ {
+		seen := make(map[string]bool)
+		for i := 0; i < 3; i++ {
+			a, b := order[round*6+2*i], order[round*6+2*i+1]
+			pa, pb := strings.Fields(a)[1], strings.Fields(b)[1]
+			if pa != pb || a == b {
+				t.Errorf("round %d: versions of a benchmark not run together: %q, %q", round, a, b)
+			}
+			seen[pa] = true
+			if want := "-test.bench=^Benchmark" + "ABC"[i:i+1] + "$"; pa != want {
+				shuffled = true
+			}
+			if strings.HasPrefix(a, "new") {
+				shuffled = true
+			}
+		}
+		if len(seen) != 3 {
+			t.Errorf("round %d ran %d benchmarks, want 3", round, len(seen))
+		}
+	}
+	if !shuffled {
+		t.Errorf("runs not shuffled:\n%s", strings.Join(order, "\n"))
+	}
+	if again := runs(1); !reflect.DeepEqual(again, order) {
+		t.Errorf("same seed, different order:\n%s\nthen\n%s", strings.Join(order, "\n"), strings.Join(again, "\n"))
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A monitor watches the frequency and temperature of the CPUs, as
+// Linux reports them in sysfs, while the benchmarks run.
+type monitor struct {
+	interval time.Duration // time between samples
+	maxTemp  float64       // temperature at which the CPUs count as throttled, if not zero
+
+	freqFiles     []string // current frequency of each CPU watched, in kHz
+	tempFiles     []string // temperature of each thermal zone, in m°C
+	throttleFiles []string // number of times each CPU watched was throttled
+}
+
+// newMonitor returns a monitor of the CPUs, or of all CPUs if cpus is
+// empty, that finds their files under the sysfs root.
+func newMonitor(root string, cpus []int, interval time.Duration, maxTemp float64) *monitor {
+	m := &monitor{interval: interval, maxTemp: maxTemp}
+	dirs := []string{"cpu[0-9]*"}
+	if len(cpus) > 0 {
+		dirs = nil
+		for _, c := range cpus {
+			dirs = append(dirs, fmt.Sprintf("cpu%d", c))
+		}
+	}
+	for _, dir := range dirs {
+		cpu := filepath.Join(root, "devices/system/cpu", dir)
+		files, _ := filepath.Glob(filepath.Join(cpu, "cpufreq/scaling_cur_freq"))
+		m.freqFiles = append(m.freqFiles, files...)
+		files, _ = filepath.Glob(filepath.Join(cpu, "thermal_throttle/*_throttle_count"))
+		m.throttleFiles = append(m.throttleFiles, files...)
+	}
+	m.tempFiles, _ = filepath.Glob(filepath.Join(root, "class/thermal/thermal_zone*/temp"))
+	return m
+}
+
+// A watch is what a monitor saw during one run.
+type watch struct {
+	MHz       float64 // mean frequency of the CPUs, or 0 if unknown
+	Temp      float64 // highest temperature, in °C, or 0 if unknown
+	Throttled bool    // whether the CPUs were throttled
+}
+
+// labels returns configuration lines recording w.
+func (w *watch) labels() []byte {
+	var buf bytes.Buffer
+	if w.MHz != 0 {
+		fmt.Fprintf(&buf, "cpu-mhz: %.0f\n", w.MHz)
+	}
+	if w.Temp != 0 {
+		fmt.Fprintf(&buf, "cpu-temp: %.0f\n", w.Temp)
+	}
+	fmt.Fprintf(&buf, "throttled: %v\n", w.Throttled)
+	return buf.Bytes()
+}
+
+// start starts sampling, until the returned function is called, which
+// returns what the monitor saw.
+func (m *monitor) start() func() *watch {
+	throttles := sumFiles(m.throttleFiles)
+	var mhz []float64
+	var temp float64
+	sample := func() {
+		if len(m.freqFiles) > 0 {
+			mhz = append(mhz, sumFiles(m.freqFiles)/float64(len(m.freqFiles))/1000)
+		}
+		for _, file := range m.tempFiles {
+			if t := readNumber(file) / 1000; t > temp {
+				temp = t
+			}
+		}
+	}
+	sample()
+	stop, done := make(chan bool), make(chan bool)
+	go func() {
+		t := time.NewTicker(m.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				sample()
+			case <-stop:
+				sample()
+				close(done)
+				return
+			}
+		}
+	}()
+	return func() *watch {
+		close(stop)
+		<-done
+		w := &watch{Temp: temp}
+		for _, f := range mhz {
+			w.MHz += f / float64(len(mhz))
+		}
+		w.Throttled = sumFiles(m.throttleFiles) > throttles || m.maxTemp != 0 && temp >= m.maxTemp
+		return w
+	}
+}
+
+// sumFiles returns the sum of the numbers in files, ignoring those
+// that cannot be read.
+func sumFiles(files []string) float64 {
+	var sum float64
+	for _, file := range files {
+		sum += readNumber(file)
+	}
+	return sum
+}
+
+// readNumber returns the number in file, or 0 if it cannot be read.
+func readNumber(file string) float64 {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0
+	}
+	x, _ := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	return x
+}
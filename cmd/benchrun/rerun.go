@@ -0,0 +1,64 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+)
+
+// compare returns the tables comparing the results of versions, with
+// changes significant at level alpha.
+func compare(versions []*version, alpha float64) []*benchstat.Table {
+	c := &benchstat.Collection{Alpha: alpha}
+	for _, v := range versions {
+		c.AddConfig(v.name, v.results.Bytes())
+	}
+	return c.Tables()
+}
+
+// borderline returns the top-level benchmarks with a result whose
+// comparison is inconclusive at level alpha: its p-value is within a
+// factor of two of alpha, on either side, or its samples are too small
+// for any change to be significant.
+func borderline(tables []*benchstat.Table, alpha float64) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, t := range tables {
+		for _, row := range t.Rows {
+			near := row.PValue >= alpha/2 && row.PValue < alpha*2
+			if !near && !row.Underpowered {
+				continue
+			}
+			top := topLevel("Benchmark" + row.Benchmark)
+			if !seen[top] {
+				seen[top] = true
+				names = append(names, top)
+			}
+		}
+	}
+	return names
+}
+
+// rerun runs the borderline benchmarks of versions again, once per
+// round, interleaved, and compares the results again after each round,
+// for at most max rounds or until none is borderline.
+func rerun(versions []*version, max int, alpha float64, iso *isolation) error {
+	for i := 0; i < max; i++ {
+		names := borderline(compare(versions, alpha), alpha)
+		if len(names) == 0 {
+			return nil
+		}
+		if *flagVerbose {
+			log.Printf("rerun %d of %s", i+1, strings.Join(names, ", "))
+		}
+		if err := runRound(versions, names, iso); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// runRound runs each of the named top-level benchmarks once for each
+// version, running the versions of a benchmark one after the other.
+// If shuffling, it runs the benchmarks, and the versions of each, in a
+// random order.
+func runRound(versions []*version, names []string, iso *isolation) error {
+	if shuffle != nil {
+		names = append([]string(nil), names...)
+		shuffle.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+	}
+	for _, name := range names {
+		order := versions
+		if shuffle != nil {
+			order = append([]*version(nil), versions...)
+			shuffle.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		}
+		for _, v := range order {
+			if err := v.runPattern(iso, benchPattern(name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// allBenchmarks returns the top-level benchmarks of any of versions
+// that match -bench, in the order first listed.
+func allBenchmarks(versions []*version) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, v := range versions {
+		list, err := v.benchmarkNames()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range list {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
@@ -6,13 +6,24 @@
 //
 // Usage:
 //
-//	benchsave [-v] [-header file] [-server url] file...
+//	benchsave [-v] [-header file] [-machine] [-server url] file...
 //
 // Each input file should contain the output from one or more runs of
 // ``go test -bench'', or another tool which uses the same format.
 //
 // Benchsave will upload the input files to the specified server and
 // print a URL where they can be viewed.
+//
+// The -machine option inserts configuration lines describing the machine
+// benchsave runs on, such as "machine-cpu: ...", at the beginning of each
+// uploaded file, after any header, so that results from different machines
+// can be told apart. Use it only when uploading results measured on the
+// same machine.
+//
+// The -label option, which may be repeated, inserts a configuration line
+// key: value for each label key=value given, such as -label branch=feature-x,
+// after the description of the machine, so that the uploaded results can be
+// searched for, split, and filtered by it.
 package main
 
 import (
@@ -27,18 +38,48 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
+	"golang.org/x/perf/benchstat"
+	"golang.org/x/perf/internal/machine"
 	"golang.org/x/perf/storage"
+	"golang.org/x/perf/storage/benchfmt"
 )
 
 var (
 	server  = flag.String("server", "https://perfdata.golang.org", "upload benchmarks to server at `url`")
 	verbose = flag.Bool("v", false, "print verbose log messages")
 	header  = flag.String("header", "", "insert `file` at the beginning of each uploaded file")
+	mach    = flag.Bool("machine", false, "insert a description of this machine at the beginning of each uploaded file")
+	labels  = make(labelFlag)
 )
 
+func init() {
+	flag.Var(labels, "label", "insert the configuration line `key=value` at the beginning of each uploaded file (may be repeated)")
+}
+
+// labelFlag is a flag.Value collecting the -label flags.
+type labelFlag benchfmt.Labels
+
+func (l labelFlag) String() string {
+	var s []string
+	for _, k := range benchfmt.Labels(l).Keys() {
+		s = append(s, k+"="+l[k])
+	}
+	return strings.Join(s, ",")
+}
+
+func (l labelFlag) Set(s string) error {
+	key, value, err := benchstat.ParseLabel(s)
+	if err != nil {
+		return err
+	}
+	l[key] = value
+	return nil
+}
+
 const userAgent = "Benchsave/1.0"
 
 // writeOneFile reads name and writes it to u.
@@ -92,6 +133,16 @@ func main() {
 		}
 		headerData = append(bytes.TrimRight(headerData, "\n"), '\n', '\n')
 	}
+	if *mach {
+		headerData = append(headerData, machine.Detect().Labels()...)
+		headerData = append(headerData, '\n')
+	}
+	if len(labels) > 0 {
+		for _, key := range benchfmt.Labels(labels).Keys() {
+			headerData = append(headerData, fmt.Sprintf("%s: %s\n", key, labels[key])...)
+		}
+		headerData = append(headerData, '\n')
+	}
 
 	// TODO(quentin): Some servers might not need authentication.
 	// We should somehow detect this and not force the user to get a token.
@@ -0,0 +1,537 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Benchseries assembles benchmark results measured at many commits of
+// a repository into a series for each benchmark, for trend analysis.
+//
+// Usage:
+//
+//	benchseries [-repo dir] [-key name] [-split keys] [-confidence level] [-range revs] [-tags patterns] [-relative rev] [-window k] [-gaps policy] [-output format] file...
+//
+// Each input file should contain the output from one or more runs of
+// "go test -bench", or another tool which uses the same format, with
+// a configuration line naming the commit measured, as in
+//
+//	commit: 1b0a2e0a5b55
+//
+// before the results. The -key option names a different configuration
+// key. The commits may be abbreviated, and results for a commit may be
+// spread over many files.
+//
+// Benchseries orders the commits by the history of the git repository
+// in the directory given by -repo, the current directory by default,
+// with every commit after its parents, rather than by date. It then
+// prints, for each benchmark and unit, the median of the values at each
+// commit, its change from the previous commit, and a confidence interval
+// for the median:
+//
+//	Encode ns/op
+//	commit        date        n  median  delta    95% CI
+//	1b0a2e0a5b55  2019-03-01  5  12.1µs           [12.0µs, 12.3µs]
+//	9c41f3a2e7d0  2019-03-02  5  12.0µs  -0.83%   [11.9µs, 12.1µs]
+//	e28d5c1f3b4a  2019-03-04  5  13.4µs  +11.67%  [13.1µs, 13.6µs]
+//
+// The intervals are computed by bootstrapping: taking the medians of
+// many resamples of the values, drawn with replacement. The -confidence
+// option sets their confidence level, 0.95 by default.
+//
+// The -range option limits the series to the commits in a range of
+// history, given as git revisions separated by spaces, as in "git log":
+// "go1.11..go1.12" names the commits reachable from go1.12 but not
+// from go1.11, and so "go1.11~1..go1.12" includes go1.11 as well.
+// The -tags option limits the series to the commits with tags matching
+// the given patterns, separated by commas, and labels them with their
+// tags, so that
+//
+//	benchseries -tags 'go1.*' results/*.txt
+//
+// reports the performance of each release.
+//
+// The -overlay option compares the series on two branches, such as
+// master and a release branch, for tracking whether backports keep
+// their performance on par. For each benchmark it prints the last point
+// on each branch, the change from the first branch to the second, the
+// last point measured on both branches, before they forked, and, if
+// they have diverged, the first point on the second branch since which
+// its confidence intervals have not overlapped those of the points on
+// the first branch committed at the same time:
+//
+//	$ benchseries -overlay master,release-branch.go1.12 results/*.txt
+//	Encode ns/op
+//	branch                 commit        date        median  delta    95% CI
+//	master                 e28d5c1f3b4a  2019-03-04  12.0µs           [11.9µs, 12.1µs]
+//	release-branch.go1.12  5f0e1d2c3b4a  2019-03-05  13.4µs  +11.67%  [13.3µs, 13.5µs]
+//	forked at 1b0a2e0a5b55 (2019-03-01)
+//	diverged since 7a6b5c4d3e2f (2019-03-03)
+//
+// The -relative option reports every point relative to the point at a
+// baseline revision, such as the tag of the latest release, in a column
+// of its own, and ends with a summary of the drift since the baseline of
+// each family of benchmarks, such as Encode/small and Encode/large: the
+// geometric mean of the changes of the last points from the baseline,
+// and the range of those changes. Benchmarks not measured at the baseline
+// are left out of the summary.
+//
+//	$ benchseries -relative go1.12 results/*.txt
+//	...
+//	drift from go1.12
+//	family  unit   benchmarks  drift   range
+//	Encode  ns/op  2           +3.12%  [+1.05%, +5.23%]
+//	Decode  ns/op  3           -0.41%  [-2.17%, +0.96%]
+//
+// The -window option tests whether the last k commits differ
+// significantly from the commits before them, to catch gradual changes
+// that no comparison of one commit with the next finds significant.
+// The -window-before option limits the comparison to the n commits
+// before the window. The test is a Mann-Whitney U-test of the medians
+// of the commits, so the variation between commits, not between the runs
+// of each, decides significance; the -alpha option sets the p-value
+// below which a change is significant, 0.05 by default:
+//
+//	$ benchseries -window 5 -window-before 20 results/*.txt
+//	...
+//	last 5 commits vs 20 before
+//	name    unit   before  recent  delta
+//	Encode  ns/op  12.0µs  12.6µs  +5.00%  (p=0.002 n=20+5)
+//	Decode  ns/op  8.10µs  8.12µs  ~       (p=0.434 n=20+5)
+//
+// A series may have no results at commits where others do, because a
+// benchmark was added later, was not run on every builder, or was run
+// less often. The -gaps option says what to do about them: skip, the
+// default, leaves those commits out of the series; show adds a row for
+// each, marked "-"; carry repeats the median before each gap; and
+// interpolate fills each gap between two results by interpolating
+// linearly, by the position of the commits in history, so that series
+// measured at different intervals can be compared commit by commit.
+// Filled medians are marked with "~" in text output, and have a count
+// of 0 in csv and json output. Series split by -split are always
+// aligned by commit, with gaps shown unless filled.
+//
+// The -output option writes the series as csv or json instead of text,
+// for dashboards and spreadsheets. The csv output has a line for each
+// point: the benchmark, the unit, the value of each -split key, and the
+// commit, its date, the median, the bounds of its confidence interval,
+// and the number of values. The json output is an array of series, each
+// with its benchmark, unit, labels, and points with the same fields.
+//
+// Results for commits not in the repository are an error.
+//
+// The -split option names configuration keys, separated by commas,
+// whose values split the results of each benchmark into separate
+// series, such as one for each builder or Go version. The series of a
+// benchmark are then aligned by commit in one table, with a column of
+// medians for each series, and the change of each series after the
+// first from the first at the same commit:
+//
+//	$ benchseries -split builder results/*.txt
+//	Encode ns/op
+//	commit        date        builder=linux-amd64  builder=linux-arm64
+//	1b0a2e0a5b55  2019-03-01  12.1µs               25.3µs (+109.09%)
+//	9c41f3a2e7d0  2019-03-02  12.0µs               -
+//	e28d5c1f3b4a  2019-03-04  13.4µs               27.9µs (+108.21%)
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/perf/benchseries"
+	"golang.org/x/perf/benchstat"
+)
+
+var (
+	flagRepo     = flag.String("repo", ".", "order commits by the history of the git repository in `dir`")
+	flagKey      = flag.String("key", "commit", "configuration `key` naming the commit of results")
+	flagSplit    = flag.String("split", "", "split series by the values of configuration `keys`, separated by commas")
+	flagConf     = flag.Float64("confidence", 0.95, "compute confidence intervals of medians at `level`")
+	flagOutput   = flag.String("output", "text", "output `format`: text, csv, or json")
+	flagRange    = flag.String("range", "", "limit series to the commits in the git `revisions`, such as go1.11..master")
+	flagTags     = flag.String("tags", "", "limit series to the commits with tags matching `patterns`, separated by commas, such as go1.*")
+	flagRelative = flag.String("relative", "", "report changes relative to the baseline `revision`, such as the latest release tag")
+	flagWindow   = flag.Int("window", 0, "test whether the last `k` commits differ significantly from the commits before them")
+	flagBefore   = flag.Int("window-before", 0, "compare the -window with the `n` commits before it, or all of them if 0")
+	flagAlpha    = flag.Float64("alpha", 0.05, "consider -window changes significant if p < `α`")
+	flagOverlay  = flag.String("overlay", "", "compare the series on two `branches`, separated by a comma, such as master,release-branch.go1.12")
+	flagGaps     = flag.String("gaps", "skip", "`policy` for commits a series has no results for: skip, show, carry, or interpolate")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: benchseries [options] file...\n")
+	fmt.Fprintf(os.Stderr, "options:\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	log.SetPrefix("benchseries: ")
+	log.SetFlags(0)
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+	}
+
+	switch *flagOutput {
+	case "text", "csv", "json":
+	default:
+		log.Fatalf("invalid -output %q: want text, csv, or json", *flagOutput)
+	}
+	policy, ok := gapPolicies[*flagGaps]
+	if !ok {
+		log.Fatalf("invalid -gaps %q: want skip, show, carry, or interpolate", *flagGaps)
+	}
+	if *flagConf <= 0 || *flagConf >= 1 {
+		log.Fatalf("invalid -confidence %g: must be between 0 and 1", *flagConf)
+	}
+	b := &benchseries.Builder{CommitKey: *flagKey, Confidence: *flagConf}
+	if *flagSplit != "" {
+		b.SplitKeys = strings.Split(*flagSplit, ",")
+	}
+	for _, file := range flag.Args() {
+		f, err := os.Open(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = b.AddFile(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %v", file, err)
+		}
+	}
+	if len(b.Commits()) == 0 {
+		log.Fatalf("no results with a %q configuration line", *flagKey)
+	}
+	if *flagOverlay != "" {
+		overlay(b)
+		return
+	}
+
+	revs := b.Commits()
+	if *flagRange != "" {
+		revs = strings.Fields(*flagRange)
+	}
+	history, err := benchseries.History(*flagRepo, revs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *flagTags != "" {
+		tags, err := benchseries.Tags(*flagRepo, strings.Split(*flagTags, ","))
+		if err != nil {
+			log.Fatal(err)
+		}
+		history = benchseries.TagCommits(history, tags)
+	}
+
+	series := b.Series(history)
+	if *flagWindow < 0 || *flagBefore < 0 {
+		log.Fatalf("invalid -window %d or -window-before %d", *flagWindow, *flagBefore)
+	}
+	if *flagWindow > 0 && *flagOutput != "text" {
+		log.Fatalf("-window supports only text output")
+	}
+	var base string
+	if *flagRelative != "" {
+		if *flagOutput != "text" {
+			log.Fatalf("-relative supports only text output")
+		}
+		if base, err = benchseries.Resolve(*flagRepo, *flagRelative); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var commits []*benchseries.Commit
+	if *flagGaps != "skip" {
+		commits = benchseries.Commits(history, series)
+	}
+	w := bufio.NewWriter(os.Stdout)
+	switch *flagOutput {
+	case "text":
+		if b.SplitKeys != nil {
+			formatAligned(w, benchseries.Align(history, series, policy), b.SplitKeys)
+		} else {
+			formatText(w, history, series, commits, policy, base)
+		}
+		if base != "" {
+			fmt.Fprintf(w, "\n")
+			formatDrifts(w, benchseries.Drifts(series, base))
+		}
+		if *flagWindow > 0 {
+			fmt.Fprintf(w, "\n")
+			formatWindows(w, series)
+		}
+	case "csv", "json":
+		if commits != nil && policy != benchseries.LeaveGaps {
+			for _, s := range series {
+				s.Points = fillPoints(history, s, commits, policy)
+			}
+		}
+	}
+	switch *flagOutput {
+	case "csv":
+		err = benchseries.WriteCSV(w, series)
+	case "json":
+		err = benchseries.WriteJSON(w, series)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// overlay prints the comparison of the series of b on the branches
+// named by -overlay.
+func overlay(b *benchseries.Builder) {
+	branches := strings.Split(*flagOverlay, ",")
+	if len(branches) != 2 {
+		log.Fatalf("invalid -overlay %q: want two branches separated by a comma", *flagOverlay)
+	}
+	if *flagOutput != "text" {
+		log.Fatalf("-overlay supports only text output")
+	}
+	var series [2][]*benchseries.Series
+	for i, branch := range branches {
+		history, err := benchseries.History(*flagRepo, []string{branch})
+		if err != nil {
+			log.Fatal(err)
+		}
+		series[i] = b.Series(history)
+	}
+	w := bufio.NewWriter(os.Stdout)
+	formatOverlays(w, benchseries.OverlayBranches(series[0], series[1]), branches)
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// formatOverlays writes each of overlays to w as a table of the last
+// point on each branch, followed by where the branches forked and, if
+// they have diverged, where.
+func formatOverlays(w io.Writer, overlays []*benchseries.Overlay, branches []string) {
+	for i, o := range overlays {
+		if i > 0 {
+			fmt.Fprintf(w, "\n")
+		}
+		fmt.Fprintf(w, "%s %s", o.Benchmark, o.Unit)
+		for _, k := range o.Labels.Keys() {
+			fmt.Fprintf(w, " %s=%s", k, o.Labels[k])
+		}
+		fmt.Fprintf(w, "\n")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "branch\tcommit\tdate\tmedian\tdelta\t%g%% CI\n", *flagConf*100)
+		for j, s := range []*benchseries.Series{o.A, o.B} {
+			p := s.Points[len(s.Points)-1]
+			scaler := benchstat.NewScaler(p.Center, o.Unit)
+			delta := ""
+			if j == 1 {
+				delta = fmt.Sprintf("%+.2f%%", o.Delta*100)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t[%s, %s]\n", branches[j], commitLabel(p.Commit), p.Commit.Date.Format("2006-01-02"), scaler(p.Center), delta, scaler(p.Lo), scaler(p.Hi))
+		}
+		tw.Flush()
+		if o.Fork != nil {
+			fmt.Fprintf(w, "forked at %s (%s)\n", commitLabel(o.Fork.Commit), o.Fork.Commit.Date.Format("2006-01-02"))
+		}
+		if o.Diverged() {
+			fmt.Fprintf(w, "diverged since %s (%s)\n", commitLabel(o.Since.Commit), o.Since.Commit.Date.Format("2006-01-02"))
+		}
+	}
+}
+
+// formatDrifts writes a table of drifts to w: the change of each
+// family of benchmarks from the -relative baseline to the last commit.
+func formatDrifts(w io.Writer, drifts []*benchseries.Drift) {
+	fmt.Fprintf(w, "drift from %s\n", *flagRelative)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "family\tunit\tbenchmarks\tdrift\trange\n")
+	for _, d := range drifts {
+		family := d.Family
+		for _, k := range d.Labels.Keys() {
+			family += " " + k + "=" + d.Labels[k]
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%+.2f%%\t[%+.2f%%, %+.2f%%]\n", family, d.Unit, d.Benchmarks, (d.Ratio-1)*100, (d.Min-1)*100, (d.Max-1)*100)
+	}
+	tw.Flush()
+}
+
+// formatWindows writes to w a table comparing the last -window points
+// of each series with those before, marking significant changes.
+func formatWindows(w io.Writer, series []*benchseries.Series) {
+	before := "all before"
+	if *flagBefore > 0 {
+		before = fmt.Sprintf("%d before", *flagBefore)
+	}
+	fmt.Fprintf(w, "last %d commits vs %s\n", *flagWindow, before)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "name\tunit\tbefore\trecent\tdelta\n")
+	for _, s := range series {
+		c := benchseries.CompareWindows(s, len(s.Points), *flagWindow, *flagBefore)
+		if c == nil {
+			continue
+		}
+		name := s.Benchmark
+		for _, k := range s.Labels.Keys() {
+			name += " " + k + "=" + s.Labels[k]
+		}
+		delta := "~"
+		if c.P < *flagAlpha {
+			delta = fmt.Sprintf("%+.2f%%", (c.Ratio-1)*100)
+		}
+		before, recent := medianCenter(c.Before), medianCenter(c.Recent)
+		scaler := benchstat.NewScaler(before, s.Unit)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t(p=%.3f n=%d+%d)\n", name, s.Unit, scaler(before), scaler(recent), delta, c.P, len(c.Before), len(c.Recent))
+	}
+	tw.Flush()
+}
+
+// medianCenter returns the median of the medians of points.
+func medianCenter(points []*benchseries.Point) float64 {
+	xs := make([]float64, len(points))
+	for i, p := range points {
+		xs[i] = p.Center
+	}
+	sort.Float64s(xs)
+	if n := len(xs); n%2 == 0 {
+		return (xs[n/2-1] + xs[n/2]) / 2
+	}
+	return xs[len(xs)/2]
+}
+
+// commitLabel returns the label of c in text output: its hash,
+// abbreviated, and its tags, if any.
+func commitLabel(c *benchseries.Commit) string {
+	label := fmt.Sprintf("%.12s", c.Hash)
+	if c.Tags != nil {
+		label += " (" + strings.Join(c.Tags, ", ") + ")"
+	}
+	return label
+}
+
+// gapPolicies maps the values of -gaps to the policies they select.
+// Skipping the gaps leaves them out of the output altogether, while
+// showing them prints a row for each.
+var gapPolicies = map[string]benchseries.GapPolicy{
+	"skip":        benchseries.LeaveGaps,
+	"show":        benchseries.LeaveGaps,
+	"carry":       benchseries.CarryForward,
+	"interpolate": benchseries.Interpolate,
+}
+
+// fillPoints returns the points of s at commits, filled by policy,
+// leaving out the gaps that remain.
+func fillPoints(history []*benchseries.Commit, s *benchseries.Series, commits []*benchseries.Commit, policy benchseries.GapPolicy) []*benchseries.Point {
+	var points []*benchseries.Point
+	for _, p := range benchseries.Fill(history, s, commits, policy) {
+		if p != nil {
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+// formatText writes each of series to w as a table of its points,
+// with their confidence intervals. If commits is not nil, each table
+// has a row for each of commits, with the gaps filled by policy, or
+// shown as "-" if left empty. Filled medians are marked with "~".
+func formatText(w io.Writer, history []*benchseries.Commit, series []*benchseries.Series, commits []*benchseries.Commit, policy benchseries.GapPolicy, base string) {
+	for i, s := range series {
+		if i > 0 {
+			fmt.Fprintf(w, "\n")
+		}
+		fmt.Fprintf(w, "%s %s\n", s.Benchmark, s.Unit)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "commit\tdate\tn\tmedian\tdelta\t")
+		var basePoint *benchseries.Point
+		if base != "" {
+			basePoint = s.At(base)
+			fmt.Fprintf(tw, "vs %s\t", *flagRelative)
+		}
+		fmt.Fprintf(tw, "%g%% CI\n", *flagConf*100)
+		points := s.Points
+		if commits != nil {
+			points = benchseries.Fill(history, s, commits, policy)
+		}
+		var prev *benchseries.Point
+		for j, p := range points {
+			if p == nil {
+				c := commits[j]
+				fmt.Fprintf(tw, "%s\t%s\t-\t-\t\t", commitLabel(c), c.Date.Format("2006-01-02"))
+				if base != "" {
+					fmt.Fprintf(tw, "\t")
+				}
+				fmt.Fprintf(tw, "-\n")
+				continue
+			}
+			scaler := benchstat.NewScaler(p.Center, s.Unit)
+			n, center, delta := fmt.Sprint(len(p.Values)), scaler(p.Center), ""
+			if p.Filled {
+				n, center = "-", "~"+center
+			}
+			if prev != nil && prev.Center != 0 {
+				delta = fmt.Sprintf("%+.2f%%", (p.Center/prev.Center-1)*100)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t", commitLabel(p.Commit), p.Commit.Date.Format("2006-01-02"), n, center, delta)
+			if base != "" {
+				rel := "-"
+				if basePoint != nil && basePoint.Center != 0 {
+					rel = fmt.Sprintf("%+.2f%%", (p.Center/basePoint.Center-1)*100)
+				}
+				fmt.Fprintf(tw, "%s\t", rel)
+			}
+			fmt.Fprintf(tw, "[%s, %s]\n", scaler(p.Lo), scaler(p.Hi))
+			prev = p
+		}
+		tw.Flush()
+	}
+}
+
+// formatAligned writes each of alignments to w as a table with a row
+// for each commit and a column for each series, labeled by the values
+// of keys, giving each series' change from the first at each commit.
+// Filled medians are marked with "~", and gaps shown as "-".
+func formatAligned(w io.Writer, alignments []*benchseries.Alignment, keys []string) {
+	for i, a := range alignments {
+		if i > 0 {
+			fmt.Fprintf(w, "\n")
+		}
+		fmt.Fprintf(w, "%s %s\n", a.Benchmark, a.Unit)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "commit\tdate")
+		for _, s := range a.Series {
+			var labels []string
+			for _, k := range keys {
+				labels = append(labels, k+"="+s.Labels[k])
+			}
+			fmt.Fprintf(tw, "\t%s", strings.Join(labels, ","))
+		}
+		fmt.Fprintf(tw, "\n")
+		for j, c := range a.Commits {
+			fmt.Fprintf(tw, "%s\t%s", commitLabel(c), c.Date.Format("2006-01-02"))
+			points := a.Points[j]
+			for k, p := range points {
+				if p == nil {
+					fmt.Fprintf(tw, "\t-")
+					continue
+				}
+				mark := ""
+				if p.Filled {
+					mark = "~"
+				}
+				fmt.Fprintf(tw, "\t%s%s", mark, benchstat.NewScaler(p.Center, a.Unit)(p.Center))
+				if first := points[0]; k > 0 && first != nil && first.Center != 0 {
+					fmt.Fprintf(tw, " (%+.2f%%)", (p.Center/first.Center-1)*100)
+				}
+			}
+			fmt.Fprintf(tw, "\n")
+		}
+		tw.Flush()
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagAnalyzer = flag.String("analyzer", "", "run `command` as an analyzer, which reads the results as JSON and returns notes and verdicts on rows")
+
+// analyzerInput is the JSON an analyzer reads on its standard input:
+// the configurations compared and a row for each benchmark in each
+// unit, as in the tables.
+type analyzerInput struct {
+	Configs []string       `json:"configs"`
+	Rows    []*analyzerRow `json:"rows"`
+}
+
+// An analyzerRow is a row of a table: the values of a benchmark in each
+// configuration, without outliers, their centers, and, for tables
+// comparing two configurations, the change, as Row describes it.
+type analyzerRow struct {
+	Unit      string      `json:"unit"`
+	Group     string      `json:"group,omitempty"`
+	Benchmark string      `json:"benchmark"`
+	Values    [][]float64 `json:"values"`
+	Centers   []float64   `json:"centers"`
+	Delta     string      `json:"delta,omitempty"`
+	Ratio     float64     `json:"ratio,omitempty"`
+	PValue    *float64    `json:"pvalue,omitempty"`
+	Change    int         `json:"change,omitempty"`
+}
+
+// analyzerOutput is the JSON an analyzer writes to its standard output.
+type analyzerOutput struct {
+	Annotations []*analyzerAnnotation `json:"annotations"`
+}
+
+// An analyzerAnnotation is an analyzer's finding on the row of a
+// benchmark in a unit: a note added to the row's own, and a verdict,
+// which is "pass" or empty, "warn" to print the note as a warning, or
+// "fail" to print it as a failure and exit with status 1.
+type analyzerAnnotation struct {
+	Unit      string `json:"unit"`
+	Group     string `json:"group,omitempty"`
+	Benchmark string `json:"benchmark"`
+	Note      string `json:"note"`
+	Verdict   string `json:"verdict,omitempty"`
+}
+
+// runAnalyzer runs command, split into fields, with the rows of tables
+// on its standard input, adds the notes it returns to their rows, and
+// returns the warnings and failures of its verdicts.
+func runAnalyzer(command string, tables []*benchstat.Table) (warnings, failures []string, err error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, nil, fmt.Errorf("empty analyzer command")
+	}
+	in := new(analyzerInput)
+	rows := make(map[[3]string]*benchstat.Row)
+	for _, table := range tables {
+		in.Configs = table.Configs
+		for _, row := range table.Rows {
+			rows[[3]string{table.Unit, row.Group, row.Benchmark}] = row
+			r := &analyzerRow{Unit: table.Unit, Group: row.Group, Benchmark: row.Benchmark}
+			for _, m := range row.Metrics {
+				r.Values = append(r.Values, m.RValues)
+				r.Centers = append(r.Centers, m.Center)
+			}
+			if table.OldNewDelta {
+				r.Delta, r.Ratio, r.Change = row.Delta, row.Ratio, row.Change
+				if p := row.PValue; p >= 0 {
+					r.PValue = &p
+				}
+			}
+			in.Rows = append(in.Rows, r)
+		}
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("analyzer %s: %v", args[0], err)
+	}
+	var result analyzerOutput
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, nil, fmt.Errorf("analyzer %s: invalid output: %v", args[0], err)
+	}
+
+	for _, a := range result.Annotations {
+		row := rows[[3]string{a.Unit, a.Group, a.Benchmark}]
+		if row == nil {
+			return nil, nil, fmt.Errorf("analyzer %s: no benchmark %s in %s", args[0], strings.TrimSpace(a.Group+" "+a.Benchmark), a.Unit)
+		}
+		if a.Note != "" {
+			row.Note = strings.TrimSpace(row.Note + " " + a.Note)
+		}
+		msg := fmt.Sprintf("%s %s: %s", strings.TrimSpace(a.Group+" "+a.Benchmark), a.Unit, a.Note)
+		switch a.Verdict {
+		case "", "pass":
+		case "warn":
+			warnings = append(warnings, msg)
+		case "fail":
+			failures = append(failures, "analyzer: "+msg)
+		default:
+			return nil, nil, fmt.Errorf("analyzer %s: invalid verdict %q for %s", args[0], a.Verdict, a.Benchmark)
+		}
+	}
+	return warnings, failures, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var flagAzure = flag.Bool("azure", false, "publish the comparison to the Azure Pipelines run, as a summary tab and an HTML artifact, with failures and warnings as issues")
+
+// azureEscaper escapes the message of an Azure Pipelines logging command.
+var azureEscaper = strings.NewReplacer(
+	"%", "%AZP25",
+	"\r", "%0D",
+	"\n", "%0A",
+)
+
+// azurePropertyEscaper escapes the value of a property of an Azure
+// Pipelines logging command.
+var azurePropertyEscaper = strings.NewReplacer(
+	"%", "%AZP25",
+	"\r", "%0D",
+	"\n", "%0A",
+	";", "%3B",
+	"]", "%5D",
+)
+
+// publishAzure publishes the Markdown report and HTML page of the
+// comparison to the Azure Pipelines run described by the environment,
+// by writing them to the agent's temporary directory and printing
+// logging commands to w that attach the report as a tab of the run's
+// summary and upload the page as a "benchstat" artifact. Failures and
+// warnings are logged as errors and warnings, which the run lists as
+// issues.
+func publishAzure(w io.Writer, report string, html []byte, warnings, failures []string) error {
+	dir := os.Getenv("AGENT_TEMPDIRECTORY")
+	if dir == "" {
+		return fmt.Errorf("AGENT_TEMPDIRECTORY must be set")
+	}
+	dir = filepath.Join(dir, reviewSlug())
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	// The summary tab is named after its file.
+	summary := filepath.Join(dir, reviewName()+".md")
+	if err := ioutil.WriteFile(summary, []byte(report), 0666); err != nil {
+		return err
+	}
+	page := filepath.Join(dir, reviewSlug()+".html")
+	if err := ioutil.WriteFile(page, html, 0666); err != nil {
+		return err
+	}
+
+	for _, f := range failures {
+		fmt.Fprintf(w, "##vso[task.logissue type=error]%s\n", azureEscaper.Replace(f))
+	}
+	for _, warn := range warnings {
+		fmt.Fprintf(w, "##vso[task.logissue type=warning]%s\n", azureEscaper.Replace(warn))
+	}
+	fmt.Fprintf(w, "##vso[task.uploadsummary]%s\n", azureEscaper.Replace(summary))
+	name := azurePropertyEscaper.Replace(reviewSlug())
+	fmt.Fprintf(w, "##vso[artifact.upload containerfolder=%s;artifactname=%s]%s\n", name, name, azureEscaper.Replace(page))
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+
+	"golang.org/x/perf/benchstat"
+	"golang.org/x/perf/benchstat/gate"
+)
+
+var (
+	flagUpdateBaseline = flag.String("update-baseline", "", "write the results of the last input, with tolerances, to the baseline `file`")
+	flagAgainst        = flag.String("against", "", "exit with status 1 if a result of the last input is worse than in the baseline `file` by more than its tolerance")
+	flagTolerance      = flag.String("tolerance", "5%", "smallest tolerance written by -update-baseline, in `percent`, optionally per unit, as in 5%,allocs/op=0%")
+)
+
+// A baseline is the expected performance of a set of benchmarks,
+// kept in a file alongside the code they measure.
+type baseline struct {
+	Benchmarks []*baselineEntry `json:"benchmarks"`
+}
+
+// A baselineEntry is the expected result of a benchmark in one unit.
+type baselineEntry struct {
+	Name  string  `json:"name"` // group and benchmark name
+	Unit  string  `json:"unit"`
+	Value float64 `json:"value"`
+
+	// Tolerance is how much worse than Value a result may be,
+	// in percent, before it fails the comparison.
+	Tolerance float64 `json:"tolerance"`
+}
+
+// readBaseline reads the baseline in file.
+func readBaseline(file string) (*baseline, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	b := new(baseline)
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return b, nil
+}
+
+// baselineResults calls f for the result of each benchmark in tables
+// in the last configuration, the one a baseline records or is
+// compared against.
+func baselineResults(tables []*benchstat.Table, f func(table *benchstat.Table, name string, m *benchstat.Metrics)) {
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			m := row.Metrics[len(row.Metrics)-1]
+			if m.Unit == "" || row.Benchmark == "[Geo mean]" {
+				continue
+			}
+			name := row.Benchmark
+			if row.Group != "" {
+				name = row.Group + " " + name
+			}
+			f(table, name, m)
+		}
+	}
+}
+
+// updateBaseline writes the results in tables to the baseline in
+// file. Each tolerance is the larger of the threshold for its unit in
+// tolerances and the variation of the result, so that noisy
+// benchmarks do not fail on the next run. A larger tolerance already
+// in file, as set by hand, is kept.
+func updateBaseline(file string, tables []*benchstat.Table, tolerances *gate.Policy) error {
+	old := make(map[[2]string]float64)
+	if b, err := readBaseline(file); err == nil {
+		for _, e := range b.Benchmarks {
+			old[[2]string{e.Name, e.Unit}] = e.Tolerance
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	b := new(baseline)
+	baselineResults(tables, func(table *benchstat.Table, name string, m *benchstat.Metrics) {
+		tol, _ := tolerances.Threshold(table.Unit)
+		if m.Center != 0 {
+			spread := math.Max(m.Max/m.Center-1, 1-m.Min/m.Center)
+			tol = math.Max(tol, math.Ceil(spread*100))
+		}
+		tol = math.Max(tol, old[[2]string{name, table.Unit}])
+		// Round off the noise of floating-point arithmetic
+		// to keep the file readable.
+		value, _ := strconv.ParseFloat(strconv.FormatFloat(m.Center, 'g', 10, 64), 64)
+		b.Benchmarks = append(b.Benchmarks, &baselineEntry{Name: name, Unit: table.Unit, Value: value, Tolerance: tol})
+	})
+	data, err := json.MarshalIndent(b, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(data, '\n'), 0666)
+}
+
+// checkBaseline returns a description of each result in b that is
+// worse in tables by more than its tolerance, or missing from tables.
+func checkBaseline(b *baseline, tables []*benchstat.Table) []string {
+	type result struct {
+		table *benchstat.Table
+		m     *benchstat.Metrics
+	}
+	results := make(map[[2]string]result)
+	baselineResults(tables, func(table *benchstat.Table, name string, m *benchstat.Metrics) {
+		results[[2]string{name, table.Unit}] = result{table, m}
+	})
+
+	var failures []string
+	for _, e := range b.Benchmarks {
+		r, ok := results[[2]string{e.Name, e.Unit}]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%s: %s not measured", e.Name, e.Unit))
+			continue
+		}
+		if e.Value == 0 {
+			continue
+		}
+		pct := (r.m.Center/e.Value - 1) * 100
+		worse := pct
+		if r.table.Better == benchstat.HigherIsBetter {
+			worse = -pct
+		}
+		if worse > e.Tolerance {
+			failures = append(failures, fmt.Sprintf("%s: %s %+.2f%% exceeds %g%% tolerance", e.Name, r.table.Metric, pct, e.Tolerance))
+		}
+	}
+	return failures
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"text/tabwriter"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagCompat = flag.String("compat", "", "print a comparison of two inputs in the layout of an older `tool`: benchcmp")
+
+// benchcmpSections lists the units benchcmp compares, in its order,
+// with the headings of its columns and the format of its values.
+var benchcmpSections = []struct {
+	unit, old, new, delta string
+	format                func(float64) string
+}{
+	{"ns/op", "old ns/op", "new ns/op", "delta", benchcmpNs},
+	{"MB/s", "old MB/s", "new MB/s", "speedup", func(v float64) string { return fmt.Sprintf("%.2f", v) }},
+	{"allocs/op", "old allocs", "new allocs", "delta", func(v float64) string { return fmt.Sprintf("%.0f", v) }},
+	{"B/op", "old bytes", "new bytes", "delta", func(v float64) string { return fmt.Sprintf("%.0f", v) }},
+}
+
+// formatBenchcmp appends to buf the tables comparing two configurations
+// in the layout of the retired benchcmp command, for scripts parsing its
+// output: a section for each of the units it knew, with a row for each
+// benchmark measured in both configurations giving the old and new
+// values, which are the centers of their samples, and the change.
+func formatBenchcmp(buf *bytes.Buffer, tables []*benchstat.Table) {
+	w := tabwriter.NewWriter(buf, 0, 0, 5, ' ', 0)
+	first := true
+	for _, s := range benchcmpSections {
+		for _, table := range tables {
+			if table.Unit != s.unit || !table.OldNewDelta {
+				continue
+			}
+			header := false
+			for _, row := range table.Rows {
+				old, new := row.Metrics[0], row.Metrics[1]
+				if row.Benchmark == "[Geo mean]" || old.Unit == "" || new.Unit == "" {
+					continue
+				}
+				if !header {
+					if !first {
+						fmt.Fprint(w, "\n")
+					}
+					fmt.Fprintf(w, "benchmark\t%s\t%s\t%s\n", s.old, s.new, s.delta)
+					header, first = true, false
+				}
+				name := "Benchmark" + row.Benchmark
+				if row.Group != "" {
+					name = row.Group + " " + name
+				}
+				ratio := benchcmpRatio(old.Center, new.Center)
+				delta := fmt.Sprintf("%+.2f%%", 100*ratio-100)
+				if s.delta == "speedup" {
+					delta = fmt.Sprintf("%.2fx", ratio)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, s.format(old.Center), s.format(new.Center), delta)
+			}
+		}
+	}
+	w.Flush()
+}
+
+// benchcmpNs formats a time in ns/op as benchcmp did, with more
+// decimal places for smaller times.
+func benchcmpNs(ns float64) string {
+	prec := 0
+	switch {
+	case ns < 10:
+		prec = 2
+	case ns < 100:
+		prec = 1
+	}
+	return strconv.FormatFloat(ns, 'f', prec, 64)
+}
+
+// benchcmpRatio returns new/old, or, if old is 0, 1 if new is also 0
+// and +Inf otherwise, as benchcmp did.
+func benchcmpRatio(old, new float64) float64 {
+	if old == 0 {
+		if new == 0 {
+			return 1
+		}
+		return math.Inf(+1)
+	}
+	return new / old
+}
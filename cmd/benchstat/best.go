@@ -0,0 +1,41 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagPickBest = flag.Bool("pick-best", false, "print which input, of two or more candidates, is significantly best for each benchmark")
+
+// formatPicksText appends to buf, for each unit, the configuration that
+// wins each benchmark and the geometric mean, as in
+//
+//	GobEncode: new.txt, -13.31% vs old.txt
+//	JSONEncode: ~ (new.txt, -1.10% vs old.txt)
+//
+// where ~ marks a benchmark no configuration wins significantly.
+func formatPicksText(buf *bytes.Buffer, picks []*benchstat.Pick) {
+	unit := ""
+	for _, p := range picks {
+		if p.Unit != unit {
+			unit = p.Unit
+			fmt.Fprintf(buf, "\nbest by %s:\n", unit)
+		}
+		name := p.Benchmark
+		if p.Group != "" {
+			name = p.Group + " " + name
+		}
+		pick := fmt.Sprintf("%s, %+.2f%% vs %s", p.Best, p.Margin, p.RunnerUp)
+		if !p.Wins && p.Benchmark != "[Geo mean]" {
+			pick = "~ (" + pick + ")"
+		}
+		fmt.Fprintf(buf, "  %s: %s\n", name, pick)
+	}
+}
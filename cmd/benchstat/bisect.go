@@ -0,0 +1,217 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+	"golang.org/x/perf/benchstat/gate"
+)
+
+func bisectUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "usage: benchstat bisect -good rev [options] command [args...]\n")
+		fmt.Fprintf(os.Stderr, "options:\n")
+		fs.PrintDefaults()
+		os.Exit(2)
+	}
+}
+
+// bisectMain runs "benchstat bisect" with the arguments that follow
+// the subcommand.
+func bisectMain(args []string) {
+	fs := flag.NewFlagSet("bisect", flag.ExitOnError)
+	fs.Usage = bisectUsage(fs)
+	good := fs.String("good", "", "known good `revision`, whose results are the baseline")
+	bad := fs.String("bad", "HEAD", "known bad `revision`")
+	repo := fs.String("repo", ".", "bisect the git repository in `dir`")
+	fail := fs.String("fail", "0%", "count a commit as bad if a significant regression exceeds `threshold`, optionally per unit, as for benchstat -fail")
+	alpha := fs.Float64("alpha", 0.05, "consider changes significant if p < `α`")
+	fs.Parse(args)
+	if *good == "" || fs.NArg() == 0 {
+		fs.Usage()
+	}
+	if *alpha <= 0 || *alpha > 1 {
+		log.Fatalf("invalid -alpha %g", *alpha)
+	}
+
+	b := &bisector{
+		dir:     *repo,
+		command: fs.Args(),
+		gate:    parseGate("fail", *fail),
+		alpha:   *alpha,
+		log:     os.Stderr,
+	}
+	first, tables, err := b.run(*good, *bad)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s is the first bad commit\n\n", first)
+	var buf bytes.Buffer
+	benchstat.FormatText(&buf, tables)
+	os.Stdout.Write(buf.Bytes())
+}
+
+// A bisector finds the commit that introduced a regression with
+// git bisect, comparing the results of a benchmark command at each
+// commit with its results at a known good commit.
+type bisector struct {
+	dir     string       // repository
+	command []string     // benchmark command and arguments, run in dir
+	gate    *gate.Policy // regressions that make a commit bad
+	alpha   float64      // significance level
+	log     io.Writer    // progress messages
+
+	baseline []byte                        // results at the good commit
+	tables   map[string][]*benchstat.Table // comparisons by commit
+}
+
+// run bisects the commits between good and bad, and returns the first
+// bad commit and its comparison with good. It leaves the repository
+// as it found it.
+func (b *bisector) run(good, bad string) (first string, tables []*benchstat.Table, err error) {
+	// Resolve bad before checking out good, in case it is HEAD.
+	bad, err = b.git("rev-parse", "--verify", bad+"^{commit}")
+	if err != nil {
+		return "", nil, err
+	}
+	bad = strings.TrimSpace(bad)
+	if _, err := b.git("bisect", "start"); err != nil {
+		return "", nil, err
+	}
+	defer func() {
+		if _, rerr := b.git("bisect", "reset"); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	if _, err := b.git("checkout", "-q", "--detach", good); err != nil {
+		return "", nil, err
+	}
+	fmt.Fprintf(b.log, "measuring good commit %s\n", good)
+	if b.baseline, err = b.measure(); err != nil {
+		return "", nil, fmt.Errorf("at good commit %s: %v", good, err)
+	}
+	if _, err := b.git("bisect", "bad", bad); err != nil {
+		return "", nil, err
+	}
+	out, err := b.git("bisect", "good", good)
+	b.tables = make(map[string][]*benchstat.Table)
+	for err == nil {
+		if first, ok := firstBad(out); ok {
+			if err := b.measureFirst(first); err != nil {
+				return "", nil, err
+			}
+			return first, b.tables[first], nil
+		}
+		if strings.Contains(out, "only 'skip'ped commits left") {
+			return "", nil, fmt.Errorf("cannot tell which commit is the first bad one:\n%s", out)
+		}
+		out, err = b.step()
+	}
+	return "", nil, err
+}
+
+// step measures the commit checked out by git bisect, marks it good,
+// bad, or, if the command failed, skipped, and returns the output of
+// git bisect.
+func (b *bisector) step() (string, error) {
+	head, err := b.git("rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	head = strings.TrimSpace(head)
+	results, err := b.measure()
+	if err != nil {
+		fmt.Fprintf(b.log, "%.12s: skip: %v\n", head, err)
+		return b.git("bisect", "skip")
+	}
+	verdict := "good"
+	regressions := checkGate(b.gate, b.compare(head, results))
+	if len(regressions) > 0 {
+		verdict = "bad"
+	}
+	fmt.Fprintf(b.log, "%.12s: %s", head, verdict)
+	for _, r := range regressions {
+		fmt.Fprintf(b.log, "; %s", r)
+	}
+	fmt.Fprintf(b.log, "\n")
+	return b.git("bisect", verdict)
+}
+
+// compare compares the results at commit head with the baseline and
+// records the comparison in b.tables.
+func (b *bisector) compare(head string, results []byte) []*benchstat.Table {
+	c := &benchstat.Collection{Alpha: b.alpha}
+	c.AddConfig("good", b.baseline)
+	c.AddConfig(fmt.Sprintf("%.12s", head), results)
+	tables := c.Tables()
+	b.tables[head] = tables
+	return tables
+}
+
+// measureFirst measures the first bad commit if git bisect found it
+// without checking it out, which it does if it is the only candidate.
+func (b *bisector) measureFirst(first string) error {
+	if b.tables[first] != nil {
+		return nil
+	}
+	if _, err := b.git("checkout", "-q", "--detach", first); err != nil {
+		return err
+	}
+	results, err := b.measure()
+	if err != nil {
+		return fmt.Errorf("at first bad commit %s: %v", first, err)
+	}
+	b.compare(first, results)
+	return nil
+}
+
+// firstBad returns the first bad commit named by the output of git
+// bisect, if it names one.
+func firstBad(out string) (string, bool) {
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasSuffix(line, " is the first bad commit") {
+			return strings.Fields(line)[0], true
+		}
+	}
+	return "", false
+}
+
+// measure runs the benchmark command and returns its output.
+func (b *bisector) measure() ([]byte, error) {
+	cmd := exec.Command(b.command[0], b.command[1:]...)
+	cmd.Dir = b.dir
+	cmd.Stderr = b.log
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(out, []byte("\nBenchmark")) && !bytes.HasPrefix(out, []byte("Benchmark")) {
+		return nil, fmt.Errorf("%q printed no benchmark results", b.command[0])
+	}
+	return out, nil
+}
+
+// git runs git with args in the repository and returns its output.
+func (b *bisector) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = b.dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v\n%s%s", strings.Join(args, " "), err, out, stderr.Bytes())
+	}
+	return string(out), nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagBitbucket = flag.Bool("bitbucket", false, "publish the comparison as a Bitbucket Cloud report on the commit being built, with an annotation for each regression")
+
+// bitbucketProxy is the proxy through which Bitbucket Pipelines
+// authenticates API requests from a build on the build's behalf.
+const bitbucketProxy = "http://localhost:29418"
+
+// Limits on the Bitbucket reports API.
+const (
+	bitbucketMaxDetails     = 2000 // bytes of report details
+	bitbucketMaxSummary     = 450  // bytes of annotation summary
+	bitbucketMaxAnnotations = 100  // annotations per request
+)
+
+// A bitbucketClient publishes reports using the Bitbucket Cloud REST API.
+type bitbucketClient struct {
+	*apiClient
+	commit string // API path of the commit
+}
+
+// bitbucketEnv returns the client for the commit being built, as
+// described by the Bitbucket Pipelines environment. If BITBUCKET_TOKEN
+// is set, the client authenticates with it; otherwise it sends requests
+// through the Pipelines authentication proxy.
+func bitbucketEnv() (*bitbucketClient, error) {
+	workspace, slug := os.Getenv("BITBUCKET_WORKSPACE"), os.Getenv("BITBUCKET_REPO_SLUG")
+	commit := os.Getenv("BITBUCKET_COMMIT")
+	if workspace == "" || slug == "" || commit == "" {
+		return nil, fmt.Errorf("BITBUCKET_WORKSPACE, BITBUCKET_REPO_SLUG, and BITBUCKET_COMMIT must be set")
+	}
+	api := strings.TrimSuffix(os.Getenv("BITBUCKET_API_URL"), "/")
+	var c *apiClient
+	if token := os.Getenv("BITBUCKET_TOKEN"); token != "" {
+		if api == "" {
+			api = "https://api.bitbucket.org/2.0"
+		}
+		c = newAPIClient(api, "Authorization", "Bearer "+token)
+	} else {
+		if api == "" {
+			api = "http://api.bitbucket.org/2.0"
+		}
+		proxy, _ := url.Parse(bitbucketProxy)
+		c = newAPIClient(api, "", "")
+		c.client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxy)}}
+	}
+	path := fmt.Sprintf("/repositories/%s/%s/commit/%s", url.PathEscape(workspace), url.PathEscape(slug), url.PathEscape(commit))
+	return &bitbucketClient{c, path}, nil
+}
+
+// A bitbucketAnnotation is an annotation of a Bitbucket report.
+type bitbucketAnnotation struct {
+	ExternalID     string `json:"external_id"`
+	AnnotationType string `json:"annotation_type"`
+	Summary        string `json:"summary"`
+	Severity       string `json:"severity"`
+	Result         string `json:"result"`
+}
+
+// postReport replaces benchstat's report on the commit with one
+// giving the text report, which fails if there are failures, and
+// annotates it with the failures and any other regressions in tables.
+func (b *bitbucketClient) postReport(text string, tables []*benchstat.Table, failures []string) error {
+	type datum struct {
+		Title string `json:"title"`
+		Type  string `json:"type"`
+		Value int    `json:"value"`
+	}
+	regressions := changes(tables, -1)
+	report := struct {
+		Title      string  `json:"title"`
+		Details    string  `json:"details"`
+		ReportType string  `json:"report_type"`
+		Reporter   string  `json:"reporter"`
+		Result     string  `json:"result"`
+		Data       []datum `json:"data"`
+	}{
+		Title:      reviewName(),
+		Details:    truncate(text, bitbucketMaxDetails),
+		ReportType: "TEST",
+		Reporter:   "benchstat",
+		Result:     "PASSED",
+		Data: []datum{
+			{"Failures", "NUMBER", len(failures)},
+			{"Regressions", "NUMBER", len(regressions)},
+			{"Improvements", "NUMBER", len(changes(tables, +1))},
+		},
+	}
+	if len(failures) > 0 {
+		report.Result = "FAILED"
+	}
+	path := b.commit + "/reports/" + reviewSlug()
+	if err := b.do("PUT", path, report, nil); err != nil {
+		return err
+	}
+
+	var annotations []bitbucketAnnotation
+	for i, f := range failures {
+		annotations = append(annotations, bitbucketAnnotation{
+			ExternalID:     fmt.Sprintf("failure-%d", i),
+			AnnotationType: "BUG",
+			Summary:        truncate(f, bitbucketMaxSummary),
+			Severity:       "HIGH",
+			Result:         "FAILED",
+		})
+	}
+	for i, r := range regressions {
+		annotations = append(annotations, bitbucketAnnotation{
+			ExternalID:     fmt.Sprintf("regression-%d", i),
+			AnnotationType: "CODE_SMELL",
+			Summary:        truncate(r, bitbucketMaxSummary),
+			Severity:       "MEDIUM",
+			Result:         "PASSED",
+		})
+	}
+	for len(annotations) > 0 {
+		n := len(annotations)
+		if n > bitbucketMaxAnnotations {
+			n = bitbucketMaxAnnotations
+		}
+		if err := b.do("POST", path+"/annotations", annotations[:n], nil); err != nil {
+			return err
+		}
+		annotations = annotations[n:]
+	}
+	return nil
+}
+
+// changes returns a description of each significant change in tables
+// in the given direction: +1 for better, or -1 for worse.
+func changes(tables []*benchstat.Table, change int) []string {
+	var descs []string
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			if !table.OldNewDelta || row.Change != change {
+				continue
+			}
+			name := row.Benchmark
+			if row.Group != "" {
+				name = row.Group + " " + name
+			}
+			descs = append(descs, fmt.Sprintf("%s: %s %s", name, table.Metric, row.Delta))
+		}
+	}
+	return descs
+}
+
+// truncate returns s shortened to at most n bytes, marking any
+// truncation with an ellipsis.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	const ellipsis = "…"
+	n -= len(ellipsis)
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + ellipsis
+}
+
+// postBitbucket publishes the report on the commit being built.
+func postBitbucket(text string, tables []*benchstat.Table, failures []string) error {
+	b, err := bitbucketEnv()
+	if err != nil {
+		return err
+	}
+	return b.postReport(text, tables, failures)
+}
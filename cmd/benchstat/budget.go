@@ -0,0 +1,146 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"golang.org/x/perf/benchstat"
+	"golang.org/x/perf/benchstat/gate"
+)
+
+var (
+	flagBudget      = flag.String("budget", "", "exit with status 1 if the drift of a benchmark accumulated in the -budget-state file exceeds `threshold`%, optionally per unit, as in 5%,allocs/op=1%")
+	flagBudgetState = flag.String("budget-state", "", "accumulate the drift of each benchmark across runs in `file`")
+	flagBudgetID    = flag.String("budget-id", "", "identify the comparison, such as by its commit, as `id` in the -budget-state file, so that it counts only once (default a hash of the compared values)")
+)
+
+// A budgetState is the drift of each benchmark accumulated over the
+// comparisons of a series of commits, such as those of a release.
+type budgetState struct {
+	Drift []*budgetDrift `json:"drift"`
+
+	// Counted lists the IDs of the comparisons already counted, so
+	// that a retried run does not count its comparison again.
+	Counted []string `json:"counted,omitempty"`
+}
+
+// A budgetDrift is the accumulated drift of a benchmark in one unit.
+type budgetDrift struct {
+	Name string `json:"name"` // group and benchmark name
+	Unit string `json:"unit"`
+
+	// Ratio is the product of the ratios of new to old value in
+	// each comparison, and Count is the number of comparisons.
+	Ratio float64 `json:"ratio"`
+	Count int     `json:"count"`
+}
+
+// updateBudget adds the change of each benchmark compared in tables to
+// the drift accumulated in the state file, creating it if needed,
+// and returns a description of each drift that exceeds its unit's
+// threshold in budget, if not nil. Changes count whether or not they are
+// significant, so that many small regressions, each lost in the noise
+// of its own comparison, add up to one that is not. The comparison
+// counts only once however many times it is run: it is identified by
+// id, or, if id is empty, by comparisonID.
+func updateBudget(file, id string, tables []*benchstat.Table, budget *gate.Policy) ([]string, error) {
+	state := new(budgetState)
+	data, err := ioutil.ReadFile(file)
+	if err == nil {
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if id == "" {
+		id = comparisonID(tables)
+	}
+	counted := false
+	for _, c := range state.Counted {
+		counted = counted || c == id
+	}
+	if !counted {
+		state.Counted = append(state.Counted, id)
+	}
+	drifts := make(map[[2]string]*budgetDrift)
+	for _, d := range state.Drift {
+		drifts[[2]string{d.Name, d.Unit}] = d
+	}
+
+	var failures []string
+	for _, table := range tables {
+		if !table.OldNewDelta {
+			continue
+		}
+		for _, row := range table.Rows {
+			if row.Ratio == 0 || row.Benchmark == "[Geo mean]" {
+				continue
+			}
+			name := row.Benchmark
+			if row.Group != "" {
+				name = row.Group + " " + name
+			}
+			d := drifts[[2]string{name, table.Unit}]
+			if d == nil {
+				d = &budgetDrift{Name: name, Unit: table.Unit, Ratio: 1}
+				drifts[[2]string{name, table.Unit}] = d
+				state.Drift = append(state.Drift, d)
+			}
+			if !counted {
+				d.Ratio *= row.Ratio
+				d.Count++
+			}
+
+			if budget == nil {
+				continue
+			}
+			limit, ok := budget.Threshold(table.Unit)
+			if !ok {
+				continue
+			}
+			pct := (d.Ratio - 1) * 100
+			worse := pct
+			if table.Better == benchstat.HigherIsBetter {
+				worse = -pct
+			}
+			if worse > limit {
+				failures = append(failures, fmt.Sprintf("%s: %s drifted %+.2f%% over %d comparisons, exceeding %g%% budget", name, table.Metric, pct, d.Count, limit))
+			}
+		}
+	}
+
+	data, err = json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(file, append(data, '\n'), 0666); err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// comparisonID returns a hash of the changes compared in tables, which
+// identifies a comparison of the same inputs when it is run again.
+func comparisonID(tables []*benchstat.Table) string {
+	h := sha256.New()
+	for _, table := range tables {
+		if !table.OldNewDelta {
+			continue
+		}
+		for _, row := range table.Rows {
+			fmt.Fprintf(h, "%s %x\n", table.RowID(row), math.Float64bits(row.Ratio))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
@@ -0,0 +1,189 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/perf/benchstat"
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+// cacheVersion identifies the format of cache entries.
+// It must be changed whenever cachedFile changes.
+const cacheVersion = "benchstat cache v3\n"
+
+// A cachedFile is the parsed form of an input file stored in the cache.
+// Results are stored split into their fields, so a cache hit need not
+// parse benchmark lines again. Label sets are stored once and referred
+// to by index, since long runs of results usually share them.
+type cachedFile struct {
+	Labels  []benchfmt.Labels
+	Results []cachedResult
+	Units   map[string]benchfmt.Labels // unit metadata
+}
+
+type cachedResult struct {
+	Labels, NameLabels int // indexes into cachedFile.Labels
+	Name               string
+	Iters              int
+	Values             []float64
+	Units              []string
+}
+
+// addCachedFile adds the results in file to the named configuration
+// of c, using the parsed results cached in dir if file's content has
+// been seen before, and populating the cache if not.
+func addCachedFile(c *benchstat.Collection, dir, config, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	io.WriteString(h, cacheVersion)
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, hex.EncodeToString(h.Sum(nil)))
+
+	if cf, err := readCache(path); err == nil {
+		addCached(c, config, cf)
+		return nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	br := benchfmt.NewReader(f)
+	var results []benchstat.ParsedResult
+	for br.Next() {
+		if p, ok := benchstat.ParseResult(br.Result()); ok {
+			results = append(results, p)
+		}
+	}
+	if err := br.Err(); err != nil {
+		return err
+	}
+	c.AddParsedResults(config, results)
+	for unit, meta := range br.UnitMetadata() {
+		c.AddUnitMetadata(unit, meta)
+	}
+
+	// Failing to write the cache only costs time on the next run,
+	// so don't report it.
+	writeCache(dir, path, results, br.UnitMetadata())
+	return nil
+}
+
+// addCached adds the results and unit metadata in cf to the named
+// configuration of c.
+func addCached(c *benchstat.Collection, config string, cf *cachedFile) {
+	results := make([]benchstat.ParsedResult, len(cf.Results))
+	for i, r := range cf.Results {
+		results[i] = benchstat.ParsedResult{
+			Labels:     cf.Labels[r.Labels],
+			NameLabels: cf.Labels[r.NameLabels],
+			Name:       r.Name,
+			Iters:      r.Iters,
+			Values:     r.Values,
+			Units:      r.Units,
+		}
+	}
+	c.AddParsedResults(config, results)
+	for unit, meta := range cf.Units {
+		c.AddUnitMetadata(unit, meta)
+	}
+}
+
+// readCache reads the cache entry at path.
+func readCache(path string) (*cachedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cf := new(cachedFile)
+	if err := gob.NewDecoder(f).Decode(cf); err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+// writeCache writes results and unit metadata to path, which must be
+// in dir. The file is written under a temporary name and renamed into
+// place, so concurrent runs never observe a partial cache entry.
+func writeCache(dir, path string, results []benchstat.ParsedResult, units map[string]benchfmt.Labels) error {
+	cf := cachedFile{Units: units}
+	index := func(l benchfmt.Labels) int {
+		// Results usually share label maps with their
+		// predecessor, so only compare against the last few.
+		for i := len(cf.Labels) - 1; i >= 0 && i >= len(cf.Labels)-4; i-- {
+			if cf.Labels[i].Equal(l) {
+				return i
+			}
+		}
+		cf.Labels = append(cf.Labels, l)
+		return len(cf.Labels) - 1
+	}
+	for _, r := range results {
+		cf.Results = append(cf.Results, cachedResult{
+			Labels:     index(r.Labels),
+			NameLabels: index(r.NameLabels),
+			Name:       r.Name,
+			Iters:      r.Iters,
+			Values:     r.Values,
+			Units:      r.Units,
+		})
+	}
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, "tmp-")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(tmp).Encode(&cf); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadState adds the results saved in file by saveState to c.
+func loadState(c *benchstat.Collection, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.ReadState(f)
+}
+
+// saveState saves the results added to c in file, for -load-state.
+func saveState(c *benchstat.Collection, file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := c.WriteState(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
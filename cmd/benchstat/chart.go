@@ -0,0 +1,106 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagDeltaChart = flag.String("delta-chart", "", "write an SVG chart ranking the changes between two inputs by size to `file`")
+
+// A chartBar is a bar of a delta chart: the change of a benchmark in
+// one unit.
+type chartBar struct {
+	label  string
+	pct    float64 // percent change from old to new
+	worse  float64 // pct, negated if higher is better
+	change int     // as in benchstat.Row
+}
+
+// Dimensions of delta charts, in pixels.
+const (
+	chartLabel = 300 // width of the labels
+	chartHalf  = 200 // width of the bars at the largest change
+	chartText  = 70  // width of the percentages beside the bars
+	chartRow   = 16  // height of each bar
+)
+
+// deltaBars returns a bar for the change of each benchmark compared in
+// tables, ordered from the greatest improvement to the greatest
+// regression.
+func deltaBars(tables []*benchstat.Table) []chartBar {
+	var bars []chartBar
+	for _, table := range tables {
+		if !table.OldNewDelta {
+			continue
+		}
+		for _, row := range table.Rows {
+			if row.Ratio == 0 {
+				continue
+			}
+			name := row.Benchmark
+			if row.Group != "" {
+				name = row.Group + " " + name
+			}
+			b := chartBar{label: name + " " + table.Metric, pct: (row.Ratio - 1) * 100, change: row.Change}
+			b.worse = b.pct
+			if table.Better == benchstat.HigherIsBetter {
+				b.worse = -b.pct
+			}
+			bars = append(bars, b)
+		}
+	}
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].worse < bars[j].worse })
+	return bars
+}
+
+// formatDeltaChart appends to buf an SVG image charting the change of
+// each benchmark compared in tables, as a horizontal bar from a center
+// line, ordered from the greatest improvement at the top to the
+// greatest regression at the bottom: a tornado chart, or waterfall.
+// Improvements extend left in green, regressions right in red, and
+// changes that are not significant are gray.
+func formatDeltaChart(buf *bytes.Buffer, tables []*benchstat.Table) {
+	bars := deltaBars(tables)
+	maxPct := 0.0
+	for _, b := range bars {
+		maxPct = math.Max(maxPct, math.Abs(b.pct))
+	}
+
+	center := chartLabel + chartText + chartHalf
+	width := center + chartHalf + chartText
+	height := chartRow * (len(bars) + 1)
+	fmt.Fprintf(buf, "<svg xmlns='http://www.w3.org/2000/svg' class='benchstat-chart' width='%d' height='%d' viewBox='0 0 %[1]d %[2]d' font-family='sans-serif' font-size='11'>\n", width, height)
+	for i, b := range bars {
+		y := chartRow/2 + i*chartRow
+		size := 0.0
+		if maxPct > 0 {
+			size = math.Abs(b.pct) / maxPct * chartHalf
+		}
+		x, anchor, tx := float64(center), "start", float64(center)+size+4
+		if b.worse < 0 {
+			x, anchor, tx = float64(center)-size, "end", float64(center)-size-4
+		}
+		color := "#aaa"
+		switch b.change {
+		case +1:
+			color = "#393"
+		case -1:
+			color = "#c33"
+		}
+		fmt.Fprintf(buf, "<text x='%d' y='%d' text-anchor='end' dominant-baseline='middle'>%s</text>", chartLabel, y+chartRow/2, html.EscapeString(b.label))
+		fmt.Fprintf(buf, "<rect x='%.1f' y='%d' width='%.1f' height='%d' fill='%s'/>", x, y+2, size, chartRow-4, color)
+		fmt.Fprintf(buf, "<text x='%.1f' y='%d' text-anchor='%s' dominant-baseline='middle'>%+.2f%%</text>\n", tx, y+chartRow/2, anchor, b.pct)
+	}
+	fmt.Fprintf(buf, "<line x1='%d' y1='0' x2='%[1]d' y2='%d' stroke='#666'/>\n", center, height)
+	buf.WriteString("</svg>\n")
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"html/template"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagEfficiency = flag.Bool("efficiency", false, "combine the time/op, alloc/op, and allocs/op comparisons into one table")
+
+// efficiencyUnits are the units combined by -efficiency, in column order.
+var efficiencyUnits = []string{"ns/op", "B/op", "allocs/op"}
+
+// An efficiencyRow relates the changes in time and memory use
+// of a single benchmark.
+type efficiencyRow struct {
+	Group, Benchmark string
+	Deltas           []string // deltas for efficiencyUnits, "" if missing
+	Pressure         string   // combined change in B/op and allocs/op
+	Change           int      // +1 better, -1 worse, 0 unchanged or mixed
+}
+
+// efficiency removes the tables comparing efficiencyUnits from tables
+// and returns rows combining their deltas, along with the remaining
+// tables. The tables must compare two configurations.
+func efficiency(tables []*benchstat.Table) ([]*efficiencyRow, []*benchstat.Table) {
+	type rowKey struct{ group, benchmark string }
+	var rows []*efficiencyRow
+	byKey := make(map[rowKey]*efficiencyRow)
+	changes := make(map[rowKey][]int)
+	var rest []*benchstat.Table
+	for _, table := range tables {
+		col := -1
+		for i, unit := range efficiencyUnits {
+			if table.Unit == unit {
+				col = i
+			}
+		}
+		if col < 0 {
+			rest = append(rest, table)
+			continue
+		}
+		for _, row := range table.Rows {
+			k := rowKey{row.Group, row.Benchmark}
+			r := byKey[k]
+			if r == nil {
+				r = &efficiencyRow{
+					Group:     row.Group,
+					Benchmark: row.Benchmark,
+					Deltas:    make([]string, len(efficiencyUnits)),
+				}
+				byKey[k] = r
+				changes[k] = make([]int, len(efficiencyUnits))
+				rows = append(rows, r)
+			}
+			r.Deltas[col] = row.Delta
+			changes[k][col] = row.Change
+		}
+	}
+	for k, r := range byKey {
+		c := changes[k]
+		r.Pressure, r.Change = pressure(c[1], c[2])
+	}
+	return rows, rest
+}
+
+// pressure summarizes the changes in B/op and allocs/op, each +1 for
+// better, -1 for worse, or 0 for no significant change, as a change
+// in memory pressure.
+func pressure(bytes, allocs int) (string, int) {
+	switch {
+	case bytes+allocs > 0 && bytes*allocs >= 0:
+		return "lower", +1
+	case bytes+allocs < 0 && bytes*allocs >= 0:
+		return "higher", -1
+	case bytes != 0:
+		return "mixed", 0
+	}
+	return "~", 0
+}
+
+// formatEfficiencyText appends a fixed-width text formatting
+// of the rows to buf.
+func formatEfficiencyText(buf *bytes.Buffer, rows []*efficiencyRow) {
+	text := [][]string{{"name", "time/op", "alloc/op", "allocs/op", "memory pressure"}}
+	var group string
+	for _, r := range rows {
+		if r.Group != group {
+			group = r.Group
+			text = append(text, []string{group})
+		}
+		text = append(text, append(append([]string{r.Benchmark}, r.Deltas...), r.Pressure))
+	}
+
+	formatGrid(buf, text, true)
+}
+
+var efficiencyTemplate = template.Must(template.New("").Funcs(template.FuncMap{
+	"replace": strings.Replace,
+}).Parse(`
+<table class='benchstat efficiency'>
+<tbody>
+<tr class='heading'><th><th>time/op<th>alloc/op<th>allocs/op<th>memory pressure
+{{range . -}}
+<tr class='{{if eq .Change 1}}better{{else if eq .Change -1}}worse{{else}}unchanged{{end}}'><td class='name'>{{if .Group}}{{.Group}} {{end}}{{.Benchmark}}{{range .Deltas}}<td class='{{if eq . "~"}}nodelta{{else}}delta{{end}}'>{{replace . "-" "−" -1}}{{end}}<td class='value'>{{.Pressure}}
+{{end -}}
+</tbody>
+</table>
+`))
+
+// formatEfficiencyHTML appends an HTML formatting of the rows to buf.
+func formatEfficiencyHTML(buf *bytes.Buffer, rows []*efficiencyRow) {
+	if err := efficiencyTemplate.Execute(buf, rows); err != nil {
+		// Only possible if template is invalid.
+		panic(err)
+	}
+}
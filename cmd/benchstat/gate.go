@@ -0,0 +1,137 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+	"golang.org/x/perf/benchstat/gate"
+)
+
+var (
+	flagFail       = flag.String("fail", "", "exit with status 1 if a significant regression exceeds `threshold`%, optionally per unit, as in 5%,allocs/op=1%")
+	flagCorrection = flag.String("fail-correction", "", "correct the significance of regressions for -fail for the number of benchmarks compared, by `method` bonferroni or holm")
+	flagMinCount   = flag.Int("min-count", 0, "exit with status 1 if any benchmark has fewer than `n` samples")
+)
+
+// parseGate parses list, the value of the flag name, such as -fail,
+// into a policy gating only regressions.
+func parseGate(name, list string) *gate.Policy {
+	p := &gate.Policy{Thresholds: make(map[string]float64)}
+	parsePerUnit(name, list, func(unit, value string) error {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || pct < 0 {
+			return fmt.Errorf("invalid threshold %q", value)
+		}
+		p.Thresholds[unit] = pct
+		return nil
+	})
+	return p
+}
+
+// gatePolicy returns the policy set by -fail, -fail-correction, and
+// -min-count, in which corrected regressions are significant at level
+// alpha.
+func gatePolicy(alpha float64) *gate.Policy {
+	p := new(gate.Policy)
+	if *flagFail != "" {
+		p = parseGate("fail", *flagFail)
+	}
+	switch c := gate.Correction(strings.ToLower(*flagCorrection)); c {
+	case gate.NoCorrection, gate.Bonferroni, gate.Holm:
+		p.Correction = c
+	case "none":
+	default:
+		fatalf("invalid -fail-correction %q: want bonferroni or holm", *flagCorrection)
+	}
+	p.MinCount = *flagMinCount
+	p.Alpha = alpha
+	return p
+}
+
+// checkGate returns a description of each violation of policy p by
+// the comparisons in tables.
+func checkGate(p *gate.Policy, tables []*benchstat.Table) []string {
+	var failures []string
+	_, violations := gate.Evaluate(tables, p)
+	for _, v := range violations {
+		failures = append(failures, v.Message)
+	}
+	return failures
+}
+
+// gateFailures returns the description of the regression in each row of
+// tables that fails policy p, which may be nil.
+func gateFailures(p *gate.Policy, tables []*benchstat.Table) map[*benchstat.Row]string {
+	failed := make(map[*benchstat.Row]string)
+	if p == nil {
+		return failed
+	}
+	_, violations := gate.Evaluate(tables, p)
+	for _, v := range violations {
+		if v.Kind == gate.Regression {
+			failed[v.Row] = v.Message
+		}
+	}
+	return failed
+}
+
+// formatBenchdiff appends to buf the outcome of the comparison in
+// tables in the benchdiff format, with the decision of policy p, if
+// not nil, on each change, and the decision on the whole comparison,
+// which fails if there are any failures.
+func formatBenchdiff(buf *bytes.Buffer, tables []*benchstat.Table, p *gate.Policy, failures []string) {
+	var decide func(*benchstat.Table, *benchstat.Row) string
+	if p != nil {
+		failed := gateFailures(p, tables)
+		decide = func(table *benchstat.Table, row *benchstat.Row) string {
+			if _, ok := p.Threshold(table.Unit); !ok {
+				return ""
+			}
+			if failed[row] != "" {
+				return "fail"
+			}
+			return "pass"
+		}
+	}
+	d := benchstat.NewBenchdiff(tables, decide)
+	if p != nil || len(failures) > 0 {
+		d.Decision = "pass"
+		if len(failures) > 0 {
+			d.Decision = "fail"
+		}
+	}
+	benchstat.WriteBenchdiff(buf, d)
+}
+
+// underpoweredWarnings returns a warning for each of tables with rows
+// whose samples are too small for the delta test to ever report a
+// change, since "~" in such rows is easily misread as "no change".
+func underpoweredWarnings(tables []*benchstat.Table) []string {
+	const maxNames = 3
+	var warnings []string
+	for _, table := range tables {
+		var names []string
+		for _, row := range table.Rows {
+			if !row.Underpowered {
+				continue
+			}
+			if len(names) == maxNames {
+				names = append(names, "...")
+				break
+			}
+			names = append(names, fmt.Sprintf("%s (n=%d+%d)", row.Benchmark, len(row.Metrics[0].RValues), len(row.Metrics[1].RValues)))
+		}
+		if names != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: too few samples for the delta test to ever report a change in %s; run more iterations with go test -count", table.Metric, strings.Join(names, ", ")))
+		}
+	}
+	return warnings
+}
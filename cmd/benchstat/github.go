@@ -0,0 +1,202 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagGitHub = flag.Bool("github", false, "post the comparison to the GitHub pull request being built, as a comment and a check run, using GITHUB_TOKEN")
+
+// A githubClient posts results to a repository using the GitHub REST API.
+type githubClient struct {
+	*apiClient
+	repo string // owner/name
+}
+
+// githubEnv returns the client and the pull request number and head
+// commit for the GitHub Actions run described by the environment.
+func githubEnv() (g *githubClient, pr int, sha string, err error) {
+	api := strings.TrimSuffix(os.Getenv("GITHUB_API_URL"), "/")
+	if api == "" {
+		api = "https://api.github.com"
+	}
+	token, repo := os.Getenv("GITHUB_TOKEN"), os.Getenv("GITHUB_REPOSITORY")
+	if token == "" || repo == "" {
+		return nil, 0, "", fmt.Errorf("GITHUB_TOKEN and GITHUB_REPOSITORY must be set")
+	}
+	g = &githubClient{newAPIClient(api, "Authorization", "token "+token), repo}
+	g.header.Set("Accept", "application/vnd.github.v3+json")
+	data, err := ioutil.ReadFile(os.Getenv("GITHUB_EVENT_PATH"))
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("reading event: %v", err)
+	}
+	var event struct {
+		PullRequest struct {
+			Number int
+			Head   struct{ SHA string }
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, 0, "", fmt.Errorf("reading event: %v", err)
+	}
+	if event.PullRequest.Number == 0 {
+		return nil, 0, "", fmt.Errorf("not building a pull request")
+	}
+	return g, event.PullRequest.Number, event.PullRequest.Head.SHA, nil
+}
+
+// postComment adds body as a comment on pull request pr, or replaces
+// the body of the comment a previous run added.
+func (g *githubClient) postComment(pr int, body string) error {
+	return g.postSticky(commentThread{
+		list: func(page int) string {
+			return fmt.Sprintf("/repos/%s/issues/%d/comments?per_page=100&page=%d", g.repo, pr, page)
+		},
+		add: fmt.Sprintf("/repos/%s/issues/%d/comments", g.repo, pr),
+		item: func(id int64) string {
+			return fmt.Sprintf("/repos/%s/issues/comments/%d", g.repo, id)
+		},
+		update: "PATCH",
+	}, body)
+}
+
+// Limits of the GitHub API.
+const (
+	githubMaxAnnotations = 50    // annotations added per request
+	githubMaxComment     = 65536 // characters of a comment
+	githubMaxSummary     = 65535 // characters of a check run summary
+)
+
+// A githubAnnotation marks a line of a file in a check run.
+type githubAnnotation struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Level     string `json:"annotation_level"`
+	Title     string `json:"title"`
+	Message   string `json:"message"`
+}
+
+// githubAnnotations returns an annotation of the function of each
+// benchmark in tables with a significant regression, if its location
+// is found in funcs.
+func githubAnnotations(tables []*benchstat.Table, funcs map[string][]sourceLoc) []githubAnnotation {
+	var annotations []githubAnnotation
+	for _, table := range tables {
+		if !table.OldNewDelta {
+			continue
+		}
+		for _, row := range table.Rows {
+			if row.Change >= 0 {
+				continue
+			}
+			loc, ok := benchmarkSource(funcs, row.Benchmark, row.Group)
+			if !ok {
+				continue
+			}
+			name := row.Benchmark
+			if row.Group != "" {
+				name = row.Group + " " + name
+			}
+			old, new := row.Metrics[0], row.Metrics[1]
+			msg := fmt.Sprintf("%s %s: %s → %s", table.Metric, row.Delta, old.Format(row.Scaler), new.Format(row.Scaler))
+			if row.Note != "" {
+				msg += " " + row.Note
+			}
+			annotations = append(annotations, githubAnnotation{
+				Path:      loc.File,
+				StartLine: loc.Line,
+				EndLine:   loc.Line,
+				Level:     "warning",
+				Title:     name,
+				Message:   msg,
+			})
+		}
+	}
+	return annotations
+}
+
+// postCheckRun adds a completed check run for commit sha that
+// fails if there are failures, with the given annotations.
+func (g *githubClient) postCheckRun(sha, summary string, failures []string, annotations []githubAnnotation) error {
+	type output struct {
+		Title       string             `json:"title"`
+		Summary     string             `json:"summary"`
+		Annotations []githubAnnotation `json:"annotations,omitempty"`
+	}
+	out := output{Title: countFailures(failures), Summary: summary}
+	batch := func() {
+		n := len(annotations)
+		if n > githubMaxAnnotations {
+			n = githubMaxAnnotations
+		}
+		out.Annotations, annotations = annotations[:n], annotations[n:]
+	}
+	batch()
+	run := struct {
+		ID         int64  `json:"id,omitempty"`
+		Name       string `json:"name"`
+		HeadSHA    string `json:"head_sha"`
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+		Output     output `json:"output"`
+	}{
+		Name:       reviewName(),
+		HeadSHA:    sha,
+		Status:     "completed",
+		Conclusion: "success",
+		Output:     out,
+	}
+	if len(failures) > 0 {
+		run.Conclusion = "failure"
+	}
+	if err := g.do("POST", fmt.Sprintf("/repos/%s/check-runs", g.repo), run, &run); err != nil {
+		return err
+	}
+	// Add the annotations beyond the first batch by updating the run.
+	for len(annotations) > 0 {
+		batch()
+		update := struct {
+			Output output `json:"output"`
+		}{out}
+		if err := g.do("PATCH", fmt.Sprintf("/repos/%s/check-runs/%d", g.repo, run.ID), update, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postGitHub posts the report to the pull request being built, and
+// annotates the functions of the benchmarks that regressed in tables,
+// found in the test files of the workspace. The annotations are
+// optional: if the workspace cannot be read, the check run is posted
+// without them.
+func postGitHub(report string, tables []*benchstat.Table, failures []string) error {
+	g, pr, sha, err := githubEnv()
+	if err != nil {
+		return err
+	}
+	if err := g.postComment(pr, truncateReport(report, githubMaxComment)); err != nil {
+		return err
+	}
+	workspace := os.Getenv("GITHUB_WORKSPACE")
+	if workspace == "" {
+		workspace = "."
+	}
+	funcs, err := findBenchmarks(workspace)
+	if err != nil {
+		log.Printf("warning: not annotating the check run: %v", err)
+	}
+	return g.postCheckRun(sha, truncateReport(report, githubMaxSummary), failures, githubAnnotations(tables, funcs))
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var flagGitLab = flag.Bool("gitlab", false, "post the comparison to the GitLab merge request being built, as a note, using GITLAB_TOKEN")
+
+// A gitlabClient posts results to a merge request using the GitLab REST API.
+type gitlabClient struct {
+	*apiClient
+	mr string // API path of the merge request
+}
+
+// gitlabEnv returns the client for the merge request pipeline
+// described by the GitLab CI environment.
+func gitlabEnv() (*gitlabClient, error) {
+	api := strings.TrimSuffix(os.Getenv("CI_API_V4_URL"), "/")
+	token := os.Getenv("GITLAB_TOKEN")
+	project, iid := os.Getenv("CI_PROJECT_ID"), os.Getenv("CI_MERGE_REQUEST_IID")
+	if api == "" || token == "" || project == "" {
+		return nil, fmt.Errorf("CI_API_V4_URL, GITLAB_TOKEN, and CI_PROJECT_ID must be set")
+	}
+	if iid == "" {
+		return nil, fmt.Errorf("not building a merge request")
+	}
+	mr := fmt.Sprintf("/projects/%s/merge_requests/%s", url.PathEscape(project), url.PathEscape(iid))
+	return &gitlabClient{newAPIClient(api, "Private-Token", token), mr}, nil
+}
+
+// postNote adds body as a note on the merge request, or replaces
+// the body of the note a previous run added.
+func (g *gitlabClient) postNote(body string) error {
+	return g.postSticky(commentThread{
+		list: func(page int) string {
+			return fmt.Sprintf("%s/notes?per_page=100&page=%d", g.mr, page)
+		},
+		add: g.mr + "/notes",
+		item: func(id int64) string {
+			return fmt.Sprintf("%s/notes/%d", g.mr, id)
+		},
+		update: "PUT",
+	}, body)
+}
+
+// gitlabMaxNote is the most characters a note may hold.
+const gitlabMaxNote = 1000000
+
+// postGitLab posts the report to the merge request being built.
+func postGitLab(report string) error {
+	g, err := gitlabEnv()
+	if err != nil {
+		return err
+	}
+	return g.postNote(truncateReport(report, gitlabMaxNote))
+}
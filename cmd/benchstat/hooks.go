@@ -0,0 +1,49 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var (
+	flagExecBefore = flag.String("exec-before", "", "run `command` with the JSON tables on its standard input before printing them")
+	flagExecAfter  = flag.String("exec-after", "", "run `command` with the JSON tables on its standard input after printing them and any reports")
+)
+
+// runHook runs command, split into fields, with the JSON output of
+// tables on its standard input and its standard output and error
+// going to benchstat's standard error, so as not to mix with the
+// tables. The variable BENCHSTAT_RESULT in its environment is "fail"
+// if benchstat is to exit with status 1 and "pass" otherwise. Values
+// are converted to units, as by formatJSON.
+func runHook(command string, tables []*benchstat.Table, units []string, failed bool) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	var in bytes.Buffer
+	formatJSON(&in, tables, units)
+	result := "pass"
+	if failed {
+		result = "fail"
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = &in
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "BENCHSTAT_RESULT="+result)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "flag"
+
+var flagInteractive = flag.Bool("interactive", false, "add controls to html output for filtering benchmarks by name, unit, and change")
+
+// htmlControls are the controls added to HTML reports by -interactive,
+// which htmlScript, following the tables, makes work.
+const htmlControls = `<div class='benchstat-controls'>
+<input type='search' id='benchstat-filter' placeholder='Filter benchmarks'>
+<select id='benchstat-changes'>
+<option value=''>all benchmarks
+<option value='changed'>significant changes
+<option value='worse'>regressions
+</select>
+<span id='benchstat-units'></span>
+</div>
+`
+
+// htmlScript filters the rows of the tables preceding it, leaving
+// group headings in place. The filter text is a case-insensitive
+// regular expression, or a substring if it is not a valid one.
+// Each table, that is, each unit, has a checkbox to hide it.
+const htmlScript = `<script>
+(function() {
+	var filter = document.getElementById('benchstat-filter');
+	var changes = document.getElementById('benchstat-changes');
+	var bodies = document.querySelectorAll('table.benchstat:not(.missing) > tbody');
+	var boxes = [];
+	for (var i = 0; i < bodies.length; i++) {
+		var box = document.createElement('input');
+		box.type = 'checkbox';
+		box.checked = true;
+		box.onchange = update;
+		var label = document.createElement('label');
+		label.appendChild(box);
+		label.appendChild(document.createTextNode(' ' + bodies[i].rows[0].cells[1].textContent + ' '));
+		document.getElementById('benchstat-units').appendChild(label);
+		boxes.push(box);
+	}
+	function update() {
+		var text = filter.value, re = null;
+		try {
+			re = new RegExp(text, 'i');
+		} catch (e) {}
+		for (var i = 0; i < bodies.length; i++) {
+			var rows = bodies[i].rows;
+			bodies[i].style.display = boxes[i].checked ? '' : 'none';
+			for (var j = 1; j < rows.length; j++) {
+				var row = rows[j];
+				if (row.className == 'group' || row.cells.length < 2) {
+					continue;
+				}
+				var name = row.cells[0].textContent;
+				var show = re ? re.test(name) : name.toLowerCase().indexOf(text.toLowerCase()) >= 0;
+				if (changes.value == 'changed') {
+					show = show && (row.className == 'better' || row.className == 'worse');
+				} else if (changes.value == 'worse') {
+					show = show && row.className == 'worse';
+				}
+				row.style.display = show ? '' : 'none';
+			}
+		}
+	}
+	filter.oninput = update;
+	changes.onchange = update;
+})();
+</script>
+`
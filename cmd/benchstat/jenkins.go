@@ -0,0 +1,93 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagJenkinsPlot = flag.String("jenkins-plot", "", "write a CSV file per benchmark to `dir` for the Jenkins Plot plugin")
+
+// A plotSeries is the data point, in each unit, that a build
+// contributes to the plot of a benchmark.
+type plotSeries struct {
+	name   string
+	units  []string
+	values []string
+}
+
+// writeJenkinsPlots writes a CSV file to dir for each benchmark in
+// tables, in the format read by the Jenkins Plot plugin: a row of
+// units followed by a row of the benchmark's values in those units,
+// taken from the last configuration. Plotting the files of each
+// build charts the benchmark's trend across builds.
+func writeJenkinsPlots(dir string, tables []*benchstat.Table) error {
+	var series []*plotSeries
+	byName := make(map[string]*plotSeries)
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			m := row.Metrics[len(row.Metrics)-1]
+			if m.Unit == "" {
+				continue
+			}
+			name := row.Benchmark
+			if row.Group != "" {
+				name = row.Group + " " + name
+			}
+			s := byName[name]
+			if s == nil {
+				s = &plotSeries{name: name}
+				byName[name] = s
+				series = append(series, s)
+			}
+			s.units = append(s.units, m.Unit)
+			// Single precision is ample for a plot and keeps
+			// rounding noise in the mean out of the file.
+			s.values = append(s.values, strconv.FormatFloat(m.Center, 'f', -1, 32))
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	for _, s := range series {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(s.units)
+		w.Write(s.values)
+		w.Flush()
+		if err := ioutil.WriteFile(filepath.Join(dir, plotFileName(s.name)), buf.Bytes(), 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plotFileName returns the name of the plot file for the named
+// benchmark.
+func plotFileName(name string) string {
+	return safeFileName(name) + ".csv"
+}
+
+// safeFileName returns name with the characters that are unsafe in
+// file names replaced.
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, name)
+}
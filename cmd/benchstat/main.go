@@ -29,16 +29,571 @@
 // The -delta-test option controls which significance test is applied:
 // utest (Mann-Whitney U-test), ttest (two-sample Welch t-test), or none.
 // The default is the U-test, sometimes also referred to as the Wilcoxon rank
-// sum test.
+// sum test. The -alpha option sets the p-value below which a change is
+// considered significant, 0.05 by default. Since one setting rarely suits
+// both nearly deterministic units like allocs/op and noisy ones like ns/op,
+// both options also accept per-unit settings following the default,
+// as in -delta-test utest,allocs/op=ttest or -alpha 0.05,allocs=0.01.
 //
 // If invoked on more than two input files, benchstat prints the per-benchmark
 // statistics for all the files, showing one column of statistics for each file,
 // with no column for percent change or statistical significance.
+// The -delta-mode option adds those columns after each file but the first:
+// -delta-mode first compares each file with the first, as when measuring several
+// candidate changes against one baseline, and -delta-mode previous compares
+// each file with the one before it, as when each file measures the next step
+// of a series of changes. With -stat-columns, each delta is followed by its
+// p-value.
+//
+// The -pick-best option follows the text tables with a summary choosing, for
+// each benchmark and unit, the best of the input files, as when each file
+// measures one of several variants of an optimization. A file wins a benchmark
+// if it is significantly better than every other file, as by -delta-test;
+// otherwise the summary shows ~ before the best file, which it did not
+// significantly beat. The geometric mean of all benchmarks, which is not
+// tested, names the file that is best overall.
+//
+// The -compat benchcmp option prints a comparison of two files in the layout
+// of the retired benchcmp command, for scripts and documentation written
+// around it: a section for each of ns/op, MB/s, allocs/op, and B/op, giving
+// the old and new value of each benchmark and the percent change, or for
+// MB/s the speedup. Each value is the center of the benchmark's runs, so
+// -center best approximates benchcmp -best. Unlike the usual tables, the
+// layout shows neither the variation of the values nor the significance of
+// the changes.
+//
+// Before computing statistics, benchstat discards outliers: by default,
+// values more than 1.5 times the interquartile range outside the first and
+// third quartiles. The -outliers option selects a different policy: none
+// keeps all values, zscore:k discards values more than k standard deviations
+// from the mean, for k > 0, and percent:p discards the lowest and highest p
+// percent of values. With -v, benchstat also prints how many values were
+// discarded from each benchmark, followed by each discarded value and the
+// limit it fell outside of, since silently discarding values can hide a
+// genuinely bimodal result. The -v option requires text output.
+//
+// The -stat-columns option prints the sample size of every value, and the
+// p-value of every delta, in columns of their own, in every output format
+// and for any number of input files, instead of in the note that follows
+// the delta when comparing two files.
+//
+// The -abs-delta option prints the absolute difference of each significant
+// change, such as -1.81ms or +32.0B, next to the percent change, since a
+// large percentage of a small value may matter less than it seems.
 //
 // The -output option causes benchstat to print the results as an either text,
-// HTML, or json table.
+// HTML, json, CSV, or Markdown table. When comparing two files, each json
+// row also gives the delta as a number, in percent, with the bounds of its
+// confidence interval at level 1-α, as Delta, DeltaLow, and DeltaHigh, so
+// that a CI gate can require the whole interval to exceed a threshold.
+// Delta compares the centers of the samples, which may lie outside the
+// interval; DeltaEstimate gives the Hodges-Lehmann estimate of the delta,
+// the median change between pairs of values, which the interval brackets.
+// CSV output gives the same numbers in its last columns, delta, delta_low,
+// delta_high, and delta_estimate.
+// Each row of values in json and CSV output, and each line of benchdiff
+// output, also carries an id, a hash of the benchmark's group, name, and
+// unit, which stays the same from report to report however the tables are
+// laid out, so that other systems can track a benchmark across reports.
+// Programs embedding benchstat can write tables in any of these formats
+// with benchstat.Format.
+//
+// The -output=benchdiff option prints only the outcome of comparing two
+// files, in the stable, versioned benchdiff format defined by
+// benchstat.Benchdiff, for CI gate tooling to consume: a JSON header line
+// with the decision of the gate on the comparison, followed by a JSON line
+// for each benchmark and unit giving the direction of the change, its
+// delta and confidence interval in percent, its p-value, and, with -fail,
+// whether it passed the gate.
+//
+// The -raw option causes benchstat to print results as unscaled values,
+// to full precision.
+//
+// The -reservoir option bounds the memory used for very large inputs,
+// such as long concatenated CI histories. Input files are always
+// streamed rather than read into memory; with -reservoir n, benchstat
+// additionally keeps only a uniform random sample of at most n values
+// for each benchmark and unit, and computes statistics from that sample.
+//
+// The -split, -filter, and -pivot options work with labels: the
+// configuration keys of the input files, such as goos, cpu, or any custom key
+// written by the tool that ran the benchmarks, and the labels parsed from
+// benchmark names. The -split option splits the benchmarks into separate
+// tables by the values of the listed labels, by default pkg, goos, and goarch.
+// The -filter option keeps only the results whose labels match each of a
+// comma-separated list of terms: key:pattern requires a label to match the
+// pattern, in which * matches any text and | separates alternatives, and
+// -key:pattern requires it not to. The -pivot option compares the values of
+// the listed labels instead of the input files, so that a single file merging
+// the results of several machines compares the machines. For example,
+//
+//	benchstat -filter 'goarch:amd64,-builder:*-race' -pivot machine all.txt
+//
+// compares the amd64 machines recorded in all.txt, leaving out the race
+// builders.
+//
+// The -label option attaches a label, written key=value, to every result
+// read, overriding any value of the same key that the input records. Labels
+// attached this way can be split, filtered, and pivoted by like any other,
+// and appear in every output format wherever the results are grouped by them.
+// For example,
+//
+//	benchstat -label runner=gha-ubuntu -split runner,pkg old.txt new.txt
+//
+// groups the results under the runner that measured them. Benchsave accepts
+// the same option, to record such labels with the uploaded results.
+//
+// Results may record the Go version they were measured with under the key go,
+// go-version, goversion, toolchain, or version, as benchrun does, either as a
+// version like go1.13.4 or as the output of "go version". The -go-version
+// option gives the version of the results in each input file that record
+// none, as a comma-separated list in the order of the files, or a single
+// version for all of them. Either way, the version becomes the label go, by
+// which results can be split, filtered, and pivoted. For example,
+//
+//	benchstat -pivot go all.txt
+//
+// compares the same code built with each Go version recorded in all.txt, and
+//
+//	benchstat -go-version go1.12,go1.13 old.txt new.txt
+//
+// labels the results of old.txt and new.txt with their Go versions.
+//
+// The -require-same option refuses to compare inputs whose values of any of
+// the listed labels differ, exiting with status 1 instead. For example,
+//
+//	benchstat -require-same cpu,goos,goarch,go-version old.txt new.txt
+//
+// fails if old.txt was measured on a laptop and new.txt on a CI machine, or
+// with different Go versions, rather than reporting changes that the
+// difference in environment, not the code, explains.
+//
+// The -machine-baseline option normalizes each value by the median of the
+// same benchmark and unit in the given file of historical results measured on
+// the same machine, as identified by the labels listed by -machine-by,
+// "machine" by default, which benchsave -machine records. The normalized
+// values are ratios, shown in units such as ns/op/base, and values from
+// machines with no baseline are dropped. Normalizing makes results from a
+// fleet of differing machines comparable, for when old and new could not run
+// on identical hardware. For example,
+//
+//	benchstat -machine-baseline history.txt old.txt new.txt
+//
+// compares old.txt and new.txt relative to each machine's history.
+//
+// When comparing two inputs, text output begins with the configuration keys
+// whose values differ between them, such as the cpu or Go version, in the
+// style of a unified diff:
+//
+//	--- old.txt
+//	+++ new.txt
+//	-cpu: Intel(R) Core(TM) i7-8650U CPU @ 1.90GHz
+//	+cpu: Intel(R) Xeon(R) CPU E5-2690 v4 @ 2.60GHz
+//
+// so that readers see at once what besides the code differed between the
+// runs. The -config-diff=false option omits it.
+//
+// An input may also be a directory, whose files, read in order by name, make
+// up a single input, as when a CI matrix writes the results of each platform
+// to a file of its own. The -matrix option compares two such inputs on each
+// platform, printing for each unit a matrix with a row for each benchmark and
+// a column for each combination of values of the listed labels, showing the
+// change from old to new on that platform. For example,
+//
+//	benchstat -matrix goos,goarch old/ new/
+//
+// prints a column for each of linux/amd64, darwin/arm64, and the other
+// platforms in the directories old and new.
+//
+// The -wide option prints a comparison of two inputs as a single table, with
+// the old, new, and delta columns of each unit, such as time/op, alloc/op,
+// and allocs/op, side by side in a row for each benchmark, in place of a
+// table per unit.
+//
+// The -sort name option orders the groups of each table, and the benchmarks
+// of each group, by name rather than as they were read in, so that reports of
+// inputs written in different orders can be compared line by line. Names sort
+// in natural order, comparing runs of digits by their value, so that Encode/2
+// sorts before Encode/10.
+//
+// The -rollup option adds the geometric mean of each group of benchmarks to
+// the end of the group, and, when comparing two inputs, precedes the tables
+// with a summary table per unit ranking the groups by the change in their
+// geometric mean, from the worst regression to the best improvement. Split
+// by package, as in
+//
+//	benchstat -split pkg -rollup old.txt new.txt
+//
+// it summarizes a sweep over the benchmarks of a large repository by package.
+//
+// The -subtotals option adds the same subtotal rows, without the summary
+// tables, and follows each table with a row giving the geometric mean of all
+// its benchmarks, as -geomean does, in every output format, so that a large
+// report split by package carries its own rollups.
+//
+// The -warmup option discards the first n results of each benchmark in each
+// input file, as written by "go test -count", before computing statistics.
+// The first runs of a benchmark are often slower than the rest, as caches
+// fill and memory pages fault in, even in Go, which compiles ahead of time;
+// discarding them keeps that start-up cost out of the comparison.
+//
+// The -cache option names a directory in which benchstat saves the parsed
+// form of each input file, keyed by a hash of the file's content. Later
+// invocations over unchanged files, as in watch or CI loops over large
+// history files, load the cached form instead of parsing the file again.
+//
+// The -save-state option saves the results read from the input files to a
+// file, and the -load-state option adds the results saved in such a file to
+// those of any input files. CI can parse the results of a large sweep once,
+// as in "benchstat -save-state sweep.state old.txt new.txt", and later
+// print them again in any format, as in
+// "benchstat -load-state sweep.state -output html", without the inputs.
+//
+// An input file ending in .csv or .json is taken to be the CSV or JSON
+// output of an earlier benchstat, which records the size, mean, and
+// variance of the sample behind each value it shows. The last configuration
+// it shows, such as the new side of a comparison, becomes a configuration
+// of its own, so that today's results can be compared with a stored summary
+// without keeping the original results:
+//
+//	benchstat -output json base.txt > base.json
+//	benchstat base.json new.txt
+//
+// Summaries are compared by their means, with the t-test unless
+// -delta-test is none.
+//
+// By default, benchstat scales each row of a table to the most readable unit,
+// so that one row may be in µs and the next in ms. The -time-unit and
+// -size-unit options instead display every time or size, in every output
+// format, in the given unit, such as -time-unit ms or -size-unit KiB,
+// so that values are directly comparable across rows and tables.
+// Sizes are scaled with decimal prefixes, as in kB and MB, unless
+// -size-prefix binary selects binary ones, as in KiB and MiB.
+//
+// Values are shown to three significant digits, and percent changes to two
+// decimal places. The -digits and -delta-precision options show more, or
+// fewer, as in -digits 5 -delta-precision 3. The -decimal and -thousands
+// options set the separators of the numbers shown, for readers used to
+// other conventions: -decimal , -thousands . shows 1234.5 as 1.234,5.
+// JSON output gives each value to full precision and without separators.
+//
+// Benchmarks that process a variable number of elements per operation
+// can report the count with b.ReportMetric(n, "items/op"). For each such
+// result, benchstat divides the other per-op measurements by the count
+// to derive per-item metrics like ns/item and B/item, so that per-element
+// costs can be compared directly. The -per-item option names a different
+// counting unit, optionally followed by = and the name of one item, as in
+// -per-item queries/op=query for ns/query; without a name, the unit less its
+// /op names the items. -per-item "" disables these metrics.
+//
+// The -derive option computes a new unit from the other measurements of each
+// result, as in -derive 'bytes-per-alloc = B/op / allocs/op'. Expressions may
+// use numbers, units, parentheses, and the operators +, -, *, and /, which must
+// be separated from units by spaces. Derived units are compared and printed like
+// any other, and -derive may be repeated, with later expressions able to use
+// units derived by earlier ones.
+//
+// The -rename option rewrites the name of each benchmark matching a regular
+// expression, as in -rename '^Marshal(/|-|$)=>Encode$1', so that results
+// recorded before and after a benchmark was renamed compare with each other.
+// Each $n in the replacement stands for the text of a parenthesized part of
+// the expression, and the replacement may be empty, as in
+// -rename '/hash=[0-9a-f]+=>', which strips a sub-benchmark naming the commit
+// measured. The option may be repeated, and each rule rewrites the names
+// written by the rules before it. Results renamed to nothing are dropped.
+//
+// When comparing two files, the -efficiency option replaces the separate
+// time/op, alloc/op, and allocs/op tables with a single table showing the
+// change in all three for each benchmark, along with a "memory pressure"
+// column summarizing the changes in alloc/op and allocs/op as lower,
+// higher, mixed, or ~ for no significant change.
+//
+// Different harnesses, or different versions of one harness, may report
+// the same measurement in different units, such as ns/op and µs/op or
+// B/s and MB/s. The -normalize-units option converts every time to ns,
+// every size to B, and every rate of size to MB/s as the input is read,
+// so that such results are compared in a single table.
+//
+// Whether an increase in a value is an improvement or a regression
+// depends on its unit. Rates, like MB/s or ops/s, are better when higher;
+// all other units are better when lower. Input files can override this
+// with unit metadata lines, such as "Unit score better=higher", and the
+// -better option overrides both, as in -better score=higher,MB/s=lower.
+//
+// Benchstat prints a table for each unit in its input, including custom
+// units reported with testing.B.ReportMetric, like p99-ms or cache-misses/op.
+// The -units option prints only the listed units, given by name or by one of
+// the abbreviations ns, b, and allocs for ns/op, B/op, and allocs/op.
+// Custom units are scaled as plain numbers unless their name ends in a unit
+// of time or size, as p99-ms does. A unit metadata line such as
+// "Unit latency scale=ms" sets the scale explicitly, and scale=none
+// disables scaling.
+//
+// The -fail option makes benchstat usable as a CI gate: after printing
+// its output, benchstat exits with status 1 if any statistically significant
+// regression exceeds the given percentage. Since memory metrics are stable
+// enough to gate more tightly than times, the threshold may be set per unit,
+// as in -fail 5%,allocs/op=1%,B/op=1%. With only per-unit thresholds,
+// other units are not gated. Gating many benchmarks at once, some will
+// regress significantly by chance alone; the -fail-correction option
+// corrects the significance of regressions for the number of benchmarks
+// compared, by the bonferroni or the less strict holm method. The
+// golang.org/x/perf/benchstat/gate package makes the same decisions,
+// for bots and servers gating changes.
+//
+// A comparison of too few samples can never be significant: with the U-test,
+// at least four samples of each benchmark are needed for p < 0.05. Rather than
+// let "~ (p=1.000 n=1+1)" be misread as "no change", benchstat prints a warning
+// for such comparisons, and the -min-count option makes benchstat exit with
+// status 1 if any benchmark has fewer than the given number of samples.
+//
+// The -center option chooses the statistic shown for each benchmark, and
+// compared by the delta column: mean, the default, or median. The median
+// is not pulled by a long tail of slow runs that survive outlier
+// rejection, as is common in latency benchmarks. On noisy shared machines,
+// where interference only ever slows a benchmark down, the convention is
+// to compare the best of N runs: -center best compares the fastest run of
+// each benchmark, or, for units where higher is better, like MB/s, the
+// largest value. -center min and -center max choose the smallest or largest
+// value of every unit, and a percentile, like -center p10, is less swayed
+// by one lucky run. Like -delta-test, -center may be given per unit, as in
+// -center best,allocs/op=mean. The ± variation is relative to the chosen
+// statistic.
+//
+// Discrete measurements, like allocs/op, are compared without the delta
+// test when it would mislead. If every run in each file reports the same
+// value for a benchmark, any difference is exact and is reported as such,
+// with a note of "(all equal)" or "(exact)" in place of the p-value, which
+// for such samples depends only on their size. If the B/op or allocs/op
+// values, which go test rounds to integers, all lie within one of each
+// other, and the centers of the two files within half of one, the delta is
+// "~ (within rounding)". These rules do not apply with -delta-test=none.
+//
+// Comparisons of samples that look to have been measured with different go
+// test settings are annotated, as they may not be comparable: when one sample
+// has at least twice as many values as the other, the note reads "(different
+// -count)", and when the time measured per result differs by a factor of two
+// or more, which go test keeps near -benchtime, it reads "(different
+// -benchtime)".
+//
+// By default, a benchmark measured in only some of the input files is omitted
+// from a comparison of two files, which then lists the benchmarks added and
+// removed after the tables, and is shown with blanks in the missing columns
+// otherwise. The -missing option changes this: -missing=blank shows blanks in
+// every table, -missing=hide omits such benchmarks from every table and lists
+// them after the tables, and -missing=fail also makes benchstat exit with
+// status 1 if any benchmark is missing from some input.
+//
+// The -plot option adds a column to html output showing the distribution of
+// each benchmark's values in each input file as an inline SVG image.
+// -plot=box draws a box plot: a box spanning the middle half of the values,
+// split at the median, with whiskers reaching to the extremes of the values
+// kept after discarding outliers, and a dot for each outlier. -plot=ecdf
+// overlays the empirical cumulative distribution functions of the values in
+// each input file, drawing the last file in the color of the text, which
+// shows whether the whole distribution moved or only its tail. -plot=trend,
+// for input files measured over time, such as nightly runs given in order,
+// scatters the values of each file in the order they were measured, in
+// alternating colors, with a least-squares trend line, so that gradual
+// drifts show. -plot=term instead adds plots drawn with Unicode block
+// characters to text output, for a quick look without a browser: a bar for
+// the change of each benchmark between two input files, ranked as by
+// -delta-chart, and a histogram of the values of each benchmark in each
+// input file.
+//
+// The -heatmap option colors the values in html output by how they compare
+// with the first input file: red if worse and green if better, deepening
+// with the size of the difference up to 25%. Comparing many input files,
+// such as one per configuration, this shows at a glance which
+// configurations regress which families of benchmarks.
+//
+// The -delta-chart option writes an SVG image to the named file charting the
+// change of each benchmark between two input files as a bar, ranked from the
+// greatest improvement at the top to the greatest regression at the bottom.
+// Improvements extend left in green and regressions right in red, while
+// changes that are not significant are gray, giving release notes a single
+// picture of what got faster, what got slower, and by how much.
+//
+// The -vega-lite option writes Vega-Lite specifications of standard charts,
+// with their data inline, to the named directory, for embedding and
+// restyling in notebooks and web pages: delta.vl.json charts the changes
+// between two input files as -delta-chart does, and samples.vl.json shows
+// box plots of the values of each benchmark in each input file, a row of
+// plots for each unit.
+//
+// The -owners option reads a file assigning benchmarks to their owners, such
+// as the teams maintaining them, and lists the significant regressions in
+// text output by owner, after the tables. Each line of the file holds a
+// pattern and one or more owners:
+//
+//	# pattern             owners
+//	example.com/store.*   storage-team
+//	*.Encode*             codec-team @alice
+//
+// A pattern matches a benchmark's package path, its pkg label, and name
+// joined by a dot, such as example.com/store.Get-8, in which * matches any
+// text; the last line matching a benchmark assigns its owners, and
+// benchmarks matching none are unowned. The -owner-dir option also writes
+// each owner's part of the comparison, as text output, to a file in the
+// named directory named for the owner, such as storage-team.txt, ready to
+// send to that owner.
+//
+// The -report-dir option writes a static report to the named directory,
+// ready to publish as a CI artifact or a web site: an index.html holding the
+// html output, preceded by the delta chart when comparing two input files
+// and links to a page for each table, the chart as delta.svg, the Vega-Lite
+// specifications written by -vega-lite, and the data as results.json, in
+// the form of -output=json, and results.csv, listing every value.
+//
+// The -interactive option adds controls to html output, implemented in
+// embedded JavaScript with no external dependencies, for navigating large
+// reports: a search box that shows only the benchmarks matching a regular
+// expression, a checkbox for each unit's table, and a choice of showing all
+// benchmarks, only significant changes, or only regressions.
+//
+// The -html-theme option selects the colors of html output: light, the
+// default, or dark. The -html-css option adds the style sheet in the named
+// file after the built-in styles, to fit the output into a page with its own
+// styling. The tables and their rows and cells have stable class names for
+// such style sheets to use: tables are benchstat, and oldnew if comparing
+// two input files; rows are configs, heading, group, spacer, or rows of
+// values, classed better, worse, or unchanged when comparing; and cells are
+// name, value, n, plot, delta or nodelta, absdelta, p, and note.
+//
+// The -github option, for use in a GitHub Actions workflow run for a pull
+// request, posts the comparison as a comment on the pull request, along
+// with any failures from -fail, -min-count, or -missing=fail, and adds a
+// "benchstat" check run that fails if there are any. The check run annotates
+// the function of each benchmark that regressed, found in the test files
+// under GITHUB_WORKSPACE, so that regressions show in the pull request's
+// diff. Later runs update the same comment rather than adding another. It
+// uses the GITHUB_TOKEN, GITHUB_REPOSITORY, GITHUB_EVENT_PATH, and
+// GITHUB_API_URL environment variables; the token needs permission to write
+// pull requests and checks.
+//
+// The -gitlab option, for use in a GitLab CI merge request pipeline, posts
+// the same report as a note on the merge request, updating the note from
+// earlier runs. It uses the CI_API_V4_URL, CI_PROJECT_ID, and
+// CI_MERGE_REQUEST_IID variables that GitLab CI sets, and a GITLAB_TOKEN
+// variable holding an access token with the api scope, since the job
+// token cannot post notes.
+//
+// The -bitbucket option, for use in Bitbucket Pipelines, publishes the
+// comparison as a "benchstat" report on the commit being built, which
+// fails if there are any failures and is annotated with each failure and
+// regression, so that they show on the pull request. It uses the
+// BITBUCKET_WORKSPACE, BITBUCKET_REPO_SLUG, and BITBUCKET_COMMIT variables
+// that Pipelines sets, and authenticates through the Pipelines proxy, or
+// with the access token in BITBUCKET_TOKEN if set.
+//
+// The -azure option, for use in Azure Pipelines, attaches the same report as
+// a tab of the run's summary and uploads the HTML comparison as a
+// "benchstat" artifact, using logging commands printed after the output.
+// Failures and warnings are also logged as errors and warnings, which the
+// run lists as issues. The files are written under AGENT_TEMPDIRECTORY.
+//
+// A run finds its earlier comment or note by a hidden marker, edits it
+// only if the comparison changed, and deletes any duplicates left by
+// concurrent runs. To post several comparisons to the same change, such as
+// one per platform, give each run a -review-id, which names its comment,
+// check run, and report.
+//
+// The -top n option keeps comments on large suites within the size limits of
+// these platforms: it shows only the n benchmarks that changed the most, by
+// the largest factor of any of their significant changes, and summarizes the
+// rest in lines like "312 benchmarks unchanged" after the tables of text and
+// markdown output.
+// Without it, a report too long for a platform is cut short at the end of its
+// comparison, keeping the failures and warnings that precede it.
+//
+// The -teamcity option also prints TeamCity service messages. Each result of
+// the last input file is reported as a build statistic named for the
+// benchmark and unit, which TeamCity can chart across builds. When comparing
+// two input files, each comparison is also reported as a test in a
+// "benchstat" suite, which fails if the regression exceeds the -fail
+// threshold, and any other failures are reported as build problems.
+//
+// The -update-baseline option writes the results of the last input file to
+// the named baseline file, which can be checked in alongside the benchmarks.
+// The -against option compares the results of the last input file with such
+// a baseline, and exits with status 1 if a result is worse than its baseline
+// value by more than its tolerance, or if a benchmark in the baseline was
+// not measured. Each tolerance is a percentage: the larger of the -tolerance
+// flag for its unit, 5% by default, and the variation of the result when the
+// baseline was written. Tolerances raised by hand in the baseline file are
+// kept when it is updated. For example:
+//
+//	benchstat -update-baseline baseline.json new.txt
+//	benchstat -against baseline.json new.txt
+//
+// The -budget-state option keeps a performance budget in the named file.
+// Each comparison of two input files multiplies the drift of every benchmark
+// recorded there by its ratio of new to old value, whether or not the change
+// is significant. With -budget, benchstat exits with status 1 if the drift
+// of a benchmark in the worse direction exceeds the threshold, as a
+// percentage, optionally given per unit as for -fail. A series of small
+// regressions, each lost in the noise of its own comparison, thus fails
+// once together they spend the budget. Run benchstat once per commit, and
+// remove the file to start the budget afresh, say for a new release:
+//
+//	benchstat -budget 3% -budget-state budget.json -budget-id $COMMIT old.txt new.txt
+//
+// The file also records the -budget-id of each comparison counted, by default
+// a hash of the compared values, so that a retried run, which would otherwise
+// count its changes twice, leaves the drift as it was.
 //
-// The -raw option causes benchstat to print results as unscaled values.
+// The -analyzer option runs the named command, with any arguments separated
+// by spaces, as an analyzer of the results, so that an organization can add
+// analyses of its own without changing benchstat. Benchstat writes to the
+// standard input of the command a JSON object giving the configurations and,
+// for each benchmark in each unit, the unit, group, and benchmark, the values
+// in each configuration without outliers, their centers, and, when comparing
+// two configurations, the delta, ratio, p-value, and change, which is 1 if
+// better and -1 if worse. The command writes to its standard output a JSON
+// object of annotations of rows, as in
+//
+//	{"annotations": [
+//		{"unit": "ns/op", "benchmark": "Encode-8", "note": "[hot path]", "verdict": "fail"}
+//	]}
+//
+// Benchstat adds the note of each annotation to the note of its row, and
+// prints it as a warning if the verdict is "warn" and as a failure, exiting
+// with status 1, if the verdict is "fail". A verdict of "pass", or none, only
+// adds the note.
+//
+// The -exec-before and -exec-after options run the named commands, with any
+// arguments separated by spaces, before printing the tables and after printing
+// them and any reports, as when archiving a report and then notifying a chat
+// channel. Each command reads the tables, as printed by -output json, on its
+// standard input, and finds BENCHSTAT_RESULT in its environment set to "fail"
+// if benchstat is to exit with status 1 and to "pass" otherwise. The output of
+// the commands goes to the standard error of benchstat. If the -exec-before
+// command fails, benchstat exits without printing the tables; if the
+// -exec-after command fails, benchstat exits with status 1.
+//
+//	benchstat -exec-after "./notify.sh #perf" old.txt new.txt
+//
+// The -jenkins-plot option writes a CSV file for each benchmark to the named
+// directory, in the format read by the Jenkins Plot plugin: a row of units
+// followed by a row of the benchmark's values, from the last input file.
+// Archiving the files of each build and plotting them by column name charts
+// each benchmark across builds.
+//
+// The bisect subcommand finds the commit that introduced a performance
+// regression. It runs a benchmark command at a known good commit, then
+// drives git bisect between that commit and a known bad one, HEAD by
+// default, running the command at each commit git bisect checks out and
+// comparing its results with those at the good commit. A commit is bad if
+// any benchmark regressed significantly, or by more than the -fail
+// threshold, and is skipped if the command fails. Finally, benchstat prints
+// the first bad commit and its comparison with the good one, and resets the
+// repository:
+//
+//	benchstat bisect -good go1.12 go test -run=NONE -bench=Encode -count=10 ./encoding/gob
+//
+// The -cpuprofile, -memprofile, and -trace options write a CPU profile,
+// memory profile, or execution trace of benchstat itself to the named file,
+// for diagnosing slow analyses of large inputs.
 //
 // Example
 //
@@ -100,17 +655,93 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"golang.org/x/perf/benchstat"
+	"golang.org/x/perf/benchstat/gate"
+	"golang.org/x/perf/storage/benchfmt"
 )
 
 const (
-  _text = "text"
-  _html = "html"
-  _json = "json"
+	_text = "text"
+	_html = "html"
+	_json = "json"
+	_csv  = "csv"
+	_md   = "markdown"
+	_diff = "benchdiff"
 )
 
+func init() {
+	flag.Var(&flagDerive, "derive", "compute a new unit from each result's other units, as in 'bytes-per-alloc = B/op / allocs/op' (may be repeated)")
+	flag.Var(&flagLabels, "label", "attach the label `key=value` to every result (may be repeated)")
+	flag.Var(&flagRename, "rename", "rewrite benchmark names matching a regular expression, as in 'pattern=>replacement' (may be repeated)")
+}
+
+// labels is a flag.Value collecting the -label flags.
+type labels benchfmt.Labels
+
+func (l *labels) String() string {
+	var s []string
+	for _, k := range benchfmt.Labels(*l).Keys() {
+		s = append(s, k+"="+(*l)[k])
+	}
+	return strings.Join(s, ",")
+}
+
+func (l *labels) Set(s string) error {
+	key, value, err := benchstat.ParseLabel(s)
+	if err != nil {
+		return err
+	}
+	if *l == nil {
+		*l = make(labels)
+	}
+	(*l)[key] = value
+	return nil
+}
+
+// derivations is a flag.Value collecting the -derive flags.
+type derivations []*benchstat.Derivation
+
+func (ds *derivations) String() string {
+	var s []string
+	for _, d := range *ds {
+		s = append(s, d.String())
+	}
+	return strings.Join(s, "; ")
+}
+
+func (ds *derivations) Set(s string) error {
+	d, err := benchstat.ParseDerivation(s)
+	if err != nil {
+		return err
+	}
+	*ds = append(*ds, d)
+	return nil
+}
+
+// renameRules is a flag.Value collecting the -rename flags.
+type renameRules []*benchstat.RenameRule
+
+func (rs *renameRules) String() string {
+	var s []string
+	for _, r := range *rs {
+		s = append(s, r.String())
+	}
+	return strings.Join(s, "; ")
+}
+
+func (rs *renameRules) Set(s string) error {
+	r, err := benchstat.ParseRenameRule(s)
+	if err != nil {
+		return err
+	}
+	*rs = append(*rs, r)
+	return nil
+}
+
 func usage() {
 	fmt.Fprintf(os.Stderr, "usage: benchstat [options] old.txt [new.txt] [more.txt ...]\n")
 	fmt.Fprintf(os.Stderr, "options:\n")
@@ -119,14 +750,50 @@ func usage() {
 }
 
 var (
-	flagDeltaTest = flag.String("delta-test", "utest", "significance `test` to apply to delta: utest, ttest, or none")
-	flagAlpha     = flag.Float64("alpha", 0.05, "consider change significant if p < `α`")
+	flagDeltaTest = flag.String("delta-test", "utest", "significance `test` to apply to delta: utest, ttest, or none, optionally per unit, as in utest,allocs/op=none")
+	flagDeltaMode = flag.String("delta-mode", "", "with three or more inputs, compare each with the `first` or previous input")
+	flagAlpha     = flag.String("alpha", "0.05", "consider change significant if p < `α`, optionally per unit, as in 0.05,allocs/op=0.01")
+	flagCenter    = flag.String("center", "mean", "`statistic` to show and compare deltas of: mean, median, min, max, best, or a percentile like p10, optionally per unit")
 	flagGeomean   = flag.Bool("geomean", false, "print the geometric mean of each file")
+	flagConfDiff  = flag.Bool("config-diff", true, "when comparing two inputs, print the configuration keys whose values differ between them")
+	flagSubtotals = flag.Bool("subtotals", false, "add the geometric mean of each group, such as each package, and of all benchmarks to each table")
+	flagRollup    = flag.Bool("rollup", false, "print the geometric mean of each group, such as each package, and a summary ranking the groups by change")
+	flagSort      = flag.String("sort", "", "order benchmarks by `order`: name, in natural order, or as read in if empty")
 	flagSplit     = flag.String("split", "pkg,goos,goarch", "split benchmarks by `labels`")
-	flagUnits     = flag.String("units", "b,allocs,ns", "prints only the given units")
+	flagFilter    = flag.String("filter", "", "keep only results whose labels match each `key:pattern`, separated by commas")
+	flagPivot     = flag.String("pivot", "", "compare the values of `labels` instead of the input files")
+	flagSame      = flag.String("require-same", "", "refuse to compare inputs whose `labels`, such as cpu,goos,goarch,go-version, differ")
+	flagGoVersion = flag.String("go-version", "", "Go `versions` of the results in each input that record none, separated by commas, or one for all")
+	flagMachBase  = flag.String("machine-baseline", "", "normalize each value by the median of the same benchmark on the same machine in `file`")
+	flagMachineBy = flag.String("machine-by", "machine", "identify the machine of each result by `labels`")
+	flagUnits     = flag.String("units", "", "comma-separated list of `units` to print, such as ns,b,allocs or p99-ms (default all)")
+	flagStatCols  = flag.Bool("stat-columns", false, "print sample sizes and p-values in columns of their own")
+	flagAbsDelta  = flag.Bool("abs-delta", false, "print the absolute difference of each change next to the percentage")
+	flagTheme     = flag.String("html-theme", "light", "color `theme` of html output: light or dark")
+	flagCSS       = flag.String("html-css", "", "add the style sheet in `file` to html output, after the built-in styles")
+	flagHeatmap   = flag.Bool("heatmap", false, "color html output by each value's performance relative to the first input")
+	flagPlot      = flag.String("plot", "", "add a plot of each benchmark's values: `kind` box, ecdf, or trend for html output, or term for text output")
 	flagOnlyDiff  = flag.Bool("diff", false, "prints only if differences appears")
-	flagRawValues  = flag.Bool("raw", false, "the raw unscaled values are printed")
-	flagOutput = flag.String("output", "text", "output format: text (default), html, or json")
+	flagRawValues = flag.Bool("raw", false, "the raw unscaled values are printed")
+	flagOutput    = flag.String("output", "text", "output format: text (default), html, json, csv, markdown, or benchdiff")
+	flagReservoir = flag.Int("reservoir", 0, "keep at most `n` randomly sampled values per benchmark and unit (0 keeps all)")
+	flagWarmup    = flag.Int("warmup", 0, "discard the first `n` results of each benchmark in each input as warmup")
+	flagCache     = flag.String("cache", "", "cache parsed input files in `dir`, keyed by their content")
+	flagSaveState = flag.String("save-state", "", "save the results read to `file`, for printing later with -load-state")
+	flagLoadState = flag.String("load-state", "", "add the results saved in `file` by -save-state before those of any input files")
+	flagTimeUnit  = flag.String("time-unit", "", "display all times in `unit` (ns, µs, ms, or s) instead of scaling each row")
+	flagSizeUnit  = flag.String("size-unit", "", "display all sizes in `unit` (B, kB, MB, GB, KiB, MiB, GiB, ...) instead of scaling each row")
+	flagPrefix    = flag.String("size-prefix", "decimal", "scale sizes with `decimal` (kB, MB) or binary (KiB, MiB) prefixes")
+	flagDigits    = flag.Int("digits", 3, "show values to `n` significant digits")
+	flagPrecision = flag.Int("delta-precision", 2, "show percent changes to `n` decimal places")
+	flagDecimal   = flag.String("decimal", ".", "decimal separator `sep` of numbers shown")
+	flagThousands = flag.String("thousands", "", "separator `sep` of groups of thousands in numbers shown (default none)")
+	flagNormalize = flag.Bool("normalize-units", false, "convert equivalent units, like µs/op and ns/op, to one canonical unit")
+	flagPerItem   = flag.String("per-item", "items/op=item", "derive per-item metrics, like ns/item, from benchmarks reporting the count `unit`, which =name may follow to name one item")
+	flagDerive    derivations
+	flagLabels    labels
+	flagRename    renameRules
+	flagBetter    = flag.String("better", "", "comma-separated `unit=higher|lower` list overriding which direction is an improvement")
 )
 
 var deltaTestNames = map[string]benchstat.DeltaTest{
@@ -139,16 +806,80 @@ var deltaTestNames = map[string]benchstat.DeltaTest{
 	"ttest":  benchstat.TTest,
 }
 
+var centerNames = map[string]benchstat.Center{
+	"mean":   benchstat.MeanCenter,
+	"median": benchstat.MedianCenter,
+	"min":    benchstat.MinCenter,
+	"max":    benchstat.MaxCenter,
+}
+
+// parseCenter parses the name of a center: a name in centerNames or a
+// percentile, like p10 or p99.9.
+func parseCenter(name string) (benchstat.Center, error) {
+	name = strings.ToLower(name)
+	if center := centerNames[name]; center != nil {
+		return center, nil
+	}
+	if strings.HasPrefix(name, "p") {
+		if p, err := strconv.ParseFloat(name[1:], 64); err == nil && p >= 0 && p <= 100 {
+			return benchstat.PercentileCenter(p / 100), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown statistic %q", name)
+}
+
+// parseNumberFormat returns the NumberFormat with the separators set
+// by -decimal and -thousands.
+func parseNumberFormat(decimal, thousands string) (benchstat.NumberFormat, error) {
+	var f benchstat.NumberFormat
+	switch {
+	case decimal == "":
+		return f, fmt.Errorf("invalid -decimal: want a separator")
+	case strings.ContainsAny(decimal, "0123456789"):
+		return f, fmt.Errorf("invalid -decimal %q: contains a digit", decimal)
+	case strings.ContainsAny(thousands, "0123456789"):
+		return f, fmt.Errorf("invalid -thousands %q: contains a digit", thousands)
+	case decimal == thousands:
+		return f, fmt.Errorf("-decimal and -thousands are both %q", decimal)
+	}
+	if decimal != "." {
+		f.Decimal = decimal
+	}
+	f.Thousands = thousands
+	return f, nil
+}
+
+var deltaModeNames = map[string]benchstat.DeltaMode{
+	"":         benchstat.DeltaDefault,
+	"first":    benchstat.DeltaFirst,
+	"previous": benchstat.DeltaPrevious,
+}
+
+var plotNames = map[string]benchstat.Plot{
+	"box":   benchstat.BoxPlot,
+	"ecdf":  benchstat.ECDFPlot,
+	"trend": benchstat.TrendPlot,
+}
+
 var unitNames = map[string]string{
 	"b":      "B/op",
 	"ns":     "ns/op",
 	"allocs": "allocs/op",
 }
 
+// noDifferences replaces the tables of text output, and of the reports
+// posted to code review systems, when -diff finds no significant
+// differences.
+const noDifferences = "No significant differences in benchmarks\n"
+
 var outputFormatNames = map[string]string{
-	"text": _text,
-	"html": _html,
-	"json": _json,
+	"text":      _text,
+	"html":      _html,
+	"json":      _json,
+	"csv":       _csv,
+	"markdown":  _md,
+	"md":        _md,
+	"benchdiff": _diff,
 }
 
 func filterDiff(tables []*benchstat.Table) []*benchstat.Table {
@@ -167,43 +898,440 @@ func filterDiff(tables []*benchstat.Table) []*benchstat.Table {
 	return tables
 }
 
+// addFile adds the results in file to c as a new configuration.
+// If file is a directory, the results in all its files make up the
+// configuration.
+func addFile(c *benchstat.Collection, file string) error {
+	if fi, err := os.Stat(file); err == nil && fi.IsDir() {
+		return addDir(c, file)
+	}
+	if ext := filepath.Ext(file); ext == ".csv" || ext == ".json" {
+		return addSummaryFile(c, file)
+	}
+	if *flagCache != "" {
+		return addCachedFile(c, *flagCache, file, file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.AddFile(file, f)
+}
+
+// addSummaryFile adds to c, as a new configuration named file, the
+// summaries in file, the CSV or JSON output of an earlier benchstat,
+// of the last configuration it shows, such as the new side of the
+// comparison it printed.
+func addSummaryFile(c *benchstat.Collection, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sums, err := benchstat.ReadSummaries(f)
+	if err != nil {
+		return err
+	}
+	var configs []string
+	seen := make(map[string]bool)
+	for _, s := range sums {
+		if !seen[s.Config] {
+			seen[s.Config] = true
+			configs = append(configs, s.Config)
+		}
+	}
+	var last []*benchstat.Summary
+	for _, s := range sums {
+		if s.Config == configs[len(configs)-1] {
+			last = append(last, s)
+		}
+	}
+	c.AddSummaries(file, last)
+	return nil
+}
+
+// addDir adds the results in the files in dir, in order by name, to c
+// as a new configuration named dir, as when a CI matrix writes the
+// results of each platform to a file of its own.
+func addDir(c *benchstat.Collection, dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var results []*benchfmt.Result
+	for _, fi := range files {
+		if fi.IsDir() {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+		br := benchfmt.NewReader(f)
+		for br.Next() {
+			results = append(results, br.Result())
+		}
+		f.Close()
+		if err := br.Err(); err != nil {
+			return fmt.Errorf("%s: %v", fi.Name(), err)
+		}
+		for unit, meta := range br.UnitMetadata() {
+			c.AddUnitMetadata(unit, meta)
+		}
+	}
+	c.AddResults(filepath.Clean(dir), results)
+	return nil
+}
+
+// parsePerUnit parses list, a comma-separated list of values for the
+// flag name, each either applying to all units or, written unit=value,
+// to a single unit. It calls set for each entry, with unit set to ""
+// for entries applying to all units, and exits if set fails.
+func parsePerUnit(name, list string, set func(unit, value string) error) {
+	for _, entry := range strings.Split(list, ",") {
+		unit, value := "", entry
+		if i := strings.LastIndex(entry, "="); i >= 0 {
+			unit, value = entry[:i], entry[i+1:]
+			if n, ok := unitNames[strings.ToLower(unit)]; ok {
+				unit = n
+			}
+		}
+		if err := set(unit, value); err != nil {
+			fatalf("invalid -%s entry %q: %v", name, entry, err)
+		}
+	}
+}
+
+// unitInfo returns c.UnitInfo[unit], adding it if necessary.
+func unitInfo(c *benchstat.Collection, unit string) *benchstat.UnitInfo {
+	if c.UnitInfo == nil {
+		c.UnitInfo = make(map[string]*benchstat.UnitInfo)
+	}
+	if c.UnitInfo[unit] == nil {
+		c.UnitInfo[unit] = new(benchstat.UnitInfo)
+	}
+	return c.UnitInfo[unit]
+}
+
 func main() {
 	log.SetPrefix("benchstat: ")
 	log.SetFlags(0)
+	if len(os.Args) > 1 && os.Args[1] == "bisect" {
+		bisectMain(os.Args[2:])
+		return
+	}
 	flag.Usage = usage
 	flag.Parse()
-	deltaTest := deltaTestNames[strings.ToLower(*flagDeltaTest)]
-	if flag.NArg() < 1 || deltaTest == nil {
+	if flag.NArg() < 1 && *flagLoadState == "" {
 		flag.Usage()
 	}
 
+	startProfiling()
+	defer stopProfiling()
+
 	outputFormat := outputFormatNames[strings.ToLower(*flagOutput)]
 
 	c := &benchstat.Collection{
-		Alpha:      *flagAlpha,
-		AddGeoMean: *flagGeomean,
-		DeltaTest:  deltaTest,
+		AddGeoMean:   *flagGeomean || *flagSubtotals,
+		GroupGeoMean: *flagRollup || *flagSubtotals,
+		Reservoir:    *flagReservoir,
+		Warmup:       *flagWarmup,
+		Derive:       flagDerive,
+		Labels:       benchfmt.Labels(flagLabels),
+	}
+	if len(flagRename) > 0 {
+		c.Rename = benchstat.RenameRules(flagRename)
+	}
+	c.PerItem = *flagPerItem
+	if i := strings.LastIndex(c.PerItem, "="); i >= 0 {
+		c.PerItem, c.ItemName = c.PerItem[:i], c.PerItem[i+1:]
 	}
 	if *flagSplit != "" {
 		c.SplitBy = strings.Split(*flagSplit, ",")
 	}
+	switch *flagSort {
+	case "":
+	case "name":
+		c.SortByName = true
+	default:
+		fatalf("invalid -sort %q: want name", *flagSort)
+	}
+	var matrixKeys []string
+	if *flagMatrix != "" {
+		if flag.NArg() != 2 {
+			fatalf("-matrix requires exactly two inputs")
+		}
+		if outputFormat != _text {
+			fatalf("-matrix supports only text output")
+		}
+		matrixKeys = strings.Split(*flagMatrix, ",")
+		c.SplitBy = append([]string{"pkg"}, matrixKeys...)
+	}
+	if *flagWide {
+		if flag.NArg() != 2 {
+			fatalf("-wide requires exactly two inputs")
+		}
+		if outputFormat != _text {
+			fatalf("-wide supports only text output")
+		}
+	}
+	if *flagEfficiency {
+		if flag.NArg() != 2 {
+			fatalf("-efficiency requires exactly two input files")
+		}
+		if outputFormat != _text && outputFormat != _html {
+			fatalf("-efficiency does not support -output %s", outputFormat)
+		}
+	}
+	if *flagPickBest && outputFormat != _text {
+		fatalf("-pick-best supports only text output")
+	}
+	if *flagVerbose && outputFormat != _text {
+		fatalf("-v supports only text output")
+	}
+	switch *flagCompat {
+	case "":
+	case "benchcmp":
+		if outputFormat != _text {
+			fatalf("-compat supports only text output")
+		}
+	default:
+		fatalf("invalid -compat %q: want benchcmp", *flagCompat)
+	}
+	if *flagFilter != "" {
+		f, err := benchstat.ParseFilter(*flagFilter)
+		if err != nil {
+			fatal(err)
+		}
+		c.Filter = f
+	}
+	if *flagPivot != "" {
+		c.Pivot = strings.Split(*flagPivot, ",")
+	}
+	if *flagSame != "" {
+		c.RequireSame = strings.Split(*flagSame, ",")
+	}
+	if *flagMachBase != "" {
+		c.Baseline = benchstat.NewBaseline(strings.Split(*flagMachineBy, ","))
+		f, err := os.Open(*flagMachBase)
+		if err != nil {
+			fatal(err)
+		}
+		err = c.Baseline.AddFile(f)
+		f.Close()
+		if err != nil {
+			fatalf("reading %s: %v", *flagMachBase, err)
+		}
+	}
+	c.NormalizeUnits = *flagNormalize
+	c.DiffLabels = *flagConfDiff && flag.NArg() == 2 && outputFormat == _text
+	c.StatColumns = *flagStatCols
+	c.Intervals = outputFormat == _json || outputFormat == _csv || outputFormat == _diff || *flagReportDir != "" || *flagExecBefore != "" || *flagExecAfter != ""
+	c.AbsDelta = *flagAbsDelta
+	c.Heatmap = *flagHeatmap
+	switch *flagPrefix {
+	case "decimal":
+	case "binary":
+		c.BinaryPrefixes = true
+	default:
+		fatalf("invalid -size-prefix %q: want decimal or binary", *flagPrefix)
+	}
+	if *flagTimeUnit != "" {
+		if _, _, ok := benchstat.ConvertUnit("ns", *flagTimeUnit); !ok {
+			fatalf("invalid -time-unit %q", *flagTimeUnit)
+		}
+		c.TimeUnit = *flagTimeUnit
+	}
+	if *flagSizeUnit != "" {
+		if _, _, ok := benchstat.ConvertUnit("B", *flagSizeUnit); !ok {
+			fatalf("invalid -size-unit %q", *flagSizeUnit)
+		}
+		c.SizeUnit = *flagSizeUnit
+	}
+	if *flagDigits < 1 {
+		fatalf("invalid -digits %d: want at least 1", *flagDigits)
+	}
+	if *flagPrecision < 1 {
+		fatalf("invalid -delta-precision %d: want at least 1", *flagPrecision)
+	}
+	if *flagTop < 0 {
+		fatalf("invalid -top %d: want at least 0", *flagTop)
+	}
+	var budget *gate.Policy
+	if *flagBudget != "" {
+		if *flagBudgetState == "" {
+			fatal("-budget requires -budget-state")
+		}
+		budget = parseGate("budget", *flagBudget)
+	}
+	if *flagBudgetID != "" && *flagBudgetState == "" {
+		fatal("-budget-id requires -budget-state")
+	}
+	var tolerance *gate.Policy
+	if *flagUpdateBaseline != "" {
+		tolerance = parseGate("tolerance", *flagTolerance)
+	}
+	c.Digits = *flagDigits
+	c.DeltaPrecision = *flagPrecision
+	numbers, err := parseNumberFormat(*flagDecimal, *flagThousands)
+	if err != nil {
+		fatal(err)
+	}
+	c.Numbers = numbers
+	outliers, err := parseOutliers(*flagOutliers)
+	if err != nil {
+		fatalf("invalid -outliers %q: %v", *flagOutliers, err)
+	}
+	c.Outliers = outliers
+	if c.Missing, err = parseMissing(*flagMissing); err != nil {
+		fatalf("invalid -missing %q: %v", *flagMissing, err)
+	}
+	var own *owners
+	if *flagOwners != "" {
+		if own, err = readOwners(*flagOwners); err != nil {
+			fatal(err)
+		}
+	} else if *flagOwnerDir != "" {
+		fatalf("-owner-dir requires -owners")
+	}
+	// The best value depends on the direction of the unit, which
+	// may come from the inputs, so it is chosen once they are read.
+	var bestUnits []string
+	parsePerUnit("center", *flagCenter, func(unit, value string) error {
+		if strings.ToLower(value) == "best" {
+			bestUnits = append(bestUnits, unit)
+			return nil
+		}
+		center, err := parseCenter(value)
+		if err != nil {
+			return err
+		}
+		if unit == "" {
+			c.Center = center
+		} else {
+			unitInfo(c, unit).Center = center
+		}
+		return nil
+	})
+	mode, ok := deltaModeNames[strings.ToLower(*flagDeltaMode)]
+	if !ok {
+		fatalf("invalid -delta-mode %q: want first or previous", *flagDeltaMode)
+	}
+	c.DeltaMode = mode
+	if _, ok := htmlThemes[strings.ToLower(*flagTheme)]; !ok {
+		fatalf("invalid -html-theme %q: want light or dark", *flagTheme)
+	}
+	if *flagCSS != "" {
+		data, err := ioutil.ReadFile(*flagCSS)
+		if err != nil {
+			fatal(err)
+		}
+		htmlCSS = string(data)
+	}
+	if *flagPlot != "" && !strings.EqualFold(*flagPlot, "term") {
+		if c.Plot = plotNames[strings.ToLower(*flagPlot)]; c.Plot == nil {
+			fatalf("invalid -plot %q: want box, ecdf, trend, or term", *flagPlot)
+		}
+	}
+	parsePerUnit("delta-test", *flagDeltaTest, func(unit, value string) error {
+		deltaTest := deltaTestNames[strings.ToLower(value)]
+		if deltaTest == nil {
+			return fmt.Errorf("unknown test %q", value)
+		}
+		if unit == "" {
+			c.DeltaTest = deltaTest
+		} else {
+			unitInfo(c, unit).DeltaTest = deltaTest
+		}
+		return nil
+	})
+	parsePerUnit("alpha", *flagAlpha, func(unit, value string) error {
+		alpha, err := strconv.ParseFloat(value, 64)
+		if err != nil || alpha <= 0 || alpha > 1 {
+			return fmt.Errorf("invalid α %q", value)
+		}
+		if unit == "" {
+			c.Alpha = alpha
+		} else {
+			unitInfo(c, unit).Alpha = alpha
+		}
+		return nil
+	})
+	if *flagBetter != "" {
+		parsePerUnit("better", *flagBetter, func(unit, value string) error {
+			if unit == "" {
+				return fmt.Errorf("want unit=higher or unit=lower")
+			}
+			d, err := benchstat.ParseDirection(value)
+			if err != nil {
+				return err
+			}
+			unitInfo(c, unit).Better = d
+			return nil
+		})
+	}
 
 	units := []string{}
 	if *flagUnits != "" {
 		unitSet := strings.Split(*flagUnits, ",")
 		for _, u := range unitSet {
 			if n, ok := unitNames[strings.ToLower(u)]; ok {
-				units = append(units, n)
+				u = n
 			}
+			units = append(units, u)
 		}
 	}
 
-	for _, file := range flag.Args() {
-		data, err := ioutil.ReadFile(file)
-		if err != nil {
-			log.Fatal(err)
+	var goVersions []string
+	if *flagGoVersion != "" {
+		goVersions = strings.Split(*flagGoVersion, ",")
+		if len(goVersions) != 1 && len(goVersions) != flag.NArg() {
+			fatalf("-go-version lists %d versions for %d input files", len(goVersions), flag.NArg())
+		}
+		for i, v := range goVersions {
+			if goVersions[i] = benchstat.ParseGoVersion(v); goVersions[i] == "" {
+				fatalf("invalid -go-version %q", v)
+			}
+		}
+	}
+	if *flagLoadState != "" {
+		if err := loadState(c, *flagLoadState); err != nil {
+			fatalf("%s: %v", *flagLoadState, err)
+		}
+	}
+	for i, file := range flag.Args() {
+		if len(goVersions) > 0 {
+			c.GoVersion = goVersions[i%len(goVersions)]
+		}
+		if err := addFile(c, file); err != nil {
+			fatalf("%s: %v", file, err)
+		}
+	}
+	if *flagSaveState != "" {
+		if err := saveState(c, *flagSaveState); err != nil {
+			fatal(err)
+		}
+	}
+
+	for _, unit := range bestUnits {
+		if unit != "" {
+			unitInfo(c, unit).Center = benchstat.BestCenter(c.Better(unit))
+			continue
+		}
+		for _, u := range c.Units {
+			if info := c.UnitInfo[u]; info == nil || info.Center == nil {
+				unitInfo(c, u).Center = benchstat.BestCenter(c.Better(u))
+			}
 		}
-		c.AddConfig(file, data)
+	}
+
+	if mismatches := c.Mismatches(); len(mismatches) > 0 {
+		for _, m := range mismatches {
+			fmt.Fprintf(os.Stderr, "benchstat: inputs differ in %s\n", m)
+		}
+		stopProfiling()
+		os.Exit(1)
 	}
 
 	if len(units) > 0 {
@@ -211,34 +1339,243 @@ func main() {
 	}
 
 	tables := c.Tables()
+	// JSON output gives values in the units of -time-unit and -size-unit.
+	jsonUnits := []string{c.TimeUnit, c.SizeUnit}
+
+	policy := gatePolicy(c.Alpha)
+	_, violations := gate.Evaluate(tables, policy)
+	var failures []string
+	for _, v := range violations {
+		failures = append(failures, v.String())
+	}
+	if *flagFail == "" {
+		policy = nil
+	}
+	missing := c.MissingBenchmarks()
+	if *flagMissing == "fail" {
+		for _, f := range checkMissing(missing) {
+			failures = append(failures, "missing: "+f)
+		}
+	}
+	if *flagAgainst != "" {
+		b, err := readBaseline(*flagAgainst)
+		if err != nil {
+			fatal(err)
+		}
+		for _, f := range checkBaseline(b, tables) {
+			failures = append(failures, "baseline: "+f)
+		}
+	}
+	if *flagBudgetState != "" {
+		drifts, err := updateBudget(*flagBudgetState, *flagBudgetID, tables, budget)
+		if err != nil {
+			fatal(err)
+		}
+		for _, f := range drifts {
+			failures = append(failures, "budget: "+f)
+		}
+	}
+	if *flagUpdateBaseline != "" {
+		if err := updateBaseline(*flagUpdateBaseline, tables, tolerance); err != nil {
+			fatal(err)
+		}
+	}
+	warnings := underpoweredWarnings(tables)
+	if *flagAnalyzer != "" {
+		w, f, err := runAnalyzer(*flagAnalyzer, tables)
+		if err != nil {
+			fatal(err)
+		}
+		warnings = append(warnings, w...)
+		failures = append(failures, f...)
+	}
+
+	if *flagJenkinsPlot != "" {
+		if err := writeJenkinsPlots(*flagJenkinsPlot, tables); err != nil {
+			fatal(err)
+		}
+	}
+
+	if *flagDeltaChart != "" {
+		var buf bytes.Buffer
+		formatDeltaChart(&buf, tables)
+		if err := ioutil.WriteFile(*flagDeltaChart, buf.Bytes(), 0666); err != nil {
+			fatal(err)
+		}
+	}
+	if *flagVegaLite != "" {
+		if err := writeVegaLite(*flagVegaLite, tables); err != nil {
+			fatal(err)
+		}
+	}
+	if *flagTeamCity {
+		var buf bytes.Buffer
+		formatTeamCity(&buf, tables, policy, failures)
+		os.Stdout.Write(buf.Bytes())
+	}
+
+	// List the missing benchmarks after the tables if the tables omit them.
+	if c.Missing != benchstat.MissingHide && (c.Missing != benchstat.MissingDefault || len(c.Configs) != 2) {
+		missing = nil
+	}
 
 	if *flagRawValues {
 		for _, table := range tables {
 			for _, row := range table.Rows {
-				row.Scaler = NewNoopScaler(row.Metrics[0].Unit)
+				row.Scaler = NewNoopScaler(table.Unit)
 			}
 		}
 	}
 
 	if *flagOnlyDiff {
 		tables = filterDiff(tables)
-		if len(tables) == 0 && outputFormat == _text {
-			os.Stdout.WriteString("No significant differences in benchmarks\n")
-			return
+	}
+
+	var elided string
+	if *flagTop > 0 {
+		tables, elided = topTables(tables, *flagTop)
+	}
+
+	var effRows []*efficiencyRow
+	if *flagEfficiency {
+		effRows, tables = efficiency(tables)
+	}
+
+	if *flagOwnerDir != "" {
+		if err := writeOwnerReports(*flagOwnerDir, own, tables, c.Configs); err != nil {
+			fatalf("writing owner reports: %v", err)
+		}
+	}
+	owned := tables
+	if *flagRollup {
+		tables = append(c.Rollups(tables), tables...)
+	}
+
+	if *flagExecBefore != "" {
+		if err := runHook(*flagExecBefore, tables, jsonUnits, len(failures) > 0); err != nil {
+			fatalf("-exec-before: %v", err)
 		}
 	}
 
 	var buf bytes.Buffer
 	switch outputFormat {
 	case _html:
-		buf.WriteString(htmlStyle)
-		benchstat.FormatHTML(&buf, tables)
+		formatHTML(&buf, tables, effRows, missing, c.Configs)
 	case _json:
-		FormatJson(&buf, tables)
+		formatJSON(&buf, tables, jsonUnits)
+	case _csv, _md:
+		benchstat.Format(&buf, tables, benchstat.FormatOptions{Format: outputFormat})
+		if outputFormat == _md {
+			writeElided(&buf, elided)
+		}
+	case _diff:
+		formatBenchdiff(&buf, tables, policy, failures)
 	case _text:
-		benchstat.FormatText(&buf, tables)
+		if *flagOnlyDiff && len(tables) == 0 {
+			buf.WriteString(noDifferences)
+			break
+		}
+		if c.DiffLabels {
+			formatLabelDiffs(&buf, c.LabelDiffs(), c.Configs)
+		}
+		if matrixKeys != nil {
+			formatMatrixText(&buf, tables, matrixKeys)
+		} else if *flagCompat != "" {
+			formatBenchcmp(&buf, owned)
+		} else if *flagWide {
+			formatWideText(&buf, tables)
+		} else {
+			formatText(&buf, tables, effRows, missing, c.Configs)
+		}
+		writeElided(&buf, elided)
+		if own != nil {
+			formatOwnersText(&buf, own, owned)
+		}
+		if *flagPickBest {
+			formatPicksText(&buf, c.PickBest(owned))
+		}
 	}
 	os.Stdout.Write(buf.Bytes())
+
+	if *flagReportDir != "" {
+		if err := writeReport(*flagReportDir, tables, effRows, missing, c.Configs, jsonUnits); err != nil {
+			fatal(err)
+		}
+	}
+
+	if *flagGitHub || *flagGitLab || *flagBitbucket || *flagAzure {
+		var text bytes.Buffer
+		if *flagOnlyDiff && len(tables) == 0 {
+			text.WriteString(noDifferences)
+		} else {
+			formatText(&text, tables, effRows, missing, c.Configs)
+			writeElided(&text, elided)
+		}
+		report := markdownReport(text.String(), warnings, failures)
+		if *flagGitHub {
+			if err := postGitHub(report, tables, failures); err != nil {
+				fatalf("posting to GitHub: %v", err)
+			}
+		}
+		if *flagGitLab {
+			if err := postGitLab(report); err != nil {
+				fatalf("posting to GitLab: %v", err)
+			}
+		}
+		if *flagBitbucket {
+			if err := postBitbucket(text.String(), tables, failures); err != nil {
+				fatalf("posting to Bitbucket: %v", err)
+			}
+		}
+		if *flagAzure {
+			var html bytes.Buffer
+			formatHTML(&html, tables, effRows, missing, c.Configs)
+			if err := publishAzure(os.Stdout, report, html.Bytes(), warnings, failures); err != nil {
+				fatalf("publishing to Azure Pipelines: %v", err)
+			}
+		}
+	}
+
+	if *flagExecAfter != "" {
+		if err := runHook(*flagExecAfter, tables, jsonUnits, len(failures) > 0); err != nil {
+			failures = append(failures, fmt.Sprintf("-exec-after: %v", err))
+		}
+	}
+
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "benchstat: warning: %s\n", w)
+	}
+	if len(failures) > 0 {
+		for _, f := range failures {
+			fmt.Fprintf(os.Stderr, "benchstat: %s\n", f)
+		}
+		stopProfiling()
+		os.Exit(1)
+	}
+}
+
+// formatHTML appends an HTML report of tables to buf, along with the
+// efficiency rows and missing benchmarks, if any, styled by -html-theme
+// and -html-css, and the controls requested by -interactive.
+func formatHTML(buf *bytes.Buffer, tables []*benchstat.Table, effRows []*efficiencyRow, missing []*benchstat.MissingBenchmark, configs []string) {
+	buf.WriteString(htmlStyle)
+	buf.WriteString(htmlThemes[strings.ToLower(*flagTheme)])
+	if htmlCSS != "" {
+		fmt.Fprintf(buf, "<style>\n%s</style>\n", htmlCSS)
+	}
+	if *flagInteractive {
+		buf.WriteString(htmlControls)
+	}
+	if effRows != nil {
+		formatEfficiencyHTML(buf, effRows)
+	}
+	benchstat.FormatHTML(buf, tables)
+	if missing != nil {
+		formatMissingHTML(buf, missing, configs)
+	}
+	if *flagInteractive {
+		buf.WriteString(htmlScript)
+	}
 }
 
 var htmlStyle = `<style>
@@ -251,3 +1588,18 @@ var htmlStyle = `<style>
 .benchstat .worse td.delta { font-weight: bold; color: #c00; }
 </style>
 `
+
+// htmlThemes are the styles of each -html-theme, added to htmlStyle.
+var htmlThemes = map[string]string{
+	"light": "",
+	"dark": `<style>
+.benchstat, .benchstat-controls { color: #ddd; background-color: #1e1e1e; }
+.benchstat tr:not(.configs) th { border-top-color: #aaa; border-bottom-color: #555; }
+.benchstat .worse td.delta { color: #f66; }
+.benchstat td.value[style] { color: #1e1e1e; }
+</style>
+`,
+}
+
+// htmlCSS is the style sheet read from the -html-css file.
+var htmlCSS string
@@ -6,13 +6,229 @@ package main
 
 import (
 	"bytes"
+	"encoding/gob"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+
+	"golang.org/x/perf/benchstat"
+	"golang.org/x/perf/benchstat/gate"
 )
 
+// TestMain runs benchstat itself, rather than the tests, if the
+// environment holds the arguments for runMain.
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv("BENCHSTAT_TEST_MAIN"); ok {
+		os.Args = append([]string{"benchstat"}, strings.Split(args, "\n")...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs benchstat with args in a new process, so that tests can
+// check its exit status, and returns its combined standard output and
+// error and whether it failed.
+func runMain(t *testing.T, args ...string) (string, bool) {
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "BENCHSTAT_TEST_MAIN="+strings.Join(args, "\n"))
+	out, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		t.Fatal(err)
+	}
+	return string(out), err != nil
+}
+
+func TestDiffExitStatus(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		fail bool
+	}{
+		{[]string{"-diff", "testdata/exampleold.txt", "testdata/exampleold.txt"}, false},
+		// With no significant differences, -diff still fails for
+		// the samples that are too small.
+		{[]string{"-diff", "-min-count", "100", "testdata/exampleold.txt", "testdata/exampleold.txt"}, true},
+		{[]string{"-diff", "-missing=fail", "testdata/exampleold.txt", "testdata/examplenew.txt", "testdata/new.txt"}, true},
+	} {
+		out, failed := runMain(t, tt.args...)
+		if failed != tt.fail {
+			t.Errorf("benchstat %s: failed = %v, want %v; output:\n%s", strings.Join(tt.args, " "), failed, tt.fail, out)
+		}
+		if !strings.HasPrefix(out, noDifferences) {
+			t.Errorf("benchstat %s: output does not start with %q:\n%s", strings.Join(tt.args, " "), noDifferences, out)
+		}
+	}
+}
+
+func TestProfiling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchstat_profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The profiles are written however benchstat exits.
+	for _, tt := range []struct {
+		args []string
+		fail bool
+	}{
+		{[]string{"testdata/exampleold.txt", "testdata/examplenew.txt"}, false},
+		{[]string{"-top", "-1", "testdata/exampleold.txt"}, true},
+		{[]string{"testdata/no-such-file.txt"}, true},
+	} {
+		var files []string
+		for _, name := range []string{"cpu", "mem", "trace"} {
+			files = append(files, filepath.Join(dir, name))
+			os.Remove(files[len(files)-1])
+		}
+		args := append([]string{"-cpuprofile", files[0], "-memprofile", files[1], "-trace", files[2]}, tt.args...)
+		out, failed := runMain(t, args...)
+		if failed != tt.fail {
+			t.Errorf("benchstat %s: failed = %v, want %v; output:\n%s", strings.Join(tt.args, " "), failed, tt.fail, out)
+		}
+		for _, file := range files {
+			if fi, err := os.Stat(file); err != nil || fi.Size() == 0 {
+				t.Errorf("benchstat %s: wrote no %s profile (Stat: %v)", strings.Join(tt.args, " "), filepath.Base(file), err)
+			}
+		}
+	}
+}
+
+func TestInvalidTop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchstat_top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	state := filepath.Join(dir, "state")
+
+	// An invalid -top stops benchstat before it writes anything.
+	out, failed := runMain(t, "-top", "-1", "-save-state", state, "testdata/exampleold.txt", "testdata/examplenew.txt")
+	if want := "invalid -top -1"; !failed || !strings.Contains(out, want) {
+		t.Errorf("benchstat -top -1: failed = %v, output:\n%s\nwant failure with %q", failed, out, want)
+	}
+	if _, err := os.Stat(state); !os.IsNotExist(err) {
+		t.Errorf("benchstat -top -1 -save-state wrote the state (Stat: %v)", err)
+	}
+}
+
+func TestParseOutliers(t *testing.T) {
+	for _, tt := range []struct {
+		policy string
+		ok     bool
+	}{
+		{"iqr", true},
+		{"none", true},
+		{"zscore:2.5", true},
+		{"zscore:0", false},
+		{"zscore:-1", false},
+		{"zscore", false},
+		{"percent:0", true},
+		{"percent:10", true},
+		{"percent:50", false},
+		{"iqr:1", false},
+	} {
+		_, err := parseOutliers(tt.policy)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseOutliers(%q): err = %v, want ok = %v", tt.policy, err, tt.ok)
+		}
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchstat_usage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	budget := filepath.Join(dir, "budget.json")
+	baseline := filepath.Join(dir, "baseline.json")
+	written := []string{budget, baseline}
+
+	// A usage error stops benchstat before it writes anything, so that
+	// the corrected run starts from the same state.
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-budget", "5%"}, "-budget requires -budget-state"},
+		{[]string{"-budget-id", "x"}, "-budget-id requires -budget-state"},
+		{[]string{"-budget", "x%", "-budget-state", budget}, `invalid -budget entry "x%"`},
+		{[]string{"-budget-state", budget, "-top", "-1"}, "invalid -top -1"},
+		{[]string{"-update-baseline", baseline, "-tolerance", "x"}, `invalid -tolerance entry "x"`},
+		{[]string{"-update-baseline", baseline, "-budget", "5%"}, "-budget requires -budget-state"},
+		{[]string{"-update-baseline", baseline, "-budget", "5%", "-budget-state", budget, "-efficiency", "-output", "json"}, "-efficiency does not support -output json"},
+		{[]string{"-update-baseline", baseline, "-owner-dir", dir}, "-owner-dir requires -owners"},
+		{[]string{"-update-baseline", baseline, "-v", "-output", "html"}, "-v supports only text output"},
+		{[]string{"-update-baseline", baseline, "-outliers", "zscore:0"}, `invalid -outliers "zscore:0"`},
+		{[]string{"-update-baseline", baseline, "-owners", filepath.Join(dir, "missing")}, "missing:"},
+	} {
+		args := append(tt.args, "testdata/exampleold.txt", "testdata/examplenew.txt")
+		out, failed := runMain(t, args...)
+		if !failed || !strings.Contains(out, tt.want) {
+			t.Errorf("benchstat %s: failed = %v, output:\n%s\nwant failure with %q", strings.Join(args, " "), failed, out, tt.want)
+		}
+		for _, file := range written {
+			if _, err := os.Stat(file); !os.IsNotExist(err) {
+				t.Errorf("benchstat %s wrote %s (Stat: %v)", strings.Join(args, " "), filepath.Base(file), err)
+				os.Remove(file)
+			}
+		}
+	}
+
+	// Without -budget, -budget-state only accumulates the drift.
+	if out, failed := runMain(t, "-budget-state", budget, "testdata/exampleold.txt", "testdata/examplenew.txt"); failed {
+		t.Errorf("benchstat -budget-state failed:\n%s", out)
+	}
+	if _, err := os.Stat(budget); err != nil {
+		t.Errorf("benchstat -budget-state did not write the state: %v", err)
+	}
+}
+
+func TestDiffPostsReport(t *testing.T) {
+	var notes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			io.WriteString(w, "[]")
+			return
+		}
+		var note struct{ Body string }
+		json.NewDecoder(r.Body).Decode(&note)
+		notes = append(notes, note.Body)
+	}))
+	defer srv.Close()
+	for k, v := range map[string]string{
+		"CI_API_V4_URL":        srv.URL + "/api/v4",
+		"GITLAB_TOKEN":         "secret",
+		"CI_PROJECT_ID":        "group/project",
+		"CI_MERGE_REQUEST_IID": "5",
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	// Without significant differences, -diff still posts the report
+	// and runs the -exec-after hook.
+	out, failed := runMain(t, "-diff", "-gitlab", "-exec-after", "echo hook ran", "testdata/exampleold.txt", "testdata/exampleold.txt")
+	if failed || !strings.Contains(out, "hook ran") {
+		t.Errorf("benchstat -diff -gitlab -exec-after: failed = %v, output:\n%s", failed, out)
+	}
+	if len(notes) != 1 || !strings.Contains(notes[0], noDifferences) {
+		t.Errorf("benchstat -diff -gitlab posted %q, want one note saying %q", notes, noDifferences)
+	}
+}
+
 func TestGolden(t *testing.T) {
 	if err := os.Chdir("testdata"); err != nil {
 		t.Fatal(err)
@@ -23,8 +239,16 @@ func TestGolden(t *testing.T) {
 	if t.Failed() {
 		t.Fatal("skipping other tests")
 	}
-	check(t, "exampleoldhtml", "-html", "exampleold.txt")
-	check(t, "examplehtml", "-html", "exampleold.txt", "examplenew.txt")
+	check(t, "exampleoldhtml", "-output=html", "exampleold.txt")
+	check(t, "examplehtml", "-output=html", "exampleold.txt", "examplenew.txt")
+	check(t, "examplejson", "-output=json", "exampleold.txt", "examplenew.txt")
+	check(t, "top", "-top", "3", "old.txt", "new.txt")
+	check(t, "summaryjson", "examplesummary.json", "examplenew.txt")
+	check(t, "summarycsv", "exampleold.txt", "examplesummary.csv")
+	check(t, "numbers", "-digits=5", "-delta-precision=3", "-decimal=,", "-thousands=.", "-stat-columns", "exampleold.txt", "examplenew.txt")
+	check(t, "examplecsv", "-output=csv", "exampleold.txt", "examplenew.txt")
+	check(t, "examplemarkdown", "-output=markdown", "exampleold.txt", "examplenew.txt")
+	check(t, "examplebenchdiff", "-output=benchdiff", "exampleold.txt", "examplenew.txt")
 	if t.Failed() {
 		t.Fatal("skipping other tests")
 	}
@@ -33,13 +257,870 @@ func TestGolden(t *testing.T) {
 	check(t, "oldnew", "old.txt", "new.txt")
 	check(t, "oldnewgeo", "-geomean", "old.txt", "new.txt")
 	check(t, "new4", "new.txt", "slashslash4.txt")
-	check(t, "oldnewhtml", "-html", "old.txt", "new.txt")
-	check(t, "oldnew4html", "-html", "old.txt", "new.txt", "slashslash4.txt")
+	check(t, "oldnewhtml", "-output=html", "old.txt", "new.txt")
+	check(t, "oldnew4html", "-output=html", "old.txt", "new.txt", "slashslash4.txt")
 	check(t, "oldnewttest", "-delta-test=ttest", "old.txt", "new.txt")
 	check(t, "packagesold", "packagesold.txt")
 	check(t, "packages", "packagesold.txt", "packagesnew.txt")
 	check(t, "units", "units-old.txt", "units-new.txt")
 	check(t, "zero", "-delta-test=none", "zero-old.txt", "zero-new.txt")
+	check(t, "betterhtml", "-output=html", "better-old.txt", "better-new.txt")
+	check(t, "betteroverridehtml", "-output=html", "-better", "ops/s=lower,score=lower", "better-old.txt", "better-new.txt")
+	check(t, "exampletimeunit", "-time-unit", "ms", "-size-unit", "KiB", "exampleold.txt", "examplenew.txt")
+	check(t, "normalize", "-normalize-units", "normalize-old.txt", "normalize-new.txt")
+	check(t, "perunit", "-delta-test", "utest,score=none", "-alpha", "0.05,ns=0.001", "custom-old.txt", "custom-new.txt")
+	check(t, "binary", "-size-prefix", "binary", "exampleold.txt", "examplenew.txt")
+	check(t, "binaryold", "-size-prefix", "binary", "normalize-old.txt")
+	check(t, "peritem", "items-old.txt", "items-new.txt")
+	check(t, "derive", "-derive", "bytes-per-alloc = B/op / allocs/op", "-derive", "kB-per-alloc = bytes-per-alloc / 1000", "items-old.txt", "items-new.txt")
+	check(t, "rename", "-rename", "^GobMarshal(-|$)=>GobEncode$1", "renameold.txt", "examplenew.txt")
+	check(t, "efficiency", "-efficiency", "alloc-old.txt", "alloc-new.txt")
+	check(t, "efficiencyhtml", "-efficiency", "-output=html", "alloc-old.txt", "alloc-new.txt")
+	check(t, "oldnewverbose", "-v", "old.txt", "new.txt")
+	check(t, "outliersnone", "-outliers", "none", "-v", "old.txt", "new.txt")
+	check(t, "outlierszscore", "-outliers", "zscore:1", "-v", "exampleold.txt", "examplenew.txt")
+	check(t, "outlierspercent", "-outliers", "percent:10", "-v", "old.txt", "new.txt", "slashslash4.txt")
+	check(t, "statcolumnsold", "-stat-columns", "exampleold.txt")
+	check(t, "statcolumns", "-stat-columns", "exampleold.txt", "examplenew.txt")
+	check(t, "statcolumnshtml", "-stat-columns", "-output=html", "exampleold.txt", "examplenew.txt")
+	check(t, "statcolumnsjson", "-stat-columns", "-output=json", "exampleold.txt", "examplenew.txt")
+	check(t, "statcolumns4", "-stat-columns", "-geomean", "old.txt", "new.txt", "slashslash4.txt")
+	check(t, "balance", "balance-old.txt", "balance-new.txt")
+	check(t, "discrete", "discrete-old.txt", "discrete-new.txt")
+	check(t, "centermean", "-outliers", "none", "latency-old.txt", "latency-new.txt")
+	check(t, "centermedian", "-outliers", "none", "-center", "median", "latency-old.txt", "latency-new.txt")
+	check(t, "centerbest", "-center", "best", "exampleold.txt", "examplenew.txt")
+	check(t, "centerp90", "-outliers", "none", "-center", "p90", "latency-old.txt", "latency-new.txt")
+	check(t, "absdelta", "-abs-delta", "exampleold.txt", "examplenew.txt")
+	check(t, "absdeltastat", "-abs-delta", "-stat-columns", "discrete-old.txt", "discrete-new.txt")
+	check(t, "absdeltahtml", "-abs-delta", "-output=html", "exampleold.txt", "examplenew.txt")
+	check(t, "plotbox", "-plot=box", "-output=html", "exampleold.txt", "examplenew.txt")
+	check(t, "plotecdf", "-plot=ecdf", "-output=html", "exampleold.txt", "examplenew.txt")
+	check(t, "interactive", "-interactive", "-output=html", "exampleold.txt", "examplenew.txt")
+	check(t, "heatmap", "-heatmap", "-output=html", "old.txt", "new.txt", "slashslash4.txt")
+	check(t, "deltafirst", "-delta-mode", "first", "old.txt", "new.txt", "slashslash4.txt")
+	check(t, "deltapreviousstathtml", "-delta-mode", "previous", "-stat-columns", "-output=html", "old.txt", "new.txt", "slashslash4.txt")
+	check(t, "benchcmp", "-compat", "benchcmp", "alloc-old.txt", "alloc-new.txt")
+	check(t, "pickbest", "-pick-best", "exampleold.txt", "examplenew.txt", "examplenext.txt")
+	check(t, "deltapreviousjson", "-delta-mode", "previous", "-output=json", "exampleold.txt", "examplenew.txt", "examplenext.txt")
+	check(t, "plottrend", "-plot=trend", "-output=html", "old.txt", "new.txt", "slashslash4.txt")
+	check(t, "plotterm", "-plot=term", "exampleold.txt", "examplenew.txt")
+	check(t, "darkhtml", "-html-theme=dark", "-html-css=custom.css", "-heatmap", "-output=html", "exampleold.txt", "examplenew.txt")
+	check(t, "teamcity", "-teamcity", "exampleold.txt", "examplenew.txt")
+	check(t, "absdeltajson", "-abs-delta", "-output=json", "exampleold.txt", "examplenew.txt")
+	check(t, "missing", "missing-old.txt", "missing-new.txt")
+	check(t, "missingblank", "-missing=blank", "missing-old.txt", "missing-new.txt")
+	check(t, "missinghide4", "-missing=hide", "old.txt", "new.txt", "slashslash4.txt")
+	check(t, "missinghtml", "-output=html", "missing-old.txt", "missing-new.txt")
+	check(t, "custom", "custom-old.txt", "custom-new.txt")
+	check(t, "customunits", "-units", "p99-ms,cache-misses/op,ns", "custom-old.txt", "custom-new.txt")
+	check(t, "warmup", "-warmup", "1", "-stat-columns", "exampleold.txt", "examplenew.txt")
+	check(t, "pivot", "-filter", "-builder:*-race", "-pivot", "machine", "machines.txt")
+	check(t, "filtersplit", "-filter", "machine:fast", "-split", "builder", "machines.txt")
+	check(t, "fleet", "-machine-baseline", "fleet-base.txt", "fleet-old.txt", "fleet-new.txt")
+	check(t, "rollup", "-split", "pkg", "-rollup", "pkgs-old.txt", "pkgs-new.txt")
+	check(t, "subtotals", "-split", "pkg", "-subtotals", "pkgs-old.txt", "pkgs-new.txt")
+	check(t, "subtotalsmd", "-split", "pkg", "-subtotals", "-output", "markdown", "pkgs-old.txt", "pkgs-new.txt")
+	check(t, "wide", "-wide", "alloc-old.txt", "alloc-new.txt")
+	check(t, "analyzer", "-split", "pkg", "-analyzer", "./analyzer.sh warn", "pkgs-old.txt", "pkgs-new.txt")
+	check(t, "hooks", "-split", "pkg", "-exec-before", "./hook.sh before", "-exec-after", "./hook.sh after", "pkgs-old.txt", "pkgs-new.txt")
+	check(t, "sortname", "-sort", "name", "-geomean", "old.txt", "new.txt")
+	check(t, "owners", "-split", "pkg", "-owners", "owners.txt", "pkgs-old.txt", "pkgs-new.txt")
+	check(t, "matrix", "-matrix", "goos,goarch", "matrix/old", "matrix/new")
+	check(t, "label", "-label", "runner=gha-ubuntu", "-label", "builder=gha", "-split", "runner,machine,builder", "machines.txt")
+	check(t, "gopivot", "-pivot", "go", "goversions.txt")
+	check(t, "goversionflag", "-go-version", "go1.12,1.13", "-split", "go", "exampleold.txt", "examplenew.txt")
+}
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchstat_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chdir("testdata"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir("..")
+	// The first run populates the cache and the second reads from it.
+	check(t, "oldnew", "-cache", dir, "old.txt", "new.txt")
+	check(t, "oldnew", "-cache", dir, "old.txt", "new.txt")
+	// Unit metadata must survive the cache too.
+	check(t, "betterhtml", "-cache", dir, "-output=html", "better-old.txt", "better-new.txt")
+	check(t, "betterhtml", "-cache", dir, "-output=html", "better-old.txt", "better-new.txt")
+}
+
+func TestCacheHit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchstat_cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "in.txt")
+	if err := ioutil.WriteFile(input, []byte("goos: linux\nBenchmarkX 1 10 ns/op\nBenchmarkX 1 11 ns/op\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(dir, "cache")
+	values := func() []float64 {
+		c := new(benchstat.Collection)
+		if err := addCachedFile(c, cache, "a", input); err != nil {
+			t.Fatal(err)
+		}
+		m := c.Metrics[benchstat.Key{Config: "a", Benchmark: "X", Unit: "ns/op"}]
+		if m == nil {
+			t.Fatal("no X ns/op metrics")
+		}
+		return m.Values
+	}
+	if have, want := values(), []float64{10, 11}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("first run: values %v, want %v", have, want)
+	}
+
+	// Rewrite the values in the cache entry: a hit must add those,
+	// not the values parsed from the input again.
+	entries, err := filepath.Glob(filepath.Join(cache, "*"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("cache holds %q, %v, want one entry", entries, err)
+	}
+	cf, err := readCache(entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range cf.Results {
+		if r.Name != "BenchmarkX" || r.Iters != 1 || !reflect.DeepEqual(r.Units, []string{"ns/op"}) {
+			t.Fatalf("cached result %+v, want BenchmarkX 1 ns/op", r)
+		}
+		r.Values[0] = 20
+	}
+	f, err := os.Create(entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = gob.NewEncoder(f).Encode(cf)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := values(), []float64{20, 20}; !reflect.DeepEqual(have, want) {
+		t.Errorf("second run: values %v, want the cached %v", have, want)
+	}
+}
+
+// readTables returns the tables comparing files, using the defaults
+// of benchstat.Collection rather than benchstat's flags.
+func readTables(t *testing.T, files ...string) []*benchstat.Table {
+	return readCollection(t, files...).Tables()
+}
+
+// readCollection returns a Collection of the results in files.
+func readCollection(t *testing.T, files ...string) *benchstat.Collection {
+	c := new(benchstat.Collection)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.AddFile(file, f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestGate(t *testing.T) {
+	tables := readTables(t, "testdata/custom-old.txt", "testdata/custom-new.txt")
+	for _, test := range []struct {
+		fail string
+		want []string
+	}{
+		{"5%", []string{"Serve: latency +285.71% exceeds 5% threshold"}},
+		{"300%", nil},
+		{"ns=1%,cache-misses/op=0", nil},
+		{"300,latency=10", []string{"Serve: latency +285.71% exceeds 10% threshold"}},
+	} {
+		have := checkGate(parseGate("fail", test.fail), tables)
+		if !reflect.DeepEqual(have, test.want) {
+			t.Errorf("-fail %s: have %q, want %q", test.fail, have, test.want)
+		}
+	}
+}
+
+func TestBenchdiff(t *testing.T) {
+	tables := readTables(t, "testdata/custom-old.txt", "testdata/custom-new.txt")
+	*flagFail = "latency=10"
+	defer func() { *flagFail = "" }()
+	var buf bytes.Buffer
+	formatBenchdiff(&buf, tables, parseGate("fail", *flagFail), checkGate(parseGate("fail", *flagFail), tables))
+	d, err := benchstat.ReadBenchdiff(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Decision != "fail" {
+		t.Errorf("decision %q, want fail", d.Decision)
+	}
+	var gated []string
+	for _, r := range d.Results {
+		if r.Gate != "" {
+			gated = append(gated, fmt.Sprintf("%s %s %s %s", r.Name, r.Unit, r.Direction, r.Gate))
+		}
+	}
+	if want := []string{"Serve latency regressed fail"}; !reflect.DeepEqual(gated, want) {
+		t.Errorf("gated results: have %q, want %q", gated, want)
+	}
+
+	if _, err := benchstat.ReadBenchdiff(strings.NewReader(`{"benchdiff":2}`)); err == nil {
+		t.Errorf("ReadBenchdiff of version 2 succeeded, want error")
+	}
+}
+
+func TestMinCount(t *testing.T) {
+	tables := readTables(t, "testdata/exampleold.txt", "testdata/examplenew.txt")
+	if have := checkGate(&gate.Policy{MinCount: 4}, tables); have != nil {
+		t.Errorf("-min-count 4: have %q, want none", have)
+	}
+	have := checkGate(&gate.Policy{MinCount: 5}, tables)
+	want := []string{
+		"GobEncode: time/op has 4 samples in testdata/exampleold.txt, fewer than 5",
+		"JSONEncode: time/op has 4 samples in testdata/exampleold.txt, fewer than 5",
+		"GobEncode: speed has 4 samples in testdata/exampleold.txt, fewer than 5",
+		"JSONEncode: speed has 4 samples in testdata/exampleold.txt, fewer than 5",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("-min-count 5: have %q, want %q", have, want)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	c := readCollection(t, "testdata/missing-old.txt", "testdata/missing-new.txt")
+	have := checkMissing(c.MissingBenchmarks())
+	want := []string{
+		"Legacy-8: not measured in testdata/missing-new.txt",
+		"Stream-8: not measured in testdata/missing-old.txt",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
+
+func TestGitHub(t *testing.T) {
+	var requests []string
+	comments := `[{"id": 3, "body": "LGTM"}]`
+	wantAnnotations := []githubAnnotation{
+		{"x/gob_test.go", 3, 3, "warning", "GobEncode", "time/op +15.35%: 11.8ms ± 1% → 13.6ms ± 1% (p=0.016 n=5+4)"},
+		{"x/gob_test.go", 3, 3, "warning", "GobEncode", "speed -13.31%: 65.1MB/s ± 1% → 56.4MB/s ± 1% (p=0.016 n=5+4)"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			t.Errorf("%s %s: missing token", r.Method, r.URL)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == "GET":
+			io.WriteString(w, comments)
+		case r.URL.Path == "/repos/o/r/check-runs":
+			var run struct {
+				HeadSHA    string `json:"head_sha"`
+				Conclusion string
+				Output     struct{ Annotations []githubAnnotation }
+			}
+			json.Unmarshal(body, &run)
+			if run.HeadSHA != "abc" || run.Conclusion != "failure" && *flagReviewID == "" {
+				t.Errorf("check run %s, want head_sha abc and conclusion failure", body)
+			}
+			if !reflect.DeepEqual(run.Output.Annotations, wantAnnotations) {
+				t.Errorf("annotations:\nhave %+v\nwant %+v", run.Output.Annotations, wantAnnotations)
+			}
+			io.WriteString(w, `{"id": 7}`)
+		}
+	}))
+	defer srv.Close()
+
+	workspace, err := ioutil.TempDir("", "benchstat_workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workspace)
+	os.Mkdir(filepath.Join(workspace, "x"), 0777)
+	src := "package x\n\nfunc BenchmarkGobEncode(b *testing.B) {}\n"
+	if err := ioutil.WriteFile(filepath.Join(workspace, "x", "gob_test.go"), []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	tables := readTables(t, "testdata/examplenew.txt", "testdata/exampleold.txt")
+
+	event, err := ioutil.TempFile("", "benchstat_event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(event.Name())
+	io.WriteString(event, `{"pull_request": {"number": 5, "head": {"sha": "abc"}}}`)
+	event.Close()
+	for k, v := range map[string]string{
+		"GITHUB_API_URL":    srv.URL,
+		"GITHUB_TOKEN":      "secret",
+		"GITHUB_REPOSITORY": "o/r",
+		"GITHUB_EVENT_PATH": event.Name(),
+		"GITHUB_WORKSPACE":  workspace,
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	report := markdownReport("table\n", nil, []string{"regression"})
+	if err := postGitHub(report, tables, []string{"regression"}); err != nil {
+		t.Fatal(err)
+	}
+	// Duplicates of the comment are deleted, and an unchanged
+	// comment is left alone.
+	comments = `[{"id": 3, "body": "LGTM"}, {"id": 4, "body": ` + strconv.Quote(report) + `}, {"id": 6, "body": ` + strconv.Quote(report) + `}]`
+	if err := postGitHub(report, tables, []string{"regression"}); err != nil {
+		t.Fatal(err)
+	}
+	report = markdownReport("table 2\n", nil, []string{"regression"})
+	if err := postGitHub(report, tables, []string{"regression"}); err != nil {
+		t.Fatal(err)
+	}
+	// A run with another ID adds its own comment.
+	*flagReviewID = "linux"
+	defer func() { *flagReviewID = "" }()
+	if err := postGitHub(markdownReport("table\n", nil, nil), tables, nil); err != nil {
+		t.Fatal(err)
+	}
+	// A workspace that cannot be read leaves the check run
+	// without annotations, but still posts it.
+	wantAnnotations = nil
+	os.Setenv("GITHUB_WORKSPACE", filepath.Join(workspace, "missing"))
+	if err := postGitHub(report, tables, []string{"regression"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"GET /repos/o/r/issues/5/comments",
+		"POST /repos/o/r/issues/5/comments",
+		"POST /repos/o/r/check-runs",
+		"GET /repos/o/r/issues/5/comments",
+		"DELETE /repos/o/r/issues/comments/6",
+		"POST /repos/o/r/check-runs",
+		"GET /repos/o/r/issues/5/comments",
+		"DELETE /repos/o/r/issues/comments/6",
+		"PATCH /repos/o/r/issues/comments/4",
+		"POST /repos/o/r/check-runs",
+		"GET /repos/o/r/issues/5/comments",
+		"POST /repos/o/r/issues/5/comments",
+		"POST /repos/o/r/check-runs",
+		"GET /repos/o/r/issues/5/comments",
+		"POST /repos/o/r/issues/5/comments",
+		"POST /repos/o/r/check-runs",
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests:\nhave %q\nwant %q", requests, want)
+	}
+}
+
+func TestGitLab(t *testing.T) {
+	var requests []string
+	notes := `[{"id": 3, "body": "LGTM"}]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Private-Token") != "secret" {
+			t.Errorf("%s %s: missing token", r.Method, r.URL)
+		}
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+		if r.Method == "GET" {
+			io.WriteString(w, notes)
+		}
+	}))
+	defer srv.Close()
+
+	for k, v := range map[string]string{
+		"CI_API_V4_URL":        srv.URL + "/api/v4",
+		"GITLAB_TOKEN":         "secret",
+		"CI_PROJECT_ID":        "group/project",
+		"CI_MERGE_REQUEST_IID": "5",
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	report := markdownReport("table\n", nil, nil)
+	if err := postGitLab(report); err != nil {
+		t.Fatal(err)
+	}
+	notes = `[{"id": 3, "body": "LGTM"}, {"id": 4, "body": ` + strconv.Quote(report) + `}]`
+	if err := postGitLab(markdownReport("table 2\n", nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"GET /api/v4/projects/group%2Fproject/merge_requests/5/notes",
+		"POST /api/v4/projects/group%2Fproject/merge_requests/5/notes",
+		"GET /api/v4/projects/group%2Fproject/merge_requests/5/notes",
+		"PUT /api/v4/projects/group%2Fproject/merge_requests/5/notes/4",
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests:\nhave %q\nwant %q", requests, want)
+	}
+}
+
+func TestBitbucket(t *testing.T) {
+	var requests []string
+	var report struct{ Result string }
+	var annotations []bitbucketAnnotation
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("%s %s: missing token", r.Method, r.URL)
+		}
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method == "PUT" {
+			json.Unmarshal(body, &report)
+		} else {
+			json.Unmarshal(body, &annotations)
+		}
+	}))
+	defer srv.Close()
+
+	for k, v := range map[string]string{
+		"BITBUCKET_API_URL":   srv.URL + "/2.0",
+		"BITBUCKET_TOKEN":     "secret",
+		"BITBUCKET_WORKSPACE": "w",
+		"BITBUCKET_REPO_SLUG": "r",
+		"BITBUCKET_COMMIT":    "abc",
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	tables := readTables(t, "testdata/custom-old.txt", "testdata/custom-new.txt")
+	failures := checkGate(parseGate("fail", "5%"), tables)
+	if err := postBitbucket("table\n", tables, failures); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"PUT /2.0/repositories/w/r/commit/abc/reports/benchstat",
+		"POST /2.0/repositories/w/r/commit/abc/reports/benchstat/annotations",
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests:\nhave %q\nwant %q", requests, want)
+	}
+	if report.Result != "FAILED" {
+		t.Errorf("report result %q, want FAILED", report.Result)
+	}
+	if len(annotations) != 2 || annotations[0].Severity != "HIGH" || annotations[1].Summary != "Serve: latency +285.71%" {
+		t.Errorf("annotations %+v, want a failure and a regression", annotations)
+	}
+}
+
+func TestAzure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchstat_azure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("AGENT_TEMPDIRECTORY", os.Getenv("AGENT_TEMPDIRECTORY"))
+	os.Setenv("AGENT_TEMPDIRECTORY", dir)
+
+	var buf bytes.Buffer
+	if err := publishAzure(&buf, "report\n", []byte("<table>"), []string{"few samples"}, []string{"regression: 100%\nworse"}); err != nil {
+		t.Fatal(err)
+	}
+	summary := filepath.Join(dir, "benchstat", "benchstat.md")
+	page := filepath.Join(dir, "benchstat", "benchstat.html")
+	want := "##vso[task.logissue type=error]regression: 100%AZP25%0Aworse\n" +
+		"##vso[task.logissue type=warning]few samples\n" +
+		"##vso[task.uploadsummary]" + summary + "\n" +
+		"##vso[artifact.upload containerfolder=benchstat;artifactname=benchstat]" + page + "\n"
+	if have := buf.String(); have != want {
+		t.Errorf("have:\n%s\nwant:\n%s", have, want)
+	}
+	if data, err := ioutil.ReadFile(summary); err != nil || string(data) != "report\n" {
+		t.Errorf("summary %q, %v", data, err)
+	}
+	if data, err := ioutil.ReadFile(page); err != nil || string(data) != "<table>" {
+		t.Errorf("page %q, %v", data, err)
+	}
+}
+
+func TestDeltaChart(t *testing.T) {
+	tables := readTables(t, "testdata/old.txt", "testdata/new.txt")
+	var buf bytes.Buffer
+	formatDeltaChart(&buf, tables)
+	want, err := ioutil.ReadFile("testdata/deltachart.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := buf.Bytes(); !bytes.Equal(have, want) {
+		t.Errorf("have:\n%s\nwant:\n%s", have, want)
+	}
+}
+
+func TestVegaLite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchstat_vegalite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := writeVegaLite(dir, readTables(t, "testdata/exampleold.txt", "testdata/examplenew.txt")); err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Schema string `json:"$schema"`
+		Data   struct {
+			Values []map[string]interface{}
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "delta.vl.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Data.Values) != 4 || spec.Data.Values[0]["benchmark"] != "GobEncode speed" || spec.Data.Values[0]["change"] != "better" {
+		t.Errorf("delta.vl.json values: %v", spec.Data.Values)
+	}
+	if data, err = ioutil.ReadFile(filepath.Join(dir, "samples.vl.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+	// Two benchmarks in two units, with 4 old and 5 new values each.
+	if len(spec.Data.Values) != 2*2*(4+5) || spec.Schema != vegaLiteSchema {
+		t.Errorf("samples.vl.json: %d values, schema %q", len(spec.Data.Values), spec.Schema)
+	}
+}
+
+func TestReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchstat_report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := readCollection(t, "testdata/exampleold.txt", "testdata/examplenew.txt")
+	if err := writeReport(dir, c.Tables(), nil, nil, c.Configs, nil); err != nil {
+		t.Fatal(err)
+	}
+	index, err := ioutil.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"ns_op.html", "MB_s.html", "delta.svg", "results.json", "results.csv", "delta.vl.json"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Error(err)
+		}
+		if file != "delta.vl.json" && !bytes.Contains(index, []byte("href='"+file+"'")) {
+			t.Errorf("index.html does not link to %s", file)
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "results.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(data), "\n")
+	if len(lines) != 1+2*2*(4+5)+1 || lines[1] != "GobEncode,ns/op,testdata/exampleold.txt,1.3552735e+07" {
+		t.Errorf("results.csv:\n%s", data)
+	}
+}
+
+func TestJenkinsPlot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchstat_plot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tables := readTables(t, "testdata/exampleold.txt", "testdata/examplenew.txt")
+	if err := writeJenkinsPlots(dir, tables); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "GobEncode.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ns/op,MB/s\n11789289,65.108\n"; string(data) != want {
+		t.Errorf("GobEncode.csv:\n%s\nwant:\n%s", data, want)
+	}
+	if name := plotFileName("pkg:x/y Foo/n=1-8"); name != "pkg_x_y_Foo_n_1-8.csv" {
+		t.Errorf("plotFileName = %q", name)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchstat_baseline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "baseline.json")
+	if err := updateBaseline(file, readTables(t, "testdata/examplenew.txt"), parseGate("tolerance", "5%")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := readBaseline(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := checkBaseline(b, readTables(t, "testdata/examplenew.txt")); f != nil {
+		t.Errorf("checking baseline against its own input: %q", f)
+	}
+	want := []string{
+		"GobEncode: time/op +15.35% exceeds 5% tolerance",
+		"GobEncode: speed -13.31% exceeds 5% tolerance",
+	}
+	if f := checkBaseline(b, readTables(t, "testdata/exampleold.txt")); !reflect.DeepEqual(f, want) {
+		t.Errorf("checkBaseline = %q, want %q", f, want)
+	}
+
+	// A tolerance raised by hand is kept.
+	b.Benchmarks[0].Tolerance = 20
+	data, _ := json.Marshal(b)
+	if err := ioutil.WriteFile(file, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateBaseline(file, readTables(t, "testdata/examplenew.txt"), parseGate("tolerance", "5%")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = readBaseline(file); err != nil {
+		t.Fatal(err)
+	}
+	if f := checkBaseline(b, readTables(t, "testdata/exampleold.txt")); !reflect.DeepEqual(f, want[1:]) {
+		t.Errorf("after raising tolerance: checkBaseline = %q, want %q", f, want[1:])
+	}
+	if f := checkBaseline(b, readTables(t, "testdata/exampleold.txt")[1:]); len(f) != 3 || f[0] != "GobEncode: ns/op not measured" {
+		t.Errorf("with time/op omitted: checkBaseline = %q", f)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchstat_budget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "budget.json")
+
+	// GobEncode gets 15% slower with each comparison,
+	// and exceeds the 40% budget on the third.
+	tables := readTables(t, "testdata/examplenew.txt", "testdata/exampleold.txt")
+	budget := parseGate("budget", "40%,MB/s=50%")
+	for i, want := range []int{0, 0, 1} {
+		f, err := updateBudget(file, fmt.Sprint("commit", i), tables, budget)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(f) != want {
+			t.Errorf("comparison %d: updateBudget = %q, want %d failures", i+1, f, want)
+		}
+	}
+	// Retrying a comparison, whether identified or not, counts it
+	// only once.
+	for _, id := range []string{"commit2", "", ""} {
+		if _, err := updateBudget(file, id, tables, budget); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state budgetState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Drift) != 4 || state.Drift[0].Count != 4 || len(state.Counted) != 4 {
+		t.Errorf("budget state:\n%s", data)
+	}
+}
+
+func TestBenchmarkSource(t *testing.T) {
+	funcs := map[string][]sourceLoc{
+		"BenchmarkA": {{"a_test.go", 10}},
+		"BenchmarkB": {{"b_test.go", 20}, {"x/b_test.go", 30}, {"x/y/b_test.go", 40}},
+	}
+	for _, tt := range []struct {
+		name, group string
+		want        sourceLoc
+		ok          bool
+	}{
+		{"A", "", sourceLoc{"a_test.go", 10}, true},
+		{"A/n=1-8", "", sourceLoc{"a_test.go", 10}, true},
+		{"A-8", "pkg:example.com/z", sourceLoc{"a_test.go", 10}, true},
+		{"B", "", sourceLoc{}, false},
+		{"B", "pkg:example.com/x goos:linux", sourceLoc{"x/b_test.go", 30}, true},
+		{"B", "pkg:example.com/x/y", sourceLoc{"x/y/b_test.go", 40}, true},
+		{"B", "pkg:example.com", sourceLoc{"b_test.go", 20}, true},
+		{"C", "", sourceLoc{}, false},
+	} {
+		loc, ok := benchmarkSource(funcs, tt.name, tt.group)
+		if loc != tt.want || ok != tt.ok {
+			t.Errorf("benchmarkSource(%q, %q) = %v, %v, want %v, %v", tt.name, tt.group, loc, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTeamCity(t *testing.T) {
+	tables := readTables(t, "testdata/examplenew.txt", "testdata/exampleold.txt")
+	var buf bytes.Buffer
+	formatTeamCity(&buf, tables[:1], parseGate("fail", "10%"), []string{"regression: GobEncode", "too few samples: [x]"})
+	want := `##teamcity[buildStatisticValue key='GobEncode ns/op' value='13599058']
+##teamcity[testSuiteStarted name='benchstat']
+##teamcity[testStarted name='GobEncode time/op']
+##teamcity[testFailed name='GobEncode time/op' message='GobEncode: time/op +15.35% exceeds 10% threshold' details='11.8ms ± 1% → 13.6ms ± 1%']
+##teamcity[testStdOut name='GobEncode time/op' out='11.8ms ± 1% → 13.6ms ± 1% +15.35% (p=0.016 n=5+4)']
+##teamcity[testFinished name='GobEncode time/op']
+##teamcity[buildStatisticValue key='JSONEncode ns/op' value='32114298.5']
+##teamcity[testStarted name='JSONEncode time/op']
+##teamcity[testStdOut name='JSONEncode time/op' out='31.8ms ± 1% → 32.1ms ± 1% ~ (p=0.286 n=5+4)']
+##teamcity[testFinished name='JSONEncode time/op']
+##teamcity[testSuiteFinished name='benchstat']
+##teamcity[buildProblem description='too few samples: |[x|]']
+`
+	if have := buf.String(); have != want {
+		t.Errorf("have:\n%s\nwant:\n%s", have, want)
+	}
+
+	// Build statistics keep the full precision of large values.
+	c := new(benchstat.Collection)
+	c.AddConfig("big", []byte("BenchmarkBig 1 123456789 ns/op\n"))
+	buf.Reset()
+	formatTeamCity(&buf, c.Tables(), nil, nil)
+	if want := "value='123456789'"; !strings.Contains(buf.String(), want) {
+		t.Errorf("have:\n%s\nwant %s", buf.String(), want)
+	}
+}
+
+func TestAnalyzer(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	tables := func() []*benchstat.Table {
+		c := &benchstat.Collection{SplitBy: []string{"pkg"}}
+		for _, file := range []string{"testdata/pkgs-old.txt", "testdata/pkgs-new.txt"} {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.AddConfig(file, data)
+		}
+		return c.Tables()
+	}
+
+	tt := tables()
+	warnings, failures, err := runAnalyzer("testdata/analyzer.sh fail", tt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %q, want none", warnings)
+	}
+	if want := []string{"analyzer: pkg:example.com/codec Encode-8 ns/op: [hot path]"}; !reflect.DeepEqual(failures, want) {
+		t.Errorf("failures = %q, want %q", failures, want)
+	}
+	if note, want := tt[0].Rows[0].Note, "(p=0.008 n=5+5) [hot path]"; note != want {
+		t.Errorf("note = %q, want %q", note, want)
+	}
+
+	if _, _, err := runAnalyzer("testdata/analyzer.sh maybe", tables()); err == nil || !strings.Contains(err.Error(), `invalid verdict "maybe"`) {
+		t.Errorf("invalid verdict: err = %v", err)
+	}
+	if _, _, err := runAnalyzer("testdata/analyzer.sh", tables()[1:]); err == nil {
+		t.Errorf("analyzer of no time/op table succeeded")
+	}
+}
+
+func TestBisect(t *testing.T) {
+	for _, cmd := range []string{"git", "sh"} {
+		if _, err := exec.LookPath(cmd); err != nil {
+			t.Skipf("%s not found", cmd)
+		}
+	}
+	dir, err := ioutil.TempDir("", "benchstat-bisect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=gopher", "-c", "user.email=gopher@golang.org"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	// The work doubles at the fourth of seven commits.
+	git("init", "-q")
+	var hashes []string
+	for i := 0; i < 7; i++ {
+		cost := "100"
+		if i >= 3 {
+			cost = "200"
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "cost"), []byte(cost), 0666); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "cost")
+		git("commit", "-q", "--allow-empty", "-m", strconv.Itoa(i))
+		hashes = append(hashes, git("rev-parse", "HEAD"))
+	}
+	git("checkout", "-q", "-b", "work")
+
+	var log bytes.Buffer
+	b := &bisector{
+		dir:     dir,
+		command: []string{"sh", "-c", `for i in 1 2 3 4 5 6 7 8; do echo "BenchmarkWork 1 $(($(cat cost) + i)) ns/op"; done`},
+		gate:    parseGate("fail", "0%"),
+		alpha:   0.05,
+		log:     &log,
+	}
+	first, tables, err := b.run(hashes[0], "HEAD")
+	if err != nil {
+		t.Fatalf("%v\n%s", err, log.Bytes())
+	}
+	if first != hashes[3] {
+		t.Errorf("have first bad commit %s, want %s\n%s", first, hashes[3], log.Bytes())
+	}
+	if len(tables) != 1 || len(tables[0].Rows) != 1 || tables[0].Rows[0].Change != -1 {
+		t.Errorf("have no regression in comparison with the first bad commit")
+	}
+	if branch := git("rev-parse", "--abbrev-ref", "HEAD"); branch != "work" {
+		t.Errorf("left repository on %s, want work", branch)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"abc", 3, "abc"},
+		{"abcdef", 5, "ab…"},
+		{"aµµµ", 6, "aµ…"},
+		{"aµµµ", 5, "a…"},
+	} {
+		if have := truncate(test.s, test.n); have != test.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", test.s, test.n, have, test.want)
+		}
+	}
+}
+
+func TestTruncateReport(t *testing.T) {
+	text := strings.Repeat("Encode-8  1.00ms ± 1%  2.00ms ± 1%  +100.00%  (p=0.008 n=5+5)\n", 2000)
+	report := markdownReport(text, nil, []string{"regression: Encode-8"})
+	if have := truncateReport(report, len(report)); have != report {
+		t.Errorf("report of %d bytes truncated to %d", len(report), len(have))
+	}
+	const n = 1000
+	have := truncateReport(report, n)
+	if len(have) > n {
+		t.Errorf("truncated report has %d bytes, want at most %d", len(have), n)
+	}
+	if !strings.Contains(have, "- regression: Encode-8\n") {
+		t.Errorf("truncated report lost the failures:\n%s", have)
+	}
+	if want := "(p=0.008 n=5+5)\n" + reportElided + reportEnd; !strings.HasSuffix(have, want) {
+		t.Errorf("truncated report ends:\n%s\nwant whole lines and:\n%s", have[len(have)-100:], want)
+	}
 }
 
 func check(t *testing.T, name string, files ...string) {
@@ -63,9 +1144,78 @@ func check(t *testing.T, name string, files ...string) {
 		os.Stdout = w
 		os.Stderr = w
 		*flagGeomean = false
-		*flagHTML = false
+		*flagRollup = false
+		*flagSubtotals = false
+		*flagWide = false
+		*flagSort = ""
+		*flagAnalyzer = ""
+		*flagExecBefore = ""
+		*flagExecAfter = ""
+		*flagConfDiff = true
+		*flagOwners = ""
+		*flagOwnerDir = ""
+		*flagMatrix = ""
+		*flagPickBest = false
+		*flagCompat = ""
+		*flagOutput = "text"
+		*flagUnits = ""
+		*flagReservoir = 0
+		*flagWarmup = 0
+		*flagCache = ""
+		*flagBetter = ""
+		*flagTimeUnit = ""
+		*flagNormalize = false
+		*flagPrefix = "decimal"
+		*flagPerItem = "items/op=item"
+		flagDerive = nil
+		flagLabels = nil
+		flagRename = nil
+		*flagEfficiency = false
+		*flagOutliers = "iqr"
+		*flagMissing = ""
+		*flagVerbose = false
+		*flagMinCount = 0
+		*flagStatCols = false
+		*flagAbsDelta = false
+		*flagPlot = ""
+		*flagInteractive = false
+		*flagHeatmap = false
+		*flagDeltaMode = ""
+		*flagDeltaChart = ""
+		*flagVegaLite = ""
+		*flagReportDir = ""
+		*flagTheme = "light"
+		*flagCSS = ""
+		htmlCSS = ""
+		*flagGitHub = false
+		*flagGitLab = false
+		*flagBitbucket = false
+		*flagJenkinsPlot = ""
+		*flagUpdateBaseline = ""
+		*flagAgainst = ""
+		*flagTolerance = "5%"
+		*flagBudget = ""
+		*flagBudgetState = ""
+		*flagBudgetID = ""
+		*flagReviewID = ""
+		*flagTeamCity = false
+		*flagAzure = false
+		*flagSizeUnit = ""
+		*flagTop = 0
+		*flagDigits = 3
+		*flagPrecision = 2
+		*flagDecimal = "."
+		*flagThousands = ""
 		*flagDeltaTest = "utest"
+		*flagCenter = "mean"
+		*flagAlpha = "0.05"
 		*flagSplit = flag.Lookup("split").DefValue
+		*flagFilter = ""
+		*flagPivot = ""
+		*flagGoVersion = ""
+		*flagSame = ""
+		*flagMachBase = ""
+		*flagMachineBy = "machine"
 
 		main()
 
@@ -103,3 +1253,61 @@ func writeTemp(t *testing.T, data []byte) string {
 	f.Close()
 	return name
 }
+
+func TestOwners(t *testing.T) {
+	o, err := readOwners("testdata/owners.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		group, benchmark string
+		want             []string
+	}{
+		{"pkg:example.com/codec goos:linux", "Encode-8", []string{"codec-team", "@alice"}},
+		{"pkg:example.com/codec goos:linux", "Decode-8", []string{"platform-team"}},
+		{"pkg:example.com/store", "Get-8", []string{"storage-team"}},
+		{"pkg:example.com/web", "Route-8", []string{"web-team"}},
+		{"pkg:other.org/x", "Route-8", []string{"unowned"}},
+		{"", "Encode-8", []string{"unowned"}},
+	} {
+		row := &benchstat.Row{Group: tt.group, Benchmark: tt.benchmark}
+		if have := o.of(row); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("owners of %s %s: have %q, want %q", tt.group, tt.benchmark, have, tt.want)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "benchstat_owners")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &benchstat.Collection{SplitBy: []string{"pkg"}}
+	for _, file := range []string{"testdata/pkgs-old.txt", "testdata/pkgs-new.txt"} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.AddConfig(file, data)
+	}
+	if err := writeOwnerReports(dir, o, c.Tables(), c.Configs); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range files {
+		files[i] = filepath.Base(f)
+	}
+	want := []string{"alice.txt", "codec-team.txt", "platform-team.txt", "storage-team.txt", "web-team.txt"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("owner reports: have %q, want %q", files, want)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "web-team.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); !strings.Contains(s, "Render-8") || strings.Contains(s, "Get-8") {
+		t.Errorf("web-team.txt holds other teams' benchmarks:\n%s", s)
+	}
+}
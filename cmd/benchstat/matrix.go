@@ -0,0 +1,100 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagMatrix = flag.String("matrix", "", "compare two inputs on each platform, as a matrix with a column per value of `labels` such as goos,goarch")
+
+// A matrixRow is the changes in one benchmark on each platform.
+type matrixRow struct {
+	group, benchmark string
+	deltas           map[string]string // by platform
+}
+
+// formatMatrixText appends to buf, for each table comparing two
+// configurations, a matrix of the changes in each benchmark on each
+// platform, where the groups of tables are split by pkg and the labels
+// keys, whose values name the platforms, such as linux/amd64.
+func formatMatrixText(buf *bytes.Buffer, tables []*benchstat.Table, keys []string) {
+	first := true
+	for _, table := range tables {
+		if !table.OldNewDelta {
+			continue
+		}
+		var platforms []string
+		seen := make(map[string]bool)
+		var rows []*matrixRow
+		index := make(map[[2]string]*matrixRow)
+		for _, row := range table.Rows {
+			if row.Benchmark == "[Geo mean]" {
+				continue
+			}
+			group, platform := splitPlatform(row.Group, keys)
+			if !seen[platform] {
+				seen[platform] = true
+				platforms = append(platforms, platform)
+			}
+			id := [2]string{group, row.Benchmark}
+			r := index[id]
+			if r == nil {
+				r = &matrixRow{group: group, benchmark: row.Benchmark, deltas: make(map[string]string)}
+				index[id] = r
+				rows = append(rows, r)
+			}
+			r.deltas[platform] = row.Delta
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		text := [][]string{append([]string{"name \\ " + table.Metric + " delta"}, platforms...)}
+		group := ""
+		for _, r := range rows {
+			if r.group != group {
+				group = r.group
+				text = append(text, []string{group})
+			}
+			line := []string{r.benchmark}
+			for _, p := range platforms {
+				line = append(line, r.deltas[p])
+			}
+			text = append(text, line)
+		}
+		if !first {
+			buf.WriteString("\n")
+		}
+		first = false
+		formatGrid(buf, text, false)
+	}
+}
+
+// splitPlatform splits the group of a row, such as
+// "pkg:example.com/codec goos:linux goarch:amd64", into the rest of
+// the group and the platform named by the values of the labels keys,
+// such as "linux/amd64".
+func splitPlatform(group string, keys []string) (rest, platform string) {
+	var others, values []string
+	for _, label := range strings.Fields(group) {
+		isKey := false
+		for _, k := range keys {
+			if strings.HasPrefix(label, k+":") {
+				isKey = true
+			}
+		}
+		if isKey {
+			values = append(values, label[strings.Index(label, ":")+1:])
+		} else {
+			others = append(others, label)
+		}
+	}
+	return strings.Join(others, " "), strings.Join(values, "/")
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagMissing = flag.String("missing", "", "how to show benchmarks missing from some inputs: `policy` blank, hide, or fail (default hide when comparing two inputs, blank otherwise)")
+
+// parseMissing parses the value of the -missing flag. A policy of
+// "fail" shows tables as by default.
+func parseMissing(policy string) (benchstat.MissingPolicy, error) {
+	switch policy {
+	case "", "fail":
+		return benchstat.MissingDefault, nil
+	case "blank":
+		return benchstat.MissingBlank, nil
+	case "hide":
+		return benchstat.MissingHide, nil
+	}
+	return 0, fmt.Errorf("want blank, hide, or fail")
+}
+
+// checkMissing returns a description of each of the missing benchmarks.
+func checkMissing(missing []*benchstat.MissingBenchmark) []string {
+	var failures []string
+	for _, m := range missing {
+		failures = append(failures, fmt.Sprintf("%s: not measured in %s", missingName(m), strings.Join(m.Configs, ", ")))
+	}
+	return failures
+}
+
+// missingStatus describes how m differs between configs. When
+// comparing two configurations, m was either added or removed.
+func missingStatus(m *benchstat.MissingBenchmark, configs []string) string {
+	if len(configs) == 2 {
+		if m.Configs[0] == configs[0] {
+			return "added"
+		}
+		return "removed"
+	}
+	return "missing from " + strings.Join(m.Configs, ", ")
+}
+
+// missingName returns the full name of m, including its group.
+func missingName(m *benchstat.MissingBenchmark) string {
+	if m.Group != "" {
+		return m.Group + " " + m.Benchmark
+	}
+	return m.Benchmark
+}
+
+// formatMissingText appends to buf a list of the missing benchmarks,
+// and how each differs between configs.
+func formatMissingText(buf *bytes.Buffer, missing []*benchstat.MissingBenchmark, configs []string) {
+	grid := [][]string{{"name", "status"}}
+	var group string
+	for _, m := range missing {
+		if m.Group != group {
+			group = m.Group
+			grid = append(grid, []string{group})
+		}
+		grid = append(grid, []string{m.Benchmark, missingStatus(m, configs)})
+	}
+	formatGrid(buf, grid, true)
+}
+
+var missingTemplate = template.Must(template.New("").Funcs(template.FuncMap{
+	"name":   missingName,
+	"status": missingStatus,
+}).Parse(`
+<table class='benchstat missing'>
+<tbody>
+<tr class='heading'><th>name<th>status
+{{$configs := .Configs}}{{range .Missing -}}
+<tr><td class='name'>{{name .}}<td class='status'>{{status . $configs}}
+{{end -}}
+</tbody>
+</table>
+`))
+
+// formatMissingHTML appends an HTML formatting of the missing
+// benchmarks to buf.
+func formatMissingHTML(buf *bytes.Buffer, missing []*benchstat.MissingBenchmark, configs []string) {
+	data := struct {
+		Missing []*benchstat.MissingBenchmark
+		Configs []string
+	}{missing, configs}
+	if err := missingTemplate.Execute(buf, data); err != nil {
+		// Only possible if template is invalid.
+		panic(err)
+	}
+}
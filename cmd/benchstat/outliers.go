@@ -0,0 +1,126 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var (
+	flagOutliers = flag.String("outliers", "iqr", "outlier rejection `policy`: iqr, none, zscore:k, or percent:p")
+	flagVerbose  = flag.Bool("v", false, "print diagnostics, such as the outliers rejected, after the tables")
+)
+
+// parseOutliers parses the value of the -outliers flag.
+func parseOutliers(policy string) (benchstat.OutlierTest, error) {
+	name, arg := policy, ""
+	if i := strings.Index(policy, ":"); i >= 0 {
+		name, arg = policy[:i], policy[i+1:]
+	}
+	var x float64
+	if arg != "" {
+		var err error
+		if x, err = strconv.ParseFloat(arg, 64); err != nil || x < 0 {
+			return nil, fmt.Errorf("invalid argument %q", arg)
+		}
+	}
+	switch {
+	case name == "iqr" && arg == "":
+		return benchstat.IQROutliers, nil
+	case name == "none" && arg == "":
+		return benchstat.NoOutliers, nil
+	case name == "zscore" && arg != "" && x > 0:
+		return benchstat.ZScoreOutliers(x), nil
+	case name == "percent" && arg != "" && x < 50:
+		return benchstat.PercentOutliers(x), nil
+	}
+	return nil, fmt.Errorf("want iqr, none, zscore:k, or percent:p")
+}
+
+// formatOutlierCounts appends to buf a table for each of tables
+// giving the number of values rejected as outliers out of the
+// number of values measured, for each benchmark and config.
+func formatOutlierCounts(buf *bytes.Buffer, tables []*benchstat.Table) {
+	for _, table := range tables {
+		buf.WriteString("\n")
+		var header []string
+		switch len(table.Configs) {
+		case 1:
+			header = []string{"outliers", table.Metric}
+		case 2:
+			header = []string{"outliers", "old " + table.Metric, "new " + table.Metric}
+		default:
+			header = append([]string{"outliers \\ " + table.Metric}, table.Configs...)
+		}
+		grid := [][]string{header}
+		var group string
+		for _, row := range table.Rows {
+			if row.Group != group {
+				group = row.Group
+				grid = append(grid, []string{group})
+			}
+			cols := []string{row.Benchmark}
+			for _, m := range row.Metrics {
+				if m.Unit == "" {
+					// No values for this config.
+					cols = append(cols, "")
+					continue
+				}
+				cols = append(cols, fmt.Sprintf("%d/%d", len(m.Values)-len(m.RValues), len(m.Values)))
+			}
+			grid = append(grid, cols)
+		}
+		formatGrid(buf, grid, false)
+	}
+}
+
+// formatOutlierValues appends to buf a list of the values rejected as
+// outliers by policy in each of tables, and the reason for each.
+// Values are printed unscaled, since a rejected value and the limit
+// it exceeded often differ only past the precision of scaled output.
+func formatOutlierValues(buf *bytes.Buffer, tables []*benchstat.Table, policy string) {
+	for _, table := range tables {
+		var grid [][]string
+		for _, row := range table.Rows {
+			name := row.Benchmark
+			if row.Group != "" {
+				name = row.Group + " " + name
+			}
+			for i, m := range row.Metrics {
+				if grid == nil && m.Unit != "" {
+					grid = [][]string{{"rejected " + m.Unit + " (" + policy + ")", "config", "value", "reason"}}
+				}
+				for _, v := range m.Outliers {
+					reason := "above " + formatLimit(m.Hi, v)
+					if v < m.Lo {
+						reason = "below " + formatLimit(m.Lo, v)
+					}
+					grid = append(grid, []string{name, table.Configs[i], strconv.FormatFloat(v, 'f', -1, 64), reason})
+				}
+			}
+		}
+		if len(grid) > 1 {
+			buf.WriteString("\n")
+			formatGrid(buf, grid, true)
+		}
+	}
+}
+
+// formatLimit formats the outlier limit lim with just enough
+// decimal places to distinguish it from the rejected value v.
+func formatLimit(lim, v float64) string {
+	for prec := 0; prec < 10; prec++ {
+		if s := strconv.FormatFloat(lim, 'f', prec, 64); s != strconv.FormatFloat(v, 'f', prec, 64) {
+			return s
+		}
+	}
+	return strconv.FormatFloat(lim, 'g', -1, 64)
+}
@@ -0,0 +1,170 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var (
+	flagOwners   = flag.String("owners", "", "list the regressions by owner, as assigned by the patterns in `file`")
+	flagOwnerDir = flag.String("owner-dir", "", "write each owner's part of the comparison to owner.txt in `dir`")
+)
+
+// unowned is the owner of benchmarks that no rule assigns.
+const unowned = "unowned"
+
+// An ownerRule assigns the benchmarks matching pattern to owners.
+type ownerRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// owners maps benchmarks to their owners.
+type owners struct {
+	rules []ownerRule
+}
+
+// readOwners reads an owners file. Each line holds a pattern followed by
+// one or more owners, such as
+//
+//	example.com/store.*  storage-team
+//	*.Encode*            codec-team @alice
+//
+// A pattern matches the package path of a benchmark, its pkg label,
+// joined by a dot to its name, in which * matches any text. The last
+// line matching a benchmark assigns its owners. Blank lines and lines
+// beginning with # are ignored.
+func readOwners(file string) (*owners, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	o := new(owners)
+	for i, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 || strings.HasPrefix(f[0], "#") {
+			continue
+		}
+		if len(f) < 2 {
+			return nil, fmt.Errorf("%s:%d: missing owner for %s", file, i+1, f[0])
+		}
+		pattern := strings.Replace(regexp.QuoteMeta(f[0]), `\*`, ".*", -1)
+		o.rules = append(o.rules, ownerRule{regexp.MustCompile("^" + pattern + "$"), f[1:]})
+	}
+	return o, nil
+}
+
+// of returns the owners of the benchmark of row, or unowned.
+func (o *owners) of(row *benchstat.Row) []string {
+	name := row.Benchmark
+	for _, label := range strings.Fields(row.Group) {
+		if strings.HasPrefix(label, "pkg:") {
+			name = strings.TrimPrefix(label, "pkg:") + "." + name
+		}
+	}
+	for i := len(o.rules) - 1; i >= 0; i-- {
+		if o.rules[i].pattern.MatchString(name) {
+			return o.rules[i].owners
+		}
+	}
+	return []string{unowned}
+}
+
+// split returns each owner's part of tables, holding only the rows of
+// the benchmarks the owner owns, omitting the geomean rows, and the
+// owners in order.
+func (o *owners) split(tables []*benchstat.Table) (map[string][]*benchstat.Table, []string) {
+	parts := make(map[string][]*benchstat.Table)
+	var names []string
+	for _, table := range tables {
+		owned := make(map[string]*benchstat.Table)
+		for _, row := range table.Rows {
+			if row.Benchmark == "[Geo mean]" {
+				continue
+			}
+			for _, owner := range o.of(row) {
+				t := owned[owner]
+				if t == nil {
+					t = new(benchstat.Table)
+					*t = *table
+					t.Rows = nil
+					owned[owner] = t
+					if parts[owner] == nil {
+						names = append(names, owner)
+					}
+					parts[owner] = append(parts[owner], t)
+				}
+				t.Rows = append(t.Rows, row)
+			}
+		}
+	}
+	sort.Strings(names)
+	return parts, names
+}
+
+// formatOwnersText appends to buf the significant regressions in
+// tables, grouped by owner.
+func formatOwnersText(buf *bytes.Buffer, o *owners, tables []*benchstat.Table) {
+	parts, names := o.split(tables)
+	first := true
+	for _, owner := range names {
+		var lines []string
+		for _, table := range parts[owner] {
+			if !table.OldNewDelta {
+				continue
+			}
+			for _, row := range table.Rows {
+				if row.Change < 0 {
+					name := row.Benchmark
+					if row.Group != "" {
+						name = row.Group + " " + name
+					}
+					lines = append(lines, fmt.Sprintf("  %s: %s %s\n", name, table.Metric, row.Delta))
+				}
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		if first {
+			buf.WriteString("\nregressions by owner:\n")
+			first = false
+		}
+		fmt.Fprintf(buf, "%s\n", owner)
+		for _, line := range lines {
+			buf.WriteString(line)
+		}
+	}
+}
+
+// writeOwnerReports writes each owner's part of tables to dir, as text
+// output in a file named for the owner, so that a nightly comparison
+// can be sent to the teams concerned.
+func writeOwnerReports(dir string, o *owners, tables []*benchstat.Table, configs []string) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	parts, names := o.split(tables)
+	for _, owner := range names {
+		var buf bytes.Buffer
+		formatText(&buf, parts[owner], nil, nil, configs)
+		file := filepath.Join(dir, safeFileName(strings.TrimPrefix(owner, "@"))+".txt")
+		if err := ioutil.WriteFile(file, buf.Bytes(), 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
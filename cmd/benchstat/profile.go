@@ -0,0 +1,88 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+var (
+	flagCPUProfile = flag.String("cpuprofile", "", "write a CPU profile of benchstat to `file`")
+	flagMemProfile = flag.String("memprofile", "", "write a memory profile of benchstat to `file`")
+	flagTrace      = flag.String("trace", "", "write an execution trace of benchstat to `file`")
+)
+
+// stopProfiling stops the profiles started by startProfiling and
+// writes their output, which must happen before benchstat exits. It
+// does nothing if they were never started or are already stopped.
+var stopProfiling = func() {}
+
+// startProfiling starts the profiles requested on the command line,
+// and sets stopProfiling to stop them.
+func startProfiling() {
+	var stops []func()
+	stopProfiling = func() {
+		stopProfiling = func() {}
+		for _, stop := range stops {
+			stop()
+		}
+	}
+	if *flagCPUProfile != "" {
+		f, err := os.Create(*flagCPUProfile)
+		if err != nil {
+			fatal(err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			fatal(err)
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			f.Close()
+		})
+	}
+	if *flagTrace != "" {
+		f, err := os.Create(*flagTrace)
+		if err != nil {
+			fatal(err)
+		}
+		if err := trace.Start(f); err != nil {
+			fatal(err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			f.Close()
+		})
+	}
+	if *flagMemProfile != "" {
+		stops = append(stops, func() {
+			f, err := os.Create(*flagMemProfile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			runtime.GC() // materialize up-to-date statistics
+			if err := pprof.Lookup("allocs").WriteTo(f, 0); err != nil {
+				log.Fatal(err)
+			}
+		})
+	}
+}
+
+// fatal is log.Fatal, but writes any profiles first.
+func fatal(v ...interface{}) {
+	stopProfiling()
+	log.Fatal(v...)
+}
+
+// fatalf is log.Fatalf, but writes any profiles first.
+func fatalf(format string, v ...interface{}) {
+	stopProfiling()
+	log.Fatalf(format, v...)
+}
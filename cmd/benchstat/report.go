@@ -0,0 +1,106 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagReportDir = flag.String("report-dir", "", "write a static report, with a page per table, charts, and the data as JSON and CSV, to `dir`")
+
+// A reportFile is a file of a report bundle, linked from its index.
+type reportFile struct {
+	name  string
+	title string
+	data  []byte
+}
+
+// writeReport writes a static report of the comparison in tables to
+// dir, ready to publish as a CI artifact or a web site: an index.html
+// with the full HTML report and links to a page for each table, the
+// delta chart when comparing two configurations, and the data as
+// JSON, with values converted to units as by formatJSON, CSV, and
+// Vega-Lite specifications.
+func writeReport(dir string, tables []*benchstat.Table, effRows []*efficiencyRow, missing []*benchstat.MissingBenchmark, configs, units []string) error {
+	var files []reportFile
+	for _, table := range tables {
+		var buf bytes.Buffer
+		formatHTML(&buf, []*benchstat.Table{table}, nil, nil, configs)
+		files = append(files, reportFile{safeFileName(table.Unit) + ".html", table.Metric, htmlPage(table.Metric, buf.Bytes())})
+	}
+	var chart bytes.Buffer
+	if bars := deltaBars(tables); len(bars) > 0 {
+		formatDeltaChart(&chart, tables)
+		files = append(files, reportFile{"delta.svg", "delta chart", chart.Bytes()})
+	}
+	var js bytes.Buffer
+	formatJSON(&js, tables, units)
+	files = append(files, reportFile{"results.json", "results as JSON", js.Bytes()})
+	files = append(files, reportFile{"results.csv", "values as CSV", valuesCSV(tables)})
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	if err := writeVegaLite(dir, tables); err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	body.WriteString("<ul class='benchstat-files'>\n")
+	for _, f := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, f.name), f.data, 0666); err != nil {
+			return err
+		}
+		fmt.Fprintf(&body, "<li><a href='%s'>%s</a>\n", html.EscapeString(f.name), html.EscapeString(f.title))
+	}
+	body.WriteString("</ul>\n")
+	if chart.Len() > 0 {
+		body.WriteString("<p><img src='delta.svg' alt='delta chart'>\n")
+	}
+	formatHTML(&body, tables, effRows, missing, configs)
+	return ioutil.WriteFile(filepath.Join(dir, "index.html"), htmlPage("benchstat", body.Bytes()), 0666)
+}
+
+// htmlPage returns a complete HTML page with the given title and body.
+func htmlPage(title string, body []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset='utf-8'>\n<title>%s</title>\n</head>\n<body>\n", html.EscapeString(title))
+	buf.Write(body)
+	buf.WriteString("</body>\n</html>\n")
+	return buf.Bytes()
+}
+
+// valuesCSV returns every value in tables as CSV, a record per value
+// giving the benchmark, unit, configuration, and value.
+func valuesCSV(tables []*benchstat.Table) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"name", "unit", "config", "value"})
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			name := row.Benchmark
+			if row.Group != "" {
+				name = row.Group + " " + name
+			}
+			for i, m := range row.Metrics {
+				for _, v := range m.Values {
+					w.Write([]string{name, table.Unit, table.Configs[i], strconv.FormatFloat(v, 'g', -1, 64)})
+				}
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes()
+}
@@ -0,0 +1,215 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+var flagReviewID = flag.String("review-id", "", "distinguish the results this run posts from those of other benchstat runs on the same change by `id`, such as the platform benchmarked")
+
+// reviewName returns the name under which results are posted: the
+// title of comments, and the name of check runs and reports.
+func reviewName() string {
+	if *flagReviewID != "" {
+		return "benchstat (" + *flagReviewID + ")"
+	}
+	return "benchstat"
+}
+
+// reviewSlug returns an identifier for the results this run posts,
+// for use in IDs and file names. The ID of a Bitbucket report, for
+// example, identifies it so that later runs replace it rather than
+// adding another.
+func reviewSlug() string {
+	if *flagReviewID != "" {
+		return "benchstat-" + *flagReviewID
+	}
+	return "benchstat"
+}
+
+// commentMarker returns the hidden marker that identifies benchstat's
+// comment on a pull or merge request, so that later runs update it
+// rather than adding another.
+func commentMarker() string {
+	if *flagReviewID != "" {
+		return "<!-- benchstat " + *flagReviewID + " -->"
+	}
+	return "<!-- benchstat -->"
+}
+
+// markdownReport returns a comment presenting the text report of
+// the comparison and any warnings and failures.
+func markdownReport(text string, warnings, failures []string) string {
+	var buf bytes.Buffer
+	buf.WriteString(commentMarker() + "\n")
+	fmt.Fprintf(&buf, "**%s**: %s.\n", reviewName(), countFailures(failures))
+	if len(warnings)+len(failures) > 0 {
+		buf.WriteString("\n")
+	}
+	for _, f := range failures {
+		fmt.Fprintf(&buf, "- %s\n", f)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(&buf, "- warning: %s\n", w)
+	}
+	buf.WriteString("\n<details><summary>Comparison</summary>\n\n```\n")
+	buf.WriteString(text)
+	buf.WriteString(reportEnd)
+	return buf.String()
+}
+
+// reportEnd closes the comparison at the end of a markdownReport.
+const reportEnd = "```\n\n</details>\n"
+
+// reportElided marks the end of a comparison cut short by truncateReport.
+const reportElided = "… (truncated)\n"
+
+// truncateReport returns report, a markdownReport, shortened to at most
+// n bytes, since code review systems refuse comments longer than a limit.
+// It drops whole lines from the end of the comparison, marking their
+// omission, and keeps the failures and warnings above it.
+func truncateReport(report string, n int) string {
+	if len(report) <= n {
+		return report
+	}
+	body := strings.TrimSuffix(report, reportEnd)
+	n -= len(reportEnd) + len(reportElided)
+	if n < 0 {
+		n = 0
+	}
+	if n < len(body) {
+		body = body[:n]
+	}
+	if i := strings.LastIndex(body, "\n"); i >= 0 {
+		body = body[:i+1]
+	}
+	return body + reportElided + reportEnd
+}
+
+// countFailures returns a phrase counting failures.
+func countFailures(failures []string) string {
+	switch len(failures) {
+	case 0:
+		return "no failures"
+	case 1:
+		return "1 failure"
+	}
+	return fmt.Sprintf("%d failures", len(failures))
+}
+
+// An apiClient sends requests to the JSON REST API of a code
+// review system.
+type apiClient struct {
+	api    string       // API root URL, without a trailing slash
+	header http.Header  // headers to add to every request
+	client *http.Client // client to send requests with, if not http.DefaultClient
+}
+
+// newAPIClient returns a client for the API rooted at api that
+// authenticates by setting the header key, if any, to value.
+func newAPIClient(api, key, value string) *apiClient {
+	c := &apiClient{api: api, header: make(http.Header)}
+	if key != "" {
+		c.header.Set(key, value)
+	}
+	return c
+}
+
+// do sends a request with the JSON encoding of in, if not nil, to the
+// API path, and decodes the JSON response into out, if not nil.
+func (c *apiClient) do(method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, c.api+path, &body)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := c.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(data))
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// A comment is a comment on a pull or merge request.
+type comment struct {
+	ID   int64  `json:"id,omitempty"`
+	Body string `json:"body"`
+}
+
+// A commentThread gives the API paths of the comments on a pull or
+// merge request.
+type commentThread struct {
+	list   func(page int) string // lists a page of 100 comments
+	add    string                // adds a comment
+	item   func(id int64) string // edits or deletes a comment
+	update string                // method that edits a comment
+}
+
+// postSticky adds body as a comment to thread, or, if a previous run
+// added one, edits that comment to match, leaving the thread as
+// readable after many runs as after one. Should concurrent runs have
+// each added a comment, all but the first are deleted.
+func (c *apiClient) postSticky(thread commentThread, body string) error {
+	marker := commentMarker()
+	var found []comment
+	for page := 1; ; page++ {
+		var comments []comment
+		if err := c.do("GET", thread.list(page), nil, &comments); err != nil {
+			return err
+		}
+		for _, cm := range comments {
+			if strings.HasPrefix(cm.Body, marker) {
+				found = append(found, cm)
+			}
+		}
+		if len(comments) < 100 {
+			break
+		}
+	}
+	if len(found) == 0 {
+		return c.do("POST", thread.add, comment{Body: body}, nil)
+	}
+	for _, cm := range found[1:] {
+		if err := c.do("DELETE", thread.item(cm.ID), nil, nil); err != nil {
+			return err
+		}
+	}
+	if found[0].Body == body {
+		// Spare watchers a notification of an edit that changes nothing.
+		return nil
+	}
+	return c.do(thread.update, thread.item(found[0].ID), comment{Body: body}, nil)
+}
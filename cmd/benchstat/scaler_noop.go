@@ -5,14 +5,15 @@
 package main
 
 import (
-	"fmt"
+	"strconv"
+
 	"golang.org/x/perf/benchstat"
 )
 
 // NewNoopScaler returns an identity Scaler, which
-// formats the value without scaling it.
+// formats the value to full precision without scaling it.
 func NewNoopScaler(unit string) benchstat.Scaler {
 	return func(val float64) string {
-		return fmt.Sprintf("%.f %s", val, unit)
+		return strconv.FormatFloat(val, 'f', -1, 64) + " " + unit
 	}
 }
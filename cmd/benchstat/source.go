@@ -0,0 +1,101 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// A sourceLoc is the location of a benchmark function.
+type sourceLoc struct {
+	File string // slash-separated path, relative to the tree searched
+	Line int
+}
+
+// benchmarkFunc matches the declaration of a benchmark function.
+var benchmarkFunc = regexp.MustCompile(`^func (Benchmark\w*)\(`)
+
+// findBenchmarks returns the locations of the benchmark functions
+// declared in the test files in the tree rooted at dir, by name.
+// It skips testdata, vendor, and hidden directories, as the go
+// command does.
+func findBenchmarks(dir string) (map[string][]sourceLoc, error) {
+	funcs := make(map[string][]sourceLoc)
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if file != dir && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		scan := bufio.NewScanner(f)
+		for line := 1; scan.Scan(); line++ {
+			if m := benchmarkFunc.FindStringSubmatch(scan.Text()); m != nil {
+				funcs[m[1]] = append(funcs[m[1]], sourceLoc{filepath.ToSlash(rel), line})
+			}
+		}
+		return scan.Err()
+	})
+	return funcs, err
+}
+
+// benchmarkSource returns the location in funcs of the function of
+// the benchmark with the given name and group, as shown in tables,
+// and whether it was found. If several packages declare the function,
+// the group's pkg: label picks one; without it, the location is
+// ambiguous and not found.
+func benchmarkSource(funcs map[string][]sourceLoc, name, group string) (sourceLoc, bool) {
+	// Trim the sub-benchmarks and GOMAXPROCS suffix,
+	// neither of which a function name can contain.
+	if i := strings.IndexAny(name, "/-"); i >= 0 {
+		name = name[:i]
+	}
+	locs := funcs["Benchmark"+name]
+	if len(locs) == 1 {
+		return locs[0], true
+	}
+	var pkg string
+	for _, label := range strings.Fields(group) {
+		if strings.HasPrefix(label, "pkg:") {
+			pkg = strings.TrimPrefix(label, "pkg:")
+		}
+	}
+	if pkg == "" {
+		return sourceLoc{}, false
+	}
+	// Pick the deepest directory that the import path ends with.
+	best, bestDir := sourceLoc{}, -1
+	for _, loc := range locs {
+		dir := path.Dir(loc.File)
+		if dir == "." {
+			dir = ""
+		}
+		if (dir == "" || pkg == dir || strings.HasSuffix(pkg, "/"+dir)) && len(dir) > bestDir {
+			best, bestDir = loc, len(dir)
+		}
+	}
+	return best, bestDir >= 0
+}
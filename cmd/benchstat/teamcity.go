@@ -0,0 +1,89 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+	"golang.org/x/perf/benchstat/gate"
+)
+
+var flagTeamCity = flag.Bool("teamcity", false, "also print TeamCity service messages reporting each result as a build statistic and each comparison as a test")
+
+// teamcityEscaper escapes the value of a TeamCity service message
+// attribute.
+var teamcityEscaper = strings.NewReplacer(
+	"|", "||",
+	"'", "|'",
+	"\n", "|n",
+	"\r", "|r",
+	"[", "|[",
+	"]", "|]",
+)
+
+// teamcityMessage appends to buf a service message with the given
+// name and alternating attribute names and values.
+func teamcityMessage(buf *bytes.Buffer, name string, attrs ...string) {
+	fmt.Fprintf(buf, "##teamcity[%s", name)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		fmt.Fprintf(buf, " %s='%s'", attrs[i], teamcityEscaper.Replace(attrs[i+1]))
+	}
+	buf.WriteString("]\n")
+}
+
+// formatTeamCity appends to buf TeamCity service messages reporting
+// the result of each benchmark in tables, from the last input, as a
+// build statistic named for the benchmark and unit, which TeamCity
+// charts across builds. When comparing two inputs, each row is also
+// reported as a test in a "benchstat" suite, which fails if policy p
+// (if not nil) fails the change. Failures not tied to a row, such as from
+// -min-count, are reported as build problems.
+func formatTeamCity(buf *bytes.Buffer, tables []*benchstat.Table, p *gate.Policy, failures []string) {
+	const suite = "benchstat"
+	failed := gateFailures(p, tables)
+	started := false
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			name := row.Benchmark
+			if row.Group != "" {
+				name = row.Group + " " + name
+			}
+			m := row.Metrics[len(row.Metrics)-1]
+			if m.Unit != "" {
+				teamcityMessage(buf, "buildStatisticValue", "key", name+" "+table.Unit, "value", strconv.FormatFloat(m.Center, 'f', -1, 64))
+			}
+			if !table.OldNewDelta || row.Metrics[0].Unit == "" || m.Unit == "" {
+				continue
+			}
+			if !started {
+				teamcityMessage(buf, "testSuiteStarted", "name", suite)
+				started = true
+			}
+			test := name + " " + table.Metric
+			teamcityMessage(buf, "testStarted", "name", test)
+			details := fmt.Sprintf("%s → %s", row.Metrics[0].Format(row.Scaler), m.Format(row.Scaler))
+			if f := failed[row]; f != "" {
+				teamcityMessage(buf, "testFailed", "name", test, "message", f, "details", details)
+			}
+			out := strings.TrimSpace(fmt.Sprintf("%s %s %s", details, row.Delta, row.Note))
+			teamcityMessage(buf, "testStdOut", "name", test, "out", out)
+			teamcityMessage(buf, "testFinished", "name", test)
+		}
+	}
+	if started {
+		teamcityMessage(buf, "testSuiteFinished", "name", suite)
+	}
+	for _, f := range failures {
+		// Regressions are already reported by failing tests.
+		if !strings.HasPrefix(f, "regression: ") {
+			teamcityMessage(buf, "buildProblem", "description", f)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/perf/benchstat"
+)
+
+// Widths of terminal plots, in characters.
+const (
+	termBarHalf = 20 // each side of a delta bar
+	termBins    = 24 // histogram of a distribution
+)
+
+// termLevels are the characters drawing the bins of a histogram,
+// from empty to the fullest bin.
+var termLevels = []rune(" ▁▂▃▄▅▆▇█")
+
+// formatTermPlot appends to buf plots of tables drawn with Unicode
+// block characters, for -plot=term: a bar for the change of each
+// benchmark compared, as in -delta-chart, with improvements to the
+// left and regressions to the right, drawn with light shading if not
+// significant; and a histogram of the values of each benchmark in each
+// configuration, on a scale shared by the configurations.
+func formatTermPlot(buf *bytes.Buffer, tables []*benchstat.Table) {
+	if bars := deltaBars(tables); len(bars) > 0 {
+		maxPct := 0.0
+		for _, b := range bars {
+			maxPct = math.Max(maxPct, math.Abs(b.pct))
+		}
+		grid := [][]string{{"name", "delta", ""}}
+		for _, b := range bars {
+			n := 0
+			if maxPct > 0 {
+				n = int(math.Ceil(math.Abs(b.pct) / maxPct * termBarHalf))
+			}
+			block := "█"
+			if b.change == 0 {
+				block = "░"
+			}
+			bar := strings.Repeat(block, n)
+			if b.worse < 0 {
+				bar = strings.Repeat(" ", termBarHalf-n) + bar + "│" + strings.Repeat(" ", termBarHalf)
+			} else {
+				bar = strings.Repeat(" ", termBarHalf) + "│" + bar + strings.Repeat(" ", termBarHalf-n)
+			}
+			grid = append(grid, []string{b.label, bar, fmt.Sprintf("%+.2f%%", b.pct)})
+		}
+		buf.WriteString("\n")
+		formatGrid(buf, grid, false)
+	}
+
+	grid := [][]string{{"name", "config", "distribution", "range"}}
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			min, max, ok := termRange(row)
+			if !ok {
+				continue
+			}
+			name := row.Benchmark
+			if row.Group != "" {
+				name = row.Group + " " + name
+			}
+			label := name + " " + table.Metric
+			for i, m := range row.Metrics {
+				if len(m.Values) == 0 {
+					continue
+				}
+				grid = append(grid, []string{label, table.Configs[i], termHistogram(m, min, max), row.Scaler(min) + " – " + row.Scaler(max)})
+				label = ""
+			}
+		}
+	}
+	if len(grid) > 1 {
+		buf.WriteString("\n")
+		formatGrid(buf, grid, true)
+	}
+}
+
+// termRange returns the smallest and largest values of the metrics in
+// row, and whether there are any values.
+func termRange(row *benchstat.Row) (min, max float64, ok bool) {
+	for _, m := range row.Metrics {
+		for _, v := range m.Values {
+			if !ok || v < min {
+				min = v
+			}
+			if !ok || v > max {
+				max = v
+			}
+			ok = true
+		}
+	}
+	return min, max, ok
+}
+
+// termHistogram returns a histogram of the values of m in [min, max],
+// a character for each of termBins bins.
+func termHistogram(m *benchstat.Metrics, min, max float64) string {
+	counts := m.Histogram(min, max, termBins)
+	most := 0
+	for _, n := range counts {
+		if n > most {
+			most = n
+		}
+	}
+	var s []rune
+	for _, n := range counts {
+		level := (n*(len(termLevels)-1) + most - 1) / most
+		s = append(s, termLevels[level])
+	}
+	return string(s)
+}
@@ -0,0 +1,9 @@
+#!/bin/sh
+# A benchstat -analyzer for tests, which flags Encode-8 in time/op with
+# the verdict given as its argument, if any.
+input=$(cat)
+case "$input" in
+*'"benchmark":"Encode-8"'*) ;;
+*) echo "analyzer.sh: no Encode-8 in input" >&2; exit 1 ;;
+esac
+echo '{"annotations":[{"unit":"ns/op","group":"pkg:example.com/codec","benchmark":"Encode-8","note":"[hot path]","verdict":"'"$1"'"}]}'
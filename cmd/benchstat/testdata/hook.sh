@@ -0,0 +1,9 @@
+#!/bin/sh
+# A benchstat -exec-before or -exec-after hook for tests, which reports
+# the result and whether the tables include Encode-8.
+input=$(cat)
+case "$input" in
+*Encode-8*) found=yes ;;
+*) found=no ;;
+esac
+echo "$1: result $BENCHSTAT_RESULT, Encode-8 $found"
@@ -0,0 +1,102 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/perf/benchstat"
+)
+
+// formatGrid appends rows to buf as fixed-width text in the style of
+// benchstat.FormatText: the first column is left-aligned and the others
+// are right-aligned, except that if note is set, the last column is a
+// left-aligned note. Rows with a single column are group headers and
+// are not aligned.
+func formatGrid(buf *bytes.Buffer, rows [][]string, note bool) {
+	var max []int
+	for _, row := range rows {
+		if len(row) == 1 {
+			continue
+		}
+		for i, s := range row {
+			for len(max) <= i {
+				max = append(max, 0)
+			}
+			if n := utf8.RuneCountInString(s); max[i] < n {
+				max[i] = n
+			}
+		}
+	}
+	for _, row := range rows {
+		for len(row) > 1 && row[len(row)-1] == "" {
+			row = row[:len(row)-1]
+		}
+		for i, s := range row {
+			switch {
+			case len(row) == 1:
+				// Group header row
+				buf.WriteString(s)
+			case i == 0:
+				fmt.Fprintf(buf, "%-*s", max[i], s)
+			case note && i == len(row)-1:
+				fmt.Fprintf(buf, "  %s", s)
+			default:
+				fmt.Fprintf(buf, "  %*s", max[i], s)
+			}
+		}
+		buf.WriteString("\n")
+	}
+}
+
+// formatText appends a text report of tables to buf, along with
+// the efficiency rows and missing benchmarks, if any, and the
+// diagnostics requested by -v, and the plots requested by -plot=term.
+func formatText(buf *bytes.Buffer, tables []*benchstat.Table, effRows []*efficiencyRow, missing []*benchstat.MissingBenchmark, configs []string) {
+	if effRows != nil {
+		formatEfficiencyText(buf, effRows)
+		if len(tables) > 0 {
+			buf.WriteString("\n")
+		}
+	}
+	benchstat.FormatText(buf, tables)
+	if missing != nil {
+		if len(tables) > 0 {
+			buf.WriteString("\n")
+		}
+		formatMissingText(buf, missing, configs)
+	}
+	if strings.EqualFold(*flagPlot, "term") {
+		formatTermPlot(buf, tables)
+	}
+	if *flagVerbose {
+		formatOutlierCounts(buf, tables)
+		formatOutlierValues(buf, tables, *flagOutliers)
+	}
+}
+
+// formatLabelDiffs appends to buf the configuration keys whose values
+// differ between the two configs, in the style of a unified diff,
+// followed by a blank line, if there are any.
+func formatLabelDiffs(buf *bytes.Buffer, diffs []*benchstat.LabelDiff, configs []string) {
+	if len(diffs) == 0 || len(configs) != 2 {
+		return
+	}
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", configs[0], configs[1])
+	for _, d := range diffs {
+		for i, sign := range []string{"-", "+"} {
+			if i >= len(d.Values) {
+				break
+			}
+			for _, v := range d.Values[i] {
+				fmt.Fprintf(buf, "%s%s: %s\n", sign, d.Key, v)
+			}
+		}
+	}
+	buf.WriteString("\n")
+}
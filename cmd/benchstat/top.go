@@ -0,0 +1,120 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagTop = flag.Int("top", 0, "show only the `n` benchmarks that changed most, summarizing the rest (0 shows all)")
+
+// topTables returns copies of tables keeping only the rows of the n
+// benchmarks that changed the most, by the largest factor of any of
+// their significant changes, so that halving a time ranks with doubling
+// a speed, along with the geometric means, and a summary of the
+// benchmarks left out, such as
+//
+//	12 benchmarks with smaller changes not shown
+//	312 benchmarks unchanged
+//
+// which is empty if none are. Tables left with no benchmarks are
+// dropped.
+func topTables(tables []*benchstat.Table, n int) ([]*benchstat.Table, string) {
+	type bench struct{ group, name string }
+	var order []bench
+	change := make(map[bench]float64) // largest change, or -1 if none
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			if row.Benchmark == "[Geo mean]" {
+				continue
+			}
+			b := bench{row.Group, row.Benchmark}
+			if _, ok := change[b]; !ok {
+				order = append(order, b)
+				change[b] = -1
+			}
+			for _, r := range append([]*benchstat.Row{row}, row.Deltas...) {
+				if r == nil || r.Change == 0 {
+					continue
+				}
+				c := 0.0
+				if r.Ratio > 0 {
+					c = math.Abs(math.Log(r.Ratio))
+				}
+				change[b] = math.Max(change[b], c)
+			}
+		}
+	}
+
+	var changed []bench
+	for _, b := range order {
+		if change[b] >= 0 {
+			changed = append(changed, b)
+		}
+	}
+	sort.SliceStable(changed, func(i, j int) bool {
+		return change[changed[i]] > change[changed[j]]
+	})
+	keep := make(map[bench]bool)
+	for i, b := range changed {
+		if i < n {
+			keep[b] = true
+		}
+	}
+
+	var out []*benchstat.Table
+	for _, table := range tables {
+		t := new(benchstat.Table)
+		*t = *table
+		t.Rows = nil
+		kept := false
+		for _, row := range table.Rows {
+			if keep[bench{row.Group, row.Benchmark}] {
+				t.Rows = append(t.Rows, row)
+				kept = true
+			} else if row.Benchmark == "[Geo mean]" {
+				t.Rows = append(t.Rows, row)
+			}
+		}
+		if kept {
+			out = append(out, t)
+		}
+	}
+
+	var summary string
+	if more := len(changed) - len(keep); more > 0 {
+		summary += fmt.Sprintf("%s with smaller changes not shown\n", benchmarks(more))
+	}
+	if same := len(order) - len(changed); same > 0 {
+		summary += fmt.Sprintf("%s unchanged\n", benchmarks(same))
+	}
+	return out, summary
+}
+
+// writeElided appends to buf the summary of the benchmarks -top left
+// out, if any, after a blank line separating it from the tables.
+func writeElided(buf *bytes.Buffer, summary string) {
+	if summary == "" {
+		return
+	}
+	if buf.Len() > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString(summary)
+}
+
+// benchmarks returns "1 benchmark" or "n benchmarks".
+func benchmarks(n int) string {
+	if n == 1 {
+		return "1 benchmark"
+	}
+	return fmt.Sprintf("%d benchmarks", n)
+}
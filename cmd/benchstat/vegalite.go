@@ -0,0 +1,122 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagVegaLite = flag.String("vega-lite", "", "write Vega-Lite specifications of the delta chart and sample distributions to `dir`")
+
+// vegaLiteSchema is the schema of the Vega-Lite specifications written.
+const vegaLiteSchema = "https://vega.github.io/schema/vega-lite/v5.json"
+
+// A vegaLiteSpec is a Vega-Lite specification, as JSON.
+type vegaLiteSpec map[string]interface{}
+
+// changeNames name the values of benchstat.Row.Change.
+var changeNames = map[int]string{-1: "worse", 0: "unchanged", +1: "better"}
+
+// deltaSpec returns a Vega-Lite specification of the chart written by
+// -delta-chart, or nil if tables compare no benchmarks.
+func deltaSpec(tables []*benchstat.Table) vegaLiteSpec {
+	bars := deltaBars(tables)
+	if len(bars) == 0 {
+		return nil
+	}
+	var values []vegaLiteSpec
+	for _, b := range bars {
+		values = append(values, vegaLiteSpec{"benchmark": b.label, "delta": b.pct, "worse": b.worse, "change": changeNames[b.change]})
+	}
+	return vegaLiteSpec{
+		"$schema":     vegaLiteSchema,
+		"description": "Change of each benchmark, from the greatest improvement to the greatest regression.",
+		"data":        vegaLiteSpec{"values": values},
+		"mark":        "bar",
+		"encoding": vegaLiteSpec{
+			"y": vegaLiteSpec{"field": "benchmark", "type": "nominal", "sort": nil, "title": nil},
+			"x": vegaLiteSpec{"field": "delta", "type": "quantitative", "title": "delta (%)"},
+			"color": vegaLiteSpec{
+				"field": "change",
+				"type":  "nominal",
+				"scale": vegaLiteSpec{"domain": []string{"better", "worse", "unchanged"}, "range": []string{"#393", "#c33", "#aaa"}},
+			},
+			"tooltip": []vegaLiteSpec{
+				{"field": "benchmark", "type": "nominal"},
+				{"field": "delta", "type": "quantitative", "format": "+.2f"},
+			},
+		},
+	}
+}
+
+// samplesSpec returns a Vega-Lite specification of box plots of the
+// values of each benchmark in each configuration, a row of plots for
+// each unit, or nil if tables have no values.
+func samplesSpec(tables []*benchstat.Table) vegaLiteSpec {
+	var values []vegaLiteSpec
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			name := row.Benchmark
+			if row.Group != "" {
+				name = row.Group + " " + name
+			}
+			for i, m := range row.Metrics {
+				for _, v := range m.Values {
+					values = append(values, vegaLiteSpec{"benchmark": name, "unit": table.Unit, "config": table.Configs[i], "value": v})
+				}
+			}
+		}
+	}
+	if values == nil {
+		return nil
+	}
+	return vegaLiteSpec{
+		"$schema":     vegaLiteSchema,
+		"description": "Distribution of the values of each benchmark in each configuration.",
+		"data":        vegaLiteSpec{"values": values},
+		"facet":       vegaLiteSpec{"row": vegaLiteSpec{"field": "unit", "type": "nominal", "sort": nil}},
+		"resolve":     vegaLiteSpec{"scale": vegaLiteSpec{"x": "independent", "y": "independent"}},
+		"spec": vegaLiteSpec{
+			"mark": vegaLiteSpec{"type": "boxplot", "extent": 1.5},
+			"encoding": vegaLiteSpec{
+				"y":       vegaLiteSpec{"field": "benchmark", "type": "nominal", "sort": nil, "title": nil},
+				"yOffset": vegaLiteSpec{"field": "config", "sort": nil},
+				"x":       vegaLiteSpec{"field": "value", "type": "quantitative", "scale": vegaLiteSpec{"zero": false}},
+				"color":   vegaLiteSpec{"field": "config", "type": "nominal", "sort": nil},
+			},
+		},
+	}
+}
+
+// writeVegaLite writes the Vega-Lite specifications of the charts of
+// tables to dir, as delta.vl.json and samples.vl.json, with their data
+// inline, for embedding in notebooks and web pages.
+func writeVegaLite(dir string, tables []*benchstat.Table) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	for file, spec := range map[string]vegaLiteSpec{
+		"delta.vl.json":   deltaSpec(tables),
+		"samples.vl.json": samplesSpec(tables),
+	} {
+		if spec == nil {
+			continue
+		}
+		data, err := json.MarshalIndent(spec, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, file), append(data, '\n'), 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
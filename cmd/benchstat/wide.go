@@ -0,0 +1,96 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"sort"
+
+	"golang.org/x/perf/benchstat"
+)
+
+var flagWide = flag.Bool("wide", false, "compare two inputs in a single table with old, new, and delta columns for each unit side by side")
+
+// A wideRow is the old and new values and the change of one benchmark
+// in each unit.
+type wideRow struct {
+	group, benchmark string
+	cols             map[int][]string // by table
+}
+
+// formatWideText appends to buf a single table of the tables comparing
+// two configurations, with a row for each benchmark and the old, new,
+// and delta columns of each unit side by side, as in
+//
+//	name    old time/op  new time/op    delta  old alloc/op  new alloc/op    delta
+//	Parse   5.18µs ± 1%  4.70µs ± 2%   -9.20%   4.10kB ± 0%   2.05kB ± 0%  -50.00%
+//	Encode   793ns ± 1%   648ns ± 2%  -18.26%     512B ± 0%     768B ± 0%  +50.00%
+//
+// The columns of a unit are empty for benchmarks not measured in it,
+// and the geometric means, if any, follow the benchmarks of their group.
+// Notes, such as p-values, are left out.
+func formatWideText(buf *bytes.Buffer, tables []*benchstat.Table) {
+	header := []string{"name"}
+	var units []int
+	var rows []*wideRow
+	index := make(map[[2]string]*wideRow)
+	for i, table := range tables {
+		if !table.OldNewDelta {
+			continue
+		}
+		units = append(units, i)
+		header = append(header, "old "+table.Metric, "new "+table.Metric, "delta")
+		for _, row := range table.Rows {
+			id := [2]string{row.Group, row.Benchmark}
+			r := index[id]
+			if r == nil {
+				r = &wideRow{group: row.Group, benchmark: row.Benchmark, cols: make(map[int][]string)}
+				index[id] = r
+				rows = append(rows, r)
+			}
+			delta := row.Delta
+			if delta == "~" {
+				delta = "~   "
+			}
+			r.cols[i] = []string{row.Metrics[0].Format(row.Scaler), row.Metrics[1].Format(row.Scaler), delta}
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	groups := make(map[string]int)
+	for _, r := range rows {
+		if _, ok := groups[r.group]; !ok {
+			groups[r.group] = len(groups)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		gi, gj := groups[rows[i].group], groups[rows[j].group]
+		if gi != gj {
+			return gi < gj
+		}
+		return rows[i].benchmark != "[Geo mean]" && rows[j].benchmark == "[Geo mean]"
+	})
+
+	text := [][]string{header}
+	group := ""
+	for _, r := range rows {
+		if r.group != group {
+			group = r.group
+			text = append(text, []string{group})
+		}
+		line := []string{r.benchmark}
+		for _, i := range units {
+			if cols := r.cols[i]; cols != nil {
+				line = append(line, cols...)
+			} else {
+				line = append(line, "", "", "")
+			}
+		}
+		text = append(text, line)
+	}
+	formatGrid(buf, text, false)
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build js && wasm
+// +build js,wasm
+
+// Benchstatjs is benchstat compiled to WebAssembly, for web pages that
+// compare benchmark results in the browser, without a server.
+//
+// Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o benchstat.wasm golang.org/x/perf/cmd/benchstatjs
+//
+// and serve benchstat.wasm with index.html, from this directory, and the
+// wasm_exec.js shipped with Go, in the lib/wasm directory of $(go env GOROOT),
+// which index.html loads.
+//
+// Once running, benchstat.wasm defines a global object benchstat with three functions,
+// each taking benchmark results as strings in the format of "go test -bench"
+// and returning an object with either a field result or a field error:
+//
+//	benchstat.parse(text)
+//
+// returns in result the benchmarks in text, as an array of objects with
+// fields group, benchmark, unit, n, and center;
+//
+//	benchstat.compare(old, new[, format])
+//
+// returns in result the comparison of old with new, formatted as by
+// benchstat -output format, which is text by default; and
+//
+//	benchstat.renderHTML(old, new)
+//
+// returns in result the comparison of old with new as HTML tables.
+// As with the benchstat command, results are split by pkg, goos, and
+// goarch, and tables end with the geometric mean of their benchmarks.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"syscall/js"
+
+	"golang.org/x/perf/benchstat"
+)
+
+func main() {
+	api := map[string]interface{}{
+		"parse":      js.FuncOf(parse),
+		"compare":    js.FuncOf(compare),
+		"renderHTML": js.FuncOf(renderHTML),
+	}
+	js.Global().Set("benchstat", js.ValueOf(api))
+	// Keep the functions alive for the life of the page.
+	select {}
+}
+
+// parse implements benchstat.parse(text).
+func parse(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return failure(fmt.Errorf("parse takes one argument, the benchmark results"))
+	}
+	tables, err := benchstat.CompareFiles(benchstat.File{Name: "results", Data: []byte(args[0].String())})
+	if err != nil {
+		return failure(err)
+	}
+	var rows []interface{}
+	for _, t := range tables {
+		for _, row := range t.Rows {
+			if row.Benchmark == "[Geo mean]" {
+				continue
+			}
+			m := row.Metrics[0]
+			rows = append(rows, map[string]interface{}{
+				"group":     row.Group,
+				"benchmark": row.Benchmark,
+				"unit":      t.Unit,
+				"n":         m.Count,
+				"center":    m.Center,
+			})
+		}
+	}
+	return success(rows)
+}
+
+// compare implements benchstat.compare(old, new[, format]).
+func compare(this js.Value, args []js.Value) interface{} {
+	if len(args) != 2 && len(args) != 3 {
+		return failure(fmt.Errorf("compare takes two or three arguments: old results, new results, and format"))
+	}
+	format := "text"
+	if len(args) == 3 {
+		format = args[2].String()
+	}
+	tables, err := compareArgs(args)
+	if err != nil {
+		return failure(err)
+	}
+	var buf bytes.Buffer
+	if err := benchstat.Format(&buf, tables, benchstat.FormatOptions{Format: format}); err != nil {
+		return failure(err)
+	}
+	return success(buf.String())
+}
+
+// renderHTML implements benchstat.renderHTML(old, new).
+func renderHTML(this js.Value, args []js.Value) interface{} {
+	if len(args) != 2 {
+		return failure(fmt.Errorf("renderHTML takes two arguments: old and new results"))
+	}
+	tables, err := compareArgs(args)
+	if err != nil {
+		return failure(err)
+	}
+	var buf bytes.Buffer
+	benchstat.FormatHTML(&buf, tables)
+	return success(buf.String())
+}
+
+// compareArgs compares the old and new results in the first two of args.
+func compareArgs(args []js.Value) ([]*benchstat.Table, error) {
+	return benchstat.CompareFiles(
+		benchstat.File{Name: "old", Data: []byte(args[0].String())},
+		benchstat.File{Name: "new", Data: []byte(args[1].String())},
+	)
+}
+
+func success(result interface{}) interface{} {
+	return map[string]interface{}{"result": result}
+}
+
+func failure(err error) interface{} {
+	return map[string]interface{}{"error": err.Error()}
+}
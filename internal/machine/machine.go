@@ -0,0 +1,157 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package machine describes the machine benchmarks run on, so that
+// results from different machines can be told apart.
+package machine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// A Fingerprint describes a machine. Fields that cannot be determined
+// are empty or zero.
+type Fingerprint struct {
+	CPU      string // CPU model, such as "Intel(R) Xeon(R) CPU E5-2690 v4 @ 2.60GHz"
+	Cores    int    // number of logical CPUs of the machine
+	Memory   int    // memory, in GiB, rounded
+	Kernel   string // operating system and kernel release, such as "linux 4.19.0-5-amd64"
+	Governor string // CPU frequency governor of the first CPU, on Linux
+	Virt     string // hypervisor, such as "kvm" or "vmware", "vm" if unknown, or "none" on bare metal
+}
+
+// Detect returns the fingerprint of the machine it runs on. It knows
+// most about Linux.
+func Detect() *Fingerprint {
+	f := detect("/")
+	if f.Cores == 0 {
+		f.Cores = runtime.NumCPU()
+	}
+	if f.Kernel == "" {
+		if out, err := exec.Command("uname", "-r").Output(); err == nil {
+			f.Kernel = runtime.GOOS + " " + strings.TrimSpace(string(out))
+		}
+	}
+	return f
+}
+
+// hypervisors maps words in the DMI system vendor or product name of a
+// virtual machine to the name of its hypervisor.
+var hypervisors = []struct{ word, name string }{
+	{"KVM", "kvm"},
+	{"QEMU", "kvm"},
+	{"VMware", "vmware"},
+	{"VirtualBox", "virtualbox"},
+	{"Microsoft", "hyperv"},
+	{"Xen", "xen"},
+	{"Amazon EC2", "aws"},
+	{"Google", "gce"},
+}
+
+// detect returns the fingerprint of the Linux machine whose /proc and
+// /sys are under root.
+func detect(root string) *Fingerprint {
+	read := func(file string) string {
+		data, err := ioutil.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	fp := new(Fingerprint)
+
+	cpuinfo := read("proc/cpuinfo")
+	hypervisor := false
+	for _, line := range strings.Split(cpuinfo, "\n") {
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		switch key {
+		case "processor":
+			fp.Cores++
+		case "model name":
+			if fp.CPU == "" {
+				fp.CPU = value
+			}
+		case "flags":
+			for _, flag := range strings.Fields(value) {
+				hypervisor = hypervisor || flag == "hypervisor"
+			}
+		}
+	}
+
+	for _, line := range strings.Split(read("proc/meminfo"), "\n") {
+		if f := strings.Fields(line); len(f) >= 2 && f[0] == "MemTotal:" {
+			kB, _ := strconv.ParseInt(f[1], 10, 64)
+			fp.Memory = int((kB + 1<<19) >> 20)
+		}
+	}
+
+	if release := read("proc/sys/kernel/osrelease"); release != "" {
+		fp.Kernel = "linux " + release
+	}
+	fp.Governor = read("sys/devices/system/cpu/cpu0/cpufreq/scaling_governor")
+
+	if cpuinfo != "" {
+		fp.Virt = "none"
+		if hypervisor {
+			fp.Virt = "vm"
+			dmi := read("sys/class/dmi/id/sys_vendor") + " " + read("sys/class/dmi/id/product_name")
+			for _, h := range hypervisors {
+				if strings.Contains(dmi, h.word) {
+					fp.Virt = h.name
+					break
+				}
+			}
+		}
+	}
+	return fp
+}
+
+// ID returns a short identifier of the hardware of the machine: its
+// CPU, cores, memory, and hypervisor. Machines with the same ID are
+// likely to run benchmarks at the same speed; those with different IDs
+// are not. The kernel and governor are left out, since they change
+// with the configuration of the same machine.
+func (f *Fingerprint) ID() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00%d\x00%s", f.CPU, f.Cores, f.Memory, f.Virt)
+	return fmt.Sprintf("%x", h.Sum(nil)[:6])
+}
+
+// Labels returns configuration lines recording f, such as
+//
+//	machine: 3f2a9c01d4e7
+//	machine-cpu: Intel(R) Xeon(R) CPU E5-2690 v4 @ 2.60GHz
+//	machine-cores: 56
+//
+// omitting the fields that are not known.
+func (f *Fingerprint) Labels() []byte {
+	var buf bytes.Buffer
+	line := func(key, value string) {
+		if value != "" && value != "0" {
+			fmt.Fprintf(&buf, "%s: %s\n", key, value)
+		}
+	}
+	line("machine", f.ID())
+	line("machine-cpu", f.CPU)
+	line("machine-cores", strconv.Itoa(f.Cores))
+	if f.Memory != 0 {
+		line("machine-memory", fmt.Sprintf("%dGiB", f.Memory))
+	}
+	line("machine-kernel", f.Kernel)
+	line("machine-governor", f.Governor)
+	line("machine-virt", f.Virt)
+	return buf.Bytes()
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package machine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	root, err := ioutil.TempDir("", "machine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	files := map[string]string{
+		"proc/cpuinfo": `processor	: 0
+model name	: Intel(R) Xeon(R) CPU E5-2690 v4 @ 2.60GHz
+flags		: fpu vme hypervisor lahf_lm
+
+processor	: 1
+model name	: Intel(R) Xeon(R) CPU E5-2690 v4 @ 2.60GHz
+flags		: fpu vme hypervisor lahf_lm
+`,
+		"proc/meminfo":                  "MemTotal:        8167848 kB\nMemFree:         1234567 kB\n",
+		"proc/sys/kernel/osrelease":     "4.19.0-5-amd64\n",
+		"sys/class/dmi/id/sys_vendor":   "QEMU\n",
+		"sys/class/dmi/id/product_name": "Standard PC (i440FX + PIIX, 1996)\n",
+	}
+	for file, data := range files {
+		file = filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := detect(root)
+	want := Fingerprint{
+		CPU:    "Intel(R) Xeon(R) CPU E5-2690 v4 @ 2.60GHz",
+		Cores:  2,
+		Memory: 8,
+		Kernel: "linux 4.19.0-5-amd64",
+		Virt:   "kvm",
+	}
+	if *f != want {
+		t.Errorf("detect:\nhave %+v\nwant %+v", *f, want)
+	}
+	wantLabels := "machine: " + f.ID() + `
+machine-cpu: Intel(R) Xeon(R) CPU E5-2690 v4 @ 2.60GHz
+machine-cores: 2
+machine-memory: 8GiB
+machine-kernel: linux 4.19.0-5-amd64
+machine-virt: kvm
+`
+	if got := string(f.Labels()); got != wantLabels {
+		t.Errorf("Labels:\nhave %s\nwant %s", got, wantLabels)
+	}
+
+	// The kernel does not change the ID; the memory does.
+	g := *f
+	g.Kernel = "linux 5.0.0"
+	if g.ID() != f.ID() {
+		t.Errorf("ID changed with the kernel")
+	}
+	g.Memory = 16
+	if g.ID() == f.ID() {
+		t.Errorf("ID did not change with the memory")
+	}
+}
@@ -110,3 +110,53 @@ func series(f func(float64) float64) float64 {
 	}
 	return y
 }
+
+// selectFloat64s partially sorts xs in place such that xs[k] holds
+// the value it would hold if xs were fully sorted, every value
+// before it is <= xs[k], and every value after it is >= xs[k].
+//
+// This uses Hoare's quickselect with a median-of-three pivot and
+// runs in expected linear time.
+func selectFloat64s(xs []float64, k int) {
+	lo, hi := 0, len(xs)-1
+	for lo < hi {
+		// Order xs[lo], xs[mid], xs[hi] and use the median as
+		// the pivot. This also places sentinels at both ends.
+		mid := lo + (hi-lo)/2
+		if xs[mid] < xs[lo] {
+			xs[mid], xs[lo] = xs[lo], xs[mid]
+		}
+		if xs[hi] < xs[lo] {
+			xs[hi], xs[lo] = xs[lo], xs[hi]
+		}
+		if xs[hi] < xs[mid] {
+			xs[hi], xs[mid] = xs[mid], xs[hi]
+		}
+		pivot := xs[mid]
+
+		i, j := lo, hi
+		for i <= j {
+			for xs[i] < pivot {
+				i++
+			}
+			for pivot < xs[j] {
+				j--
+			}
+			if i <= j {
+				xs[i], xs[j] = xs[j], xs[i]
+				i++
+				j--
+			}
+		}
+		// Now xs[lo:j+1] <= pivot <= xs[i:hi+1], and any
+		// values between j and i are equal to pivot.
+		switch {
+		case k <= j:
+			hi = j
+		case k >= i:
+			lo = i
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stats
+
+import "math"
+
+// P2Quantile estimates a quantile of a stream of values in constant
+// space using the P² algorithm of Jain and Chlamtac (1985).
+//
+// Until five values have been added, the estimate is exact.
+type P2Quantile struct {
+	p     float64
+	count int
+	q     [5]float64 // marker heights
+	n     [5]float64 // actual marker positions
+	np    [5]float64 // desired marker positions
+	dn    [5]float64 // increments of desired positions
+}
+
+// NewP2Quantile returns an estimator for the pth quantile, where p
+// is in the range [0, 1].
+func NewP2Quantile(p float64) *P2Quantile {
+	return &P2Quantile{
+		p:  p,
+		n:  [5]float64{1, 2, 3, 4, 5},
+		np: [5]float64{1, 1 + 2*p, 1 + 4*p, 3 + 2*p, 5},
+		dn: [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+	}
+}
+
+// Count returns the number of values added to e.
+func (e *P2Quantile) Count() int {
+	return e.count
+}
+
+// Add adds x to the stream of values.
+func (e *P2Quantile) Add(x float64) {
+	if e.count < 5 {
+		e.q[e.count] = x
+		e.count++
+		if e.count == 5 {
+			s := Sample{Xs: e.q[:]}
+			s.Sort()
+		}
+		return
+	}
+	e.count++
+
+	// Find the cell k containing x, extending the extreme
+	// markers if necessary.
+	var k int
+	switch {
+	case x < e.q[0]:
+		e.q[0] = x
+		k = 0
+	case x >= e.q[4]:
+		e.q[4] = x
+		k = 3
+	default:
+		for k = 0; k < 3 && x >= e.q[k+1]; k++ {
+		}
+	}
+	for i := k + 1; i < 5; i++ {
+		e.n[i]++
+	}
+	for i := range e.np {
+		e.np[i] += e.dn[i]
+	}
+
+	// Adjust the heights of the middle markers if they are
+	// off from their desired positions.
+	for i := 1; i <= 3; i++ {
+		d := e.np[i] - e.n[i]
+		if (d >= 1 && e.n[i+1]-e.n[i] > 1) || (d <= -1 && e.n[i-1]-e.n[i] < -1) {
+			d = mathSign(d)
+			q := e.parabolic(i, d)
+			if !(e.q[i-1] < q && q < e.q[i+1]) {
+				q = e.linear(i, d)
+			}
+			e.q[i] = q
+			e.n[i] += d
+		}
+	}
+}
+
+func (e *P2Quantile) parabolic(i int, d float64) float64 {
+	return e.q[i] + d/(e.n[i+1]-e.n[i-1])*
+		((e.n[i]-e.n[i-1]+d)*(e.q[i+1]-e.q[i])/(e.n[i+1]-e.n[i])+
+			(e.n[i+1]-e.n[i]-d)*(e.q[i]-e.q[i-1])/(e.n[i]-e.n[i-1]))
+}
+
+func (e *P2Quantile) linear(i int, d float64) float64 {
+	j := i + int(d)
+	return e.q[i] + d*(e.q[j]-e.q[i])/(e.n[j]-e.n[i])
+}
+
+// Value returns the current estimate of the quantile. If no values
+// have been added, it returns NaN.
+func (e *P2Quantile) Value() float64 {
+	switch {
+	case e.count == 0:
+		return math.NaN()
+	case e.count <= 5:
+		xs := make([]float64, e.count)
+		copy(xs, e.q[:e.count])
+		return Sample{Xs: xs}.Percentile(e.p)
+	}
+	return e.q[2]
+}
@@ -0,0 +1,41 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestP2Quantile(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, p := range []float64{0.1, 0.25, 0.5, 0.75, 0.9} {
+		e := NewP2Quantile(p)
+		var xs []float64
+		for i := 0; i < 10000; i++ {
+			x := r.Float64()
+			xs = append(xs, x)
+			e.Add(x)
+		}
+		want := Sample{Xs: xs}.Percentile(p)
+		if got := e.Value(); math.Abs(got-want) > 0.01 {
+			t.Errorf("P2 quantile %v of uniform: want %v, got %v", p, want, got)
+		}
+	}
+}
+
+func TestP2QuantileSmall(t *testing.T) {
+	e := NewP2Quantile(0.5)
+	if got := e.Value(); !math.IsNaN(got) {
+		t.Errorf("empty P2 median: want NaN, got %v", got)
+	}
+	for _, x := range []float64{40, 15, 50} {
+		e.Add(x)
+	}
+	if got := e.Value(); got != 40 {
+		t.Errorf("P2 median of 3 values: want 40, got %v", got)
+	}
+}
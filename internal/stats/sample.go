@@ -233,7 +233,8 @@ func (s Sample) StdDev() float64 {
 // Percentile(0.5) is the median. Percentile(0.25) and
 // Percentile(0.75) are the first and third quartiles, respectively.
 //
-// This is constant time if s.Sorted and s.Weights == nil.
+// This is constant time if s.Sorted and s.Weights == nil, and
+// expected linear time if only s.Weights == nil.
 func (s Sample) Percentile(pctile float64) float64 {
 	if len(s.Xs) == 0 {
 		return math.NaN()
@@ -245,11 +246,6 @@ func (s Sample) Percentile(pctile float64) float64 {
 		return max
 	}
 
-	if !s.Sorted {
-		// TODO(austin) Use select algorithm instead
-		s = *s.Copy().Sort()
-	}
-
 	if s.Weights == nil {
 		N := float64(len(s.Xs))
 		//n := pctile * (N + 1) // R6
@@ -257,12 +253,28 @@ func (s Sample) Percentile(pctile float64) float64 {
 		kf, frac := math.Modf(n)
 		k := int(kf)
 		if k <= 0 {
-			return s.Xs[0]
+			min, _ := s.Bounds()
+			return min
 		} else if k >= len(s.Xs) {
-			return s.Xs[len(s.Xs)-1]
+			_, max := s.Bounds()
+			return max
+		}
+		if s.Sorted {
+			return s.Xs[k-1] + frac*(s.Xs[k]-s.Xs[k-1])
 		}
-		return s.Xs[k-1] + frac*(s.Xs[k]-s.Xs[k-1])
+		// Rather than sorting, select the k'th smallest value
+		// in a copy of Xs. The next smallest value is then the
+		// minimum of everything after it.
+		xs := make([]float64, len(s.Xs))
+		copy(xs, s.Xs)
+		selectFloat64s(xs, k-1)
+		next, _ := Bounds(xs[k:])
+		return xs[k-1] + frac*(next-xs[k-1])
 	} else {
+		if !s.Sorted {
+			s = *s.Copy().Sort()
+		}
+
 		// TODO(austin): Implement interpolation
 
 		target := s.Weight() * pctile
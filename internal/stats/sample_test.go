@@ -4,7 +4,10 @@
 
 package stats
 
-import "testing"
+import (
+	"math/rand"
+	"testing"
+)
 
 func TestSamplePercentile(t *testing.T) {
 	s := Sample{Xs: []float64{15, 20, 35, 40, 50}}
@@ -19,3 +22,19 @@ func TestSamplePercentile(t *testing.T) {
 		2:   50,
 	})
 }
+
+func TestSamplePercentileSelect(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	xs := make([]float64, 1001)
+	for i := range xs {
+		// Use few distinct values to exercise ties.
+		xs[i] = float64(r.Intn(50))
+	}
+	unsorted := Sample{Xs: xs}
+	sorted := unsorted.Copy().Sort()
+	for _, p := range []float64{0.001, 0.1, 0.25, 0.5, 0.75, 0.9, 0.999} {
+		if want, got := sorted.Percentile(p), unsorted.Percentile(p); want != got {
+			t.Errorf("Percentile(%v): sorted %v, unsorted %v", p, want, got)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// shiftExactLimit gives the largest sample size for which
+// ShiftInterval uses the exact U distribution. Above it, the normal
+// approximation is used, as computing the exact distribution takes
+// time quartic in the sample size.
+const shiftExactLimit = 20
+
+// ShiftInterval returns a distribution-free confidence interval
+// [lo, hi] for the shift d such that ys - d has the same distribution
+// as xs, at the given confidence level, such as 0.95.
+//
+// The interval is bounded by order statistics of the pairwise
+// differences ys[j] - xs[i], chosen using the distribution of the
+// Mann-Whitney U statistic, as described in Hollander, Wolfe, and
+// Chicken (2014), "Nonparametric Statistical Methods", section 4.3.
+// Ties are ignored when choosing the order statistics, which are
+// selected without computing all n1·n2 differences.
+//
+// If the samples are too small for any interval to have the
+// requested confidence, ShiftInterval returns ErrSampleSize.
+func ShiftInterval(xs, ys []float64, confidence float64) (lo, hi float64, err error) {
+	n1, n2 := len(xs), len(ys)
+	if n1 == 0 || n2 == 0 {
+		return 0, 0, ErrSampleSize
+	}
+	alpha := 1 - confidence
+
+	// Find the largest k such that P(U < k) <= alpha/2.
+	// The interval is then [d(k), d(n1*n2-k+1)], indexing the
+	// sorted differences d from 1.
+	var k int
+	if n1 <= shiftExactLimit && n2 <= shiftExactLimit {
+		pmf := UDist{N1: n1, N2: n2}.p(n1 * n2 / 2)
+		cdf := 0.0
+		for k < len(pmf) && cdf+pmf[k] <= alpha/2 {
+			cdf += pmf[k]
+			k++
+		}
+	} else {
+		mu := float64(n1*n2) / 2
+		sigma := math.Sqrt(float64(n1*n2*(n1+n2+1)) / 12)
+		z := StdNormal.InvCDF(1 - alpha/2)
+		k = int(math.Floor(mu - z*sigma + 0.5))
+	}
+	if k < 1 {
+		return 0, 0, ErrSampleSize
+	}
+
+	d := newPairDiffs(xs, ys)
+	return d.kth(k), d.kth(n1*n2 - k + 1), nil
+}
+
+// ShiftEstimate returns the Hodges-Lehmann estimate of the shift d
+// such that ys - d has the same distribution as xs: the median of the
+// pairwise differences ys[j] - xs[i]. It is the point estimate that
+// matches the interval of ShiftInterval, which always contains it.
+// It returns NaN if either sample is empty.
+func ShiftEstimate(xs, ys []float64) float64 {
+	n := len(xs) * len(ys)
+	if n == 0 {
+		return math.NaN()
+	}
+	d := newPairDiffs(xs, ys)
+	if n%2 == 1 {
+		return d.kth((n + 1) / 2)
+	}
+	return (d.kth(n/2) + d.kth(n/2+1)) / 2
+}
+
+// pairDiffs selects order statistics of the pairwise differences
+// ys[j] - xs[i] of two samples without computing all of them, which
+// would take time and memory proportional to the product of the
+// sample sizes.
+type pairDiffs struct {
+	xs, ys []float64 // sorted
+}
+
+func newPairDiffs(xs, ys []float64) *pairDiffs {
+	d := &pairDiffs{append([]float64(nil), xs...), append([]float64(nil), ys...)}
+	sort.Float64s(d.xs)
+	sort.Float64s(d.ys)
+	return d
+}
+
+// countLE returns the number of differences at most t. Since each row
+// ys[j] - xs[...] of differences decreases, and each column increases,
+// it walks the boundary of the differences at most t in linear time.
+func (d *pairDiffs) countLE(t float64) int {
+	n, i := 0, 0
+	for _, y := range d.ys {
+		for i < len(d.xs) && y-d.xs[i] > t {
+			i++
+		}
+		n += len(d.xs) - i
+	}
+	return n
+}
+
+// kth returns the kth smallest difference, counting from 1.
+//
+// It bisects the range of differences until few lie between the
+// bounds, counting the differences below each midpoint, and then
+// sorts those that remain.
+func (d *pairDiffs) kth(k int) float64 {
+	nx, ny := len(d.xs), len(d.ys)
+	// The differences at most a number na < k, and those at most b
+	// number nb >= k.
+	a := d.ys[0] - d.xs[nx-1]
+	na := d.countLE(a)
+	if na >= k {
+		return a
+	}
+	b, nb := d.ys[ny-1]-d.xs[0], nx*ny
+	for nb-na > nx+ny {
+		mid := a + (b-a)/2
+		if mid <= a || mid >= b {
+			// No difference lies strictly between a and b,
+			// so those above a all equal b.
+			return b
+		}
+		if n := d.countLE(mid); n >= k {
+			b, nb = mid, n
+		} else {
+			a, na = mid, n
+		}
+	}
+	between := make([]float64, 0, nb-na)
+	ia, ib := 0, 0
+	for _, y := range d.ys {
+		for ia < nx && y-d.xs[ia] > a {
+			ia++
+		}
+		for ib < nx && y-d.xs[ib] > b {
+			ib++
+		}
+		for _, x := range d.xs[ib:ia] {
+			between = append(between, y-x)
+		}
+	}
+	sort.Float64s(between)
+	return between[k-na-1]
+}
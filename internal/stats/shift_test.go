@@ -0,0 +1,94 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestShiftInterval(t *testing.T) {
+	xs := []float64{1, 2, 3, 4, 5}
+	ys := []float64{11, 12, 13, 14, 15}
+	// For n1 = n2 = 5, P(U <= 2) = 4/252 <= 0.025 < P(U <= 3),
+	// so the interval is from the 3rd to the 23rd difference.
+	lo, hi, err := ShiftInterval(xs, ys, 0.95)
+	if err != nil || lo != 7 || hi != 13 {
+		t.Errorf("ShiftInterval(%v, %v, 0.95) = %v, %v, %v, want 7, 13, nil", xs, ys, lo, hi, err)
+	}
+
+	// Lower confidence gives a narrower interval.
+	lo, hi, err = ShiftInterval(xs, ys, 0.5)
+	if err != nil || lo <= 7 || hi >= 13 || lo > 10 || hi < 10 {
+		t.Errorf("ShiftInterval(%v, %v, 0.5) = %v, %v, %v, want within (7, 13) around 10", xs, ys, lo, hi, err)
+	}
+
+	if _, _, err := ShiftInterval([]float64{1, 2}, []float64{3, 4}, 0.95); err != ErrSampleSize {
+		t.Errorf("ShiftInterval with n=2+2: err = %v, want %v", err, ErrSampleSize)
+	}
+
+	// Large samples use the normal approximation.
+	var big1, big2 []float64
+	for i := 0; i < 30; i++ {
+		big1 = append(big1, float64(i))
+		big2 = append(big2, float64(i)+100)
+	}
+	lo, hi, err = ShiftInterval(big1, big2, 0.95)
+	if err != nil || lo >= 100 || hi <= 100 || lo < 90 || hi > 110 {
+		t.Errorf("ShiftInterval(n=30+30) = %v, %v, %v, want around 100", lo, hi, err)
+	}
+}
+
+func TestShiftEstimate(t *testing.T) {
+	xs := []float64{1, 2, 3, 4, 5}
+	ys := []float64{11, 12, 13, 14, 100}
+	if got := ShiftEstimate(xs, ys); got != 10 {
+		t.Errorf("ShiftEstimate(%v, %v) = %v, want 10", xs, ys, got)
+	}
+	if got := ShiftEstimate([]float64{0, 1}, []float64{5, 7}); got != 5.5 {
+		t.Errorf("ShiftEstimate of an even number of differences = %v, want 5.5", got)
+	}
+	if got := ShiftEstimate(nil, ys); !math.IsNaN(got) {
+		t.Errorf("ShiftEstimate of an empty sample = %v, want NaN", got)
+	}
+}
+
+func TestPairDiffs(t *testing.T) {
+	// Compare the selected order statistics with those of all the
+	// sorted differences, with and without ties.
+	r := rand.New(rand.NewSource(1))
+	for _, n := range [][2]int{{1, 1}, {1, 7}, {5, 3}, {40, 60}, {100, 100}} {
+		for _, ties := range []bool{false, true} {
+			sample := func(n int, shift float64) []float64 {
+				var xs []float64
+				for i := 0; i < n; i++ {
+					x := r.NormFloat64() + shift
+					if ties {
+						x = math.Round(x)
+					}
+					xs = append(xs, x)
+				}
+				return xs
+			}
+			xs, ys := sample(n[0], 0), sample(n[1], 1)
+			var all []float64
+			for _, y := range ys {
+				for _, x := range xs {
+					all = append(all, y-x)
+				}
+			}
+			sort.Float64s(all)
+			d := newPairDiffs(xs, ys)
+			for k := 1; k <= len(all); k++ {
+				if got := d.kth(k); got != all[k-1] {
+					t.Errorf("n=%v ties=%v: kth(%d) = %v, want %v", n, ties, k, got, all[k-1])
+					break
+				}
+			}
+		}
+	}
+}
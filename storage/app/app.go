@@ -44,9 +44,9 @@ var ErrResponseWritten = errors.New("response written")
 func (a *App) RegisterOnMux(mux *http.ServeMux) {
 	// TODO(quentin): Should we just make the App itself be an http.Handler?
 	mux.HandleFunc("/", a.index)
-	mux.HandleFunc("/upload", a.upload)
-	mux.HandleFunc("/search", a.search)
-	mux.HandleFunc("/uploads", a.uploads)
+	mux.HandleFunc("/upload", compressed(a.upload))
+	mux.HandleFunc("/search", compressed(a.search))
+	mux.HandleFunc("/uploads", compressed(a.uploads))
 }
 
 // index serves the readme on /
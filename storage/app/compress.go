@@ -0,0 +1,73 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressed wraps h to compress its responses with zstd for clients
+// that accept it, such as storage.Client, which cuts the transfer of
+// large query results by an order of magnitude.
+func compressed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsEncoding(r.Header.Get("Accept-Encoding"), "zstd") {
+			h(w, r)
+			return
+		}
+		zw, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Encoding", "zstd")
+		h(&zstdResponseWriter{w, zw}, r)
+		if err := zw.Close(); err != nil {
+			errorf(requestContext(r), "compressing response: %v", err)
+		}
+	}
+}
+
+// A zstdResponseWriter compresses the body of a response with zstd.
+type zstdResponseWriter struct {
+	http.ResponseWriter
+	zw *zstd.Encoder
+}
+
+func (w *zstdResponseWriter) Write(p []byte) (int, error) {
+	return w.zw.Write(p)
+}
+
+func (w *zstdResponseWriter) WriteHeader(code int) {
+	// The length of the compressed body is not known in advance.
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header value
+// header lists encoding with a nonzero quality.
+func acceptsEncoding(header, encoding string) bool {
+	for _, field := range strings.Split(header, ",") {
+		params := strings.Split(field, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), encoding) {
+			continue
+		}
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
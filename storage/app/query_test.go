@@ -12,8 +12,10 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/klauspost/compress/zstd"
 	"golang.org/x/perf/storage"
 	"golang.org/x/perf/storage/benchfmt"
 )
@@ -31,7 +33,7 @@ func TestQuery(t *testing.T) {
 	status := app.uploadFiles(t, func(mpw *multipart.Writer) {
 		w, err := mpw.CreateFormFile("file", "path/1.txt")
 		if err != nil {
-			t.Errorf("CreateFormFile: %v", err)
+			t.Fatalf("CreateFormFile: %v", err)
 		}
 		bp := benchfmt.NewPrinter(w)
 		for i := 0; i < 1024; i++ {
@@ -101,6 +103,54 @@ func TestQuery(t *testing.T) {
 	}
 }
 
+func TestQueryCompressed(t *testing.T) {
+	app := createTestApp(t)
+	defer app.Close()
+
+	app.uploadFiles(t, func(mpw *multipart.Writer) {
+		w, err := mpw.CreateFormFile("file", "path/1.txt")
+		if err != nil {
+			t.Fatalf("CreateFormFile: %v", err)
+		}
+		fmt.Fprintf(w, "key: value\nBenchmarkName 1 ns/op\n")
+	})
+
+	for _, accept := range []string{"", "gzip", "gzip, zstd;q=0", "gzip, zstd"} {
+		req, err := http.NewRequest("GET", app.srv.URL+"/search?q=key:value", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body io.Reader = resp.Body
+		enc := resp.Header.Get("Content-Encoding")
+		if wantEnc := map[bool]string{true: "zstd"}[strings.HasSuffix(accept, "zstd")]; enc != wantEnc {
+			t.Errorf("Accept-Encoding %q: Content-Encoding = %q, want %q", accept, enc, wantEnc)
+		}
+		if enc == "zstd" {
+			d, err := zstd.NewReader(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer d.Close()
+			body = d
+		}
+		br := benchfmt.NewReader(body)
+		if !br.Next() {
+			t.Fatalf("Accept-Encoding %q: Next() = false, want true (Err() = %v)", accept, br.Err())
+		}
+		if have, want := br.Result().Content, "BenchmarkName 1 ns/op"; have != want {
+			t.Errorf("Accept-Encoding %q: Content = %q, want %q", accept, have, want)
+		}
+	}
+}
+
 func TestUploads(t *testing.T) {
 	app := createTestApp(t)
 	defer app.Close()
@@ -113,7 +163,7 @@ func TestUploads(t *testing.T) {
 		status := app.uploadFiles(t, func(mpw *multipart.Writer) {
 			w, err := mpw.CreateFormFile("file", "path/1.txt")
 			if err != nil {
-				t.Errorf("CreateFormFile: %v", err)
+				t.Fatalf("CreateFormFile: %v", err)
 			}
 			bp := benchfmt.NewPrinter(w)
 			for j := 0; j <= i; j++ {
@@ -5,10 +5,12 @@
 package app
 
 import (
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -17,6 +19,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"golang.org/x/net/context"
 	"golang.org/x/perf/storage/benchfmt"
 	"golang.org/x/perf/storage/db"
@@ -47,6 +50,16 @@ func (a *App) upload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Clients may compress the whole request body.
+	body, err := decodeContent(r.Body, r.Header.Get("Content-Encoding"))
+	if err != nil {
+		errorf(ctx, "%v", err)
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+	defer body.Close()
+	r.Body = body
+
 	// We use r.MultipartReader instead of r.ParseForm to avoid
 	// storing uploaded data in memory.
 	mr, err := r.MultipartReader()
@@ -71,6 +84,44 @@ func (a *App) upload(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// decoders maps each supported Content-Encoding to a function
+// returning a reader of the decoded content.
+var decoders = map[string]func(io.Reader) (io.ReadCloser, error){
+	"gzip": func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	"zstd": func(r io.Reader) (io.ReadCloser, error) {
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+}
+
+// decodeContent returns a reader of the content of r, which has
+// been encoded with encoding, a Content-Encoding header value.
+func decodeContent(r io.Reader, encoding string) (io.ReadCloser, error) {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if encoding == "" || encoding == "identity" {
+		return ioutil.NopCloser(r), nil
+	}
+	dec := decoders[encoding]
+	if dec == nil {
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+	}
+	return dec(r)
+}
+
+// NewStoredReader returns a reader of the content of the file name, as
+// stored by the server and read from r. Files stored since the server
+// began compressing them with zstd have the suffix .txt.zst; earlier
+// ones, with the suffix .txt, are read as they are.
+func NewStoredReader(name string, r io.Reader) (io.ReadCloser, error) {
+	if strings.HasSuffix(name, ".zst") {
+		return decodeContent(r, "zstd")
+	}
+	return ioutil.NopCloser(r), nil
+}
+
 // uploadStatus is the response to an /upload POST served as JSON.
 type uploadStatus struct {
 	// UploadID is the upload ID assigned to the upload.
@@ -146,7 +197,14 @@ func (a *App) processUpload(ctx context.Context, user string, mr *multipart.Read
 		// AND if anything fails, attempt to clean up both the
 		// FS and the index records.
 
-		if err := a.indexFile(ctx, upload, p, meta); err != nil {
+		// Individual files may also be compressed.
+		content, err := decodeContent(p, p.Header.Get("Content-Encoding"))
+		if err != nil {
+			return nil, err
+		}
+		err = a.indexFile(ctx, upload, content, meta)
+		content.Close()
+		if err != nil {
 			return nil, err
 		}
 
@@ -170,8 +228,10 @@ func (a *App) processUpload(ctx context.Context, user string, mr *multipart.Read
 	return status, nil
 }
 
+// indexFile stores the file p, compressed with zstd, and indexes its
+// content.
 func (a *App) indexFile(ctx context.Context, upload *db.Upload, p io.Reader, meta map[string]string) (err error) {
-	path := fmt.Sprintf("uploads/%s.txt", meta["upload-part"])
+	path := fmt.Sprintf("uploads/%s.txt.zst", meta["upload-part"])
 	fw, err := a.FS.NewWriter(ctx, path, meta)
 	if err != nil {
 		return err
@@ -185,21 +245,30 @@ func (a *App) indexFile(ctx context.Context, upload *db.Upload, p io.Reader, met
 		}
 		infof(ctx, "Close(%q) took %.2f seconds", path, time.Since(start).Seconds())
 	}()
+	zw, err := zstd.NewWriter(fw, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+	}()
 	var keys []string
 	for k := range meta {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 	for _, k := range keys {
-		if _, err := fmt.Fprintf(fw, "%s: %s\n", k, meta[k]); err != nil {
+		if _, err := fmt.Fprintf(zw, "%s: %s\n", k, meta[k]); err != nil {
 			return err
 		}
 	}
 	// Write a blank line to separate metadata from user-generated content.
-	fmt.Fprintf(fw, "\n")
+	fmt.Fprintf(zw, "\n")
 
 	// TODO(quentin): Add a separate goroutine and buffer for writes to fw?
-	tr := io.TeeReader(p, fw)
+	tr := io.TeeReader(p, zw)
 	br := benchfmt.NewReader(tr)
 	br.AddLabels(meta)
 	i := 0
@@ -5,6 +5,8 @@
 package app
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,10 +14,14 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/perf/storage/benchfmt"
 	"golang.org/x/perf/storage/db"
 	"golang.org/x/perf/storage/db/dbtest"
 	_ "golang.org/x/perf/storage/db/sqlite3"
@@ -102,7 +108,7 @@ func TestUpload(t *testing.T) {
 	status := app.uploadFiles(t, func(mpw *multipart.Writer) {
 		w, err := mpw.CreateFormFile("file", "1.txt")
 		if err != nil {
-			t.Errorf("CreateFormFile: %v", err)
+			t.Fatalf("CreateFormFile: %v", err)
 		}
 		fmt.Fprintf(w, "key: value\nBenchmarkOne 5 ns/op\nkey:value2\nBenchmarkTwo 10 ns/op\n")
 	})
@@ -120,4 +126,83 @@ func TestUpload(t *testing.T) {
 	if len(app.fs.Files()) != 1 {
 		t.Errorf("/upload wrote %d files, want 1", len(app.fs.Files()))
 	}
+
+	// The file is stored compressed with zstd.
+	name := "uploads/" + wantID + "/0.txt.zst"
+	r, err := NewStoredReader(name, bytes.NewReader(app.fs.Content(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decompressing %s: %v", name, err)
+	}
+	if want := "BenchmarkTwo 10 ns/op\n"; !strings.HasSuffix(string(data), want) {
+		t.Errorf("%s = %q, want a file ending in %q", name, data, want)
+	}
+}
+
+func TestNewStoredReaderUncompressed(t *testing.T) {
+	// Files stored before uploads were compressed are read as they are.
+	const stored = "by: user\nupload: 20170101.1\nupload-part: 20170101.1/0\n\nkey: value\nBenchmarkOne 5 ns/op\n"
+	r, err := NewStoredReader("uploads/20170101.1/0.txt", strings.NewReader(stored))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	br := benchfmt.NewReader(r)
+	var results []string
+	for br.Next() {
+		res := br.Result()
+		results = append(results, res.Labels["upload-part"]+" "+res.Labels["key"]+" "+res.Content)
+	}
+	if err := br.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"20170101.1/0 value BenchmarkOne 5 ns/op"}; !reflect.DeepEqual(results, want) {
+		t.Errorf("read %q, want %q", results, want)
+	}
+}
+
+func TestUploadCompressed(t *testing.T) {
+	for _, tt := range []struct {
+		encoding string
+		compress func(io.Writer) io.WriteCloser
+	}{
+		{"gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
+		{"zstd", func(w io.Writer) io.WriteCloser {
+			zw, err := zstd.NewWriter(w)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return zw
+		}},
+	} {
+		t.Run(tt.encoding, func(t *testing.T) {
+			app := createTestApp(t)
+			defer app.Close()
+
+			status := app.uploadFiles(t, func(mpw *multipart.Writer) {
+				h := make(textproto.MIMEHeader)
+				h.Set("Content-Disposition", `form-data; name="file"; filename="1.txt"`)
+				h.Set("Content-Encoding", tt.encoding)
+				w, err := mpw.CreatePart(h)
+				if err != nil {
+					t.Fatalf("CreatePart: %v", err)
+				}
+				zw := tt.compress(w)
+				fmt.Fprintf(zw, "key: value\nBenchmarkOne 5 ns/op\n")
+				if err := zw.Close(); err != nil {
+					t.Errorf("%s Close: %v", tt.encoding, err)
+				}
+			})
+
+			// The upload is rejected unless the content was decompressed,
+			// since it would contain no valid benchmark lines.
+			if len(status.FileIDs) != 1 {
+				t.Errorf("fileids = %v, want 1 file", status.FileIDs)
+			}
+		})
+	}
 }
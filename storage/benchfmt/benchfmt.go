@@ -16,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Reader reads benchmark results from an io.Reader.
@@ -35,9 +36,11 @@ type Reader struct {
 	// file or provided by AddLabels. They cannot be overridden.
 	permLabels Labels
 	lineNum    int
-	// cached from last call to newResult, to save on allocations
-	lastName       string
-	lastNameLabels Labels
+	// units holds metadata read from "Unit" lines
+	units map[string]Labels
+	// nameLabels caches the parsed labels of recently seen
+	// benchmark names, to save on allocations
+	nameLabels map[string]Labels
 	// cached from the last call to Next
 	result *Result
 	err    error
@@ -203,15 +206,23 @@ func (r *Reader) newResult(labels Labels, lineNum int, name, content string) *Re
 		LineNum: lineNum,
 		Content: content,
 	}
-	if r.lastName != name {
-		r.lastName = name
-		r.lastNameLabels = make(Labels)
-		parseNameLabels(name, r.lastNameLabels)
+	labels, ok := r.nameLabels[name]
+	if !ok {
+		if r.nameLabels == nil || len(r.nameLabels) >= maxCachedNames {
+			r.nameLabels = make(map[string]Labels)
+		}
+		labels = make(Labels)
+		parseNameLabels(name, labels)
+		r.nameLabels[name] = labels
 	}
-	res.NameLabels = r.lastNameLabels
+	res.NameLabels = labels
 	return res
 }
 
+// maxCachedNames bounds the number of benchmark names whose parsed
+// labels are cached by a Reader.
+const maxCachedNames = 1024
+
 // Copy returns a new copy of the labels map, to protect against
 // future modifications to labels.
 func (l Labels) Copy() Labels {
@@ -234,11 +245,14 @@ func (r *Reader) Next() bool {
 	havePerm := r.permLabels != nil
 	for r.s.Scan() {
 		r.lineNum++
-		line := r.s.Text()
-		if key, value, ok := parseKeyValueLine(line); ok {
-			if _, ok := r.permLabels[key]; ok {
+		// Work on the scanner's buffer directly and only
+		// allocate strings for the parts of the line we keep.
+		line := r.s.Bytes()
+		if k, v, ok := parseKeyValueLine(line); ok {
+			if _, ok := r.permLabels[string(k)]; ok {
 				continue
 			}
+			key, value := string(k), string(v)
 			if !copied {
 				copied = true
 				r.labels = r.labels.Copy()
@@ -253,16 +267,24 @@ func (r *Reader) Next() bool {
 			}
 			continue
 		}
+		if bytes.HasPrefix(line, unitPrefix) {
+			r.parseUnitLine(string(line[len(unitPrefix):]))
+			continue
+		}
 		// Blank line delimits the header. If we find anything else, the file must not have a header.
 		if !havePerm {
-			if line == "" {
+			if len(line) == 0 {
 				r.permLabels = r.labels.Copy()
 			} else {
 				r.permLabels = Labels{}
 			}
 		}
-		if fullName, ok := parseBenchmarkLine(line); ok {
-			r.result = r.newResult(r.labels, r.lineNum, fullName, line)
+		if !bytes.HasPrefix(line, benchmarkPrefix) {
+			continue
+		}
+		content := string(line)
+		if fullName, ok := parseBenchmarkLine(content); ok {
+			r.result = r.newResult(r.labels, r.lineNum, fullName, content)
 			return true
 		}
 	}
@@ -274,6 +296,43 @@ func (r *Reader) Next() bool {
 	return false
 }
 
+// UnitMetadata returns the unit metadata read so far, keyed by unit.
+// Unit metadata lines have the form
+//
+//	Unit <unit> <key>=<value> [<key>=<value>...]
+//
+// and describe how values of that unit should be interpreted, for
+// example "Unit MB/s better=higher". Later lines for the same unit
+// and key override earlier ones. The returned map must not be modified.
+func (r *Reader) UnitMetadata() map[string]Labels {
+	return r.units
+}
+
+var unitPrefix = []byte("Unit ")
+
+// parseUnitLine records the metadata in line, a unit metadata line
+// with the "Unit " prefix removed. Malformed fields are ignored.
+func (r *Reader) parseUnitLine(line string) {
+	f := strings.Fields(line)
+	if len(f) < 2 {
+		return
+	}
+	unit := f[0]
+	if r.units == nil {
+		r.units = make(map[string]Labels)
+	}
+	meta := r.units[unit]
+	if meta == nil {
+		meta = make(Labels)
+		r.units[unit] = meta
+	}
+	for _, kv := range f[1:] {
+		if i := strings.Index(kv, "="); i > 0 {
+			meta[kv[:i]] = kv[i+1:]
+		}
+	}
+}
+
 // Result returns the most recent result generated by a call to Next.
 func (r *Reader) Result() *Result {
 	return r.result
@@ -289,8 +348,9 @@ func (r *Reader) Err() error {
 
 // parseKeyValueLine attempts to parse line as a key: value pair. ok
 // indicates whether the line could be parsed.
-func parseKeyValueLine(line string) (key, val string, ok bool) {
-	for i, c := range line {
+func parseKeyValueLine(line []byte) (key, val []byte, ok bool) {
+	for i := 0; i < len(line); {
+		c, size := utf8.DecodeRune(line[i:])
 		if i == 0 && !unicode.IsLower(c) {
 			return
 		}
@@ -302,11 +362,12 @@ func parseKeyValueLine(line string) (key, val string, ok bool) {
 			val = line[i+1:]
 			break
 		}
+		i += size
 	}
-	if key == "" {
+	if len(key) == 0 {
 		return
 	}
-	if val == "" {
+	if len(val) == 0 {
 		ok = true
 		return
 	}
@@ -317,6 +378,8 @@ func parseKeyValueLine(line string) (key, val string, ok bool) {
 	return
 }
 
+var benchmarkPrefix = []byte("Benchmark")
+
 // parseBenchmarkLine attempts to parse line as a benchmark result. If
 // successful, fullName is the name of the benchmark with the
 // "Benchmark" prefix stripped, and ok is true.
@@ -122,6 +122,26 @@ BenchmarkOne 1 ns/sec
 	}
 }
 
+func TestUnitMetadata(t *testing.T) {
+	r := NewReader(strings.NewReader(`Unit MB/s better=higher
+Unit items/op better=higher assume=exact
+BenchmarkOne 1 5 MB/s 10 items/op
+Unit items/op better=lower
+Unit bogus
+`))
+	results := readAllResults(t, r)
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	want := map[string]Labels{
+		"MB/s":     {"better": "higher"},
+		"items/op": {"better": "lower", "assume": "exact"},
+	}
+	if got := r.UnitMetadata(); !reflect.DeepEqual(got, want) {
+		t.Errorf("UnitMetadata() = %v, want %v", got, want)
+	}
+}
+
 func TestBenchmarkPrinter(t *testing.T) {
 	tests := []struct {
 		name, input, want string
@@ -181,3 +201,26 @@ BenchmarkOne 1 ns/sec
 		})
 	}
 }
+
+func BenchmarkReader(b *testing.B) {
+	var buf bytes.Buffer
+	buf.WriteString("goos: linux\ngoarch: amd64\npkg: example.com/pkg\n\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&buf, "BenchmarkOne/size=%d-8\t1000\t%d ns/op\t%d B/op\t2 allocs/op\n", i%10, 1000+i, 64+i%3)
+		if i%100 == 0 {
+			fmt.Fprintf(&buf, "commit: %d\n", i)
+		}
+	}
+	data := buf.Bytes()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		br := NewReader(bytes.NewReader(data))
+		for br.Next() {
+		}
+		if err := br.Err(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
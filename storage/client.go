@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/klauspost/compress/zstd"
 	"golang.org/x/net/context"
 	"golang.org/x/net/context/ctxhttp"
 	"golang.org/x/perf/storage/benchfmt"
@@ -36,6 +37,46 @@ func (c *Client) httpClient() *http.Client {
 	return http.DefaultClient
 }
 
+// get issues a GET request for u, asking for a response compressed
+// with zstd, and returns the response with its body decompressed.
+func get(ctx context.Context, hc *http.Client, u string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept-Encoding", "zstd")
+	resp, err := ctxhttp.Do(ctx, hc, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Header.Get("Content-Encoding") == "zstd" {
+		d, err := zstd.NewReader(resp.Body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		resp.Body = &zstdBody{d, resp.Body}
+		resp.Header.Del("Content-Encoding")
+		resp.ContentLength = -1
+	}
+	return resp, nil
+}
+
+// A zstdBody decompresses a response body compressed with zstd.
+type zstdBody struct {
+	d    *zstd.Decoder
+	body io.ReadCloser
+}
+
+func (b *zstdBody) Read(p []byte) (int, error) {
+	return b.d.Read(p)
+}
+
+func (b *zstdBody) Close() error {
+	b.d.Close()
+	return b.body.Close()
+}
+
 // Query searches for results matching the given query string.
 //
 // The query string is first parsed into quoted words (as in the shell)
@@ -46,7 +87,7 @@ func (c *Client) httpClient() *http.Client {
 func (c *Client) Query(ctx context.Context, q string) *Query {
 	hc := c.httpClient()
 
-	resp, err := ctxhttp.Get(ctx, hc, c.BaseURL+"/search?"+url.Values{"q": []string{q}}.Encode())
+	resp, err := get(ctx, hc, c.BaseURL+"/search?"+url.Values{"q": []string{q}}.Encode())
 	if err != nil {
 		return &Query{err: err}
 	}
@@ -141,7 +182,7 @@ func (c *Client) ListUploads(ctx context.Context, q string, extraLabels []string
 	if len(v) > 0 {
 		u += "?" + v.Encode()
 	}
-	resp, err := ctxhttp.Get(ctx, hc, u)
+	resp, err := get(ctx, hc, u)
 	if err != nil {
 		return &UploadList{err: err}
 	}
@@ -24,6 +24,7 @@ import (
 
 	"cloud.google.com/go/storage"
 	_ "github.com/GoogleCloudPlatform/cloudsql-proxy/proxy/dialers/mysql"
+	"golang.org/x/perf/storage/app"
 	"golang.org/x/perf/storage/benchfmt"
 	"golang.org/x/perf/storage/db"
 	"google.golang.org/api/iterator"
@@ -131,7 +132,12 @@ func reindexOne(ctx context.Context, u *db.Upload, bucket *storage.BucketHandle,
 		return err
 	}
 	defer r.Close()
-	br := benchfmt.NewReader(r)
+	in, err := app.NewStoredReader(name, r)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	br := benchfmt.NewReader(in)
 	for br.Next() {
 		if err := u.InsertRecord(br.Result()); err != nil {
 			return err
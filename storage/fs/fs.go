@@ -66,6 +66,17 @@ func (fs *MemFS) Files() []string {
 	return files
 }
 
+// Content returns the content of the file name written to fs, or nil
+// if there is no such file.
+func (fs *MemFS) Content(name string) []byte {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if f := fs.content[name]; f != nil {
+		return f.content
+	}
+	return nil
+}
+
 // memFile represents a file in a MemFS. While the file is being
 // written, fs points to the filesystem. Close writes the file's
 // content to fs and sets fs to nil.